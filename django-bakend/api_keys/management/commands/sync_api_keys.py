@@ -0,0 +1,25 @@
+"""Backfill / reconcile the Redis copy of API keys used by the Go API.
+
+Writes every valid key and removes revoked / expired ones. Safe to re-run.
+"""
+from django.core.management.base import BaseCommand, CommandError
+
+from api_keys.models import APIKey
+from api_keys.signals import sync_key
+from clients.signals import _get_redis
+
+
+class Command(BaseCommand):
+    help = "Sync API keys (hash, tenant, scopes, rate limit) into Redis for the Go API."
+
+    def handle(self, *args, **opts):
+        rdb = _get_redis()
+        if rdb is None:
+            raise CommandError("Redis unavailable: set REDIS_URL")
+        synced = failed = 0
+        for key in APIKey.objects.iterator():
+            if sync_key(key, rdb):
+                synced += 1
+            else:
+                failed += 1
+        self.stdout.write(self.style.SUCCESS(f"synced={synced} failed={failed}"))
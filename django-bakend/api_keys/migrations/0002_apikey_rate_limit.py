@@ -0,0 +1,15 @@
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+    dependencies = [
+        ("api_keys", "0001_initial"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="apikey",
+            name="rate_limit",
+            field=models.PositiveIntegerField(blank=True, null=True),
+        ),
+    ]
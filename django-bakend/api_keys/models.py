@@ -23,6 +23,8 @@ class APIKey(models.Model):
     prefix = models.CharField(max_length=8, editable=False)
     key_hash = models.CharField(max_length=64, editable=False)
     scopes = models.JSONField(default=list)
+    # Limită per cheie în API-ul Go (req/min); null → APIKEY_RATE_LIMIT din go-iot-platform.
+    rate_limit = models.PositiveIntegerField(null=True, blank=True)
     expires_at = models.DateTimeField(null=True, blank=True)
     last_used_at = models.DateTimeField(null=True, blank=True)
     revoked = models.BooleanField(default=False)
@@ -32,7 +34,7 @@ class APIKey(models.Model):
         ordering = ["-created_at"]
 
     @classmethod
-    def generate(cls, tenant, name, created_by=None, scopes=None, expires_at=None):
+    def generate(cls, tenant, name, created_by=None, scopes=None, expires_at=None, rate_limit=None):
         """Create a new APIKey and return (instance, plain_key).
 
         The plain_key is returned only once and never stored.
@@ -48,6 +50,7 @@ class APIKey(models.Model):
             key_hash=key_hash,
             scopes=scopes or [],
             expires_at=expires_at,
+            rate_limit=rate_limit,
         )
         return instance, plain
 
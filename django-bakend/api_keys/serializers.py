@@ -12,7 +12,7 @@ class APIKeySerializer(serializers.ModelSerializer):
     class Meta:
         model = APIKey
         fields = [
-            "id", "name", "prefix", "scopes",
+            "id", "name", "prefix", "scopes", "rate_limit",
             "expires_at", "last_used_at", "revoked",
             "created_at", "created_by_username",
         ]
@@ -26,6 +26,7 @@ class APIKeyCreateSerializer(serializers.Serializer):
     """Input for creating a new key. Returns the plain key once."""
     name = serializers.CharField(max_length=100)
     scopes = serializers.ListField(child=serializers.CharField(), default=list)
+    rate_limit = serializers.IntegerField(required=False, allow_null=True, min_value=1)
     expires_at = serializers.DateTimeField(required=False, allow_null=True)
 
 
@@ -0,0 +1,72 @@
+"""Sincronizează cheile API în Redis pentru API-ul Go (header X-API-Key).
+
+Go nu are acces la MySQL: verifică `apikey:{sha256}` în Redis. Cheia există doar
+cât timp APIKey e validă — revocarea / ștergerea fac DEL, iar expires_at devine
+TTL-ul Redis. Backfill pentru chei create înainte: `manage.py sync_api_keys`.
+"""
+import json
+import logging
+
+from django.db.models.signals import post_delete, post_save
+from django.dispatch import receiver
+from django.utils import timezone
+
+from clients.signals import _get_redis
+
+from .models import APIKey
+
+logger = logging.getLogger(__name__)
+
+REDIS_PREFIX = "apikey:"
+
+
+def redis_key(key_hash: str) -> str:
+    return REDIS_PREFIX + key_hash
+
+
+def record(key: APIKey) -> dict:
+    """Payload-ul citit de go-iot-platform/internal/cache/apikeys.go."""
+    return {
+        "id": key.id,
+        "tenant_id": key.tenant_id,
+        "name": key.name,
+        "prefix": key.prefix,
+        "scopes": key.scopes or [],
+        "rate_limit": key.rate_limit or 0,
+        "expires_at": key.expires_at.isoformat() if key.expires_at else None,
+    }
+
+
+def sync_key(key: APIKey, rdb=None) -> bool:
+    """Scrie / șterge cheia în Redis. Întoarce False dacă Redis lipsește sau a eșuat."""
+    rdb = rdb or _get_redis()
+    if rdb is None:
+        return False
+    try:
+        if not key.is_valid():
+            rdb.delete(redis_key(key.key_hash))
+            return True
+        ttl = None
+        if key.expires_at:
+            ttl = max(int((key.expires_at - timezone.now()).total_seconds()), 1)
+        rdb.set(redis_key(key.key_hash), json.dumps(record(key)), ex=ttl)
+        return True
+    except Exception as e:
+        logger.warning("sync API key %s în Redis eșuat: %s", key.prefix, e)
+        return False
+
+
+@receiver(post_save, sender=APIKey)
+def _on_key_save(sender, instance, **kwargs):
+    sync_key(instance)
+
+
+@receiver(post_delete, sender=APIKey)
+def _on_key_delete(sender, instance, **kwargs):
+    rdb = _get_redis()
+    if rdb is None:
+        return
+    try:
+        rdb.delete(redis_key(instance.key_hash))
+    except Exception as e:
+        logger.warning("ștergere API key %s din Redis eșuată: %s", instance.prefix, e)
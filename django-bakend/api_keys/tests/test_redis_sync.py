@@ -0,0 +1,60 @@
+"""API keys are mirrored into Redis (apikey:{sha256}) for the Go API's X-API-Key check."""
+import json
+from datetime import timedelta
+from unittest.mock import patch
+
+import pytest
+from django.utils import timezone
+
+from api_keys.models import APIKey
+from api_keys.signals import redis_key
+from tenants.models import Tenant
+
+
+class FakeRedis:
+    def __init__(self):
+        self.data, self.ttl = {}, {}
+
+    def set(self, key, value, ex=None):
+        self.data[key], self.ttl[key] = value, ex
+
+    def delete(self, key):
+        self.data.pop(key, None)
+
+
+@pytest.fixture
+def rdb():
+    fake = FakeRedis()
+    with patch("api_keys.signals._get_redis", return_value=fake):
+        yield fake
+
+
+@pytest.fixture
+def tenant(db):
+    return Tenant.objects.create(name="SyncCo", slug="syncco")
+
+
+def test_create_writes_record(rdb, tenant):
+    key, plain = APIKey.generate(tenant=tenant, name="scada", scopes=["metrics:read"], rate_limit=120)
+    rec = json.loads(rdb.data[redis_key(APIKey.hash_key(plain))])
+    assert rec == {
+        "id": key.id, "tenant_id": tenant.id, "name": "scada", "prefix": plain[:8],
+        "scopes": ["metrics:read"], "rate_limit": 120, "expires_at": None,
+    }
+    assert rdb.ttl[redis_key(key.key_hash)] is None
+
+
+def test_expiry_becomes_ttl(rdb, tenant):
+    key, _ = APIKey.generate(tenant=tenant, name="bms", expires_at=timezone.now() + timedelta(hours=1))
+    assert 3500 < rdb.ttl[redis_key(key.key_hash)] <= 3600
+
+
+def test_revoke_and_delete_remove_record(rdb, tenant):
+    key, _ = APIKey.generate(tenant=tenant, name="a")
+    key.revoked = True
+    key.save(update_fields=["revoked"])
+    assert redis_key(key.key_hash) not in rdb.data
+
+    other, _ = APIKey.generate(tenant=tenant, name="b")
+    other.delete()
+    assert redis_key(other.key_hash) not in rdb.data
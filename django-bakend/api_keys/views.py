@@ -49,6 +49,7 @@ class APIKeyListCreateView(generics.GenericAPIView):
             created_by=request.user if request.user.is_authenticated else None,
             scopes=ser.validated_data.get("scopes", []),
             expires_at=ser.validated_data.get("expires_at"),
+            rate_limit=ser.validated_data.get("rate_limit"),
         )
         data = APIKeyCreateResponseSerializer(key).data
         data["plain_key"] = plain
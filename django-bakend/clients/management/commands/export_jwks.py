@@ -0,0 +1,55 @@
+"""Print the JWKS (public keys) the Go API uses to verify RS256/ES256 tokens.
+
+Rotation: generate the new key, export a set with both keys
+(`--key new.pem --key old.pem`), deploy it to JWT_JWKS, then switch
+JWT_PRIVATE_KEY_FILE. The old key can be dropped once its tokens expired.
+"""
+import base64
+import hashlib
+import json
+
+from django.conf import settings
+from django.core.management.base import BaseCommand, CommandError
+from jwt.algorithms import ECAlgorithm, RSAAlgorithm
+from cryptography.hazmat.primitives import serialization
+from cryptography.hazmat.primitives.asymmetric import ec, rsa
+
+
+def _thumbprint(jwk):
+    """RFC 7638 kid: sha256 over the required members, sorted, no whitespace."""
+    required = ("e", "kty", "n") if jwk["kty"] == "RSA" else ("crv", "kty", "x", "y")
+    canonical = json.dumps({k: jwk[k] for k in required}, separators=(",", ":"), sort_keys=True)
+    return base64.urlsafe_b64encode(hashlib.sha256(canonical.encode()).digest()).rstrip(b"=").decode()
+
+
+def public_jwk(pem):
+    key = serialization.load_pem_private_key(pem, password=None).public_key()
+    if isinstance(key, rsa.RSAPublicKey):
+        jwk, alg = RSAAlgorithm.to_jwk(key, as_dict=True), "RS256"
+    elif isinstance(key, ec.EllipticCurvePublicKey):
+        jwk = ECAlgorithm.to_jwk(key, as_dict=True)
+        alg = {"P-256": "ES256", "P-384": "ES384", "P-521": "ES512"}[jwk["crv"]]
+    else:
+        raise CommandError("unsupported key type (RSA or EC only)")
+    jwk.update({"use": "sig", "alg": alg, "kid": _thumbprint(jwk)})
+    return jwk
+
+
+class Command(BaseCommand):
+    help = "Export the JWT verification keys as a JWKS document."
+
+    def add_arguments(self, parser):
+        parser.add_argument(
+            "--key", action="append", dest="keys",
+            help="private key PEM (repeatable; default JWT_PRIVATE_KEY_FILE)",
+        )
+
+    def handle(self, *args, **opts):
+        paths = opts["keys"] or [settings.JWT_PRIVATE_KEY_FILE]
+        if not paths or not paths[0]:
+            raise CommandError("no key: pass --key or set JWT_PRIVATE_KEY_FILE")
+        keys = []
+        for path in paths:
+            with open(path, "rb") as fh:
+                keys.append(public_jwk(fh.read()))
+        self.stdout.write(json.dumps({"keys": keys}, indent=2))
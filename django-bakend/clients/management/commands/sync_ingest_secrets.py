@@ -0,0 +1,27 @@
+"""Backfill / reconcile the Redis copy of device ingest secrets used by POST /go/ingest
+and of the CoAP token hashes used by the CoAP listener.
+
+Writes every device with an ingest_secret / coap_token_hash and removes the keys for
+devices without one.
+Safe to re-run.
+"""
+from django.core.management.base import BaseCommand, CommandError
+
+from clients.models import Device
+from clients.signals import _get_redis, sync_ingest_secret
+
+
+class Command(BaseCommand):
+    help = "Sync device ingest secrets (HMAC, tenant) and CoAP token hashes into Redis for the Go ingest API."
+
+    def handle(self, *args, **opts):
+        rdb = _get_redis()
+        if rdb is None:
+            raise CommandError("Redis unavailable: set REDIS_URL")
+        synced = failed = 0
+        for device in Device.objects.iterator():
+            if sync_ingest_secret(device, rdb):
+                synced += 1
+            else:
+                failed += 1
+        self.stdout.write(self.style.SUCCESS(f"synced={synced} failed={failed}"))
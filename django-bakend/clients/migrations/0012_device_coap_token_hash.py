@@ -0,0 +1,18 @@
+# Generated by Django 5.2.4 on 2026-10-19 12:00
+
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ("clients", "0011_device_ingest_secret"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="device",
+            name="coap_token_hash",
+            field=models.CharField(blank=True, max_length=64),
+        ),
+    ]
@@ -32,6 +32,12 @@ class Device(models.Model):
     device_type = models.CharField(max_length=20, choices=DEVICE_CHOICES)
     # Faza 3.1: hash BCrypt al parolei MQTT per-device. Gol = auth fără parolă (compat).
     mqtt_password_hash = models.CharField(max_length=128, blank=True)
+    # Secret HMAC pentru POST /go/ingest (device-uri fără MQTT). Ținut în clar:
+    # Go trebuie să recalculeze semnătura. Gol = ingest HMAC dezactivat.
+    ingest_secret = models.CharField(max_length=64, blank=True)
+    # SHA-256 (hex) al token-ului CoAP (?t=, circulă în clar pe UDP) — separat de
+    # ingest_secret ca o captură să nu permită semnarea HMAC. Gol = CoAP oprit.
+    coap_token_hash = models.CharField(max_length=64, blank=True)
 
     objects = TenantQuerySet.as_manager()
 
@@ -1,19 +1,30 @@
 """Publish device-cache-invalidate notifications to Redis when a Device changes.
 
-Format mesaj: {"serial": "<serial_number>"}
+Format mesaj: {"serial": "<serial_number>", "tenant_id": <id>}
 Special: "" payload = invalidate-all (re-fetch).
+`tenant_id` e folosit de cache-ul de autorizare din Go (internal/cache.AuthzCache)
+ca să invalideze doar tenantul device-ului; lipsa lui → invalidare completă.
+
+Un device mutat în alt tenant publică pentru ambii tenanți (tenantul vechi e citit
+în pre_save), altfel userii tenantului vechi îl mai văd până la expirarea cache-ului.
 
 Go-ul (internal/cache.SubscribeInvalidations) ascultă pe canalul `device-cache-invalidate`
 și șterge entry-ul din Redis pe save/delete → propagare <1s a schimbărilor de tenant.
 
 Faza 2.4. Redis e opțional: dacă REDIS_URL nu e setat, semnalele devin no-op (logging la
 debug nivel).
+
+Tot aici: `ingestsecret:{serial}` → {"tenant_id", "secret"} pentru verificarea HMAC din
+POST /go/ingest (go-iot-platform/internal/cache/ingestsecrets.go). Cheia există doar cât
+timp device-ul are ingest_secret. La fel `coaptoken:{serial}` → {"tenant_id", "token_sha256"}
+pentru listener-ul CoAP (internal/cache/coaptokens.go), cât timp device-ul are
+coap_token_hash. Backfill (ambele): `manage.py sync_ingest_secrets`.
 """
 import json
 import logging
 
 from django.conf import settings
-from django.db.models.signals import post_delete, post_save
+from django.db.models.signals import post_delete, post_save, pre_save
 from django.dispatch import receiver
 
 from .models import Device
@@ -21,6 +32,8 @@ from .models import Device
 logger = logging.getLogger(__name__)
 
 INVALIDATE_CHANNEL = "device-cache-invalidate"
+INGEST_SECRET_PREFIX = "ingestsecret:"
+COAP_TOKEN_PREFIX = "coaptoken:"
 
 _redis_client = None
 
@@ -47,21 +60,71 @@ def _get_redis():
         return None
 
 
-def _publish(serial: str):
+def publish_json(channel: str, payload: dict):
+    """Publică payload-ul JSON pe un canal Redis; no-op fără Redis (folosit și de tenants.signals)."""
     rdb = _get_redis()
     if rdb is None:
         return
     try:
-        rdb.publish(INVALIDATE_CHANNEL, json.dumps({"serial": serial}))
+        rdb.publish(channel, json.dumps(payload))
+    except Exception as e:
+        logger.warning("publish pe %s eșuat (%s): %s", channel, payload, e)
+
+
+def _publish(serial: str, tenant_id=None):
+    payload = {"serial": serial}
+    if tenant_id is not None:
+        payload["tenant_id"] = tenant_id
+    publish_json(INVALIDATE_CHANNEL, payload)
+
+
+def sync_ingest_secret(device: Device, rdb=None) -> bool:
+    """Scrie / șterge secretul de ingest și token-ul CoAP în Redis. False dacă Redis lipsește sau a eșuat."""
+    rdb = rdb or _get_redis()
+    if rdb is None:
+        return False
+    key = INGEST_SECRET_PREFIX + device.serial_number
+    coap_key = COAP_TOKEN_PREFIX + device.serial_number
+    try:
+        if device.ingest_secret:
+            rdb.set(key, json.dumps({"tenant_id": device.tenant_id, "secret": device.ingest_secret}))
+        else:
+            rdb.delete(key)
+        if device.coap_token_hash:
+            rdb.set(coap_key, json.dumps({"tenant_id": device.tenant_id, "token_sha256": device.coap_token_hash}))
+        else:
+            rdb.delete(coap_key)
+        return True
     except Exception as e:
-        logger.warning("publish invalidation eșuat pentru %s: %s", serial, e)
+        logger.warning("sync ingest secret %s în Redis eșuat: %s", device.serial_number, e)
+        return False
+
+
+@receiver(pre_save, sender=Device)
+def _remember_old_tenant(sender, instance, **kwargs):
+    instance._old_tenant_id = None
+    if instance.pk:
+        instance._old_tenant_id = (
+            Device.objects.filter(pk=instance.pk).values_list("tenant_id", flat=True).first()
+        )
 
 
 @receiver(post_save, sender=Device)
 def _on_device_save(sender, instance, **kwargs):
-    _publish(instance.serial_number)
+    old_tenant_id = getattr(instance, "_old_tenant_id", None)
+    if old_tenant_id is not None and old_tenant_id != instance.tenant_id:
+        _publish(instance.serial_number, old_tenant_id)
+    _publish(instance.serial_number, instance.tenant_id)
+    sync_ingest_secret(instance)
 
 
 @receiver(post_delete, sender=Device)
 def _on_device_delete(sender, instance, **kwargs):
-    _publish(instance.serial_number)
+    _publish(instance.serial_number, instance.tenant_id)
+    rdb = _get_redis()
+    if rdb is None:
+        return
+    try:
+        rdb.delete(INGEST_SECRET_PREFIX + instance.serial_number, COAP_TOKEN_PREFIX + instance.serial_number)
+    except Exception as e:
+        logger.warning("ștergere ingest secret %s din Redis eșuată: %s", instance.serial_number, e)
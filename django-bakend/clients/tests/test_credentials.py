@@ -1,4 +1,5 @@
 """Tests for Faza 3.1 — Device credentials (mqtt_password_hash + rotate endpoint)."""
+import hashlib
 import json
 
 import pytest
@@ -135,3 +136,100 @@ def test_auth_no_hash_allows_any_password(http, device):
     # Fără hash, orice parolă e acceptată (device legacy)
     r = _auth(http, username="SHELF001", password="anything")
     assert r.json()["result"] == "allow"
+
+
+# ── ingest secret (HMAC pentru POST /go/ingest) ────────────────────────────────
+
+class FakeRedis:
+    def __init__(self):
+        self.data = {}
+
+    def set(self, key, value, ex=None):
+        self.data[key] = value
+
+    def delete(self, *keys):
+        for key in keys:
+            self.data.pop(key, None)
+
+    def publish(self, channel, message):
+        pass
+
+
+@pytest.fixture
+def rdb():
+    from unittest.mock import patch
+
+    fake = FakeRedis()
+    with patch("clients.signals._get_redis", return_value=fake):
+        yield fake
+
+
+def test_rotate_ingest_secret_syncs_redis(api, device, owner, tenant, rdb):
+    login(api, "alice", tenant_slug="acme")
+    r = api.post(f"/api/devices/{device.id}/ingest-secret/rotate/")
+    assert r.status_code == 200
+    secret = r.json()["ingest_secret"]
+    assert len(secret) == 64
+    assert json.loads(rdb.data["ingestsecret:SHELF001"]) == {"tenant_id": tenant.id, "secret": secret}
+
+    r = api.post(f"/api/devices/{device.id}/ingest-secret/rotate/", {"disable": True}, format="json")
+    assert r.json()["ingest_secret"] is None
+    assert "ingestsecret:SHELF001" not in rdb.data
+
+
+def test_rotate_ingest_secret_requires_owner_or_admin(api, device, viewer, tenant):
+    login(api, "viewer1", tenant_slug="acme")
+    r = api.post(f"/api/devices/{device.id}/ingest-secret/rotate/")
+    assert r.status_code == 403
+
+
+def test_ingest_secret_not_serialized(api, device, owner, tenant, rdb):
+    device.ingest_secret = "x" * 64
+    device.save()
+    login(api, "alice", tenant_slug="acme")
+    r = api.get(f"/api/devices/{device.id}/")
+    assert "ingest_secret" not in r.json()
+
+
+def test_delete_device_removes_ingest_secret(device, rdb):
+    device.ingest_secret = "s"
+    device.save()
+    assert "ingestsecret:SHELF001" in rdb.data
+    device.delete()
+    assert "ingestsecret:SHELF001" not in rdb.data
+
+
+# ── token CoAP (separat de ingest_secret) ──────────────────────────────────────
+
+def test_rotate_coap_token_syncs_hash_only(api, device, owner, tenant, rdb):
+    device.ingest_secret = "i" * 64
+    device.save()
+    login(api, "alice", tenant_slug="acme")
+    r = api.post(f"/api/devices/{device.id}/coap-token/rotate/")
+    assert r.status_code == 200
+    token = r.json()["coap_token"]
+    digest = hashlib.sha256(token.encode()).hexdigest()
+    assert json.loads(rdb.data["coaptoken:SHELF001"]) == {"tenant_id": tenant.id, "token_sha256": digest}
+    device.refresh_from_db()
+    assert device.coap_token_hash == digest
+    # rotirea token-ului CoAP nu atinge secretul HMAC
+    assert device.ingest_secret == "i" * 64
+    assert token not in rdb.data["ingestsecret:SHELF001"]
+
+    r = api.post(f"/api/devices/{device.id}/coap-token/rotate/", {"disable": True}, format="json")
+    assert r.json()["coap_token"] is None
+    assert "coaptoken:SHELF001" not in rdb.data
+
+
+def test_rotate_coap_token_requires_owner_or_admin(api, device, viewer, tenant):
+    login(api, "viewer1", tenant_slug="acme")
+    r = api.post(f"/api/devices/{device.id}/coap-token/rotate/")
+    assert r.status_code == 403
+
+
+def test_delete_device_removes_coap_token(device, rdb):
+    device.coap_token_hash = "a" * 64
+    device.save()
+    assert "coaptoken:SHELF001" in rdb.data
+    device.delete()
+    assert "coaptoken:SHELF001" not in rdb.data
@@ -63,3 +63,19 @@ def test_protect_on_tenant_delete(alice, acme):
     from django.db.models.deletion import ProtectedError
     with pytest.raises(ProtectedError):
         acme.delete()
+
+
+def test_moving_device_invalidates_both_tenants(alice, acme, globex):
+    """Userii tenantului vechi nu trebuie să mai vadă device-ul din cache-ul authz (Go)."""
+    from unittest.mock import patch
+
+    device = Device.objects.create(client=alice, tenant=acme, serial_number="MOVE-001", device_type="shelly_em")
+    with patch("clients.signals.publish_json") as publish:
+        device.tenant = globex
+        device.save()
+    tenants = [call.args[1].get("tenant_id") for call in publish.call_args_list]
+    assert tenants == [acme.id, globex.id]
+
+    with patch("clients.signals.publish_json") as publish:
+        device.save()
+    assert [call.args[1].get("tenant_id") for call in publish.call_args_list] == [globex.id]
@@ -0,0 +1,33 @@
+"""export_jwks: the JWKS the Go API loads (JWT_JWKS) verifies Django-signed tokens."""
+import json
+from io import StringIO
+
+import jwt
+from cryptography.hazmat.primitives import serialization
+from cryptography.hazmat.primitives.asymmetric import ec, rsa
+from django.core.management import call_command
+
+
+def _pem(key):
+    return key.private_bytes(
+        serialization.Encoding.PEM, serialization.PrivateFormat.PKCS8, serialization.NoEncryption()
+    )
+
+
+def test_export_rsa_and_ec(tmp_path):
+    rsa_key = rsa.generate_private_key(public_exponent=65537, key_size=2048)
+    ec_key = ec.generate_private_key(ec.SECP256R1())
+    (tmp_path / "rsa.pem").write_bytes(_pem(rsa_key))
+    (tmp_path / "ec.pem").write_bytes(_pem(ec_key))
+
+    out = StringIO()
+    call_command("export_jwks", "--key", str(tmp_path / "rsa.pem"), "--key", str(tmp_path / "ec.pem"), stdout=out)
+    keys = json.loads(out.getvalue())["keys"]
+
+    assert [k["alg"] for k in keys] == ["RS256", "ES256"]
+    assert all(k["use"] == "sig" and k["kid"] for k in keys)
+    assert "d" not in keys[0] and "d" not in keys[1]  # doar chei publice
+
+    token = jwt.encode({"username": "alice"}, _pem(rsa_key), algorithm="RS256")
+    public = jwt.PyJWK(keys[0]).key
+    assert jwt.decode(token, public, algorithms=["RS256"])["username"] == "alice"
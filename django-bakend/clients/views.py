@@ -1,3 +1,4 @@
+import hashlib
 import json
 import logging
 import secrets
@@ -136,6 +137,42 @@ class DeviceViewSet(viewsets.ModelViewSet):
         device.save(update_fields=["mqtt_password_hash"])
         return Response({"serial_number": device.serial_number, "mqtt_password": plain})
 
+    @action(detail=True, methods=["post"], url_path="ingest-secret/rotate")
+    def rotate_ingest_secret(self, request, pk=None):
+        """POST /api/devices/{id}/ingest-secret/rotate/ — secret HMAC nou pentru POST /go/ingest.
+
+        Body opțional {"disable": true} șterge secretul (ingest HMAC oprit pentru device).
+        Secretul e returnat o singură dată. Roluri permise: OWNER, ADMIN (și cross-tenant).
+        """
+        device = self.get_object()
+        role = getattr(request, "role", None)
+        if not _is_cross_tenant(request.user) and role not in {"OWNER", "ADMIN"}:
+            raise PermissionDenied("Only OWNER or ADMIN can rotate device credentials.")
+        disable = request.data.get("disable") is True
+        device.ingest_secret = "" if disable else secrets.token_hex(32)
+        # post_save sincronizează ingestsecret:{serial} în Redis
+        device.save(update_fields=["ingest_secret"])
+        return Response({"serial_number": device.serial_number, "ingest_secret": device.ingest_secret or None})
+
+    @action(detail=True, methods=["post"], url_path="coap-token/rotate")
+    def rotate_coap_token(self, request, pk=None):
+        """POST /api/devices/{id}/coap-token/rotate/ — token nou pentru ingest-ul CoAP (?t=).
+
+        Separat de ingest_secret: token-ul circulă în clar pe UDP, deci se rotește
+        independent. Se salvează doar hash-ul; token-ul e returnat o singură dată.
+        Body opțional {"disable": true} oprește CoAP pentru device.
+        Roluri permise: OWNER, ADMIN (și cross-tenant).
+        """
+        device = self.get_object()
+        role = getattr(request, "role", None)
+        if not _is_cross_tenant(request.user) and role not in {"OWNER", "ADMIN"}:
+            raise PermissionDenied("Only OWNER or ADMIN can rotate device credentials.")
+        token = None if request.data.get("disable") is True else secrets.token_urlsafe(24)
+        device.coap_token_hash = hashlib.sha256(token.encode()).hexdigest() if token else ""
+        # post_save sincronizează coaptoken:{serial} în Redis
+        device.save(update_fields=["coap_token_hash"])
+        return Response({"serial_number": device.serial_number, "coap_token": token})
+
     @action(detail=True, methods=["post"], url_path="relay")
     def relay(self, request, pk=None):
         """POST /api/devices/{id}/relay/ — comanda ON/OFF/TOGGLE pentru Tasmota.
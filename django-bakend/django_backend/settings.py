@@ -128,13 +128,33 @@ STATIC_URL = "static/"
 DEFAULT_AUTO_FIELD = "django.db.models.BigAutoField"
 
 
+# JWT: HS256 cu secret partajat (default) sau RS256/ES256 cu cheie privată doar
+# în Django — Go-ul verifică atunci cu cheia publică din JWKS
+# (`manage.py export_jwks`, JWT_JWKS în go-iot-platform/.env).
+JWT_ALGORITHM = config("JWT_ALGORITHM", default="HS256")
+JWT_PRIVATE_KEY_FILE = config("JWT_PRIVATE_KEY_FILE", default="")
+
 SIMPLE_JWT = {
-    "SIGNING_KEY": config("JWT_SECRET"),
-    "ALGORITHM": "HS256",
+    "SIGNING_KEY": config("JWT_SECRET", default=""),
+    "ALGORITHM": JWT_ALGORITHM,
     "ACCESS_TOKEN_LIFETIME": timedelta(minutes=100),
     "REFRESH_TOKEN_LIFETIME": timedelta(days=7),
 }
 
+if not JWT_ALGORITHM.startswith("HS"):
+    from cryptography.hazmat.primitives import serialization
+
+    if not JWT_PRIVATE_KEY_FILE:
+        raise RuntimeError(f"JWT_ALGORITHM={JWT_ALGORITHM} requires JWT_PRIVATE_KEY_FILE")
+    _jwt_private_pem = Path(JWT_PRIVATE_KEY_FILE).read_bytes()
+    _jwt_public_pem = serialization.load_pem_private_key(_jwt_private_pem, password=None).public_key().public_bytes(
+        serialization.Encoding.PEM, serialization.PublicFormat.SubjectPublicKeyInfo
+    )
+    SIMPLE_JWT["SIGNING_KEY"] = _jwt_private_pem.decode()
+    SIMPLE_JWT["VERIFYING_KEY"] = _jwt_public_pem.decode()
+elif not SIMPLE_JWT["SIGNING_KEY"]:
+    raise RuntimeError("JWT_SECRET is required for HS* JWT algorithms")
+
 # Kill-switch pentru întreg flow-ul multi-tenant (TenantMiddleware + queryset filter).
 # La False: middleware devine no-op și viewset-urile permit acces ne-tenant-scoped.
 # Util pentru rollback de urgență fără rollback de migrare DB.
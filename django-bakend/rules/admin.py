@@ -7,8 +7,8 @@ from .models import Rule, RuleExecution
 
 
 class RuleAdmin(admin.ModelAdmin):
-    list_display = ["name", "tenant", "trigger_stream_pattern", "enabled", "cooldown_seconds", "updated_at"]
-    list_filter = ["enabled", "tenant"]
+    list_display = ["name", "tenant", "trigger_type", "trigger_stream_pattern", "enabled", "cooldown_seconds", "updated_at"]
+    list_filter = ["enabled", "trigger_type", "tenant"]
     search_fields = ["name", "tenant__name"]
     readonly_fields = ["created_at", "updated_at", "conditions_pretty", "actions_pretty"]
     list_editable = ["enabled"]
@@ -18,11 +18,19 @@ class RuleAdmin(admin.ModelAdmin):
         (None, {
             "fields": ["tenant", "name", "description", "enabled", "cooldown_seconds", "trigger_stream_pattern"],
         }),
+        ("Trigger", {
+            "fields": ["trigger_type", "schedule", "absence_seconds"],
+            "description": (
+                'message = la fiecare mesaj; schedule = cron "0 22 * * *" / "@every 15m" pe ultima stare; '
+                'absence = device fără mesaj pe stream de absence_seconds.'
+            ),
+        }),
         ("Conditions (DSL)", {
             "fields": ["conditions", "conditions_pretty"],
             "description": (
                 'Arbore JSON cu operator AND/OR/NOT și frunze {field, op, value}. '
-                'Operatori: eq, ne, gt, gte, lt, lte, in, not_in, contains, not_contains, regex, is_null, is_not_null, changed.'
+                'Operatori: eq, ne, gt, gte, lt, lte, in, not_in, contains, not_contains, regex, is_null, is_not_null, changed. '
+                'field / value_field acceptă expresii: "pv_input_power - house_load_kw_est", "sum(measurements[*].value)".'
             ),
         }),
         ("Actions", {
@@ -0,0 +1,22 @@
+from django.db import migrations, models
+import django.utils.timezone
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ("rules", "0002_alter_rule_actions_alter_rule_conditions_and_more"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="ruleexecution",
+            name="occurrences",
+            field=models.PositiveIntegerField(default=1),
+        ),
+        migrations.AlterField(
+            model_name="ruleexecution",
+            name="triggered_at",
+            field=models.DateTimeField(db_index=True, default=django.utils.timezone.now),
+        ),
+    ]
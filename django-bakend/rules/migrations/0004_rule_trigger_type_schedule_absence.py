@@ -0,0 +1,38 @@
+from django.db import migrations, models
+
+
+class Migration(migrations.Migration):
+
+    dependencies = [
+        ("rules", "0003_ruleexecution_occurrences_triggered_at"),
+    ]
+
+    operations = [
+        migrations.AddField(
+            model_name="rule",
+            name="trigger_type",
+            field=models.CharField(
+                choices=[("message", "Message"), ("schedule", "Schedule"), ("absence", "Absence")],
+                default="message",
+                max_length=10,
+            ),
+        ),
+        migrations.AddField(
+            model_name="rule",
+            name="schedule",
+            field=models.CharField(
+                blank=True,
+                help_text='Cron "0 22 * * *", "@daily" or "@every 15m" (trigger_type=schedule).',
+                max_length=100,
+            ),
+        ),
+        migrations.AddField(
+            model_name="rule",
+            name="absence_seconds",
+            field=models.PositiveIntegerField(
+                blank=True,
+                help_text="Silence duration that fires the rule (trigger_type=absence).",
+                null=True,
+            ),
+        ),
+    ]
@@ -1,4 +1,5 @@
 from django.db import models
+from django.utils import timezone
 
 
 class Rule(models.Model):
@@ -6,13 +7,26 @@ class Rule(models.Model):
 
     conditions: condition DSL tree — see validators.py for schema.
     actions:    list of action objects (downlink/notify/webhook/set_shadow).
+    trigger_type: what fires the rule (evaluated by the Go rule-engine).
+        - message  → every incoming message on trigger_stream_pattern
+        - schedule → on `schedule`, against the latest known state of each device
+        - absence  → when a device hasn't published on trigger_stream_pattern
+                     for `absence_seconds`
     trigger_stream_pattern: stream name(s) that activate this rule.
         - "*"             → any stream
         - "telemetry"     → exact match
         - "telemetry,emeter" → comma-separated list (match any)
+    schedule: 5-field cron ("0 22 * * *"), @hourly/@daily/@weekly/@monthly,
+        or "@every 15m". Only for trigger_type=schedule.
     cooldown_seconds: minimum interval between consecutive firings for the
         same rule + device pair. Tracked in Redis.
     """
+
+    class TriggerType(models.TextChoices):
+        MESSAGE = "message"
+        SCHEDULE = "schedule"
+        ABSENCE = "absence"
+
     tenant = models.ForeignKey(
         "tenants.Tenant",
         on_delete=models.CASCADE,
@@ -20,11 +34,26 @@ class Rule(models.Model):
     )
     name = models.CharField(max_length=100)
     description = models.TextField(blank=True)
+    trigger_type = models.CharField(
+        max_length=10,
+        choices=TriggerType.choices,
+        default=TriggerType.MESSAGE,
+    )
     trigger_stream_pattern = models.CharField(
         max_length=200,
         default="*",
         help_text='Stream name(s): "*", "telemetry", "telemetry,emeter"',
     )
+    schedule = models.CharField(
+        max_length=100,
+        blank=True,
+        help_text='Cron "0 22 * * *", "@daily" or "@every 15m" (trigger_type=schedule).',
+    )
+    absence_seconds = models.PositiveIntegerField(
+        null=True,
+        blank=True,
+        help_text="Silence duration that fires the rule (trigger_type=absence).",
+    )
     conditions = models.JSONField(
         help_text="Condition DSL tree. See API docs for schema.",
     )
@@ -48,7 +77,12 @@ class Rule(models.Model):
 
 
 class RuleExecution(models.Model):
-    """Audit trail — one record per rule firing."""
+    """Audit trail — one record per rule firing.
+
+    occurrences > 1 only for cooldown_skipped: Go rule-engine aggregates the
+    skips of a (rule, device) pair within one flush window into one record.
+    triggered_at is set by the rule-engine (records arrive batched, late).
+    """
 
     class Status(models.TextChoices):
         TRIGGERED = "triggered"
@@ -70,7 +104,7 @@ class RuleExecution(models.Model):
     )
     device_serial = models.CharField(max_length=100)
     stream = models.CharField(max_length=50)
-    triggered_at = models.DateTimeField(auto_now_add=True, db_index=True)
+    triggered_at = models.DateTimeField(default=timezone.now, db_index=True)
     conditions_snapshot = models.JSONField(default=dict)
     actions_taken = models.JSONField(default=list)
     status = models.CharField(
@@ -79,6 +113,7 @@ class RuleExecution(models.Model):
         default=Status.TRIGGERED,
     )
     error_message = models.TextField(blank=True)
+    occurrences = models.PositiveIntegerField(default=1)
 
     class Meta:
         ordering = ["-triggered_at"]
@@ -1,7 +1,12 @@
 from rest_framework import serializers
 
 from .models import Rule, RuleExecution
-from .validators import validate_condition_node, validate_actions
+from .validators import (
+    MIN_ABSENCE_SECONDS,
+    validate_actions,
+    validate_condition_node,
+    validate_schedule,
+)
 
 
 class RuleSerializer(serializers.ModelSerializer):
@@ -9,7 +14,8 @@ class RuleSerializer(serializers.ModelSerializer):
         model = Rule
         fields = [
             "id", "name", "description",
-            "trigger_stream_pattern",
+            "trigger_type", "trigger_stream_pattern",
+            "schedule", "absence_seconds",
             "conditions", "actions",
             "cooldown_seconds", "enabled",
             "created_at", "updated_at",
@@ -17,6 +23,9 @@ class RuleSerializer(serializers.ModelSerializer):
         read_only_fields = ["id", "created_at", "updated_at"]
 
     def validate_conditions(self, value):
+        # {} = no conditions; allowed only for schedule/absence (checked in validate()).
+        if value == {}:
+            return value
         validate_condition_node(value)
         return value
 
@@ -29,7 +38,31 @@ class RuleSerializer(serializers.ModelSerializer):
             raise serializers.ValidationError("trigger_stream_pattern cannot be empty.")
         return value.strip()
 
+    def _effective(self, attrs, name, default=None):
+        if name in attrs:
+            return attrs[name]
+        return getattr(self.instance, name, default) if self.instance else default
+
+    def _validate_trigger(self, attrs):
+        trigger_type = self._effective(attrs, "trigger_type", Rule.TriggerType.MESSAGE)
+        conditions = self._effective(attrs, "conditions")
+        if trigger_type == Rule.TriggerType.MESSAGE and conditions == {}:
+            raise serializers.ValidationError({"conditions": "Message rules require conditions."})
+        if trigger_type == Rule.TriggerType.SCHEDULE:
+            validate_schedule(self._effective(attrs, "schedule", ""))
+        elif attrs.get("schedule"):
+            raise serializers.ValidationError({"schedule": "Only valid for trigger_type=schedule."})
+        if trigger_type == Rule.TriggerType.ABSENCE:
+            seconds = self._effective(attrs, "absence_seconds")
+            if not seconds or seconds < MIN_ABSENCE_SECONDS:
+                raise serializers.ValidationError(
+                    {"absence_seconds": f"Absence rules require absence_seconds >= {MIN_ABSENCE_SECONDS}."}
+                )
+        elif attrs.get("absence_seconds"):
+            raise serializers.ValidationError({"absence_seconds": "Only valid for trigger_type=absence."})
+
     def validate(self, attrs):
+        self._validate_trigger(attrs)
         request = self.context.get("request")
         tenant = getattr(request, "tenant", None) if request else None
         if tenant and "name" in attrs:
@@ -41,12 +74,46 @@ class RuleSerializer(serializers.ModelSerializer):
         return attrs
 
 
+class InternalRuleSerializer(RuleSerializer):
+    """RuleSerializer + tenant_id, for the Go rule-engine (InternalRuleListView).
+
+    The Scheduler loads timed rules of all tenants in one call and needs the
+    owner of each; the public API stays tenant-implicit.
+    """
+    class Meta(RuleSerializer.Meta):
+        fields = RuleSerializer.Meta.fields + ["tenant_id"]
+        read_only_fields = RuleSerializer.Meta.read_only_fields + ["tenant_id"]
+
+
 class RuleExecutionSerializer(serializers.ModelSerializer):
     class Meta:
         model = RuleExecution
         fields = [
             "id", "rule", "rule_name", "device_serial", "stream",
             "triggered_at", "conditions_snapshot", "actions_taken",
-            "status", "error_message",
+            "status", "error_message", "occurrences",
         ]
         read_only_fields = fields
+
+
+class ExecutionRecordSerializer(serializers.Serializer):
+    """One record of the Go ExecLogger bulk payload (InternalRuleLogBulkView).
+
+    Go encodes empty slices as null, hence allow_null on the JSON fields.
+    """
+
+    rule_id = serializers.IntegerField(required=False, allow_null=True)
+    rule_name = serializers.CharField(max_length=100, required=False, allow_blank=True, default="")
+    tenant_id = serializers.IntegerField(min_value=1)
+    device_serial = serializers.CharField(max_length=100)
+    stream = serializers.CharField(max_length=50, required=False, allow_blank=True, default="")
+    conditions_snapshot = serializers.DictField(required=False, allow_null=True, default=dict)
+    actions_taken = serializers.ListField(
+        child=serializers.DictField(), required=False, allow_null=True, default=list
+    )
+    status = serializers.ChoiceField(
+        choices=RuleExecution.Status.choices, required=False, default=RuleExecution.Status.TRIGGERED
+    )
+    error_message = serializers.CharField(required=False, allow_blank=True, default="")
+    occurrences = serializers.IntegerField(min_value=1, required=False, default=1)
+    triggered_at = serializers.DateTimeField(required=False, allow_null=True)
@@ -1,4 +1,10 @@
-"""Invalidate Redis rule cache when a Rule is saved or deleted."""
+"""Invalidate Redis rule cache when a Rule is saved or deleted.
+
+Pe lângă ștergerea cheii rules:v1:{tenant}, publicăm {"tenant_id": N} pe canalul
+`rules-cache-invalidate` — Go rule-engine (internal/rules.RuleCache) ține un
+RuleSet compilat in-process și îl aruncă la primirea mesajului.
+"""
+import json
 import logging
 
 from django.db.models.signals import post_delete, post_save
@@ -8,6 +14,8 @@ from .models import Rule
 
 logger = logging.getLogger(__name__)
 
+INVALIDATE_CHANNEL = "rules-cache-invalidate"
+
 
 def _invalidate(tenant_id):
     try:
@@ -19,6 +27,7 @@ def _invalidate(tenant_id):
         rdb = _redis.Redis.from_url(url, socket_connect_timeout=1, socket_timeout=1)
         key = f"rules:v1:{tenant_id}"
         rdb.delete(key)
+        rdb.publish(INVALIDATE_CHANNEL, json.dumps({"tenant_id": tenant_id}))
         logger.debug("rules: cache invalidated for tenant %s", tenant_id)
     except Exception as exc:
         logger.warning("rules: cache invalidation failed: %s", exc)
@@ -4,7 +4,7 @@ from django.contrib.auth import get_user_model
 from rest_framework.test import APIClient
 
 from rules.models import Rule, RuleExecution
-from rules.validators import validate_condition_node, validate_actions
+from rules.validators import validate_condition_node, validate_actions, validate_schedule
 from tenants.models import Membership, Tenant
 from rest_framework.exceptions import ValidationError
 
@@ -101,6 +101,25 @@ class TestConditionValidator:
     def test_changed_no_value_required(self):
         validate_condition_node({"field": "relay_state", "op": "changed"})
 
+    def test_arithmetic_field(self):
+        validate_condition_node({"field": "pv_input_power - house_load_kw_est", "op": "gt", "value": 2})
+
+    def test_aggregate_field(self):
+        validate_condition_node({"field": "sum(measurements[*].value)", "op": "gte", "value": 10})
+
+    def test_invalid_expression(self):
+        with pytest.raises(ValidationError):
+            validate_condition_node({"field": "(a - b", "op": "gt", "value": 1})
+        with pytest.raises(ValidationError):
+            validate_condition_node({"field": "median(a)", "op": "gt", "value": 1})
+
+    def test_value_field(self):
+        validate_condition_node({"field": "temperature", "op": "gt", "value_field": "setpoint + 2"})
+
+    def test_value_and_value_field_exclusive(self):
+        with pytest.raises(ValidationError):
+            validate_condition_node({"field": "t", "op": "gt", "value": 1, "value_field": "s"})
+
 
 class TestActionValidator:
     def test_valid_downlink(self):
@@ -267,3 +286,135 @@ class TestRuleAPI:
         names = [r["name"] for r in resp.data]
         assert "enabled-rule" in names
         assert "disabled-rule" not in names
+
+
+# ── Internal bulk log (Go ExecLogger) ─────────────────────────────────────────
+
+@pytest.mark.django_db
+class TestInternalBulkLog:
+    def _svc(self, api):
+        svc = User.objects.create_superuser(username="rules_svc", password="pw", prenume="Svc")
+        api.force_authenticate(user=svc)
+
+    def test_bulk_creates_records(self, api, tenant):
+        rule = Rule.objects.create(
+            tenant=tenant, name="bulk-rule",
+            conditions=SIMPLE_CONDITION, actions=SIMPLE_ACTIONS,
+        )
+        self._svc(api)
+        resp = api.post("/api/internal/rules/log/bulk/", {"executions": [
+            {"rule_id": rule.id, "rule_name": rule.name, "tenant_id": tenant.id,
+             "device_serial": "DEV001", "stream": "telemetry", "status": "triggered",
+             "triggered_at": "2026-05-10T12:00:00Z"},
+            {"rule_id": rule.id, "rule_name": rule.name, "tenant_id": tenant.id,
+             "device_serial": "DEV001", "stream": "telemetry",
+             "status": "cooldown_skipped", "occurrences": 42},
+        ]}, format="json")
+        assert resp.status_code == 201
+        assert resp.data == {"logged": 2, "rejected": 0}
+        skipped = RuleExecution.objects.get(status=RuleExecution.Status.COOLDOWN)
+        assert skipped.occurrences == 42
+        fired = RuleExecution.objects.get(status=RuleExecution.Status.TRIGGERED)
+        assert fired.triggered_at.isoformat().startswith("2026-05-10T12:00:00")
+
+    def test_bulk_skips_malformed(self, api, tenant):
+        self._svc(api)
+        resp = api.post("/api/internal/rules/log/bulk/", {"executions": [
+            {"rule_name": "x", "tenant_id": tenant.id, "device_serial": "D1"},
+            {"rule_name": "missing-serial", "tenant_id": tenant.id},
+        ]}, format="json")
+        assert resp.status_code == 201
+        assert resp.data == {"logged": 1, "rejected": 1}
+
+    def test_bulk_validates_fields(self, api, tenant):
+        self._svc(api)
+        base = {"rule_name": "x", "tenant_id": tenant.id, "device_serial": "D1"}
+        resp = api.post("/api/internal/rules/log/bulk/", {"executions": [
+            {**base, "status": "exploded"},
+            {**base, "occurrences": 0},
+            {**base, "tenant_id": 999999},
+            {**base, "device_serial": "x" * 101},
+            {**base, "triggered_at": "yesterday"},
+            "not-a-record",
+            # Go trimite slice-urile goale ca null.
+            {**base, "status": "error", "actions_taken": None, "error_message": "boom"},
+        ]}, format="json")
+        assert resp.status_code == 201
+        assert resp.data == {"logged": 1, "rejected": 6}
+        rec = RuleExecution.objects.get()
+        assert rec.status == RuleExecution.Status.ERROR
+        assert rec.actions_taken == []
+
+    def test_bulk_requires_service_account(self, api, owner, tenant):
+        token = _jwt(owner, tenant)
+        api.credentials(HTTP_AUTHORIZATION=f"Bearer {token}")
+        resp = api.post("/api/internal/rules/log/bulk/", {"executions": []}, format="json")
+        assert resp.status_code == 403
+
+
+# ── Schedule / absence triggers ──────────────────────────────────────────────
+
+class TestScheduleValidator:
+    @pytest.mark.parametrize("spec", [
+        "0 22 * * *", "*/15 8-18 * * MON-FRI", "0 0 1 jan *", "@daily", "@every 15m", "@every 1h30m",
+    ])
+    def test_valid(self, spec):
+        validate_schedule(spec)
+
+    @pytest.mark.parametrize("spec", [
+        "", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *",
+        "@sometimes", "@every 5s", "@every soon",
+    ])
+    def test_invalid(self, spec):
+        with pytest.raises(ValidationError):
+            validate_schedule(spec)
+
+
+@pytest.mark.django_db
+class TestTimedRules:
+    def _create(self, api, owner, tenant, **extra):
+        api.credentials(HTTP_AUTHORIZATION=f"Bearer {_jwt(owner, tenant)}")
+        body = {"name": "timed", "conditions": SIMPLE_CONDITION, "actions": SIMPLE_ACTIONS}
+        body.update(extra)
+        return api.post("/api/v1/rules/", body, format="json")
+
+    def test_create_schedule_rule(self, api, owner, tenant):
+        resp = self._create(api, owner, tenant, trigger_type="schedule", schedule="0 22 * * *",
+                            conditions={"field": "battery_soc", "op": "lt", "value": 30})
+        assert resp.status_code == 201
+        assert resp.data["trigger_type"] == "schedule"
+        assert "tenant" not in resp.data
+
+    def test_schedule_required(self, api, owner, tenant):
+        resp = self._create(api, owner, tenant, trigger_type="schedule")
+        assert resp.status_code == 400
+        assert "schedule" in resp.data
+
+    def test_absence_requires_seconds(self, api, owner, tenant):
+        resp = self._create(api, owner, tenant, trigger_type="absence", conditions={})
+        assert resp.status_code == 400
+        assert "absence_seconds" in resp.data
+        resp = self._create(api, owner, tenant, trigger_type="absence", conditions={},
+                            absence_seconds=900, trigger_stream_pattern="telemetry")
+        assert resp.status_code == 201
+
+    def test_message_rule_requires_conditions(self, api, owner, tenant):
+        resp = self._create(api, owner, tenant, conditions={})
+        assert resp.status_code == 400
+
+    def test_internal_list_by_trigger_type(self, api, tenant, other_tenant):
+        Rule.objects.create(tenant=tenant, name="msg", conditions=SIMPLE_CONDITION, actions=SIMPLE_ACTIONS)
+        Rule.objects.create(tenant=tenant, name="nightly", trigger_type="schedule", schedule="@daily",
+                            conditions=SIMPLE_CONDITION, actions=SIMPLE_ACTIONS)
+        Rule.objects.create(tenant=other_tenant, name="silent", trigger_type="absence", absence_seconds=600,
+                            conditions={}, actions=SIMPLE_ACTIONS)
+        svc = User.objects.create_superuser(username="timed_svc", password="pw", prenume="Svc")
+        api.force_authenticate(user=svc)
+
+        resp = api.get("/api/internal/rules/?trigger_type=schedule,absence")
+        assert resp.status_code == 200
+        assert sorted(r["name"] for r in resp.data) == ["nightly", "silent"]
+        assert {r["tenant_id"] for r in resp.data} == {tenant.id, other_tenant.id}
+
+        assert api.get("/api/internal/rules/?trigger_type=bogus").status_code == 400
+        assert api.get("/api/internal/rules/").status_code == 400
@@ -7,6 +7,7 @@ from .views import (
     RuleExecutionAllView,
     InternalRuleListView,
     InternalRuleLogView,
+    InternalRuleLogBulkView,
 )
 
 urlpatterns = [
@@ -21,4 +22,5 @@ urlpatterns = [
 internal_urlpatterns = [
     path("rules/", InternalRuleListView.as_view(), name="internal-rules"),
     path("rules/log/", InternalRuleLogView.as_view(), name="internal-rule-log"),
+    path("rules/log/bulk/", InternalRuleLogBulkView.as_view(), name="internal-rule-log-bulk"),
 ]
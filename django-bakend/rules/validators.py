@@ -1,4 +1,6 @@
-"""Validators for the rule condition DSL and action list."""
+"""Validators for the rule condition DSL, action list and schedule spec."""
+import re
+
 from rest_framework.exceptions import ValidationError
 
 LEAF_OPS = {
@@ -44,9 +46,20 @@ def validate_condition_node(node, path="conditions"):
         # Leaf
         if not isinstance(node["field"], str) or not node["field"]:
             raise ValidationError({path: "'field' must be a non-empty string."})
+        validate_field_expr(node["field"], path=f"{path}.field")
         leaf_op = node.get("op")
         if leaf_op not in LEAF_OPS:
             raise ValidationError({path: f"'op' must be one of {sorted(LEAF_OPS)} (got '{leaf_op}')."})
+        value_field = node.get("value_field")
+        if value_field is not None:
+            if not isinstance(value_field, str) or not value_field:
+                raise ValidationError({path: "'value_field' must be a non-empty string."})
+            if leaf_op in NO_VALUE_OPS or leaf_op in ("in", "not_in"):
+                raise ValidationError({path: f"op '{leaf_op}' does not accept 'value_field'."})
+            if "value" in node:
+                raise ValidationError({path: "Use either 'value' or 'value_field', not both."})
+            validate_field_expr(value_field, path=f"{path}.value_field")
+            return
         if leaf_op not in NO_VALUE_OPS and "value" not in node:
             raise ValidationError({path: f"op '{leaf_op}' requires a 'value'."})
         if leaf_op == "in" or leaf_op == "not_in":
@@ -54,6 +67,113 @@ def validate_condition_node(node, path="conditions"):
                 raise ValidationError({path: f"op '{leaf_op}' requires 'value' to be a list."})
 
 
+# ── Field expressions — mirrors go internal/rules/expr.go ─────────────────────
+
+EXPR_FUNCS = {"sum", "avg", "min", "max", "len", "abs"}
+_NUMBER_RE = re.compile(r"\d*\.?\d+")
+
+
+def _tokenize_expr(src):
+    tokens, i = [], 0
+    while i < len(src):
+        ch = src[i]
+        if ch.isspace():
+            i += 1
+        elif ch in "+-*/()":
+            tokens.append(("op", ch))
+            i += 1
+        elif ch.isdigit() or (ch == "." and src[i + 1:i + 2].isdigit()):
+            m = _NUMBER_RE.match(src, i)
+            j = i
+            while j < len(src) and (src[j].isdigit() or src[j] == "."):
+                j += 1
+            if not m or m.end() != j:
+                raise ValueError(f"invalid number '{src[i:j]}'")
+            tokens.append(("num", src[i:j]))
+            i = j
+        else:
+            j, depth = i, 0
+            while j < len(src):
+                c = src[j]
+                if c == "[":
+                    depth += 1
+                elif c == "]":
+                    if depth == 0:
+                        raise ValueError("unbalanced ']'")
+                    depth -= 1
+                elif depth > 0:
+                    pass
+                elif c.isspace() or c in "+/()":
+                    break
+                elif c == "*" and not (j > i and src[j - 1] == "."):
+                    break
+                j += 1
+            if depth:
+                raise ValueError("unbalanced '['")
+            tokens.append(("ident", src[i:j]))
+            i = j
+    if not tokens:
+        raise ValueError("empty expression")
+    return tokens
+
+
+def _parse_expr(tokens, pos=0):
+    """Recursive descent: sum → product → unary → primary. Returns next pos."""
+    def primary(pos):
+        if pos >= len(tokens):
+            raise ValueError("unexpected end of expression")
+        kind, text = tokens[pos]
+        if kind == "num":
+            return pos + 1
+        if kind == "ident":
+            if pos + 1 < len(tokens) and tokens[pos + 1] == ("op", "("):
+                if text.lower() not in EXPR_FUNCS:
+                    raise ValueError(f"unknown function '{text}' (allowed: {sorted(EXPR_FUNCS)})")
+                pos = sum_(pos + 2)
+                if pos >= len(tokens) or tokens[pos] != ("op", ")"):
+                    raise ValueError(f"missing ')' after {text}(")
+                return pos + 1
+            return pos + 1
+        if text == "(":
+            pos = sum_(pos + 1)
+            if pos >= len(tokens) or tokens[pos] != ("op", ")"):
+                raise ValueError("missing ')'")
+            return pos + 1
+        raise ValueError(f"unexpected '{text}'")
+
+    def unary(pos):
+        if pos < len(tokens) and tokens[pos] == ("op", "-"):
+            return unary(pos + 1)
+        return primary(pos)
+
+    def product(pos):
+        pos = unary(pos)
+        while pos < len(tokens) and tokens[pos] in (("op", "*"), ("op", "/")):
+            pos = unary(pos + 1)
+        return pos
+
+    def sum_(pos):
+        pos = product(pos)
+        while pos < len(tokens) and tokens[pos] in (("op", "+"), ("op", "-")):
+            pos = product(pos + 1)
+        return pos
+
+    return sum_(pos)
+
+
+def validate_field_expr(expr, path="field"):
+    """A field is a path ("a.b[k=v].c", "m[*].value") or an arithmetic expression
+    ("pv_input_power - house_load_kw_est", "sum(m[*].value)"). Note: subtraction
+    needs a space before '-' ("a - b"); "a-b" is the field name "a-b"."""
+    try:
+        tokens = _tokenize_expr(expr)
+        pos = _parse_expr(tokens)
+        if pos != len(tokens):
+            raise ValueError(f"unexpected '{tokens[pos][1]}'")
+    except ValueError as exc:
+        raise ValidationError({path: f"invalid field expression '{expr}': {exc}."})
+
+
 def validate_actions(actions, path="actions"):
     if not isinstance(actions, list) or len(actions) == 0:
         raise ValidationError({path: "Must be a non-empty list of actions."})
@@ -78,3 +198,83 @@ def validate_actions(actions, path="actions"):
         elif t == "set_shadow":
             if not isinstance(action.get("desired"), dict):
                 raise ValidationError({p: "set_shadow action requires 'desired' object."})
+
+
+# ── Schedule (trigger_type=schedule) — mirrors go rules.ParseSchedule ──────────
+
+CRON_DESCRIPTORS = {"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+MIN_ABSENCE_SECONDS = 60
+MIN_EVERY_SECONDS = 10
+
+# (min, max, names) per cron field: minute hour day-of-month month day-of-week
+_CRON_FIELDS = [
+    ("minute", 0, 59, {}),
+    ("hour", 0, 23, {}),
+    ("day-of-month", 1, 31, {}),
+    ("month", 1, 12, {m: i + 1 for i, m in enumerate(
+        ["jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"])}),
+    ("day-of-week", 0, 7, {d: i for i, d in enumerate(["sun", "mon", "tue", "wed", "thu", "fri", "sat"])}),
+]
+_GO_DURATION_RE = re.compile(r"(\d+(?:\.\d+)?)(ns|us|µs|ms|s|m|h)")
+_GO_DURATION_UNITS = {"ns": 1e-9, "us": 1e-6, "µs": 1e-6, "ms": 1e-3, "s": 1, "m": 60, "h": 3600}
+
+
+def _go_duration_seconds(text):
+    """Parse a Go duration string ("15m", "1h30m"); None if invalid."""
+    pos, total = 0, 0.0
+    for m in _GO_DURATION_RE.finditer(text):
+        if m.start() != pos:
+            return None
+        total += float(m.group(1)) * _GO_DURATION_UNITS[m.group(2)]
+        pos = m.end()
+    return total if text and pos == len(text) else None
+
+
+def _cron_value(token, names):
+    token = token.lower()
+    if token in names:
+        return names[token]
+    if not token.isdigit():
+        raise ValueError(f"invalid value '{token}'")
+    return int(token)
+
+
+def _validate_cron_field(text, name, lo, hi, names):
+    for part in text.split(","):
+        if not part:
+            raise ValueError(f"{name}: empty list element")
+        rng, _, step = part.partition("/")
+        if step and (not step.isdigit() or int(step) == 0):
+            raise ValueError(f"{name}: invalid step in '{part}'")
+        if rng == "*":
+            continue
+        bounds = rng.split("-", 1)
+        start = _cron_value(bounds[0], names)
+        end = _cron_value(bounds[1], names) if len(bounds) == 2 else start
+        if start < lo or end > hi or start > end:
+            raise ValueError(f"{name}: '{part}' out of range {lo}-{hi}")
+
+
+def validate_schedule(spec, path="schedule"):
+    if not isinstance(spec, str) or not spec.strip():
+        raise ValidationError({path: "schedule is required for trigger_type=schedule."})
+    spec = spec.strip()
+    if spec.startswith("@every "):
+        seconds = _go_duration_seconds(spec[len("@every "):].strip())
+        if seconds is None:
+            raise ValidationError({path: f"invalid duration in '{spec}' (e.g. '@every 15m')."})
+        if seconds < MIN_EVERY_SECONDS:
+            raise ValidationError({path: f"@every interval must be at least {MIN_EVERY_SECONDS}s."})
+        return
+    if spec.lower() in CRON_DESCRIPTORS:
+        return
+    if spec.startswith("@"):
+        raise ValidationError({path: f"unknown descriptor '{spec}'."})
+    fields = spec.split()
+    if len(fields) != 5:
+        raise ValidationError({path: f"cron needs 5 fields (minute hour dom month dow), got {len(fields)}."})
+    try:
+        for text, (name, lo, hi, names) in zip(fields, _CRON_FIELDS):
+            _validate_cron_field(text, name, lo, hi, names)
+    except ValueError as exc:
+        raise ValidationError({path: str(exc)})
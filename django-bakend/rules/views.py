@@ -7,9 +7,15 @@ from rest_framework.permissions import IsAuthenticated
 from rest_framework.response import Response
 from rest_framework.views import APIView
 
+from tenants.models import Tenant
 from tenants.permissions import TenantRolePermission
 from .models import Rule, RuleExecution
-from .serializers import RuleSerializer, RuleExecutionSerializer
+from .serializers import (
+    ExecutionRecordSerializer,
+    InternalRuleSerializer,
+    RuleExecutionSerializer,
+    RuleSerializer,
+)
 
 logger = logging.getLogger(__name__)
 
@@ -45,6 +51,9 @@ class RuleListCreateView(generics.ListCreateAPIView):
         stream = self.request.query_params.get("stream")
         if stream:
             qs = qs.filter(trigger_stream_pattern__icontains=stream)
+        trigger_type = self.request.query_params.get("trigger_type")
+        if trigger_type:
+            qs = qs.filter(trigger_type=trigger_type)
         return qs
 
     def perform_create(self, serializer):
@@ -133,6 +142,8 @@ class InternalRuleListView(APIView):
     """GET /api/internal/rules/?tenant_id=2 — service account only.
 
     Returns all enabled rules for a tenant (used by Go rule-engine cache miss).
+    ?trigger_type=schedule,absence filters by trigger type; with it, tenant_id
+    is optional (Go Scheduler loads the timed rules of all tenants at once).
     """
     permission_classes = [IsAuthenticated]
 
@@ -141,10 +152,20 @@ class InternalRuleListView(APIView):
         if not (user.is_superuser or user.has_perm("clients.view_device")):
             raise PermissionDenied("Service account required.")
         tenant_id = request.query_params.get("tenant_id")
-        if not tenant_id:
+        trigger_types = [
+            t.strip() for t in request.query_params.get("trigger_type", "").split(",") if t.strip()
+        ]
+        if not tenant_id and not trigger_types:
             return Response({"detail": "tenant_id required."}, status=400)
-        rules = Rule.objects.filter(tenant_id=tenant_id, enabled=True)
-        return Response(RuleSerializer(rules, many=True).data)
+        unknown = set(trigger_types) - set(Rule.TriggerType.values)
+        if unknown:
+            return Response({"detail": f"unknown trigger_type: {sorted(unknown)}."}, status=400)
+        rules = Rule.objects.filter(enabled=True)
+        if tenant_id:
+            rules = rules.filter(tenant_id=tenant_id)
+        if trigger_types:
+            rules = rules.filter(trigger_type__in=trigger_types)
+        return Response(InternalRuleSerializer(rules, many=True).data)
 
 
 class InternalRuleLogView(APIView):
@@ -174,3 +195,67 @@ class InternalRuleLogView(APIView):
             logger.error("rule log: %s", exc)
             return Response({"detail": str(exc)}, status=400)
         return Response({"logged": True}, status=201)
+
+
+class InternalRuleLogBulkView(APIView):
+    """POST /api/internal/rules/log/bulk/ — batch of rule executions (Go ExecLogger).
+
+    Body: {"executions": [{rule_id, rule_name, tenant_id, device_serial, stream,
+    conditions_snapshot, actions_taken, status, error_message, occurrences,
+    triggered_at}, ...]}. Each record is validated by ExecutionRecordSerializer;
+    invalid ones (bad status, occurrences < 1, unknown tenant, ...) are skipped
+    and counted in "rejected", so that one bad record doesn't make the Go side
+    re-spool the whole batch.
+    """
+    permission_classes = [IsAuthenticated]
+    MAX_BATCH = 1000
+
+    def post(self, request):
+        user = request.user
+        if not (user.is_superuser or user.has_perm("clients.view_device")):
+            raise PermissionDenied("Service account required.")
+        items = request.data.get("executions")
+        if not isinstance(items, list):
+            return Response({"detail": "executions list required."}, status=400)
+        if len(items) > self.MAX_BATCH:
+            return Response({"detail": f"max {self.MAX_BATCH} executions per batch."}, status=400)
+
+        valid, rejected = [], 0
+        for data in items:
+            ser = ExecutionRecordSerializer(data=data)
+            if ser.is_valid():
+                valid.append(ser.validated_data)
+            else:
+                logger.warning("rule bulk log: rejected record: %s", ser.errors)
+                rejected += 1
+
+        tenant_ids = set(Tenant.objects.filter(pk__in={r["tenant_id"] for r in valid}).values_list("pk", flat=True))
+        rule_tenants = dict(
+            Rule.objects.filter(pk__in={r["rule_id"] for r in valid if r.get("rule_id")}).values_list("pk", "tenant_id")
+        )
+
+        objs = []
+        for rec in valid:
+            if rec["tenant_id"] not in tenant_ids:
+                logger.warning("rule bulk log: rejected record: unknown tenant %s", rec["tenant_id"])
+                rejected += 1
+                continue
+            rule_id = rec.get("rule_id")
+            obj = RuleExecution(
+                # Regula ștearsă între timp (sau din alt tenant) → doar rule_name.
+                rule_id=rule_id if rule_tenants.get(rule_id) == rec["tenant_id"] else None,
+                rule_name=rec["rule_name"],
+                tenant_id=rec["tenant_id"],
+                device_serial=rec["device_serial"],
+                stream=rec["stream"],
+                conditions_snapshot=rec["conditions_snapshot"] or {},
+                actions_taken=rec["actions_taken"] or [],
+                status=rec["status"],
+                error_message=rec["error_message"],
+                occurrences=rec["occurrences"],
+            )
+            if rec.get("triggered_at") is not None:
+                obj.triggered_at = rec["triggered_at"]
+            objs.append(obj)
+        RuleExecution.objects.bulk_create(objs)
+        return Response({"logged": len(objs), "rejected": rejected}, status=201)
@@ -78,7 +78,8 @@ def invalidate_membership_cache(user_id=None, tenant_id=None):
 class TenantMiddleware:
     def __init__(self, get_response):
         self.get_response = get_response
-        self.signing_key = settings.SIMPLE_JWT["SIGNING_KEY"]
+        # RS*/ES*: verificăm cu cheia publică; HS*: VERIFYING_KEY lipsește → secretul.
+        self.signing_key = settings.SIMPLE_JWT.get("VERIFYING_KEY") or settings.SIMPLE_JWT["SIGNING_KEY"]
         self.algorithm = settings.SIMPLE_JWT.get("ALGORITHM", "HS256")
         self.enabled = getattr(settings, "MULTI_TENANT_ENABLED", True)
 
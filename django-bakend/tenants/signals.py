@@ -1,20 +1,31 @@
 """Invalidate the membership cache when Memberships or Tenants change.
 
 Without this, a revoked membership stays "valid" for up to TTL_CACHE seconds.
+
+The same events are published on the Redis channel `authz-membership-invalidate`
+so the Go API's authorization cache (internal/cache.AuthzCache) drops its
+(user, tenant) entries too. Payload: {"username": "...", "tenant_id": N};
+tenant-only payload → every user of that tenant.
 """
 from django.db.models.signals import post_delete, post_save
 from django.dispatch import receiver
 
+from clients.signals import publish_json
+
 from .middleware import invalidate_membership_cache
 from .models import Membership, Tenant
 
+MEMBERSHIP_CHANNEL = "authz-membership-invalidate"
+
 
 @receiver(post_save, sender=Membership)
 @receiver(post_delete, sender=Membership)
 def _on_membership_change(sender, instance, **kwargs):
     invalidate_membership_cache(user_id=instance.user_id, tenant_id=instance.tenant_id)
+    publish_json(MEMBERSHIP_CHANNEL, {"username": instance.user.username, "tenant_id": instance.tenant_id})
 
 
 @receiver(post_save, sender=Tenant)
 def _on_tenant_change(sender, instance, **kwargs):
     invalidate_membership_cache(tenant_id=instance.id)
+    publish_json(MEMBERSHIP_CHANNEL, {"tenant_id": instance.id})
@@ -0,0 +1,41 @@
+"""Membership / Tenant changes publish on the Go authz-cache invalidation channel."""
+from unittest.mock import patch
+
+import pytest
+from django.contrib.auth import get_user_model
+
+from clients.models import Device
+from tenants.models import Membership, Tenant
+from tenants.signals import MEMBERSHIP_CHANNEL
+
+
+@pytest.fixture
+def acme(db):
+    return Tenant.objects.create(name="Acme", slug="acme")
+
+
+@pytest.fixture
+def alice(db):
+    return get_user_model().objects.create_user(username="alice", password="pw", prenume="Alice")
+
+
+def test_membership_create_and_delete_publish(acme, alice):
+    with patch("tenants.signals.publish_json") as pub:
+        m = Membership.objects.create(user=alice, tenant=acme, role=Membership.Role.VIEWER)
+        m.delete()
+    assert pub.call_count == 2
+    for call in pub.call_args_list:
+        assert call.args == (MEMBERSHIP_CHANNEL, {"username": "alice", "tenant_id": acme.id})
+
+
+def test_tenant_change_publishes_tenant_only(acme):
+    with patch("tenants.signals.publish_json") as pub:
+        acme.status = Tenant.Status.SUSPENDED
+        acme.save()
+    pub.assert_called_once_with(MEMBERSHIP_CHANNEL, {"tenant_id": acme.id})
+
+
+def test_device_invalidation_carries_tenant(acme, alice):
+    with patch("clients.signals.publish_json") as pub:
+        Device.objects.create(client=alice, tenant=acme, serial_number="SN1", device_type="shelly_em")
+    pub.assert_called_once_with("device-cache-invalidate", {"serial": "SN1", "tenant_id": acme.id})
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/registry"
+)
+
+func runExplain(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dd-lint explain", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	dir := fs.String("dir", "../configs/devices", "directorul cu Device Definitions")
+	asJSON := fs.Bool("json", false, "output JSON (matcher.Explanation)")
+	all := fs.Bool("all", false, "listează și pattern-urile care nu prind topicul")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: dd-lint explain [-dir d] [-json] [-all] <topic> [payload|-]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		fs.Usage()
+		return 2
+	}
+	topic := fs.Arg(0)
+	var payload []byte
+	if fs.NArg() == 2 {
+		payload = []byte(fs.Arg(1))
+		if fs.Arg(1) == "-" {
+			raw, err := io.ReadAll(stdin)
+			if err != nil {
+				fmt.Fprintf(stderr, "dd-lint: read payload: %v\n", err)
+				return 2
+			}
+			payload = raw
+		}
+	}
+
+	reg, loadErrs, err := registry.LoadDir(*dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "dd-lint: %v\n", err)
+		return 2
+	}
+	for _, e := range loadErrs {
+		fmt.Fprintf(stderr, "warning: %v\n", e)
+	}
+	m, errs := matcher.New(reg)
+	for _, e := range errs {
+		fmt.Fprintf(stderr, "warning: matcher: %v\n", e)
+	}
+
+	ex := m.Explain(topic, payload)
+	if *asJSON {
+		out := struct {
+			*matcher.Explanation
+			Definition string `json:"definition,omitempty"`
+			Stream     string `json:"stream,omitempty"`
+		}{Explanation: ex}
+		if ex.Winner != nil {
+			out.Definition, out.Stream = ex.Winner.Definition.ID, ex.Winner.Stream
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(out)
+	} else {
+		printExplanation(stdout, ex, *all)
+	}
+	if ex.Winner == nil {
+		return 1
+	}
+	return 0
+}
+
+func printExplanation(w io.Writer, ex *matcher.Explanation, all bool) {
+	fmt.Fprintf(w, "topic:     %s\n", ex.Topic)
+	if ex.Winner == nil {
+		fmt.Fprintln(w, "winner:    none (ingest → handler generic / auto_detected)")
+	} else {
+		c := ex.Candidates[ex.WinnerRank]
+		fmt.Fprintf(w, "winner:    dd=%s topic_match[%d] %q\n", c.Definition, c.Index, c.Pattern)
+		fmt.Fprintf(w, "stream:    %s\n", c.Stream)
+		fmt.Fprintf(w, "extracted: %s\n", formatVars(c.Extracted))
+	}
+
+	fmt.Fprintln(w, "\ncandidates (match order):")
+	hidden := 0
+	for _, c := range ex.Candidates {
+		if !c.TopicMatched && !all {
+			hidden++
+			continue
+		}
+		mark := "  "
+		switch {
+		case c.Winner:
+			mark = "=>"
+		case c.TopicMatched:
+			mark = " ~"
+		}
+		prio := ""
+		if c.Priority != 0 {
+			prio = fmt.Sprintf(" priority=%d", c.Priority)
+		}
+		fmt.Fprintf(w, "%s #%-3d dd=%s topic_match[%d] %q%s — %s\n", mark, c.Rank, c.Definition, c.Index, c.Pattern, prio, c.Reason)
+		if c.TopicMatched && !c.Winner && len(c.Extracted) > 0 {
+			fmt.Fprintf(w, "         extracted: %s\n", formatVars(c.Extracted))
+		}
+	}
+	if hidden > 0 {
+		fmt.Fprintf(w, "   (%d pattern(s) do not match the topic; -all to list them)\n", hidden)
+	}
+
+	if !ex.Payload || ex.Winner == nil {
+		return
+	}
+	handler := "parser.type=" + ex.Winner.Definition.Parser.Type
+	if ex.Builtin {
+		handler = "built-in " + ex.Winner.Stream + " handler, not the DD parser"
+	}
+	if ex.ParseError != "" {
+		fmt.Fprintf(w, "\nparse error (%s): %s\n", handler, ex.ParseError)
+		return
+	}
+	fmt.Fprintf(w, "\nwritten fields (%s; source=%s type=%s):\n", handler, ex.Source, ex.Type)
+	keys := make([]string, 0, len(ex.Parsed))
+	for k := range ex.Parsed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(w, "  %s = %s\n", k, formatValue(ex.Parsed[k]))
+	}
+	fmt.Fprintln(w, "\nnormalized fields (added for rules, from the DD parser):")
+	if len(ex.Normalized) == 0 {
+		fmt.Fprintln(w, "  (none — no normalized_fields source present in payload)")
+	}
+	for _, f := range ex.Normalized {
+		fmt.Fprintf(w, "  %s = %s   ← %s\n", f.Name, strings.TrimSpace(formatValue(f.Value)+" "+f.Unit), f.Source)
+	}
+}
+
+func formatVars(vars map[string]string) string {
+	if len(vars) == 0 {
+		return "(none)"
+	}
+	parts := make([]string, 0, len(vars))
+	for k, v := range vars {
+		parts = append(parts, k+"="+v)
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// formatValue — JSON pentru obiecte / array-uri (câmpuri nested rămase după parse).
+func formatValue(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+	return fmt.Sprint(v)
+}
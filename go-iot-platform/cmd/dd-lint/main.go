@@ -0,0 +1,96 @@
+// cmd/dd-lint — verificare offline a Device Definitions (configs/devices/),
+// pentru pre-commit și CI.
+//
+// Rulează, pe fiecare director dat (default ../configs/devices, ca DD_DIR):
+//
+//  1. încărcarea strictă din LoadDir (YAML, câmpuri necunoscute, Validate, id duplicat)
+//  2. registry.ValidateAll — pattern-uri suprapuse / shadowed între DD-uri, unit-uri
+//     conflictuale, stream-uri lipsă din telemetry_streams, placeholder-e necunoscute
+//  3. compilarea în matcher
+//  4. vocabularul de capabilities (field-uri / comenzi obligatorii)
+//
+// Exit code: 0 = curat, 1 = probleme găsite, 2 = director inexistent / eroare de citire.
+//
+//	go run ./cmd/dd-lint ../configs/devices
+//
+// Subcomanda explain arată routing-ul unui topic (matcher.Explain): toate
+// pattern-urile în ordinea de match, care prinde și de ce nu celelalte,
+// variabilele extrase, DD-ul și stream-ul câștigător; cu payload, și field-urile
+// parsate / normalizate. Payload "-" = stdin. Exit code 1 = niciun DD nu prinde.
+//
+//	go run ./cmd/dd-lint explain zigbee2mqtt/front_door '{"contact":false,"battery":91}'
+//	go run ./cmd/dd-lint explain -json -dir ../configs/devices tele/plug-1/SENSOR - < sensor.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go-iot-platform/internal/capabilities"
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/registry"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "explain" {
+		return runExplain(args[1:], stdin, stdout, stderr)
+	}
+	fs := flag.NewFlagSet("dd-lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	quiet := fs.Bool("q", false, "afișează doar problemele, fără sumar")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: dd-lint [-q] [dir ...]   (default ../configs/devices)")
+		fmt.Fprintln(stderr, "       dd-lint explain [-dir d] [-json] [-all] <topic> [payload|-]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	dirs := fs.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"../configs/devices"}
+	}
+
+	exit := 0
+	for _, dir := range dirs {
+		problems, count, err := lint(dir)
+		if err != nil {
+			fmt.Fprintf(stderr, "dd-lint: %v\n", err)
+			exit = 2
+			continue
+		}
+		for _, p := range problems {
+			fmt.Fprintf(stdout, "%s: %v\n", dir, p)
+		}
+		if !*quiet {
+			fmt.Fprintf(stdout, "dd-lint: %s — %d definition(s), %d problem(s)\n", dir, count, len(problems))
+		}
+		if len(problems) > 0 && exit == 0 {
+			exit = 1
+		}
+	}
+	return exit
+}
+
+// lint întoarce toate problemele dintr-un director și numărul de DD-uri încărcate.
+func lint(dir string) ([]error, int, error) {
+	reg, loadErrs, err := registry.LoadDir(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	problems := append([]error{}, loadErrs...)
+	problems = append(problems, registry.ValidateAll(reg)...)
+	if _, errs := matcher.New(reg); len(errs) > 0 {
+		for _, e := range errs {
+			problems = append(problems, fmt.Errorf("matcher: %w", e))
+		}
+	}
+	problems = append(problems, capabilities.ValidateRegistry(reg)...)
+	return problems, reg.Count(), nil
+}
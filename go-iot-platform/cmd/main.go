@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"expvar"
 	"fmt"
 	"io"
 	"log"
@@ -18,24 +19,28 @@ import (
 	mqtt "github.com/eclipse/paho.mqtt.golang"
 	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
 	"github.com/influxdata/influxdb-client-go/v2/api/write"
-	"golang.org/x/text/cases"
-	"golang.org/x/text/language"
+	"github.com/redis/go-redis/v9"
 
 	"go-iot-platform/internal/api"
 	"go-iot-platform/internal/buffer"
 	"go-iot-platform/internal/cache"
+	"go-iot-platform/internal/capabilities"
+	"go-iot-platform/internal/coap"
 	"go-iot-platform/internal/django"
+	"go-iot-platform/internal/export"
 	"go-iot-platform/internal/influx"
+	"go-iot-platform/internal/ingest"
+	"go-iot-platform/internal/jwks"
 	"go-iot-platform/internal/logging"
 	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/parsers"
 	"go-iot-platform/internal/ratelimit"
 	"go-iot-platform/internal/registry"
+	"go-iot-platform/internal/stream"
 	"go-iot-platform/internal/topics"
 )
 
 var (
-	titleCaser = cases.Title(language.Und)
-
 	// Rate limit: 10 msg/s per device (burst 20), 200 msg/s per tenant (burst 400).
 	limiter = ratelimit.New(10, 20, 200, 400)
 
@@ -48,6 +53,12 @@ var (
 	// Topic matcher generic — Faza 3 înlocuiește strings.Contains/HasSuffix din
 	// vechea logică de routing. Nil dacă MATCHER_ENABLED=false sau registry gol.
 	topicMatcher *matcher.Matcher
+
+	// Push real-time al punctelor acceptate către /go/stream (Redis "telemetry:{tenant}").
+	streamPublisher *stream.Publisher
+	// streamNormalizer — normalized_fields ale DD-urilor pentru evenimentele SSE;
+	// nil (fără registry) → field-urile sunt publicate cu numele brute.
+	streamNormalizer *export.Normalizer
 )
 
 func main() {
@@ -145,15 +156,111 @@ func main() {
 			topicMatcher = m
 			log.Printf("✅ topic matcher: %d patterns from %d device definitions",
 				m.Count(), reg.Count())
+			for _, e := range registry.ValidateAll(reg) {
+				log.Printf("⚠️ registry: %v", e)
+			}
+			for _, e := range capabilities.ValidateRegistry(reg) {
+				log.Printf("⚠️ capabilities: %v", e)
+			}
+			api.SetDeviceDefinitions(reg, m)
+			streamNormalizer = export.NewNormalizer(reg, nil)
 		}
 	} else {
 		log.Println("⚠️ MATCHER_ENABLED=false — folosesc routing-ul vechi (strings.Contains)")
 	}
 
-	go startMQTTSubscriber(ctx, writePool)
+	// Cache de autorizare (user, tenant) → device-uri pentru API-ul de metrici.
+	// Fără Redis rămâne doar deduplicarea miss-urilor concurente (singleflight).
+	var authzRedis *redis.Client
+	if deviceCache != nil {
+		authzRedis = deviceCache.Client()
+	}
+	authzTTL, _ := time.ParseDuration(os.Getenv("AUTHZ_CACHE_TTL"))
+	authz := cache.NewAuthzCache(authzRedis, authzTTL)
+	go func() {
+		for err := range authz.SubscribeInvalidations(ctx) {
+			log.Printf("⚠️ authz cache invalidation error: %v", err)
+		}
+	}()
+	api.SetAuthzCache(authz)
+
+	// Chei API de tenant (X-API-Key), sincronizate de Django în Redis.
+	if deviceCache != nil {
+		perMin, _ := strconv.Atoi(os.Getenv("APIKEY_RATE_LIMIT"))
+		api.SetAPIKeyStore(cache.NewAPIKeyStore(deviceCache.Client()), perMin)
+	} else {
+		log.Println("⚠️ REDIS_ADDR nesetat — X-API-Key dezactivat în API")
+	}
+
+	verifier, err := newTokenVerifier(ctx)
+	if err != nil {
+		log.Fatalf("JWT config: %v", err)
+	}
+	api.SetTokenVerifier(verifier)
+
+	// Stream real-time: ingest-ul publică pe Redis, hub-ul distribuie conexiunilor
+	// SSE ale acestei instanțe. Fără Redis → livrare locală (o singură instanță).
+	streamHub := stream.NewHub()
+	if deviceCache != nil {
+		go streamHub.Run(ctx, deviceCache.Client())
+		streamPublisher = stream.NewPublisher(deviceCache.Client(), streamHub)
+		go streamPublisher.Run(ctx)
+		api.SetStreamTickets(stream.NewTicketStore(deviceCache.Client()))
+	} else {
+		streamPublisher = stream.NewPublisher(nil, streamHub)
+		api.SetStreamTickets(stream.NewTicketStore(nil))
+	}
+	api.SetStreamHub(streamHub)
+
+	mqttClient := startMQTTSubscriber(ctx, writePool)
+
+	// POST /go/ingest/{serial}/{stream} — același pipeline ca MQTT. HMAC per
+	// device doar cu Redis (secretele vin din Django ca ingestsecret:{serial}).
+	// Citirile acceptate sunt republicate pe relay/… pentru rule-engine
+	// (ingest.WithRelay) — altfel regulile nu le-ar vedea.
+	ingestPipeline := ingest.WithRelay(func(ctx context.Context, topic string, readings []ingest.Reading) ([]error, error) {
+		return processMessage(ctx, topic, readings, writePool)
+	}, func(topic string, payload []byte) error {
+		tok := mqttClient.Publish(topic, 1, false, payload)
+		if !tok.WaitTimeout(5 * time.Second) {
+			return fmt.Errorf("publish timeout")
+		}
+		return tok.Error()
+	})
+	api.SetIngestPipeline(ingestPipeline)
+	if deviceCache != nil {
+		api.SetIngestSecretStore(cache.NewIngestSecretStore(deviceCache.Client()))
+	}
+
+	// CoAP/UDP pentru device-urile NB-IoT pe baterie (COAP_ADDR, ex. ":5683").
+	// Token-urile pre-partajate (coaptoken:{serial}, separate de secretul HMAC)
+	// vin din Redis → listener-ul cere Redis.
+	if addr := os.Getenv("COAP_ADDR"); addr != "" {
+		if deviceCache == nil {
+			log.Printf("⚠️ COAP_ADDR=%s ignorat: token-urile CoAP cer Redis", addr)
+		} else {
+			coapServer := coap.NewServer(ingestPipeline, cache.NewCoAPTokenStore(deviceCache.Client()))
+			go func() {
+				log.Printf("✅ CoAP ingest pe udp %s", addr)
+				if err := coapServer.ListenAndServe(ctx, addr); err != nil {
+					log.Printf("❌ CoAP listener: %v", err)
+				}
+			}()
+		}
+	}
+
+	// Clientul de citire Influx e partajat între request-uri (conexiuni refolosite).
+	defer influx.DefaultQueryClient().Close()
+	exportTimeout, _ := time.ParseDuration(os.Getenv("INFLUX_EXPORT_TIMEOUT"))
+	api.SetExportTimeout(exportTimeout)
 
 	mux := http.NewServeMux()
 	api.RegisterRoutes(mux)
+	// Metrici runtime + latență query Influx per bucket (expvar "influx_query").
+	// Doar cu DEBUG_VARS=true — endpoint-ul nu trece prin autentificare.
+	if os.Getenv("DEBUG_VARS") == "true" {
+		mux.Handle("/debug/vars", expvar.Handler())
+	}
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		log.Printf("⚠️ Request necunoscut: %s %s", r.Method, r.URL.Path)
 		http.NotFound(w, r)
@@ -167,6 +274,7 @@ func main() {
 		Addr:    "0.0.0.0:" + apiPort,
 		Handler: api.EnableCORS(http.StripPrefix("/go", mux)),
 	}
+	server.RegisterOnShutdown(streamHub.Close)
 
 	go func() {
 		log.Printf("✅ API Go disponibil pe http://localhost:%s/go/*", apiPort)
@@ -184,7 +292,48 @@ func main() {
 	}
 }
 
-func startMQTTSubscriber(ctx context.Context, pool *influx.WritePool) {
+// newTokenVerifier — verificarea JWT în API-ul Go, independentă de Kong.
+// Fără JWT_JWKS rămâne HS256 cu JWT_SECRET.
+func newTokenVerifier(ctx context.Context) (*api.TokenVerifier, error) {
+	cfg := api.TokenVerifierConfig{
+		Issuer:   os.Getenv("JWT_ISSUER"),
+		Audience: os.Getenv("JWT_AUDIENCE"),
+		Leeway:   30 * time.Second,
+	}
+	if v := os.Getenv("JWT_LEEWAY"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("JWT_LEEWAY: %w", err)
+		}
+		cfg.Leeway = d
+	}
+	for _, alg := range strings.Split(os.Getenv("JWT_ALGORITHMS"), ",") {
+		if alg = strings.TrimSpace(alg); alg != "" {
+			cfg.Algorithms = append(cfg.Algorithms, alg)
+		}
+	}
+	if src := os.Getenv("JWT_JWKS"); src != "" {
+		keys, err := jwks.Load(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+		refresh := 10 * time.Minute
+		if d, err := time.ParseDuration(os.Getenv("JWT_JWKS_REFRESH")); err == nil {
+			refresh = d
+		}
+		go keys.Run(ctx, refresh)
+		cfg.Keys = keys
+		if len(cfg.Algorithms) == 0 {
+			cfg.Algorithms = []string{"RS256", "ES256"}
+		}
+		log.Printf("✅ JWKS: %d keys from %s (refresh %s)", keys.Len(), src, refresh)
+	}
+	return api.NewTokenVerifier(cfg)
+}
+
+// startMQTTSubscriber conectează clientul de ingest (log.Fatal la eșec) și îl
+// întoarce — e folosit și pentru relay-ul citirilor HTTP / CoAP.
+func startMQTTSubscriber(ctx context.Context, pool *influx.WritePool) mqtt.Client {
 	mqttBroker := os.Getenv("MQTT_BROKER")
 	mqttUsername := os.Getenv("MQTT_USER")
 	mqttPassword := os.Getenv("MQTT_PASS")
@@ -238,51 +387,50 @@ func startMQTTSubscriber(ctx context.Context, pool *influx.WritePool) {
 		log.Fatalf("Eroare la conectarea MQTT: %v\n", token.Error())
 	}
 
-	<-ctx.Done()
-	log.Println("🛑 MQTT: deconectare graceful…")
-	client.Disconnect(250)
-}
-
-// --- Structuri JSON ---
-type StateMessage struct {
-	POWER string `json:"POWER"`
-	RSSI  int    `json:"RSSI"`
-}
-
-type EnergyData struct {
-	Total          float64 `json:"Total"`
-	Today          float64 `json:"Today"`
-	Yesterday      float64 `json:"Yesterday"`
-	Power          float64 `json:"Power"`
-	ApparentPower  float64 `json:"ApparentPower"`
-	ReactivePower  float64 `json:"ReactivePower"`
-	Factor         float64 `json:"Factor"`
-	Voltage        float64 `json:"Voltage"`
-	Current        float64 `json:"Current"`
-}
-
-type SensorMessage struct {
-	Time   string     `json:"Time"`
-	ENERGY EnergyData `json:"ENERGY"`
+	go func() {
+		<-ctx.Done()
+		log.Println("🛑 MQTT: deconectare graceful…")
+		client.Disconnect(250)
+	}()
+	return client
 }
 
 // writePoint scrie un punct în Influx pe bucket-ul planului dat. Loghează enqueue-ul structurat.
 func writePoint(p *write.Point, pool *influx.WritePool, plan string, fields logging.Fields) {
 	pool.WritePoint(plan, p)
 	logging.Info("influx write enqueued", fields)
+	if streamPublisher != nil {
+		if ev, ok := stream.FromPoint(p, streamNormalizer); ok {
+			if err := streamPublisher.Publish(context.Background(), ev); err != nil {
+				logging.Error("stream publish failed", logging.Fields{"device_id": ev.Device, "error": err.Error()})
+			}
+		}
+	}
 }
 
 func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
-	topic := msg.Topic()
-	payload := msg.Payload()
+	logging.Info("mqtt message received", logging.Fields{"topic": msg.Topic(), "size": len(msg.Payload())})
+	// Mesajele respinse sunt deja log-ate (logging.Drop) în processMessage.
+	_, _ = processMessage(context.Background(), msg.Topic(), []ingest.Reading{{Payload: msg.Payload()}}, pool)
+}
 
-	logging.Info("mqtt message received", logging.Fields{"topic": topic, "size": len(payload)})
+// ingestDevice — device-ul unui mesaj, după lookup-ul tenant + plan.
+type ingestDevice struct {
+	ID     string
+	Tenant string // tag Influx: id-ul tenantului sau "unassigned"
+	Plan   string
+}
 
+// processMessage — pipeline-ul comun pentru MQTT și POST /go/ingest
+// (ingest.Pipeline): lookup device→tenant, validare tenant pentru schema nouă,
+// rate limit (o dată per apel — un batch HTTP contează ca un mesaj), apoi
+// matcher + parser + scriere pentru fiecare citire.
+func processMessage(ctx context.Context, topic string, readings []ingest.Reading, pool *influx.WritePool) ([]error, error) {
 	// Parse topic
 	parsed, err := topics.Parse(topic)
 	if err != nil {
 		logging.Drop("topic invalid", logging.Fields{"topic": topic, "error": err.Error()})
-		return
+		return nil, err
 	}
 
 	var deviceID string
@@ -293,7 +441,7 @@ func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
 	}
 	if deviceID == "" {
 		logging.Drop("empty device_id", logging.Fields{"topic": topic})
-		return
+		return nil, fmt.Errorf("empty device_id in topic %q", topic)
 	}
 
 	// Faza 2.4 + 2.7: lookup device→tenant+plan via Redis cache sau fallback Django.
@@ -302,7 +450,7 @@ func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
 	var deviceTenantID int64
 	found := false
 	if deviceCache != nil {
-		if entry, ok := deviceCache.GetDeviceInfo(context.Background(), deviceID); ok {
+		if entry, ok := deviceCache.GetDeviceInfo(ctx, deviceID); ok {
 			found = true
 			deviceTenantID = entry.TenantID
 			tenantTag = cache.ParseTenantTag(entry.TenantID)
@@ -334,7 +482,7 @@ func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
 			logging.Drop("unknown device on tenant-scoped topic", logging.Fields{
 				"topic": topic, "device_id": deviceID, "tenant_id": parsed.TenantID,
 			})
-			return
+			return nil, ingest.ErrUnknownDevice
 		}
 		if deviceTenantID != parsed.TenantID {
 			logging.Drop("device-tenant mismatch", logging.Fields{
@@ -343,7 +491,7 @@ func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
 				"device_tenant": deviceTenantID,
 				"topic":         topic,
 			})
-			return
+			return nil, ingest.ErrTenantMismatch
 		}
 	}
 
@@ -352,7 +500,7 @@ func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
 		logging.Drop("rate limited", logging.Fields{
 			"device_id": deviceID, "tenant_id": tenantTag, "topic": topic,
 		})
-		return
+		return nil, ingest.ErrRateLimited
 	}
 
 	if !found {
@@ -361,64 +509,49 @@ func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
 		})
 	}
 
+	dev := ingestDevice{ID: deviceID, Tenant: tenantTag, Plan: tenantPlan}
+	errs := make([]error, len(readings))
+	for i, rd := range readings {
+		errs[i] = writeMessage(dev, parsed, topic, rd, pool)
+	}
+	return errs, nil
+}
+
+// writeMessage identifică stream-ul unei citiri și o scrie. Întoarce motivul
+// pentru care citirea a fost aruncată (payload invalid), nil altfel.
+func writeMessage(dev ingestDevice, parsed topics.Parsed, topic string, rd ingest.Reading, pool *influx.WritePool) error {
+	deviceID, tenantTag, tenantPlan, payload := dev.ID, dev.Tenant, dev.Plan, rd.Payload
+	// Momentul citirii: din batch (device bufferat) sau acum; handler-ele care
+	// au `ts` / `Time` în payload îl preferă.
+	now := rd.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+
 	// ── Faza 3: Stream-based dispatcher ───────────────────────────────────
 	// Determinăm `streamID` prin matcher (preferred) sau fallback la parsed.Stream
 	// din topics.Parse. Toate handler-urile (cmd_ack/ota/shadow/telemetry/state/
 	// sensor/emeter/relay/zigbee/generic) sunt dispatch-uite pe baza acestui ID.
 	// Asta înlocuiește lanțul de `strings.Contains/HasSuffix` din versiunea pre-Faza-3.
 	streamID := ""
-	var matchedDDID string
+	var mch *matcher.Match
 	if topicMatcher != nil {
-		if mch := topicMatcher.Match(topic); mch != nil {
+		// MatchMessage: și payload_match (ex. zigbee2mqtt/+ → contact vs temperatură)
+		if mch = topicMatcher.MatchMessage(topic, payload); mch != nil {
 			streamID = mch.Stream
-			matchedDDID = mch.Definition.ID
 		}
 	}
 	if streamID == "" {
 		streamID = parsed.Stream
 	}
 
-	if matchedDDID != "" {
+	if mch != nil {
 		logging.Info("matcher hit", logging.Fields{
-			"topic": topic, "dd_id": matchedDDID, "stream": streamID,
+			"topic": topic, "dd_id": mch.Definition.ID, "stream": streamID,
 		})
 	}
 
 	switch streamID {
-	case "telemetry":
-		// SUN2000 — payload cu array measurements
-		var sun struct {
-			Ts           string                   `json:"ts"`
-			Measurements []map[string]interface{} `json:"measurements"`
-			HouseLoad    float64                  `json:"house_load_kw_est"`
-		}
-		if err := json.Unmarshal(payload, &sun); err == nil && len(sun.Measurements) > 0 {
-			fields := make(map[string]interface{}, len(sun.Measurements)+1)
-			for _, m := range sun.Measurements {
-				if key, ok := m["key"].(string); ok {
-					if val, ok := m["value"]; ok {
-						fields[key] = val
-					}
-				}
-			}
-			if sun.HouseLoad != 0 {
-				fields["house_load_kw_est"] = sun.HouseLoad
-			}
-			t := time.Now()
-			if sun.Ts != "" {
-				if pt, err := time.Parse(time.RFC3339, sun.Ts); err == nil {
-					t = pt
-				}
-			}
-			p := influxdb2.NewPoint("devices",
-				map[string]string{"device": deviceID, "source": "sun2000", "type": "solar_inverter", "tenant_id": tenantTag},
-				fields, t)
-			writePoint(p, pool, tenantPlan, logging.Fields{
-				"source": "sun2000", "type": "solar_inverter", "device_id": deviceID, "tenant_id": tenantTag,
-			})
-		}
-		return
-
 	case "cmd_ack":
 		// Faza 3.3: ACK pentru comenzi downlink
 		var ack struct {
@@ -428,7 +561,7 @@ func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
 		}
 		if err := json.Unmarshal(payload, &ack); err != nil {
 			logging.Drop("cmd_ack parse failed", logging.Fields{"error": err.Error(), "device_id": deviceID})
-			return
+			return err
 		}
 		cmdStatus := "executed"
 		if !ack.Success {
@@ -437,7 +570,7 @@ func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
 		if err := django.AckCommand(ack.CommandID, cmdStatus, ack.Result); err != nil {
 			logging.Warn("AckCommand failed", logging.Fields{"cmd_id": ack.CommandID, "error": err.Error()})
 		}
-		return
+		return nil
 
 	case "ota":
 		// Faza 3.5: OTA status raportat de device
@@ -448,161 +581,49 @@ func handleMessage(msg mqtt.Message, pool *influx.WritePool) {
 		}
 		if err := json.Unmarshal(payload, &ota); err != nil {
 			logging.Drop("ota parse failed", logging.Fields{"error": err.Error(), "device_id": deviceID})
-			return
+			return err
 		}
 		if err := django.UpdateOTAStatus(deviceID, ota.FirmwareID, ota.Status, ota.Error); err != nil {
 			logging.Warn("UpdateOTAStatus failed", logging.Fields{"device_id": deviceID, "error": err.Error()})
 		}
-		return
+		return nil
 
 	case "shadow":
 		// Faza 3.4: shadow reported de la device
 		var reported map[string]interface{}
 		if err := json.Unmarshal(payload, &reported); err != nil {
 			logging.Drop("shadow parse failed", logging.Fields{"error": err.Error(), "device_id": deviceID})
-			return
+			return err
 		}
 		if err := django.UpdateShadowReported(deviceID, reported); err != nil {
 			logging.Warn("UpdateShadowReported failed", logging.Fields{"device_id": deviceID, "error": err.Error()})
 		}
-		return
-
-	case "emeter":
-		// Shelly EM — payload e plain string per topic (ex: "1234.56")
-		// Field-ul e ultimul segment al topicului ("power", "voltage", etc.)
-		valStr := string(payload)
-		var value float64
-		if _, err := fmt.Sscanf(valStr, "%f", &value); err != nil {
-			log.Printf("❌ Eroare conversie la float pentru %s: %v", valStr, err)
-			return
-		}
-		topicParts := strings.Split(topic, "/")
-		field := topicParts[len(topicParts)-1]
-		p := influxdb2.NewPoint("devices",
-			map[string]string{"device": deviceID, "source": "shelly", "type": "power_meter", "tenant_id": tenantTag},
-			map[string]interface{}{titleCaser.String(field): value},
-			time.Now())
-		writePoint(p, pool, tenantPlan, logging.Fields{
-			"source": "shelly", "field": field, "value": value, "device_id": deviceID, "tenant_id": tenantTag,
-		})
-		return
-
-	case "relay":
-		// Shelly relay — payload "on"/"off"
-		valStr := strings.ToLower(string(payload))
-		state := 0
-		if valStr == "on" {
-			state = 1
-		}
-		p := influxdb2.NewPoint("devices",
-			map[string]string{"device": deviceID, "source": "shelly", "type": "relay", "tenant_id": tenantTag},
-			map[string]interface{}{"state": state},
-			time.Now())
-		writePoint(p, pool, tenantPlan, logging.Fields{
-			"source": "shelly", "type": "relay", "state": state, "device_id": deviceID, "tenant_id": tenantTag,
-		})
-		return
-
-	case "state":
-		// Tasmota STATE — Scriem AMBELE:
-		//   relay_state — string "ON"/"OFF" (audit, lizibil)
-		//   relay_on    — int 1/0 (pentru polling/UI confirmation)
-		var state StateMessage
-		if err := json.Unmarshal(payload, &state); err != nil {
-			logging.Drop("parse STATE failed", logging.Fields{"error": err.Error(), "topic": topic, "device_id": deviceID})
-			return
-		}
-		relayOn := 0
-		if strings.EqualFold(state.POWER, "ON") {
-			relayOn = 1
-		}
-		p := influxdb2.NewPoint("devices",
-			map[string]string{"device": deviceID, "source": "nousat", "type": "state", "tenant_id": tenantTag},
-			map[string]interface{}{
-				"relay_state": state.POWER,
-				"relay_on":    relayOn,
-				"rssi":        state.RSSI,
-			},
-			time.Now())
-		writePoint(p, pool, tenantPlan, logging.Fields{
-			"source": "nousat", "type": "state", "device_id": deviceID, "tenant_id": tenantTag,
-		})
-		return
-
-	case "sensor":
-		// Tasmota SENSOR — payload cu nested ENERGY object
-		// Folosim prefix `nousat_` pentru toate field-urile ca să evităm type conflicts
-		// pe scopul global al measurement="devices".
-		var sensor SensorMessage
-		if err := json.Unmarshal(payload, &sensor); err != nil {
-			logging.Drop("parse SENSOR failed", logging.Fields{"error": err.Error(), "topic": topic, "device_id": deviceID})
-			return
-		}
-		t, err := time.Parse(time.RFC3339, sensor.Time)
-		if err != nil {
-			t = time.Now()
-		}
-		p := influxdb2.NewPoint("devices",
-			map[string]string{"device": deviceID, "source": "nousat", "type": "energy", "tenant_id": tenantTag},
-			map[string]interface{}{
-				"nousat_power":          sensor.ENERGY.Power,
-				"nousat_apparent_power": sensor.ENERGY.ApparentPower,
-				"nousat_reactive_power": sensor.ENERGY.ReactivePower,
-				"nousat_power_factor":   sensor.ENERGY.Factor,
-				"nousat_voltage":        sensor.ENERGY.Voltage,
-				"nousat_current":        sensor.ENERGY.Current,
-				"nousat_total":          sensor.ENERGY.Total,
-				"nousat_today":          sensor.ENERGY.Today,
-				"nousat_yesterday":      sensor.ENERGY.Yesterday,
-			},
-			t)
-		writePoint(p, pool, tenantPlan, logging.Fields{
-			"source": "nousat", "type": "energy", "device_id": deviceID, "tenant_id": tenantTag,
-		})
-		return
-
-	case "zigbee":
-		// Zigbee2MQTT — flat JSON cu chei standard
-		var data map[string]interface{}
-		if err := json.Unmarshal(payload, &data); err != nil {
-			logging.Drop("parse zigbee2mqtt failed", logging.Fields{"error": err.Error(), "topic": topic, "device_id": deviceID})
-			return
-		}
-		p := influxdb2.NewPoint("devices",
-			map[string]string{"device": deviceID, "source": "zigbee2mqtt", "type": "sensor", "tenant_id": tenantTag},
-			data,
-			time.Now())
-		writePoint(p, pool, tenantPlan, logging.Fields{
-			"source": "zigbee2mqtt", "type": "sensor", "device_id": deviceID, "tenant_id": tenantTag,
-		})
-		return
-
-	default:
-		// Generic / auto_detected fallback — pentru topice care nu match nici un DD.
-		var data map[string]interface{}
-		if err := json.Unmarshal(payload, &data); err == nil {
-			p := influxdb2.NewPoint("devices",
-				map[string]string{"device": deviceID, "source": "generic", "type": "auto_detected", "tenant_id": tenantTag},
-				data,
-				time.Now())
-			writePoint(p, pool, tenantPlan, logging.Fields{
-				"source": "generic", "type": "auto_detected", "device_id": deviceID, "tenant_id": tenantTag,
-			})
-		} else {
-			valStr := string(payload)
-			var val interface{}
-			if f, err := strconv.ParseFloat(valStr, 64); err == nil {
-				val = f
-			} else {
-				val = valStr
-			}
-			p := influxdb2.NewPoint("devices",
-				map[string]string{"device": deviceID, "source": "generic", "type": "auto_detected", "tenant_id": tenantTag},
-				map[string]interface{}{"value": val},
-				time.Now())
-			writePoint(p, pool, tenantPlan, logging.Fields{
-				"source": "generic", "type": "auto_detected_simple", "device_id": deviceID, "tenant_id": tenantTag,
-			})
-		}
+		return nil
+	}
+
+	// Field-urile (și numele lor) vin din parsers.Decode — aceleași pe care
+	// le vede rule-engine-ul: handler dedicat, parser-ul DD-ului sau generic.
+	var dd *registry.DeviceDefinition
+	var vars map[string]string
+	if mch != nil {
+		dd, vars = mch.Definition, mch.Extracted
+	}
+	pt, err := parsers.Decode(dd, streamID, topic, vars, payload)
+	if err != nil {
+		logging.Drop("parse failed", logging.Fields{"error": err.Error(), "stream": streamID, "topic": topic, "device_id": deviceID})
+		return err
 	}
+	if !pt.Time.IsZero() {
+		now = pt.Time
+	}
+	tags := map[string]string{"device": deviceID, "source": pt.Source, "type": pt.Type, "tenant_id": tenantTag}
+	if dd != nil {
+		// dd_id — exportul normalizat aplică normalized_fields ale DD-ului din matcher
+		tags["dd_id"] = dd.ID
+	}
+	p := influxdb2.NewPoint("devices", tags, pt.Fields, now)
+	writePoint(p, pool, tenantPlan, logging.Fields{
+		"source": pt.Source, "type": pt.Type, "device_id": deviceID, "tenant_id": tenantTag,
+	})
+	return nil
 }
@@ -0,0 +1,108 @@
+// cmd/modbus-collector — Faza 7.5: collector Modbus TCP.
+//
+// Pentru device-urile care expun Modbus TCP în loc de un collector MQTT (ex.
+// Huawei SUN2000 prin SDongle / SmartLogger): citește periodic registrele
+// declarate în DD-ul endpoint-ului (protocol: modbus_tcp, blocul `modbus:`) și
+// publică valorile pe tenants/{tid}/devices/{serial}/up/telemetry, în formatul
+// parser-ului DD-ului. De acolo ingest-ul, rule-engine-ul și capabilities le
+// tratează ca pe orice altă telemetrie platform-nativă.
+//
+// Config:
+//
+//	MODBUS_ENDPOINTS   fișierul cu endpoint-uri (default ../configs/modbus/endpoints.yaml)
+//	DD_DIR             directorul DD-urilor (default ../configs/devices)
+//	MQTT_BROKER / MQTT_USER / MQTT_PASS
+//
+// Flag -once: o singură citire a tuturor endpoint-urilor, apoi exit (diagnostic;
+// cod 1 dacă vreun endpoint a eșuat).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/joho/godotenv"
+
+	"go-iot-platform/internal/modbus"
+	"go-iot-platform/internal/registry"
+)
+
+func main() {
+	once := flag.Bool("once", false, "poll every endpoint once and exit")
+	flag.Parse()
+	_ = godotenv.Load()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	ddDir := os.Getenv("DD_DIR")
+	if ddDir == "" {
+		ddDir = "../configs/devices"
+	}
+	reg, err := registry.LoadDirOrLog(ddDir, false)
+	if err != nil {
+		log.Fatalf("modbus-collector: registry load %q: %v", ddDir, err)
+	}
+
+	endpointsPath := os.Getenv("MODBUS_ENDPOINTS")
+	if endpointsPath == "" {
+		endpointsPath = "../configs/modbus/endpoints.yaml"
+	}
+	endpoints, err := modbus.LoadEndpoints(endpointsPath)
+	if err != nil {
+		log.Fatalf("modbus-collector: endpoints: %v", err)
+	}
+	if len(endpoints) == 0 {
+		log.Fatalf("modbus-collector: no endpoints in %s", endpointsPath)
+	}
+
+	broker := os.Getenv("MQTT_BROKER")
+	if broker == "" {
+		log.Fatal("modbus-collector: MQTT_BROKER not set")
+	}
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(broker)
+	opts.SetClientID(fmt.Sprintf("modbus-collector-%d", time.Now().UnixNano()))
+	opts.SetUsername(os.Getenv("MQTT_USER"))
+	opts.SetPassword(os.Getenv("MQTT_PASS"))
+	opts.SetAutoReconnect(true)
+	opts.SetMaxReconnectInterval(30 * time.Second)
+	client := mqtt.NewClient(opts)
+	if tok := client.Connect(); tok.Wait() && tok.Error() != nil {
+		log.Fatalf("modbus-collector: mqtt connect: %v", tok.Error())
+	}
+	defer client.Disconnect(500)
+
+	publish := func(topic string, payload []byte) error {
+		tok := client.Publish(topic, 1, false, payload)
+		if !tok.WaitTimeout(10 * time.Second) {
+			return fmt.Errorf("publish timeout")
+		}
+		return tok.Error()
+	}
+
+	collector, err := modbus.New(reg, endpoints, publish)
+	if err != nil {
+		log.Fatalf("modbus-collector: %v", err)
+	}
+
+	if *once {
+		if err := collector.PollOnce(ctx); err != nil {
+			log.Printf("modbus-collector: %v", err)
+			client.Disconnect(500)
+			os.Exit(1)
+		}
+		return
+	}
+
+	log.Printf("modbus-collector: polling %d endpoint(s) from %s", len(endpoints), endpointsPath)
+	collector.Run(ctx)
+	log.Println("modbus-collector: stopped")
+}
@@ -1,8 +1,21 @@
 // cmd/rule-engine — Faza 4.1: evaluator de reguli IoT în timp real.
 //
-// Subscrie la $share/rules/tenants/+/devices/+/up/#
-// Pentru fiecare mesaj: încarcă regulile tenantului din Redis (sau Django),
-// evaluează condițiile DSL, verifică cooldown, execută acțiunile.
+// Subscrie la $share/rules/tenants/+/devices/+/up/# (+ topicurile vendor legacy
+// când RULES_LEGACY_TOPICS=true) și la relay/tenants/+/devices/+/up/# — citirile
+// primite de go-iot-platform pe HTTP / CoAP, republicate după scriere
+// (ingest.WithRelay). Mesajele sunt identificate prin registry/matcher
+// și decodate cu parser-ul DD-ului (raw / keyvalue / measurements array / json),
+// deci regulile văd același field map indiferent de formatul payload-ului.
+// Pentru fiecare mesaj: ia regulile tenantului din cache-ul in-process (miss →
+// Redis → Django), evaluează condițiile DSL, verifică cooldown, execută acțiunile.
+// Mesajele relay poartă momentul citirii (ingest.RelayMessage): citirile mai
+// vechi de RULES_MAX_READING_AGE (default 5m — batch-uri bufferate) nu declanșează
+// reguli, doar actualizează rule_seen cu momentul lor.
+// Cache-ul local e invalidat prin pub/sub rules-cache-invalidate + resync periodic.
+//
+// Regulile trigger_type=schedule (cron / @every) și absence (device tăcut pe un
+// stream) sunt rulate de rules.Scheduler pe ultima stare salvată în Redis, doar
+// pe instanța care deține lock-ul de leader (RULES_SCHEDULER=false îl dezactivează).
 //
 // Acțiuni suportate:
 //   - downlink:   publică MQTT pe tenants/{tid}/devices/{serial}/down/cmd
@@ -15,7 +28,6 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"log"
 	"os"
@@ -28,8 +40,13 @@ import (
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
 
+	"go-iot-platform/internal/cache"
 	"go-iot-platform/internal/django"
+	"go-iot-platform/internal/ingest"
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/registry"
 	"go-iot-platform/internal/rules"
+	"go-iot-platform/internal/topics"
 )
 
 func main() {
@@ -65,6 +82,50 @@ func main() {
 		}
 	}
 
+	// ── Registry + matcher (identificare DD + parser per mesaj) ───────────────
+	var topicMatcher *matcher.Matcher
+	if os.Getenv("MATCHER_ENABLED") != "false" {
+		ddDir := os.Getenv("DD_DIR")
+		if ddDir == "" {
+			ddDir = "../configs/devices"
+		}
+		reg, err := registry.LoadDirOrLog(ddDir, false)
+		if err != nil {
+			log.Printf("rule-engine: registry load %q failed: %v (doar payload JSON)", ddDir, err)
+		} else {
+			m, mErrs := matcher.New(reg)
+			for _, e := range mErrs {
+				log.Printf("rule-engine: matcher compile: %v", e)
+			}
+			topicMatcher = m
+			log.Printf("rule-engine: topic matcher: %d patterns from %d device definitions",
+				m.Count(), reg.Count())
+		}
+	}
+
+	// Topicurile legacy nu au tenant în topic → lookup device→tenant în cache.
+	legacyTopics := os.Getenv("RULES_LEGACY_TOPICS") == "true"
+	var deviceCache *cache.Cache
+	if legacyTopics && rdb != nil {
+		dbNum, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
+		c, err := cache.New(ctx, cache.Config{
+			Addr:     os.Getenv("REDIS_ADDR"),
+			Password: os.Getenv("REDIS_PASSWORD"),
+			DB:       dbNum,
+		})
+		if err != nil {
+			log.Printf("rule-engine: device cache unavailable (%v); legacy topics ignored", err)
+		} else {
+			deviceCache = c
+			defer deviceCache.Close()
+			go func() {
+				for err := range deviceCache.SubscribeInvalidations(ctx) {
+					log.Printf("rule-engine: device cache invalidation error: %v", err)
+				}
+			}()
+		}
+	}
+
 	djangoBase := os.Getenv("DJANGO_BASE_URL")
 	// DJANGO_BASE_URL is typically "http://host:port/api" — strip /api suffix
 	if len(djangoBase) > 4 && djangoBase[len(djangoBase)-4:] == "/api" {
@@ -74,6 +135,24 @@ func main() {
 	svcPass := os.Getenv("DJANGO_SERVICE_PASS")
 
 	ruleCache := rules.NewRuleCache(rdb, djangoBase, svcUser, svcPass)
+	go func() {
+		for err := range ruleCache.SubscribeInvalidations(ctx) {
+			log.Printf("rule-engine: rules invalidation error: %v", err)
+		}
+	}()
+	resyncEvery := rules.DefaultResyncInterval
+	if v := os.Getenv("RULES_RESYNC_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			resyncEvery = d
+		}
+	}
+	go ruleCache.RunResync(ctx, resyncEvery)
+	maxReadingAge := rules.DefaultMaxReadingAge
+	if v := os.Getenv("RULES_MAX_READING_AGE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			maxReadingAge = d
+		}
+	}
 
 	// ── MQTT pub client (for downlink actions) ────────────────────────────────
 	broker := os.Getenv("MQTT_BROKER")
@@ -97,6 +176,46 @@ func main() {
 
 	executor := rules.NewExecutor(pubClient, djangoBase, svcUser, svcPass)
 
+	// Execuțiile sunt log-ate async, în batch-uri, prin /api/internal/rules/log/bulk/.
+	// Dacă Django pică, batch-urile merg în spool-ul local și sunt re-trimise ulterior.
+	spoolPath := os.Getenv("RULES_EXEC_SPOOL")
+	if spoolPath == "" {
+		spoolPath = "logs/rule_exec_spool.jsonl"
+	}
+	if _, err := os.Stat("logs"); os.IsNotExist(err) {
+		_ = os.Mkdir("logs", 0755)
+	}
+	// RULES_EXEC_SPOOL_MAX_MB — plafonul spool-ului (default 64); peste el se
+	// pierd cele mai vechi execuții.
+	spoolMaxMB, _ := strconv.ParseInt(os.Getenv("RULES_EXEC_SPOOL_MAX_MB"), 10, 64)
+	execLog := rules.NewExecLogger(executor, rules.ExecLogConfig{SpoolPath: spoolPath, SpoolMaxBytes: spoolMaxMB << 20})
+	execLogDone := make(chan struct{})
+	go func() {
+		execLog.Run(ctx)
+		close(execLogDone)
+	}()
+
+	// ── Scheduler (schedule / absence) — necesită Redis pentru stare + leader lock ──
+	schedDone := make(chan struct{})
+	if rdb != nil && os.Getenv("RULES_SCHEDULER") != "false" {
+		schedCfg := rules.SchedulerConfig{}
+		if tz := os.Getenv("RULES_SCHEDULE_TZ"); tz != "" {
+			if loc, err := time.LoadLocation(tz); err == nil {
+				schedCfg.Location = loc
+			} else {
+				log.Printf("rule-engine: RULES_SCHEDULE_TZ %q: %v (folosim ora locală)", tz, err)
+			}
+		}
+		scheduler := rules.NewScheduler(rdb, ruleCache.FetchTimedRules, executor, execLog, schedCfg)
+		go func() {
+			scheduler.Run(ctx)
+			close(schedDone)
+		}()
+	} else {
+		log.Println("rule-engine: scheduler disabled (schedule/absence rules will not fire)")
+		close(schedDone)
+	}
+
 	// ── MQTT sub client ───────────────────────────────────────────────────────
 	subClientID := fmt.Sprintf("rule-engine-sub-%d", time.Now().UnixNano())
 	subOpts := mqtt.NewClientOptions()
@@ -106,12 +225,28 @@ func main() {
 	subOpts.SetPassword(os.Getenv("MQTT_PASS"))
 	subOpts.SetAutoReconnect(true)
 	subOpts.SetMaxReconnectInterval(30 * time.Second)
+	subscriptions := []string{
+		"$share/rules/tenants/+/devices/+/up/#",
+		"$share/rules/" + ingest.RelayPrefix + "tenants/+/devices/+/up/#",
+	}
+	if deviceCache != nil {
+		// Activ doar când bridge-ul (Faza 2.2) NU rulează — altfel mesajul ar fi
+		// evaluat de două ori (o dată legacy, o dată după re-publish).
+		subscriptions = append(subscriptions,
+			"$share/rules-legacy/shellies/+/#",
+			"$share/rules-legacy/tele/+/#",
+			"$share/rules-legacy/zigbee2mqtt/+",
+			"$share/rules-legacy//+/+/+/telemetry",
+		)
+	}
+	handler := makeHandler(ctx, ruleCache, executor, execLog, rdb, topicMatcher, deviceCache, maxReadingAge)
 	subOpts.OnConnect = func(c mqtt.Client) {
-		topic := "$share/rules/tenants/+/devices/+/up/#"
-		if tok := c.Subscribe(topic, 0, makeHandler(ctx, ruleCache, executor, rdb)); tok.Wait() && tok.Error() != nil {
-			log.Printf("rule-engine: subscribe error: %v", tok.Error())
-		} else {
-			log.Printf("rule-engine: subscribed %s", topic)
+		for _, topic := range subscriptions {
+			if tok := c.Subscribe(topic, 0, handler); tok.Wait() && tok.Error() != nil {
+				log.Printf("rule-engine: subscribe %s: %v", topic, tok.Error())
+			} else {
+				log.Printf("rule-engine: subscribed %s", topic)
+			}
 		}
 	}
 
@@ -124,33 +259,81 @@ func main() {
 	log.Println("rule-engine: running — Ctrl-C to stop")
 	<-ctx.Done()
 	log.Println("rule-engine: shutting down")
+	<-schedDone
+	<-execLogDone
 }
 
 func makeHandler(
 	ctx context.Context,
-	cache *rules.RuleCache,
+	ruleCache *rules.RuleCache,
 	exec *rules.Executor,
+	execLog *rules.ExecLogger,
 	rdb *redis.Client,
+	topicMatcher *matcher.Matcher,
+	deviceCache *cache.Cache,
+	maxReadingAge time.Duration,
 ) mqtt.MessageHandler {
 	return func(_ mqtt.Client, msg mqtt.Message) {
-		topic := msg.Topic()
+		// relay/… → topicul original, ca pentru un mesaj MQTT direct; payload-ul
+		// și momentul citirii vin din plic
+		topic, relayed := ingest.FromRelay(msg.Topic())
+		raw, at := msg.Payload(), time.Now()
+		if relayed {
+			rm := ingest.DecodeRelay(raw)
+			raw = rm.Payload
+			if !rm.Time.IsZero() {
+				at = rm.Time
+			}
+		}
+
+		var mch *matcher.Match
+		if topicMatcher != nil {
+			mch = topicMatcher.MatchMessage(topic, raw)
+		}
+
 		tenantID, serial, stream, ok := rules.ParseTopic(topic)
-		if !ok {
-			return
+		if ok {
+			if mch != nil && mch.Stream != "" {
+				stream = mch.Stream
+			}
+		} else {
+			// Topic vendor legacy: device din matcher, tenant din cache-ul device→tenant.
+			if mch == nil || deviceCache == nil {
+				return
+			}
+			serial = mch.Extracted["device_id"]
+			if serial == "" {
+				serial = topics.LegacyDeviceID(topic)
+			}
+			tid, found := deviceCache.GetDeviceTenant(ctx, serial)
+			if !found || tid <= 0 {
+				return
+			}
+			tenantID, stream = tid, mch.Stream
+			if stream == "" {
+				stream = "telemetry"
+			}
 		}
 
-		var payload map[string]interface{}
-		if err := json.Unmarshal(msg.Payload(), &payload); err != nil {
-			log.Printf("rule-engine: invalid JSON on %s: %v", topic, err)
+		payload, err := rules.DecodePayload(mch, stream, topic, raw)
+		if err != nil {
+			log.Printf("rule-engine: undecodable payload on %s: %v", topic, err)
 			return
 		}
 
-		ruleList, err := cache.GetRules(ctx, tenantID)
+		ruleSet, err := ruleCache.GetRuleSet(ctx, tenantID)
 		if err != nil {
 			log.Printf("rule-engine: load rules tenant %d: %v", tenantID, err)
 			return
 		}
 
+		if time.Since(at) > maxReadingAge {
+			// citire bufferată: fără acțiuni pe date vechi, fără rule_prev / stare
+			// suprascrise; rule_seen nu coboară (ZADD GT)
+			rules.RecordSeen(ctx, rdb, ruleSet, serial, stream, at)
+			return
+		}
+
 		prevState := rules.GetPrevState(ctx, rdb, tenantID, serial)
 
 		msgCtx := rules.MessageContext{
@@ -161,51 +344,21 @@ func makeHandler(
 			RawTopic: topic,
 		}
 
-		for _, rule := range ruleList {
-			if !rule.Enabled {
-				continue
-			}
-			if !rules.MatchesStream(rule, stream) {
-				continue
-			}
-			if !rules.Evaluate(rule.Conditions, payload, prevState) {
+		for _, rule := range ruleSet.ForStream(stream) {
+			if !ruleSet.Evaluate(rule.Conditions, payload, prevState) {
 				continue
 			}
 			if !rules.CheckAndSetCooldown(ctx, rdb, rule.ID, serial, rule.CooldownSeconds) {
-				logExecution(ctx, exec, rule, msgCtx, nil, rules.StatusCooldown, "")
+				execLog.CooldownSkipped(rule, msgCtx)
 				continue
 			}
 
 			results := exec.Execute(ctx, rule, msgCtx, 0)
-			logExecution(ctx, exec, rule, msgCtx, results, rules.StatusTriggered, "")
+			execLog.Log(rules.NewExecRecord(rule, msgCtx, results, rules.StatusTriggered, ""))
 			log.Printf("rule-engine: rule %q fired on %s/%s → %d actions", rule.Name, serial, stream, len(results))
 		}
 
 		rules.SetPrevState(ctx, rdb, tenantID, serial, payload)
-	}
-}
-
-func logExecution(
-	ctx context.Context,
-	exec *rules.Executor,
-	rule rules.Rule,
-	msgCtx rules.MessageContext,
-	actionResults []map[string]interface{},
-	status rules.ExecStatus,
-	errMsg string,
-) {
-	body := map[string]interface{}{
-		"rule_id":            rule.ID,
-		"rule_name":          rule.Name,
-		"tenant_id":          msgCtx.TenantID,
-		"device_serial":      msgCtx.Serial,
-		"stream":             msgCtx.Stream,
-		"conditions_snapshot": rule.Conditions,
-		"actions_taken":      actionResults,
-		"status":             string(status),
-		"error_message":      errMsg,
-	}
-	if err := exec.LogExecution(ctx, body); err != nil {
-		log.Printf("rule-engine: log failed: %v", err)
+		rules.RecordState(ctx, rdb, ruleSet, serial, stream, payload, at)
 	}
 }
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"go-iot-platform/internal/cache"
+	"go-iot-platform/internal/ratelimit"
+)
+
+// apiKeyHeader — alternativa la Bearer JWT pentru integrări M2M; în Kong,
+// go-api-key-route trimite aceste request-uri fără plugin-ul jwt.
+const apiKeyHeader = "X-API-Key"
+
+// RoleAPIKey — rolul efectiv al cheilor API; permisiunile vin din scopes.
+const RoleAPIKey = "API_KEY"
+
+// defaultAPIKeyScopes — cheie fără scopes → doar citire metrici.
+var defaultAPIKeyScopes = []Permission{PermReadMetrics, PermReadSeries}
+
+// APIKeyLookup — implementat de cache.APIKeyStore; interfață ca să putem testa fără Redis.
+type APIKeyLookup interface {
+	Lookup(ctx context.Context, plain string) (cache.APIKey, error)
+}
+
+// apiKeys — setat din cmd/main.go (SetAPIKeyStore); nil → X-API-Key refuzat.
+var (
+	apiKeys        APIKeyLookup
+	apiKeyLimiters = newKeyLimiters(600)
+)
+
+// SetAPIKeyStore activează X-API-Key; defaultPerMin se aplică cheilor fără rate_limit.
+func SetAPIKeyStore(s APIKeyLookup, defaultPerMin int) {
+	apiKeys = s
+	apiKeyLimiters = newKeyLimiters(defaultPerMin)
+}
+
+// apiKeyContext verifică cheia și construiește contextul: tenant din cheie,
+// permisiuni din scopes (cele necunoscute pentru Go sunt ignorate).
+func apiKeyContext(ctx context.Context, plain string) (tokenContext, error) {
+	if apiKeys == nil {
+		return tokenContext{}, errors.New("API keys not enabled")
+	}
+	k, err := apiKeys.Lookup(ctx, plain)
+	if err != nil {
+		return tokenContext{}, err
+	}
+	tc := tokenContext{
+		Username:  "apikey:" + k.Prefix,
+		TenantID:  k.TenantID,
+		Role:      RoleAPIKey,
+		APIKeyID:  k.ID,
+		RateLimit: k.RateLimit,
+	}
+	for _, s := range k.Scopes {
+		p := Permission(s)
+		if _, known := permissionNames[p]; known {
+			tc.Scopes = append(tc.Scopes, p)
+		}
+	}
+	if len(k.Scopes) == 0 {
+		tc.Scopes = defaultAPIKeyScopes
+	}
+	return tc, nil
+}
+
+var permissionNames = map[Permission]struct{}{
+	PermReadMetrics: {}, PermReadSeries: {}, PermExport: {}, PermIngest: {},
+}
+
+// keyLimiters — un token bucket per cheie (per instanță), capacitate = limita
+// pe minut. Dacă rate_limit se schimbă în Django, bucket-ul e recreat.
+type keyLimiters struct {
+	mu            sync.Mutex
+	defaultPerMin int
+	buckets       map[int64]*keyBucket
+}
+
+type keyBucket struct {
+	perMin int
+	b      *ratelimit.Bucket
+}
+
+func newKeyLimiters(defaultPerMin int) *keyLimiters {
+	if defaultPerMin <= 0 {
+		defaultPerMin = 600
+	}
+	return &keyLimiters{defaultPerMin: defaultPerMin, buckets: map[int64]*keyBucket{}}
+}
+
+// Allow întoarce (permis, limita efectivă req/min).
+func (l *keyLimiters) Allow(keyID int64, perMin int) (bool, int) {
+	if perMin <= 0 {
+		perMin = l.defaultPerMin
+	}
+	l.mu.Lock()
+	kb, ok := l.buckets[keyID]
+	if !ok || kb.perMin != perMin {
+		kb = &keyBucket{perMin: perMin, b: ratelimit.NewBucket(float64(perMin)/60, float64(perMin))}
+		l.buckets[keyID] = kb
+	}
+	l.mu.Unlock()
+	return kb.b.Allow(), perMin
+}
+
+// rateLimitAPIKey scrie 429 + Retry-After când cheia și-a depășit limita.
+func rateLimitAPIKey(w http.ResponseWriter, r *http.Request, tc tokenContext, perm Permission) bool {
+	ok, perMin := apiKeyLimiters.Allow(tc.APIKeyID, tc.RateLimit)
+	if ok {
+		return true
+	}
+	auditDenied(r, tc, perm, "rate_limit", nil)
+	retry := int(math.Ceil(60 / float64(perMin)))
+	w.Header().Set("Retry-After", strconv.Itoa(retry))
+	http.Error(w, fmt.Sprintf("Too Many Requests: API key limit %d/min", perMin), http.StatusTooManyRequests)
+	return false
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-iot-platform/internal/cache"
+)
+
+type stubKeys map[string]cache.APIKey
+
+func (s stubKeys) Lookup(_ context.Context, plain string) (cache.APIKey, error) {
+	if plain == "redis-down" {
+		return cache.APIKey{}, cache.ErrAPIKeyUnavailable
+	}
+	k, ok := s[plain]
+	if !ok {
+		return cache.APIKey{}, cache.ErrAPIKeyInvalid
+	}
+	return k, nil
+}
+
+func withAPIKeys(t *testing.T, keys stubKeys, perMin int) {
+	t.Helper()
+	prevStore, prevLim := apiKeys, apiKeyLimiters
+	SetAPIKeyStore(keys, perMin)
+	t.Cleanup(func() { apiKeys, apiKeyLimiters = prevStore, prevLim })
+}
+
+func keyRequest(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/series", nil)
+	req.Header.Set(apiKeyHeader, key)
+	return req
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	withAPIKeys(t, stubKeys{
+		"reader":   {ID: 1, TenantID: 9, Prefix: "reader"},
+		"exporter": {ID: 2, TenantID: 9, Scopes: []string{"export", "devices:write"}},
+	}, 100)
+
+	cases := []struct {
+		key  string
+		perm Permission
+		code int
+	}{
+		{"reader", PermReadMetrics, http.StatusOK},
+		{"reader", PermReadSeries, http.StatusOK},
+		{"reader", PermExport, http.StatusForbidden},
+		{"exporter", PermExport, http.StatusOK},
+		{"exporter", PermReadMetrics, http.StatusForbidden},
+		{"nope", PermReadMetrics, http.StatusUnauthorized},
+		{"redis-down", PermReadMetrics, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		rec.Code = http.StatusOK
+		tc, ok := authorize(rec, keyRequest(c.key), c.perm)
+		if rec.Code != c.code || ok != (c.code == http.StatusOK) {
+			t.Errorf("%s %s: code=%d ok=%v", c.key, c.perm, rec.Code, ok)
+		}
+		if ok && (tc.TenantID != 9 || tc.effectiveRole() != RoleAPIKey) {
+			t.Errorf("%s: tc = %+v", c.key, tc)
+		}
+	}
+}
+
+func TestAPIKeyRateLimit(t *testing.T) {
+	withAPIKeys(t, stubKeys{
+		"slow": {ID: 1, TenantID: 9, RateLimit: 2},
+		"fast": {ID: 2, TenantID: 9},
+	}, 5)
+
+	codes := map[int]int{}
+	for i := 0; i < 4; i++ {
+		rec := httptest.NewRecorder()
+		if _, ok := authorize(rec, keyRequest("slow"), PermReadMetrics); !ok {
+			codes[rec.Code]++
+			if rec.Header().Get("Retry-After") != "30" {
+				t.Errorf("Retry-After = %q", rec.Header().Get("Retry-After"))
+			}
+		}
+	}
+	if codes[http.StatusTooManyRequests] != 2 {
+		t.Errorf("slow key: rejections = %v, want 2×429", codes)
+	}
+	// limita e per cheie; "fast" folosește default-ul (5/min)
+	for i := 0; i < 5; i++ {
+		if _, ok := authorize(httptest.NewRecorder(), keyRequest("fast"), PermReadMetrics); !ok {
+			t.Fatalf("fast key rejected at request %d", i+1)
+		}
+	}
+}
+
+func TestAPIKeyDisabled(t *testing.T) {
+	withAPIKeys(t, nil, 0)
+	apiKeys = nil
+	rec := httptest.NewRecorder()
+	if _, ok := authorize(rec, keyRequest("x"), PermReadMetrics); ok || rec.Code != http.StatusUnauthorized {
+		t.Errorf("disabled: ok=%v code=%d", ok, rec.Code)
+	}
+}
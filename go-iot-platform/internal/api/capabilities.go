@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-iot-platform/internal/capabilities"
+	"go-iot-platform/internal/django"
+	"go-iot-platform/internal/export"
+	"go-iot-platform/internal/influx"
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/registry"
+)
+
+// deviceMatcher — topicurile unui device (din Django) → Device Definition.
+// nil (MATCHER_ENABLED=false / registry negăsit) → /devices/{serial}/capabilities întoarce 503.
+var deviceMatcher *matcher.Matcher
+
+// SetDeviceDefinitions activează endpoint-urile bazate pe DD: /registry,
+// normalizarea (/export?mode=normalized) și capabilities (/devices/{serial}/capabilities).
+func SetDeviceDefinitions(reg *registry.Registry, m *matcher.Matcher) {
+	definitions = reg
+	fieldNormalizer = export.NewNormalizer(reg, nil)
+	deviceMatcher = m
+}
+
+// capabilitiesDefaultRange — senzorii pe baterie raportează rar (offline_after 4h),
+// așa că fereastra implicită pentru valorile curente e mai largă decât la /metrics.
+const capabilitiesDefaultRange = "-24h"
+
+// GET /go/capabilities
+//
+// Vocabularul de capabilities (field-uri canonice cu unit, comenzi, Implies) —
+// frontend-ul și regulile îl folosesc ca să țintească "orice power_meter".
+func capabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if _, ok := authorize(w, r, PermReadMetrics); !ok {
+		return
+	}
+	out := make([]capabilities.Capability, 0, len(capabilities.Vocabulary))
+	for _, name := range capabilities.Names() {
+		c, _ := capabilities.Lookup(name)
+		out = append(out, c)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"capabilities": out})
+}
+
+// GET /go/devices/{serial}/capabilities?range=24h
+//
+// Capabilities device-ului (din DD-ul găsit după topicurile lui, inclusiv cele
+// implicate) cu ultima valoare normalizată a fiecărui field canonic:
+//
+//	{"device":"plug-1","definition":"nous_a1t","tenant_id":2,
+//	 "capabilities":[{"name":"power_meter","fields":{"active_power_w":{"value":1500,"unit":"W","time":"…"},
+//	                  "voltage_v":null}},
+//	                 {"name":"relay","fields":{…},"commands":["relay_on","relay_off","relay_toggle"]}]}
+func deviceCapabilitiesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tc, ok := authorize(w, r, PermReadMetrics)
+	if !ok {
+		return
+	}
+	serial, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/devices/"), "/capabilities")
+	if !ok || serial == "" || strings.Contains(serial, "/") {
+		http.Error(w, "Invalid path. Use /devices/{serial}/capabilities", http.StatusBadRequest)
+		return
+	}
+	rangeStr := r.URL.Query().Get("range")
+	if rangeStr == "" {
+		rangeStr = capabilitiesDefaultRange
+	}
+	since, err := influx.ParseRelativeRange(rangeStr)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if deviceMatcher == nil {
+		http.Error(w, "device definitions not loaded", http.StatusServiceUnavailable)
+		return
+	}
+
+	devices, ok := authorizedDevices(w, r, tc, PermReadMetrics, []string{serial})
+	if !ok {
+		return
+	}
+	recs, err := influx.DefaultQueryClient().Snapshot(r.Context(), tc.TenantID, devices[0].TenantPlan, serial, since)
+	if err != nil {
+		log.Printf("❌ Influx snapshot error %s: %v", serial, err)
+		http.Error(w, "Influx error: "+err.Error(), influxErrorStatus(err))
+		return
+	}
+	dd := definitionFor(devices[0], recs)
+	if dd == nil {
+		http.Error(w, "no device definition matches device "+serial, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"device":       serial,
+		"definition":   dd.ID,
+		"tenant_id":    tc.TenantID,
+		"capabilities": capabilities.ForDevice(dd, canonicalValues(dd.ID, recs)),
+	})
+}
+
+// definitionFor — primul DD care prinde unul din topicurile device-ului
+// (TOPIC_TEMPLATES din Django, per device_type). Ultimele valori stocate țin loc
+// de payload pentru payload_match: pe zigbee2mqtt/+ un device cu field-ul
+// `contact` e zigbee_contact, nu zigbee_temperature.
+func definitionFor(d django.Device, recs []influx.ExportRecord) *registry.DeviceDefinition {
+	latest := map[string]interface{}{}
+	seen := map[string]time.Time{}
+	for _, rec := range recs {
+		if t, ok := seen[rec.Field]; !ok || rec.Time.After(t) {
+			latest[rec.Field], seen[rec.Field] = rec.Value, rec.Time
+		}
+	}
+	for _, topic := range d.Topics {
+		if m := deviceMatcher.MatchPayload(topic, latest); m != nil {
+			return m.Definition
+		}
+	}
+	return nil
+}
+
+// canonicalValues — field-urile stocate → numele canonice ale DD-ului; dacă două
+// field-uri dau același canonic, câștigă cel mai nou.
+func canonicalValues(ddID string, recs []influx.ExportRecord) map[string]capabilities.Value {
+	out := map[string]capabilities.Value{}
+	if fieldNormalizer == nil {
+		return out
+	}
+	for _, rec := range recs {
+		id := ddID
+		if rec.DD != "" {
+			id = rec.DD // DD-ul de la ingest bate cel dedus din payload-ul curent
+		}
+		row, ok := fieldNormalizer.ApplyDefinition(id, rec.Source,
+			export.Row{Time: rec.Time, Device: rec.Device, Field: rec.Field, Value: rec.Value})
+		if !ok {
+			continue
+		}
+		if prev, seen := out[row.Field]; seen && !row.Time.After(prev.Time) {
+			continue
+		}
+		out[row.Field] = capabilities.Value{Value: row.Value, Unit: row.Unit, Time: row.Time}
+	}
+	return out
+}
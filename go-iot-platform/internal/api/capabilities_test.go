@@ -0,0 +1,64 @@
+package api
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-iot-platform/internal/django"
+	"go-iot-platform/internal/influx"
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/registry"
+)
+
+func TestDeviceCapabilityValues(t *testing.T) {
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("load DDs: %v %v", err, errs)
+	}
+	m, _ := matcher.New(reg)
+	prevM, prevN := deviceMatcher, fieldNormalizer
+	t.Cleanup(func() { deviceMatcher, fieldNormalizer = prevM, prevN })
+	SetDeviceDefinitions(reg, m)
+
+	// topicurile vin din TOPIC_TEMPLATES (Django), per device_type
+	for topic, want := range map[string]string{
+		"tele/plug-1/STATE":            "nous_a1t",
+		"shellies/em-1/emeter/0/power": "shelly_em",
+		"/1234/+/+/telemetry":          "huawei_sun2000_3phase",
+		"zigbee2mqtt/living_room":      "zigbee_temperature",
+	} {
+		dd := definitionFor(django.Device{Serial: "x", Topics: []string{"unknown/topic", topic}}, nil)
+		if dd == nil || dd.ID != want {
+			t.Errorf("%s → %v, want %s", topic, dd, want)
+		}
+	}
+	if dd := definitionFor(django.Device{Topics: []string{"plug-9"}}, nil); dd != nil {
+		t.Errorf("auto_detected topic matched %s", dd.ID)
+	}
+
+	// același topic Z2M, dar ultimele valori conțin `contact` → zigbee_contact
+	now := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+	door := django.Device{Serial: "door", Topics: []string{"zigbee2mqtt/door"}}
+	if dd := definitionFor(door, []influx.ExportRecord{
+		{Time: now, Source: "zigbee2mqtt", Field: "battery", Value: 91.0},
+		{Time: now, Source: "zigbee2mqtt", Field: "contact", Value: false},
+	}); dd == nil || dd.ID != "zigbee_contact" {
+		t.Errorf("contact sensor → %v, want zigbee_contact", dd)
+	}
+
+	got := canonicalValues("nous_a1t", []influx.ExportRecord{
+		{Time: now, Source: "nousat", Field: "nousat_power", Value: 1499.6},
+		{Time: now, Source: "nousat", Field: "nousat_total", Value: 12.34567},
+		{Time: now, Source: "nousat", Field: "nousat_uptime", Value: 7.0},
+	})
+	if v := got["active_power_w"]; v.Value != 1500.0 || v.Unit != "W" {
+		t.Errorf("active_power_w = %+v", v)
+	}
+	if v := got["total_energy_kwh"]; v.Value != 12.346 || v.Unit != "kWh" {
+		t.Errorf("total_energy_kwh = %+v", v)
+	}
+	if len(got) != 2 {
+		t.Errorf("unexpected canonical values: %+v", got)
+	}
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go-iot-platform/internal/export"
+	"go-iot-platform/internal/influx"
+)
+
+// fieldNormalizer — din registry-ul de DD (SetDeviceDefinitions); nil → /export doar mode=raw.
+var fieldNormalizer *export.Normalizer
+
+// exportTimeout — plafonul per bucket al query-ului de export (SetExportTimeout).
+var exportTimeout = influx.DefaultExportTimeout
+
+// SetExportTimeout — 0 păstrează default-ul.
+func SetExportTimeout(d time.Duration) {
+	if d > 0 {
+		exportTimeout = d
+	}
+}
+
+// exportFlushEvery — rânduri între două Flush-uri (clientul vede progresul).
+const exportFlushEvery = 5000
+
+type exportRequest struct {
+	format     export.Format
+	normalized bool
+	canonical  map[string]bool // mode=normalized: fields= se referă la numele canonice
+	query      influx.ExportQuery
+}
+
+// GET /go/export?format=csv|ndjson|lp|parquet&start=-7d&stop=now&devices=a,b&fields=power&mode=raw|normalized
+//
+// Istoricul brut al tenantului (reconciliere facturare, portabilitate GDPR),
+// scris pe măsură ce vine din Influx — nimic nu e bufferat complet în memorie.
+// devices lipsă → toate device-urile vizibile token-ului. Fereastra maximă e
+// influx.ExportMaxRange(plan). mode=normalized exportă doar field-urile care au
+// nume canonic în Device Definitions (cu unit); mode=raw (default) — tot, ca în Influx.
+//
+// O eroare după primul rând nu mai poate schimba status-ul: conexiunea e
+// întreruptă (http.ErrAbortHandler), ca fișierul trunchiat să nu pară complet.
+func exportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tc, ok := authorize(w, r, PermExport)
+	if !ok {
+		return
+	}
+
+	req, err := parseExportRequest(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.normalized && fieldNormalizer == nil {
+		http.Error(w, "normalized export unavailable (no device definitions loaded)", http.StatusBadRequest)
+		return
+	}
+	q := req.query
+	q.TenantID = tc.TenantID
+
+	if len(q.Devices) == 0 {
+		devices, err := userDevices(r.Context(), tc)
+		if err != nil {
+			log.Printf("❌ Django error: %v", err)
+			http.Error(w, "Django error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, d := range devices {
+			q.Devices = append(q.Devices, d.Serial)
+			q.Plan = d.TenantPlan
+		}
+		if len(q.Devices) == 0 {
+			http.Error(w, "no devices in tenant", http.StatusNotFound)
+			return
+		}
+	} else {
+		plan, ok := authorizeDevices(w, r, tc, PermExport, q.Devices)
+		if !ok {
+			return
+		}
+		q.Plan = plan
+	}
+	if err := q.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		ew   export.Writer
+		rows int
+	)
+	flusher, _ := w.(http.Flusher)
+	start := func() error {
+		filename := fmt.Sprintf("export-%d-%s-%s.%s", tc.TenantID,
+			q.Start.UTC().Format("20060102T150405Z"), q.Stop.UTC().Format("20060102T150405Z"), req.format.Extension())
+		w.Header().Set("Content-Type", req.format.ContentType())
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(http.StatusOK)
+		var err error
+		ew, err = export.NewWriter(req.format, w)
+		return err
+	}
+
+	err = influx.DefaultQueryClient().Export(r.Context(), q, exportTimeout, func(rec influx.ExportRecord) error {
+		row := export.Row{Time: rec.Time, Device: rec.Device, Field: rec.Field, Value: rec.Value,
+			TenantID: tc.TenantID, Source: rec.Source, DD: rec.DD}
+		if req.normalized {
+			var ok bool
+			if row, ok = fieldNormalizer.Apply(rec.DD, rec.Source, row); !ok {
+				return nil
+			}
+			if req.canonical != nil && !req.canonical[row.Field] {
+				return nil
+			}
+		}
+		if ew == nil {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := ew.Write(row); err != nil {
+			return err
+		}
+		if rows++; rows%exportFlushEvery == 0 && flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err != nil && ew == nil {
+		log.Printf("❌ Influx export error tenant=%d: %v", tc.TenantID, err)
+		http.Error(w, "Influx error: "+err.Error(), influxErrorStatus(err))
+		return
+	}
+	if err == nil && ew == nil {
+		err = start() // export gol: fișier valid (header CSV, Parquet fără row groups)
+	}
+	if err == nil {
+		err = ew.Close()
+	}
+	if err != nil {
+		log.Printf("❌ export aborted tenant=%d after %d rows: %v", tc.TenantID, rows, err)
+		panic(http.ErrAbortHandler)
+	}
+	log.Printf("✅ export tenant=%d format=%s rows=%d devices=%d", tc.TenantID, req.format, rows, len(q.Devices))
+}
+
+func parseExportRequest(v url.Values, now time.Time) (exportRequest, error) {
+	var req exportRequest
+	var err error
+	if req.format, err = export.ParseFormat(v.Get("format")); err != nil {
+		return req, err
+	}
+	switch v.Get("mode") {
+	case "", "raw":
+	case "normalized":
+		req.normalized = true
+	default:
+		return req, fmt.Errorf("mode: expected raw or normalized, got %q", v.Get("mode"))
+	}
+	req.query.Devices = listParam(v, "devices", "device")
+	req.query.Fields = listParam(v, "fields", "field")
+	if req.normalized && len(req.query.Fields) > 0 {
+		req.canonical = map[string]bool{}
+		for _, f := range req.query.Fields {
+			req.canonical[f] = true
+		}
+		req.query.Fields = nil
+	}
+
+	start := v.Get("start")
+	if start == "" {
+		start = "-24h"
+	}
+	if req.query.Start, err = parseTimeParam(start, now); err != nil {
+		return req, fmt.Errorf("start: %w", err)
+	}
+	if req.query.Stop, err = parseTimeParam(v.Get("stop"), now); err != nil {
+		return req, fmt.Errorf("stop: %w", err)
+	}
+	return req, nil
+}
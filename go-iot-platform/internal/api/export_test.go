@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"go-iot-platform/internal/export"
+)
+
+func TestParseExportRequest(t *testing.T) {
+	now := time.Date(2026, 5, 13, 12, 0, 0, 0, time.UTC)
+	v, _ := url.ParseQuery("format=ndjson&devices=a,b&fields=power&start=-7d")
+	req, err := parseExportRequest(v, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.format != export.FormatNDJSON || req.normalized {
+		t.Errorf("format=%s normalized=%v", req.format, req.normalized)
+	}
+	if len(req.query.Devices) != 2 || len(req.query.Fields) != 1 {
+		t.Errorf("devices=%v fields=%v", req.query.Devices, req.query.Fields)
+	}
+	if !req.query.Start.Equal(now.Add(-7*24*time.Hour)) || !req.query.Stop.Equal(now) {
+		t.Errorf("window %s..%s", req.query.Start, req.query.Stop)
+	}
+
+	// default: CSV, ultimele 24h; normalized → fields= filtrează pe canonic, nu în Flux
+	v, _ = url.ParseQuery("mode=normalized&fields=active_power_w")
+	req, err = parseExportRequest(v, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.format != export.FormatCSV || !req.normalized || len(req.query.Fields) != 0 || !req.canonical["active_power_w"] {
+		t.Errorf("normalized req = %+v", req)
+	}
+	if !req.query.Start.Equal(now.Add(-24 * time.Hour)) {
+		t.Errorf("default start %s", req.query.Start)
+	}
+
+	for _, bad := range []string{"format=xlsx", "mode=pivot", "start=-1x", "stop=yesterday"} {
+		v, _ := url.ParseQuery(bad)
+		if _, err := parseExportRequest(v, now); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
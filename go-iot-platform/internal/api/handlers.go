@@ -1,24 +1,39 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"strings"
 
-	"github.com/golang-jwt/jwt/v5"
-
-	"go-iot-platform/internal/config"
-	"go-iot-platform/internal/django"
+	"go-iot-platform/internal/cache"
 	"go-iot-platform/internal/influx"
 )
 
 // Înregistrăm rutele API-ului Go.
 func RegisterRoutes(mux *http.ServeMux) {
 	mux.Handle("/metrics/", http.HandlerFunc(metricsHandler))
+	mux.Handle("/metrics/latest", http.HandlerFunc(latestHandler))
+	mux.Handle("/series", http.HandlerFunc(seriesHandler))
+	mux.Handle("/stream", http.HandlerFunc(streamHandler))
+	mux.Handle("/stream/ticket", http.HandlerFunc(streamTicketHandler))
+	mux.Handle("/export", http.HandlerFunc(exportHandler))
+	mux.Handle("/capabilities", http.HandlerFunc(capabilitiesHandler))
+	mux.Handle("/devices/", http.HandlerFunc(deviceCapabilitiesHandler))
+	mux.Handle("/registry", http.HandlerFunc(registryHandler))
+	mux.Handle("/registry/", http.HandlerFunc(registryHandler))
+	mux.Handle("/ingest/", http.HandlerFunc(ingestHandler))
 }
 
+// authzCache — setat din cmd/main.go (SetAuthzCache); nil → Django la fiecare request.
+var authzCache *cache.AuthzCache
+
+// SetAuthzCache activează cache-ul (user, tenant) → device-uri pentru toate endpoint-urile.
+func SetAuthzCache(c *cache.AuthzCache) { authzCache = c }
+
 // Claims-urile relevante extrase din JWT după validarea făcută de Kong.
 type tokenContext struct {
 	Username   string
@@ -26,20 +41,30 @@ type tokenContext struct {
 	TenantSlug string
 	Role       string
 	IsService  bool
+
+	// Doar pentru X-API-Key (apikey.go).
+	APIKeyID  int64
+	Scopes    []Permission
+	RateLimit int
 }
 
 func getTokenContext(r *http.Request) (tokenContext, error) {
+	if key := r.Header.Get(apiKeyHeader); key != "" {
+		return apiKeyContext(r.Context(), key)
+	}
 	authHeader := r.Header.Get("Authorization")
 	if !strings.HasPrefix(authHeader, "Bearer ") {
 		return tokenContext{}, fmt.Errorf("missing bearer token")
 	}
-	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+	return parseTokenContext(r.Context(), strings.TrimPrefix(authHeader, "Bearer "))
+}
 
-	claims := jwt.MapClaims{}
-	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
-		return []byte(config.Get("JWT_SECRET")), nil
-	})
-	if err != nil || !token.Valid {
+// parseTokenContext validează un JWT și extrage claim-urile (header sau query param).
+// reqCtx e al request-ului: un kid necunoscut poate declanșa un fetch JWKS, care
+// trebuie să se oprească odată cu clientul.
+func parseTokenContext(reqCtx context.Context, tokenStr string) (tokenContext, error) {
+	claims, err := tokenVerifier.Verify(reqCtx, tokenStr)
+	if err != nil {
 		return tokenContext{}, fmt.Errorf("invalid token: %v", err)
 	}
 
@@ -68,15 +93,13 @@ func getTokenContext(r *http.Request) (tokenContext, error) {
 }
 
 // GET /go/metrics/{device}/{field}?range=15m
-// JWT validat de Kong; Go re-decodează ca să extragă tenant_id + verifică în Django
-// că device-ul e al userului IN tenant-ul curent, apoi citește din Influx (filtrat pe tenant_id).
+// JWT validat de Kong; Go re-decodează ca să extragă tenant_id + rolul (policy.go),
+// verifică în Django că device-ul e al userului IN tenant-ul curent, apoi citește din Influx (filtrat pe tenant_id).
 func metricsHandler(w http.ResponseWriter, r *http.Request) {
 	log.Printf("👉 Request primit: %s %s", r.Method, r.URL.Path)
 
-	tc, err := getTokenContext(r)
-	if err != nil {
-		log.Printf("❌ JWT error: %v", err)
-		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+	tc, ok := authorize(w, r, PermReadMetrics)
+	if !ok {
 		return
 	}
 	log.Printf("✅ Token: user=%s tenant=%d (%s) role=%s", tc.Username, tc.TenantID, tc.TenantSlug, tc.Role)
@@ -90,33 +113,17 @@ func metricsHandler(w http.ResponseWriter, r *http.Request) {
 	device := segments[0]
 	field := segments[1]
 
-	devices, err := django.GetDevicesForUserInTenant(tc.Username, tc.TenantID)
-	if err != nil {
-		log.Printf("❌ Django error: %v", err)
-		http.Error(w, "Django error: "+err.Error(), http.StatusInternalServerError)
-		return
-	}
-	allowed := false
-	plan := ""
-	for _, d := range devices {
-		if d.Serial == device {
-			allowed = true
-			plan = d.TenantPlan
-			break
-		}
-	}
-	if !allowed {
-		log.Printf("⛔ user=%s tenant=%d nu are acces la device=%s (nu e în tenantul curent)", tc.Username, tc.TenantID, device)
-		http.Error(w, "Device not allowed for user/tenant", http.StatusForbidden)
+	plan, ok := authorizeDevices(w, r, tc, PermReadMetrics, []string{device})
+	if !ok {
 		return
 	}
 
 	rangeStr := r.URL.Query().Get("range")
 	// Strict tenant isolation: query-ul Influx filtrează pe tenant_id-ul userului curent.
-	val, err := influx.GetFieldForDevice(device, field, rangeStr, tc.TenantID, plan)
+	val, err := influx.GetFieldForDevice(r.Context(), device, field, rangeStr, tc.TenantID, plan)
 	if err != nil {
 		log.Printf("❌ Influx error pentru %s/%s: %v", device, field, err)
-		http.Error(w, "Influx error: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, "Influx error: "+err.Error(), influxErrorStatus(err))
 		return
 	}
 
@@ -128,3 +135,16 @@ func metricsHandler(w http.ResponseWriter, r *http.Request) {
 		"tenant_id": tc.TenantID,
 	})
 }
+
+// influxErrorStatus: fără date → 404, Influx indisponibil / request abandonat → 503,
+// restul (range / query invalid) → 400.
+func influxErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, influx.ErrNoData):
+		return http.StatusNotFound
+	case errors.Is(err, influx.ErrUnavailable), errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -8,6 +10,8 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"go-iot-platform/internal/influx"
 )
 
 func signedToken(t *testing.T, secret string, claims jwt.MapClaims) string {
@@ -113,3 +117,17 @@ func TestGetTokenContext(t *testing.T) {
 		})
 	}
 }
+
+func TestInfluxErrorStatus(t *testing.T) {
+	cases := map[error]int{
+		influx.ErrNoData: http.StatusNotFound,
+		fmt.Errorf("%w: dial tcp", influx.ErrUnavailable): http.StatusServiceUnavailable,
+		context.DeadlineExceeded:                          http.StatusServiceUnavailable,
+		fmt.Errorf("invalid range \"x\""):                 http.StatusBadRequest,
+	}
+	for err, want := range cases {
+		if got := influxErrorStatus(err); got != want {
+			t.Errorf("%v → %d, want %d", err, got, want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-iot-platform/internal/cache"
+	"go-iot-platform/internal/ingest"
+	"go-iot-platform/internal/logging"
+)
+
+// POST /ingest/{serial}/{stream} — ingest HTTP pentru device-urile care nu pot
+// folosi MQTT. Body-ul e payload-ul device-ului (JSON / raw / keyvalue, după
+// parser-ul DD-ului care prinde tenants/{tid}/devices/{serial}/up/{stream});
+// cu ?batch=true e un array de citiri bufferate (ingest.DecodeBatch).
+//
+// Autentificare, una din:
+//   - X-API-Key cu scope-ul "ingest" — tenantul cheii trebuie să fie al device-ului
+//   - X-Timestamp + X-Signature — HMAC-SHA256 cu secretul device-ului (ingest.Sign);
+//     o semnătură e acceptată o singură dată în ingest.ReplayWindow
+//
+// Răspuns 202 {"accepted": n, "rejected": [{"index", "error"}]}; 422 dacă nicio
+// citire nu a fost acceptată.
+
+// maxIngestBody — limita body-ului (batch-uri incluse).
+const maxIngestBody = 1 << 20
+
+// IngestSecretLookup — implementat de cache.IngestSecretStore.
+type IngestSecretLookup interface {
+	Lookup(ctx context.Context, serial string) (cache.IngestSecret, error)
+	ClaimSignature(ctx context.Context, serial, signature string, ttl time.Duration) (bool, error)
+}
+
+var (
+	ingestPipeline ingest.Pipeline
+	ingestSecrets  IngestSecretLookup
+	ingestNow      = time.Now
+)
+
+// SetIngestPipeline activează /ingest (cmd/main.go: processMessage).
+func SetIngestPipeline(p ingest.Pipeline) { ingestPipeline = p }
+
+// SetIngestSecretStore activează autentificarea HMAC; nil → doar X-API-Key.
+func SetIngestSecretStore(s IngestSecretLookup) { ingestSecrets = s }
+
+type ingestRejected struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
+}
+
+func ingestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/ingest/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	serial, streamName := parts[0], parts[1]
+	if ingestPipeline == nil {
+		http.Error(w, "ingest not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIngestBody))
+	if err != nil {
+		http.Error(w, "body too large or unreadable", http.StatusRequestEntityTooLarge)
+		return
+	}
+	tenantID, ok := authenticateIngest(w, r, serial, streamName, body)
+	if !ok {
+		return
+	}
+	topic, err := ingest.Topic(tenantID, serial, streamName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var readings []ingest.Reading
+	if r.URL.Query().Get("batch") == "true" {
+		if readings, err = ingest.DecodeBatch(body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		if len(body) == 0 {
+			http.Error(w, "empty body", http.StatusBadRequest)
+			return
+		}
+		readings = []ingest.Reading{{Payload: body}}
+	}
+
+	errs, err := ingestPipeline(r.Context(), topic, readings)
+	switch {
+	case errors.Is(err, ingest.ErrUnknownDevice), errors.Is(err, ingest.ErrTenantMismatch):
+		auditDenied(r, tokenContext{TenantID: tenantID}, PermIngest, "device", logging.Fields{"device_id": serial, "error": err.Error()})
+		http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+		return
+	case errors.Is(err, ingest.ErrRateLimited):
+		w.Header().Set("Retry-After", "1")
+		http.Error(w, "Too Many Requests: device rate limit", http.StatusTooManyRequests)
+		return
+	case err != nil:
+		log.Printf("❌ ingest %s: %v", topic, err)
+		http.Error(w, "ingest error", http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Accepted int              `json:"accepted"`
+		Rejected []ingestRejected `json:"rejected"`
+	}{Rejected: []ingestRejected{}}
+	for i := range readings {
+		if i < len(errs) && errs[i] != nil {
+			resp.Rejected = append(resp.Rejected, ingestRejected{Index: i, Error: errs[i].Error()})
+		} else {
+			resp.Accepted++
+		}
+	}
+	code := http.StatusAccepted
+	if resp.Accepted == 0 {
+		code = http.StatusUnprocessableEntity
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// authenticateIngest întoarce tenantul în numele căruia se scrie. La refuz
+// scrie răspunsul (401 / 403 / 429 / 503) și intrarea de audit.
+func authenticateIngest(w http.ResponseWriter, r *http.Request, serial, streamName string, body []byte) (int64, bool) {
+	if r.Header.Get(apiKeyHeader) != "" {
+		tc, ok := authorize(w, r, PermIngest)
+		return tc.TenantID, ok
+	}
+
+	signature := r.Header.Get("X-Signature")
+	if signature == "" {
+		auditDenied(r, tokenContext{}, PermIngest, "invalid_token", logging.Fields{"device_id": serial, "error": "no credentials"})
+		http.Error(w, "Unauthorized: X-API-Key or X-Signature required", http.StatusUnauthorized)
+		return 0, false
+	}
+	if ingestSecrets == nil {
+		http.Error(w, "Unauthorized: HMAC ingest not enabled", http.StatusUnauthorized)
+		return 0, false
+	}
+	rec, err := ingestSecrets.Lookup(r.Context(), serial)
+	if errors.Is(err, cache.ErrIngestSecretUnavailable) {
+		log.Printf("❌ ingest secret store: %v", err)
+		http.Error(w, "signature verification unavailable", http.StatusServiceUnavailable)
+		return 0, false
+	}
+	if err == nil {
+		err = ingest.Verify([]byte(rec.Secret), r.Header.Get("X-Timestamp"), signature, serial, streamName, body, ingestNow())
+	}
+	if err != nil {
+		auditDenied(r, tokenContext{}, PermIngest, "invalid_signature", logging.Fields{"device_id": serial, "error": err.Error()})
+		http.Error(w, "Unauthorized: invalid signature", http.StatusUnauthorized)
+		return 0, false
+	}
+	// Doar semnăturile valide sunt reținute — altfel oricine ar umple Redis.
+	fresh, err := ingestSecrets.ClaimSignature(r.Context(), serial, ingest.CanonicalSignature(signature), ingest.ReplayWindow)
+	if err != nil {
+		log.Printf("❌ ingest secret store: %v", err)
+		http.Error(w, "signature verification unavailable", http.StatusServiceUnavailable)
+		return 0, false
+	}
+	if !fresh {
+		auditDenied(r, tokenContext{}, PermIngest, "replayed_signature", logging.Fields{"device_id": serial})
+		http.Error(w, "Unauthorized: signature already used", http.StatusUnauthorized)
+		return 0, false
+	}
+	return rec.TenantID, true
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-iot-platform/internal/cache"
+	"go-iot-platform/internal/ingest"
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/registry"
+	"go-iot-platform/internal/rules"
+)
+
+type stubSecrets struct {
+	recs map[string]cache.IngestSecret
+	used map[string]time.Duration // serial:signature → ttl
+}
+
+func (s *stubSecrets) Lookup(_ context.Context, serial string) (cache.IngestSecret, error) {
+	if serial == "REDIS-DOWN" {
+		return cache.IngestSecret{}, cache.ErrIngestSecretUnavailable
+	}
+	rec, ok := s.recs[serial]
+	if !ok {
+		return cache.IngestSecret{}, cache.ErrIngestSecretUnknown
+	}
+	return rec, nil
+}
+
+func (s *stubSecrets) ClaimSignature(_ context.Context, serial, signature string, ttl time.Duration) (bool, error) {
+	key := serial + ":" + signature
+	if _, ok := s.used[key]; ok {
+		return false, nil
+	}
+	s.used[key] = ttl
+	return true, nil
+}
+
+// pipelineCall — ce a primit pipeline-ul stub.
+type pipelineCall struct {
+	topic    string
+	readings []ingest.Reading
+}
+
+// withIngest instalează un pipeline stub: device-urile din owners (serial →
+// tenant) sunt acceptate, payload-ul "bad" e respins per citire.
+func withIngest(t *testing.T, owners map[string]int64) *[]pipelineCall {
+	t.Helper()
+	prevP, prevS, prevNow := ingestPipeline, ingestSecrets, ingestNow
+	t.Cleanup(func() { ingestPipeline, ingestSecrets, ingestNow = prevP, prevS, prevNow })
+
+	var calls []pipelineCall
+	SetIngestPipeline(func(_ context.Context, topic string, readings []ingest.Reading) ([]error, error) {
+		calls = append(calls, pipelineCall{topic, readings})
+		var tid int64
+		var serial string
+		fmt.Sscanf(strings.ReplaceAll(topic, "/", " "), "tenants %d devices %s", &tid, &serial)
+		owner, ok := owners[serial]
+		switch {
+		case serial == "HOT":
+			return nil, ingest.ErrRateLimited
+		case !ok:
+			return nil, ingest.ErrUnknownDevice
+		case owner != tid:
+			return nil, ingest.ErrTenantMismatch
+		}
+		errs := make([]error, len(readings))
+		for i, r := range readings {
+			if string(r.Payload) == "bad" {
+				errs[i] = errors.New("unparseable")
+			}
+		}
+		return errs, nil
+	})
+	SetIngestSecretStore(&stubSecrets{
+		recs: map[string]cache.IngestSecret{"M1": {TenantID: 3, Secret: "k3y"}, "M9": {TenantID: 9, Secret: "k9"}},
+		used: map[string]time.Duration{},
+	})
+	ingestNow = func() time.Time { return time.Unix(1778414400, 0) }
+	return &calls
+}
+
+func ingestRequest(target, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	ingestHandler(rec, req)
+	return rec
+}
+
+func signed(serial, stream, body string, secret string) map[string]string {
+	return map[string]string{
+		"X-Timestamp": "1778414400",
+		"X-Signature": ingest.Sign([]byte(secret), 1778414400, serial, stream, []byte(body)),
+	}
+}
+
+func TestIngestHMAC(t *testing.T) {
+	calls := withIngest(t, map[string]int64{"M1": 3, "M9": 3})
+
+	rec := ingestRequest("/ingest/M1/meter", "power=812", signed("M1", "meter", "power=812", "k3y"))
+	if rec.Code != http.StatusAccepted || !strings.Contains(rec.Body.String(), `"accepted":1`) {
+		t.Fatalf("signed: %d %s", rec.Code, rec.Body)
+	}
+	if len(*calls) != 1 || (*calls)[0].topic != "tenants/3/devices/M1/up/meter" || string((*calls)[0].readings[0].Payload) != "power=812" {
+		t.Errorf("pipeline calls = %+v", *calls)
+	}
+	if ttl := ingestSecrets.(*stubSecrets).used["M1:"+signed("M1", "meter", "power=812", "k3y")["X-Signature"]]; ttl != ingest.ReplayWindow {
+		t.Errorf("signature claimed for %s, want %s", ttl, ingest.ReplayWindow)
+	}
+
+	// aceeași cerere reluată în fereastră — inclusiv cu hex-ul în altă formă
+	replay := signed("M1", "meter", "power=812", "k3y")
+	upper := map[string]string{"X-Timestamp": replay["X-Timestamp"], "X-Signature": "sha256=" + strings.ToUpper(replay["X-Signature"])}
+	for name, h := range map[string]map[string]string{"replay": replay, "replay upper": upper} {
+		if rec := ingestRequest("/ingest/M1/meter", "power=812", h); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: %d %s", name, rec.Code, rec.Body)
+		}
+	}
+	if len(*calls) != 1 {
+		t.Errorf("replayed request reached the pipeline: %+v", *calls)
+	}
+
+	cases := []struct {
+		name    string
+		target  string
+		body    string
+		headers map[string]string
+		code    int
+	}{
+		{"no credentials", "/ingest/M1/meter", "x", nil, http.StatusUnauthorized},
+		{"wrong secret", "/ingest/M1/meter", "x", signed("M1", "meter", "x", "nope"), http.StatusUnauthorized},
+		{"tampered body", "/ingest/M1/meter", "y", signed("M1", "meter", "x", "k3y"), http.StatusUnauthorized},
+		{"signed for other stream", "/ingest/M1/status", "x", signed("M1", "meter", "x", "k3y"), http.StatusUnauthorized},
+		{"no secret", "/ingest/M2/meter", "x", signed("M2", "meter", "x", "k3y"), http.StatusUnauthorized},
+		{"store down", "/ingest/REDIS-DOWN/meter", "x", signed("REDIS-DOWN", "meter", "x", "k3y"), http.StatusServiceUnavailable},
+		// secretul e al tenantului 9, device-ul e în tenantul 3
+		{"tenant mismatch", "/ingest/M9/meter", "x", signed("M9", "meter", "x", "k9"), http.StatusForbidden},
+		{"bad stream", "/ingest/M1/Meter", "x", signed("M1", "Meter", "x", "k3y"), http.StatusBadRequest},
+		{"empty body", "/ingest/M1/meter", "", signed("M1", "meter", "", "k3y"), http.StatusBadRequest},
+		{"rejected reading", "/ingest/M1/meter", "bad", signed("M1", "meter", "bad", "k3y"), http.StatusUnprocessableEntity},
+		{"no stream", "/ingest/M1", "x", nil, http.StatusNotFound},
+		{"extra segment", "/ingest/M1/meter/x", "x", nil, http.StatusNotFound},
+	}
+	for _, c := range cases {
+		if rec := ingestRequest(c.target, c.body, c.headers); rec.Code != c.code {
+			t.Errorf("%s: %d %s, want %d", c.name, rec.Code, rec.Body, c.code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ingest/M1/meter", nil)
+	rec = httptest.NewRecorder()
+	ingestHandler(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET: %d", rec.Code)
+	}
+}
+
+// TestIngestReachesRules — o citire HTTP e republicată pe relay/ (cum o face
+// cmd/main.go) și, decodată exact ca în cmd/rule-engine, declanșează regula.
+func TestIngestReachesRules(t *testing.T) {
+	withIngest(t, map[string]int64{"M1": 3})
+	type relayed struct {
+		topic   string
+		payload []byte
+	}
+	var sent []relayed
+	ingestPipeline = ingest.WithRelay(ingestPipeline, func(topic string, payload []byte) error {
+		sent = append(sent, relayed{topic, payload})
+		return nil
+	})
+
+	body := `{"temp": 31.5, "rh": 40}`
+	if rec := ingestRequest("/ingest/M1/env", body, signed("M1", "env", body, "k3y")); rec.Code != http.StatusAccepted {
+		t.Fatalf("ingest: %d %s", rec.Code, rec.Body)
+	}
+	if len(sent) != 1 || sent[0].topic != "relay/tenants/3/devices/M1/up/env" {
+		t.Fatalf("relayed = %+v", sent)
+	}
+
+	reg, errs, err := registry.LoadDir("../../../configs/devices")
+	if err != nil || len(errs) > 0 {
+		t.Skipf("configs/devices/ not available: %v %v", err, errs)
+	}
+	m, _ := matcher.New(reg)
+	topic, _ := ingest.FromRelay(sent[0].topic)
+	tid, serial, stream, ok := rules.ParseTopic(topic)
+	if !ok || tid != 3 || serial != "M1" || stream != "env" {
+		t.Fatalf("ParseTopic(%q) = %d %s %s %v", topic, tid, serial, stream, ok)
+	}
+	rm := ingest.DecodeRelay(sent[0].payload)
+	if time.Since(rm.Time) > rules.DefaultMaxReadingAge {
+		t.Errorf("live reading relayed as stale: %s", rm.Time)
+	}
+	mch := m.MatchMessage(topic, rm.Payload)
+	fields, err := rules.DecodePayload(mch, stream, topic, rm.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cond rules.ConditionNode
+	json.Unmarshal([]byte(`{"field":"temperature_c","op":"gt","value":30}`), &cond)
+	rs := rules.NewRuleSet(3, []rules.Rule{{ID: 1, Enabled: true, TriggerStreamPattern: "env", Conditions: cond}})
+	fired := 0
+	for _, r := range rs.ForStream(stream) {
+		if rs.Evaluate(r.Conditions, fields, nil) {
+			fired++
+		}
+	}
+	if fired != 1 {
+		t.Errorf("rule did not fire on relayed reading: fields=%v", fields)
+	}
+	// Batch bufferat: plicul poartă ts-ul citirii, pe care rule-engine-ul îl
+	// vede ca vechi și nu evaluează regulile pe el.
+	sent = nil
+	batch := `[{"ts": "2026-05-10T11:58:00Z", "payload": {"temp": 35}}]`
+	if rec := ingestRequest("/ingest/M1/env?batch=true", batch, signed("M1", "env", batch, "k3y")); rec.Code != http.StatusAccepted {
+		t.Fatalf("batch: %d %s", rec.Code, rec.Body)
+	}
+	if len(sent) != 1 {
+		t.Fatalf("relayed batch = %+v", sent)
+	}
+	if rm := ingest.DecodeRelay(sent[0].payload); !rm.Time.Equal(time.Date(2026, 5, 10, 11, 58, 0, 0, time.UTC)) || time.Since(rm.Time) <= rules.DefaultMaxReadingAge {
+		t.Errorf("buffered reading relayed with time %s", rm.Time)
+	}
+}
+
+func TestIngestAPIKey(t *testing.T) {
+	withIngest(t, map[string]int64{"M1": 3, "HOT": 3})
+	withAPIKeys(t, stubKeys{
+		"gateway": {ID: 1, TenantID: 3, Scopes: []string{"ingest"}},
+		"reader":  {ID: 2, TenantID: 3, Scopes: []string{"metrics:read"}},
+		"other":   {ID: 3, TenantID: 4, Scopes: []string{"ingest"}},
+	}, 100)
+
+	for key, code := range map[string]int{
+		"gateway": http.StatusAccepted,
+		"reader":  http.StatusForbidden,
+		"other":   http.StatusForbidden, // device-ul nu e în tenantul cheii
+		"nope":    http.StatusUnauthorized,
+	} {
+		if rec := ingestRequest("/ingest/M1/meter", `{"power": 1}`, map[string]string{apiKeyHeader: key}); rec.Code != code {
+			t.Errorf("%s: %d %s, want %d", key, rec.Code, rec.Body, code)
+		}
+	}
+	rec := ingestRequest("/ingest/HOT/meter", "x", map[string]string{apiKeyHeader: "gateway"})
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Errorf("rate limited: %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestIngestBatch(t *testing.T) {
+	calls := withIngest(t, map[string]int64{"M1": 3})
+	body := `[
+		{"ts": "2026-05-10T11:58:00Z", "payload": "power=800"},
+		{"ts": 1778414340, "payload": "bad"},
+		{"ts": "2026-05-10T12:00:00Z", "payload": {"power": 812}}
+	]`
+	rec := ingestRequest("/ingest/M1/meter?batch=true", body, signed("M1", "meter", body, "k3y"))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("batch: %d %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Accepted int `json:"accepted"`
+		Rejected []struct {
+			Index int    `json:"index"`
+			Error string `json:"error"`
+		} `json:"rejected"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Accepted != 2 || len(resp.Rejected) != 1 || resp.Rejected[0].Index != 1 || resp.Rejected[0].Error != "unparseable" {
+		t.Errorf("response = %+v", resp)
+	}
+	// un singur apel de pipeline (rate limit o dată per batch), cu timestamp-urile citirilor
+	if len(*calls) != 1 || len((*calls)[0].readings) != 3 || !(*calls)[0].readings[1].Time.Equal(time.Unix(1778414340, 0)) {
+		t.Errorf("pipeline calls = %+v", *calls)
+	}
+
+	bad := `[{"payload": 1}]`
+	if rec := ingestRequest("/ingest/M1/meter?batch=true", bad, signed("M1", "meter", bad, "k3y")); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid batch: %d", rec.Code)
+	}
+	big := strings.Repeat("x", maxIngestBody+1)
+	if rec := ingestRequest("/ingest/M1/meter", big, signed("M1", "meter", big, "k3y")); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("oversized body: %d", rec.Code)
+	}
+}
+
+func TestIngestDisabled(t *testing.T) {
+	prev := ingestPipeline
+	ingestPipeline = nil
+	t.Cleanup(func() { ingestPipeline = prev })
+	if rec := ingestRequest("/ingest/M1/meter", "x", nil); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("no pipeline: %d", rec.Code)
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"go-iot-platform/internal/influx"
+)
+
+// maxLatestBody — body-ul batch e mic (≤ influx.MaxLatestPairs perechi).
+const maxLatestBody = 64 << 10
+
+type latestRequest struct {
+	Range   string `json:"range"`
+	Devices []struct {
+		Device string   `json:"device"`
+		Fields []string `json:"fields"`
+	} `json:"devices"`
+}
+
+type latestPair struct {
+	Device string `json:"device"`
+	Field  string `json:"field"`
+}
+
+// POST /go/metrics/latest
+//
+//	{"range":"-1h","devices":[{"device":"a","fields":["power","voltage"]},…]}
+//
+// Varianta batch a /metrics/{device}/{field} pentru dashboard-uri cu multe
+// gauge-uri: un singur lookup Django pentru autorizare și un singur query Flux
+// per bucket. Perechile fără date în range apar în "missing".
+//
+//	{"tenant_id":2,"now":"…","values":{"a":{"power":{"value":1.5,"time":"…"}}},
+//	 "missing":[{"device":"a","field":"voltage"}]}
+func latestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tc, ok := authorize(w, r, PermReadMetrics)
+	if !ok {
+		return
+	}
+
+	q, err := parseLatestRequest(http.MaxBytesReader(w, r.Body, maxLatestBody))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.TenantID = tc.TenantID
+
+	serials := make([]string, 0, len(q.Fields))
+	for serial := range q.Fields {
+		serials = append(serials, serial)
+	}
+	plan, ok := authorizeDevices(w, r, tc, PermReadMetrics, serials)
+	if !ok {
+		return
+	}
+	q.Plan = plan
+
+	values, err := influx.DefaultQueryClient().Latest(r.Context(), q)
+	if err != nil {
+		log.Printf("❌ Influx latest error tenant=%d: %v", tc.TenantID, err)
+		http.Error(w, "Influx error: "+err.Error(), influxErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenant_id": tc.TenantID,
+		"now":       time.Now().UTC().Format(time.RFC3339),
+		"values":    values,
+		"missing":   missingPairs(q.Fields, values),
+	})
+}
+
+// parseLatestRequest decodează body-ul; limitele (nr. perechi, nume) sunt
+// verificate în influx.QueryClient.Latest.
+func parseLatestRequest(body io.Reader) (influx.LatestQuery, error) {
+	var req latestRequest
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		return influx.LatestQuery{}, fmt.Errorf("invalid JSON body: %v", err)
+	}
+	q := influx.LatestQuery{Fields: map[string][]string{}}
+	if req.Range != "" {
+		d, err := influx.ParseRelativeRange(req.Range)
+		if err != nil {
+			return q, err
+		}
+		q.Since = d
+	}
+	for _, d := range req.Devices {
+		if d.Device == "" || len(d.Fields) == 0 {
+			return q, fmt.Errorf("each entry needs a device and at least one field")
+		}
+		seen := map[string]bool{}
+		for _, f := range q.Fields[d.Device] {
+			seen[f] = true
+		}
+		for _, f := range d.Fields {
+			if !seen[f] {
+				seen[f] = true
+				q.Fields[d.Device] = append(q.Fields[d.Device], f)
+			}
+		}
+	}
+	if len(q.Fields) == 0 {
+		return q, fmt.Errorf("devices required")
+	}
+	return q, nil
+}
+
+func missingPairs(want map[string][]string, got map[string]map[string]influx.LatestValue) []latestPair {
+	out := []latestPair{}
+	for device, fields := range want {
+		for _, f := range fields {
+			if _, ok := got[device][f]; !ok {
+				out = append(out, latestPair{Device: device, Field: f})
+			}
+		}
+	}
+	return out
+}
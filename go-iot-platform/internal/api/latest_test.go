@@ -0,0 +1,46 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"go-iot-platform/internal/influx"
+)
+
+func TestParseLatestRequest(t *testing.T) {
+	q, err := parseLatestRequest(strings.NewReader(`{"range":"-1h","devices":[
+		{"device":"a","fields":["power","voltage"]},
+		{"device":"b","fields":["power"]},
+		{"device":"a","fields":["power","energy"]}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Since != time.Hour {
+		t.Errorf("since=%s", q.Since)
+	}
+	if got := strings.Join(q.Fields["a"], ","); got != "power,voltage,energy" {
+		t.Errorf("a fields=%s", got)
+	}
+
+	for _, bad := range []string{
+		`{}`,
+		`{"devices":[{"device":"a","fields":[]}]}`,
+		`{"devices":[{"device":"","fields":["x"]}]}`,
+		`{"range":"-1x","devices":[{"device":"a","fields":["x"]}]}`,
+		`{"devices":[{"device":"a","fields":["x"]}],"extra":1}`,
+		`not json`,
+	} {
+		if _, err := parseLatestRequest(strings.NewReader(bad)); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestMissingPairs(t *testing.T) {
+	got := map[string]map[string]influx.LatestValue{"a": {"power": {Value: 1.0}}}
+	miss := missingPairs(map[string][]string{"a": {"power", "voltage"}}, got)
+	if len(miss) != 1 || miss[0] != (latestPair{"a", "voltage"}) {
+		t.Errorf("missing=%v", miss)
+	}
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"go-iot-platform/internal/cache"
+	"go-iot-platform/internal/django"
+	"go-iot-platform/internal/logging"
+)
+
+// Permission — o acțiune pe endpoint-urile Go, verificată contra rolului din JWT.
+type Permission string
+
+const (
+	PermReadMetrics Permission = "metrics:read" // /metrics/{device}/{field}, /metrics/latest, /stream, /devices/{serial}/capabilities
+	PermReadSeries  Permission = "series:read"  // /series (istoric brut, agregat)
+	PermExport      Permission = "export"       // export bulk (CSV / NDJSON …)
+	PermIngest      Permission = "ingest"       // POST /ingest/{serial}/{stream}; doar scope de cheie API, niciun rol JWT
+)
+
+// RoleService — rolul efectiv al token-urilor cu is_service=true, indiferent de claim-ul role.
+const RoleService = "SERVICE"
+
+// rolePermissions — aliniat cu tenants/permissions.py: VIEWER / INSTALLER doar
+// citesc, OPERATOR poate și exporta. Comenzile (downlink) trec prin Django, nu
+// prin API-ul Go — nicio permisiune aici până nu există un endpoint care s-o verifice.
+var rolePermissions = map[string][]Permission{
+	"OWNER":     {PermReadMetrics, PermReadSeries, PermExport},
+	"ADMIN":     {PermReadMetrics, PermReadSeries, PermExport},
+	"OPERATOR":  {PermReadMetrics, PermReadSeries, PermExport},
+	"INSTALLER": {PermReadMetrics, PermReadSeries},
+	"VIEWER":    {PermReadMetrics, PermReadSeries},
+	RoleService: {PermReadMetrics, PermReadSeries, PermExport},
+}
+
+// effectiveRole — is_service câștigă; rol lipsă / necunoscut → fără permisiuni.
+func (tc tokenContext) effectiveRole() string {
+	if tc.APIKeyID != 0 {
+		return RoleAPIKey
+	}
+	if tc.IsService {
+		return RoleService
+	}
+	return strings.ToUpper(tc.Role)
+}
+
+// Can verifică o permisiune pentru token.
+func (tc tokenContext) Can(p Permission) bool {
+	granted := rolePermissions[tc.effectiveRole()]
+	if tc.APIKeyID != 0 {
+		granted = tc.Scopes
+	}
+	for _, have := range granted {
+		if have == p {
+			return true
+		}
+	}
+	return false
+}
+
+// authorize: token valid + permisiune. La refuz scrie 401 / 403 și lasă o
+// intrare de audit; handler-ul doar face return.
+func authorize(w http.ResponseWriter, r *http.Request, perm Permission) (tokenContext, bool) {
+	return authorizeWith(w, r, perm, getTokenContext)
+}
+
+func authorizeWith(w http.ResponseWriter, r *http.Request, perm Permission, token func(*http.Request) (tokenContext, error)) (tokenContext, bool) {
+	tc, err := token(r)
+	if errors.Is(err, cache.ErrAPIKeyUnavailable) {
+		log.Printf("❌ API key store: %v", err)
+		http.Error(w, "API key verification unavailable", http.StatusServiceUnavailable)
+		return tc, false
+	}
+	if err != nil {
+		auditDenied(r, tc, perm, "invalid_token", logging.Fields{"error": err.Error()})
+		http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+		return tc, false
+	}
+	if !tc.Can(perm) {
+		auditDenied(r, tc, perm, "role", nil)
+		http.Error(w, fmt.Sprintf("Forbidden: role %q lacks %s", tc.effectiveRole(), perm), http.StatusForbidden)
+		return tc, false
+	}
+	if tc.APIKeyID != 0 && !rateLimitAPIKey(w, r, tc, perm) {
+		return tc, false
+	}
+	return tc, true
+}
+
+// serviceDevicesUser — cheia sub care sunt cache-uite device-urile unui tenant
+// pentru token-urile de serviciu (":" nu e permis în username-urile Django).
+const serviceDevicesUser = ":service"
+
+// userDevices — device-urile vizibile token-ului în tenantul lui. Token-urile de
+// serviciu și cheile API nu au binding user↔device: văd tot tenantul.
+func userDevices(ctx context.Context, tc tokenContext) ([]django.Device, error) {
+	username := tc.Username
+	if tc.IsService || tc.APIKeyID != 0 {
+		username = serviceDevicesUser
+	}
+	if authzCache != nil {
+		return authzCache.Devices(ctx, username, tc.TenantID)
+	}
+	return django.GetDevicesForUserInTenant(username, tc.TenantID)
+}
+
+// authorizeDevices verifică că toate device-urile cerute sunt vizibile token-ului
+// și întoarce planul tenantului (pentru routing-ul pe bucket). La refuz: 403 + audit.
+func authorizeDevices(w http.ResponseWriter, r *http.Request, tc tokenContext, perm Permission, serials []string) (string, bool) {
+	devices, ok := authorizedDevices(w, r, tc, perm, serials)
+	if !ok {
+		return "", false
+	}
+	plan := ""
+	for _, d := range devices {
+		plan = d.TenantPlan
+	}
+	return plan, true
+}
+
+// authorizedDevices — ca authorizeDevices, dar întoarce device-urile (topics,
+// plan), în ordinea din serials.
+func authorizedDevices(w http.ResponseWriter, r *http.Request, tc tokenContext, perm Permission, serials []string) ([]django.Device, bool) {
+	devices, err := userDevices(r.Context(), tc)
+	if err != nil {
+		log.Printf("❌ Django error: %v", err)
+		http.Error(w, "Django error: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	byserial := make(map[string]django.Device, len(devices))
+	for _, d := range devices {
+		byserial[d.Serial] = d
+	}
+	out := make([]django.Device, 0, len(serials))
+	for _, serial := range serials {
+		d, ok := byserial[serial]
+		if !ok {
+			auditDenied(r, tc, perm, "device", logging.Fields{"device_id": serial})
+			http.Error(w, "Device not allowed for user/tenant: "+serial, http.StatusForbidden)
+			return nil, false
+		}
+		out = append(out, d)
+	}
+	return out, true
+}
+
+// auditDenied — intrare structurată (level "audit") pentru fiecare refuz.
+func auditDenied(r *http.Request, tc tokenContext, perm Permission, reason string, extra logging.Fields) {
+	f := logging.Fields{
+		"event":       "authz_denied",
+		"reason":      reason,
+		"permission":  string(perm),
+		"method":      r.Method,
+		"path":        r.URL.Path,
+		"remote_addr": r.RemoteAddr,
+	}
+	if tc.Username != "" {
+		f["user_id"] = tc.Username
+		f["tenant_id"] = tc.TenantID
+		f["role"] = tc.effectiveRole()
+		f["is_service"] = tc.IsService
+	}
+	if tc.APIKeyID != 0 {
+		f["api_key_id"] = tc.APIKeyID
+	}
+	for k, v := range extra {
+		f[k] = v
+	}
+	logging.Audit("access denied", f)
+}
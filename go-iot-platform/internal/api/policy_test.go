@@ -0,0 +1,87 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-iot-platform/internal/logging"
+)
+
+func TestRolePermissions(t *testing.T) {
+	cases := []struct {
+		tc   tokenContext
+		perm Permission
+		want bool
+	}{
+		{tokenContext{Role: "VIEWER"}, PermReadMetrics, true},
+		{tokenContext{Role: "VIEWER"}, PermReadSeries, true},
+		{tokenContext{Role: "VIEWER"}, PermExport, false},
+		{tokenContext{Role: "viewer"}, PermExport, false},
+		{tokenContext{Role: "INSTALLER"}, PermExport, false},
+		{tokenContext{Role: "OPERATOR"}, PermExport, true},
+		{tokenContext{Role: "OWNER"}, PermExport, true},
+		{tokenContext{Role: ""}, PermReadMetrics, false},
+		{tokenContext{Role: "ROOT"}, PermReadMetrics, false},
+		{tokenContext{Role: "VIEWER", IsService: true}, PermExport, true},
+	}
+	for _, c := range cases {
+		if got := c.tc.Can(c.perm); got != c.want {
+			t.Errorf("%+v Can(%s) = %v, want %v", c.tc, c.perm, got, c.want)
+		}
+	}
+}
+
+func TestAuthorizeAuditsDenials(t *testing.T) {
+	const secret = "unit-test-secret"
+	t.Setenv("JWT_SECRET", secret)
+	var buf bytes.Buffer
+	logging.SetOutput(log.New(&buf, "", 0))
+	defer logging.SetOutput(log.New(&bytes.Buffer{}, "", 0))
+
+	tok := signedToken(t, secret, jwt.MapClaims{
+		"username": "vera", "tenant_id": 4, "role": "VIEWER", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	req := httptest.NewRequest(http.MethodGet, "/export", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	rec := httptest.NewRecorder()
+	if _, ok := authorize(rec, req, PermExport); ok || rec.Code != http.StatusForbidden {
+		t.Fatalf("viewer export: ok=%v code=%d", ok, rec.Code)
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &entry); err != nil {
+		t.Fatalf("audit entry not JSON: %q", buf.String())
+	}
+	for k, want := range map[string]interface{}{
+		"level": "audit", "event": "authz_denied", "reason": "role", "permission": "export",
+		"user_id": "vera", "tenant_id": 4.0, "role": "VIEWER", "path": "/export",
+	} {
+		if entry[k] != want {
+			t.Errorf("audit %s = %v, want %v", k, entry[k], want)
+		}
+	}
+
+	buf.Reset()
+	req = httptest.NewRequest(http.MethodGet, "/series", nil)
+	rec = httptest.NewRecorder()
+	if _, ok := authorize(rec, req, PermReadSeries); ok || rec.Code != http.StatusUnauthorized {
+		t.Fatalf("no token: ok=%v code=%d", ok, rec.Code)
+	}
+	if !strings.Contains(buf.String(), `"reason":"invalid_token"`) {
+		t.Errorf("missing invalid_token audit: %s", buf.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/series", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	rec = httptest.NewRecorder()
+	if tc, ok := authorize(rec, req, PermReadSeries); !ok || tc.Username != "vera" {
+		t.Fatalf("viewer series: ok=%v code=%d", ok, rec.Code)
+	}
+}
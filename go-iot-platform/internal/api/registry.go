@@ -0,0 +1,243 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go-iot-platform/internal/capabilities"
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/registry"
+)
+
+// definitions — registry-ul de DD încărcat la startup (SetDeviceDefinitions);
+// nil → /registry întoarce 503.
+var definitions *registry.Registry
+
+// maxDefinitionBody — un DD YAML are câțiva KB; limita e doar plasă de siguranță.
+const maxDefinitionBody = 256 << 10
+
+// ddSummary — o intrare din GET /go/registry.
+type ddSummary struct {
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Vendor        string   `json:"vendor"`
+	Model         string   `json:"model,omitempty"`
+	Protocol      string   `json:"protocol"`
+	SchemaVersion string   `json:"schema_version"`
+	Capabilities  []string `json:"capabilities"`
+}
+
+// ddStream — un stream logic: pattern-urile din topic_match care îl produc +
+// hint-urile din telemetry_streams.
+type ddStream struct {
+	Name         string   `json:"name"`
+	Patterns     []string `json:"patterns"`
+	IntervalHint string   `json:"interval_hint,omitempty"`
+	OfflineAfter string   `json:"offline_after,omitempty"`
+}
+
+// registryHandler servește Device Definitions încărcate (read-only; DD-urile
+// nu sunt date de tenant, orice token cu metrics:read le poate citi):
+//
+//	GET  /go/registry?vendor=shelly&capability=power_meter&protocol=mqtt
+//	GET  /go/registry/{id}
+//	GET  /go/registry/{id}/commands
+//	GET  /go/registry/{id}/streams
+//	POST /go/registry/validate   (body: YAML)
+//
+// Filtrul capability include capabilities implicate (smart_plug → power_meter).
+func registryHandler(w http.ResponseWriter, r *http.Request) {
+	if _, ok := authorize(w, r, PermReadMetrics); !ok {
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/registry"), "/")
+	if path == "validate" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		validateDefinitionHandler(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if definitions == nil {
+		http.Error(w, "device definitions not loaded", http.StatusServiceUnavailable)
+		return
+	}
+	if path == "" {
+		q := r.URL.Query()
+		writeJSON(w, http.StatusOK, listDefinitions(q.Get("vendor"), q.Get("capability"), q.Get("protocol")))
+		return
+	}
+
+	id, sub, _ := strings.Cut(path, "/")
+	dd := definitions.Get(id)
+	if dd == nil {
+		http.Error(w, "device definition not found: "+id, http.StatusNotFound)
+		return
+	}
+	switch sub {
+	case "":
+		out := *dd
+		out.SourcePath = filepath.Base(dd.SourcePath) // fără path-ul de pe server
+		writeJSON(w, http.StatusOK, out)
+	case "commands":
+		commands := dd.Commands
+		if commands == nil {
+			commands = map[string]registry.CommandSpec{}
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": dd.ID, "commands": commands})
+	case "streams":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": dd.ID, "streams": definitionStreams(dd)})
+	default:
+		http.Error(w, "Invalid path. Use /registry/{id}[/commands|/streams]", http.StatusNotFound)
+	}
+}
+
+func listDefinitions(vendor, capability, protocol string) map[string]interface{} {
+	out := []ddSummary{}
+	for _, dd := range definitions.All() {
+		if vendor != "" && dd.Vendor != vendor ||
+			protocol != "" && dd.Protocol != protocol ||
+			capability != "" && !capabilities.Has(dd, capability) {
+			continue
+		}
+		out = append(out, ddSummary{
+			ID: dd.ID, Name: dd.Name, Vendor: dd.Vendor, Model: dd.Model,
+			Protocol: dd.Protocol, SchemaVersion: dd.SchemaVersion, Capabilities: dd.Capabilities,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return map[string]interface{}{"count": len(out), "definitions": out}
+}
+
+// definitionStreams — stream-urile din topic_match (ordinea primei apariții),
+// plus cele declarate doar în telemetry_streams (sortate).
+func definitionStreams(dd *registry.DeviceDefinition) []ddStream {
+	out := []ddStream{}
+	idx := map[string]int{}
+	add := func(name string) *ddStream {
+		if i, ok := idx[name]; ok {
+			return &out[i]
+		}
+		spec := dd.TelemetryStreams[name]
+		idx[name] = len(out)
+		out = append(out, ddStream{Name: name, Patterns: []string{}, IntervalHint: spec.IntervalHint, OfflineAfter: spec.OfflineAfter})
+		return &out[len(out)-1]
+	}
+	for _, tm := range dd.Identification.TopicMatch {
+		s := add(tm.Stream)
+		s.Patterns = append(s.Patterns, tm.Pattern)
+	}
+	var rest []string
+	for name := range dd.TelemetryStreams {
+		if _, ok := idx[name]; !ok {
+			rest = append(rest, name)
+		}
+	}
+	sort.Strings(rest)
+	for _, name := range rest {
+		add(name)
+	}
+	return out
+}
+
+// definitionReport — răspunsul POST /go/registry/validate.
+type definitionReport struct {
+	Valid    bool     `json:"valid"`
+	ID       string   `json:"id,omitempty"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// validateDefinitionHandler rulează pe YAML-ul primit tot ce face loader-ul la
+// startup (decode strict + extends / mixins + Violations), compilarea în matcher, verificarea
+// contra vocabularului de capabilities și ValidateAll față de DD-urile încărcate
+// — și întoarce toate erorile deodată.
+// 200 = valid, 422 = invalid (același body).
+func validateDefinitionHandler(w http.ResponseWriter, r *http.Request) {
+	raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxDefinitionBody))
+	if err != nil {
+		http.Error(w, "body too large or unreadable: "+err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	report := validateDefinition(raw, definitions)
+	status := http.StatusOK
+	if !report.Valid {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, report)
+}
+
+func validateDefinition(raw []byte, loaded *registry.Registry) definitionReport {
+	report := definitionReport{Errors: []string{}, Warnings: []string{}}
+	dd, err := registry.Decode(raw)
+	if err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+	report.ID = dd.ID
+	// schema 1.1: extends / mixins se rezolvă față de template-urile și DD-urile
+	// încărcate la startup (fișierul nou nu poate aduce template-uri noi)
+	if dd, err = registry.Resolve(dd, registry.RegistryLookup(loaded)); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+		return report
+	}
+
+	topicErrors := false
+	for _, e := range dd.Violations() {
+		report.Errors = append(report.Errors, e.Error())
+		topicErrors = topicErrors || strings.HasPrefix(e.Error(), "identification.topic_match")
+	}
+	if !topicErrors {
+		// validateTopicMatch prinde regex-urile invalide; compilarea din matcher
+		// prinde restul (ex. un `+` lipit de text într-un segment).
+		single := registry.NewRegistry()
+		_ = single.Add(dd)
+		_, errs := matcher.New(single)
+		for _, e := range errs {
+			report.Errors = append(report.Errors, "matcher: "+e.Error())
+		}
+	}
+	for _, e := range capabilities.Validate(dd) {
+		report.Errors = append(report.Errors, e.Error())
+	}
+
+	if loaded != nil && dd.ID != "" {
+		if prev := loaded.Get(dd.ID); prev != nil {
+			report.Warnings = append(report.Warnings, "id "+dd.ID+" already loaded — would replace "+
+				filepath.Base(prev.SourcePath))
+		}
+		// conflictele cu DD-urile deja încărcate (ValidateAll), ca și cum
+		// fișierul ar fi adăugat / l-ar înlocui pe cel cu același id
+		merged := registry.NewRegistry()
+		for _, other := range loaded.All() {
+			if other.ID != dd.ID {
+				_ = merged.Add(other)
+			}
+		}
+		_ = merged.Add(dd)
+		mine := regexp.MustCompile(`dd=` + regexp.QuoteMeta(dd.ID) + `\b`)
+		for _, e := range registry.ValidateAll(merged) {
+			if mine.MatchString(e.Error()) {
+				report.Errors = append(report.Errors, e.Error())
+			}
+		}
+	}
+	report.Valid = len(report.Errors) == 0
+	return report
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/registry"
+)
+
+func registryRequest(t *testing.T, method, target, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	const secret = "unit-test-secret"
+	t.Setenv("JWT_SECRET", secret)
+	tok := signedToken(t, secret, jwt.MapClaims{
+		"username": "vera", "tenant_id": 4, "role": "VIEWER", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+tok)
+	rec := httptest.NewRecorder()
+	registryHandler(rec, req)
+	return rec
+}
+
+func loadProductionDefinitions(t *testing.T) *registry.Registry {
+	t.Helper()
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("load DDs: %v %v", err, errs)
+	}
+	m, _ := matcher.New(reg)
+	prevD, prevM, prevN := definitions, deviceMatcher, fieldNormalizer
+	t.Cleanup(func() { definitions, deviceMatcher, fieldNormalizer = prevD, prevM, prevN })
+	SetDeviceDefinitions(reg, m)
+	return reg
+}
+
+func TestRegistryList(t *testing.T) {
+	loadProductionDefinitions(t)
+	cases := map[string][]string{
+		"/registry":                                {"http_power_meter", "huawei_sun2000_3phase", "huawei_sun2000_modbus", "nbiot_env_sensor", "nous_a1t", "shelly_em", "zigbee_contact", "zigbee_temperature"},
+		"/registry?capability=battery_powered":     {"nbiot_env_sensor", "zigbee_contact", "zigbee_temperature"},
+		"/registry?vendor=shelly":                  {"shelly_em"},
+		"/registry?capability=power_meter":         {"http_power_meter", "huawei_sun2000_3phase", "huawei_sun2000_modbus", "nous_a1t", "shelly_em"},
+		"/registry?capability=relay&protocol=mqtt": {"nous_a1t"},
+		"/registry?protocol=modbus_tcp":            {"huawei_sun2000_modbus"},
+		"/registry?protocol=coap":                  {"nbiot_env_sensor"},
+	}
+	for target, want := range cases {
+		rec := registryRequest(t, http.MethodGet, target, "")
+		var body struct {
+			Count       int `json:"count"`
+			Definitions []struct {
+				ID string `json:"id"`
+			} `json:"definitions"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+			t.Fatalf("%s: %d %s", target, rec.Code, rec.Body)
+		}
+		var got []string
+		for _, d := range body.Definitions {
+			got = append(got, d.ID)
+		}
+		if strings.Join(got, ",") != strings.Join(want, ",") || body.Count != len(want) {
+			t.Errorf("%s = %v, want %v", target, got, want)
+		}
+	}
+}
+
+func TestRegistryGet(t *testing.T) {
+	loadProductionDefinitions(t)
+	rec := registryRequest(t, http.MethodGet, "/registry/nous_a1t", "")
+	var dd registry.DeviceDefinition
+	if err := json.Unmarshal(rec.Body.Bytes(), &dd); err != nil || dd.ID != "nous_a1t" {
+		t.Fatalf("get: %d %s", rec.Code, rec.Body)
+	}
+	if dd.SourcePath != "nous_a1t.yaml" {
+		t.Errorf("source_path leaks server path: %q", dd.SourcePath)
+	}
+
+	rec = registryRequest(t, http.MethodGet, "/registry/nous_a1t/commands", "")
+	if !strings.Contains(rec.Body.String(), `"relay_toggle"`) {
+		t.Errorf("commands: %s", rec.Body)
+	}
+
+	rec = registryRequest(t, http.MethodGet, "/registry/huawei_sun2000_3phase/streams", "")
+	var streams struct {
+		Streams []ddStream `json:"streams"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &streams)
+	if len(streams.Streams) != 4 || streams.Streams[0].Name != "telemetry" ||
+		len(streams.Streams[0].Patterns) != 2 || streams.Streams[0].OfflineAfter != "3m" {
+		t.Errorf("streams: %s", rec.Body)
+	}
+
+	for target, code := range map[string]int{
+		"/registry/nope":           http.StatusNotFound,
+		"/registry/nous_a1t/parts": http.StatusNotFound,
+	} {
+		if rec := registryRequest(t, http.MethodGet, target, ""); rec.Code != code {
+			t.Errorf("%s: %d", target, rec.Code)
+		}
+	}
+	if rec := registryRequest(t, http.MethodPost, "/registry/nous_a1t", ""); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST get: %d", rec.Code)
+	}
+}
+
+func TestRegistryValidate(t *testing.T) {
+	loadProductionDefinitions(t)
+	good, err := os.ReadFile(filepath.Join("..", "..", "..", "configs", "devices", "shelly_em.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := registryRequest(t, http.MethodPost, "/registry/validate", string(good))
+	var report definitionReport
+	_ = json.Unmarshal(rec.Body.Bytes(), &report)
+	if rec.Code != http.StatusOK || !report.Valid || len(report.Warnings) != 1 {
+		t.Errorf("shelly_em: %d %s", rec.Code, rec.Body)
+	}
+
+	bad := `
+schema_version: "1.0"
+id: Bad-Id
+protocol: lorawan
+identification:
+  topic_match:
+    - pattern: "sensors/dev+/up"
+parser:
+  type: json
+capabilities: [relay]
+commands:
+  relay_on: {topic: "", payload: "ON"}
+`
+	rec = registryRequest(t, http.MethodPost, "/registry/validate", bad)
+	report = definitionReport{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &report)
+	if rec.Code != http.StatusUnprocessableEntity || report.Valid {
+		t.Fatalf("bad: %d %s", rec.Code, rec.Body)
+	}
+	all := strings.Join(report.Errors, "\n")
+	for _, want := range []string{
+		`id "Bad-Id" invalid`,
+		"name required",
+		`protocol "lorawan" unknown`,
+		"commands[relay_on].topic required",
+		"matcher: dd=Bad-Id",
+		"capability relay: normalized_fields.relay_state_str required",
+		"capability relay: commands.relay_off required",
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing %q in:\n%s", want, all)
+		}
+	}
+
+	// valid singur, dar în conflict cu nous_a1t deja încărcat (ValidateAll)
+	clash := `
+schema_version: "1.0"
+id: acme_plug
+name: "Acme Plug"
+protocol: mqtt
+identification:
+  topic_match:
+    - pattern: "tele/+/SENSOR"
+      stream: sensor
+      extract: {device_id: "$1"}
+parser:
+  type: json
+capabilities: [temperature_sensor]
+normalized_fields:
+  temperature_c: {source: temp, unit: "°F"}
+telemetry_streams:
+  sensor: {interval_hint: 1m}
+`
+	rec = registryRequest(t, http.MethodPost, "/registry/validate", clash)
+	report = definitionReport{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &report)
+	all = strings.Join(report.Errors, "\n")
+	for _, want := range []string{
+		`dd=nous_a1t topic_match[0] "tele/+/SENSOR" is shadowed by dd=acme_plug`,
+		`normalized field temperature_c: unit "°C" in dd=zigbee_temperature vs "°F" in dd=acme_plug`,
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing %q in:\n%s", want, all)
+		}
+	}
+
+	// schema 1.1: template-ul din configs/devices e disponibil la validare
+	child := `
+schema_version: "1.1"
+id: acme_inverter
+extends: base_platform_native
+name: "Acme Inverter"
+vendor: acme
+identification:
+  topic_match:
+    - pattern: "acme/+/telemetry"
+      stream: telemetry
+      extract: {device_id: "$1"}
+parser:
+  type: json
+capabilities: [temperature_sensor]
+normalized_fields:
+  temperature_c: {source: temp, unit: "°C"}
+telemetry_streams:
+  telemetry: {interval_hint: 1m}
+`
+	rec = registryRequest(t, http.MethodPost, "/registry/validate", child)
+	report = definitionReport{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &report)
+	all = strings.Join(report.Errors, "\n")
+	// protocol vine din template; shadow / cmd_ack / ota moștenite îl umbresc pe huawei (acme < huawei)
+	if strings.Contains(all, "protocol required") || !strings.Contains(all, `"tenants/+/devices/+/up/shadow" is shadowed by dd=acme_inverter topic_match[1]`) {
+		t.Errorf("extends: %d %s", rec.Code, rec.Body)
+	}
+	rec = registryRequest(t, http.MethodPost, "/registry/validate", strings.Replace(child, "base_platform_native", "base_missing", 1))
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `extends \"base_missing\"`) {
+		t.Errorf("missing template: %d %s", rec.Code, rec.Body)
+	}
+
+	rec = registryRequest(t, http.MethodPost, "/registry/validate", "id: x\ncolour: red\n")
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "yaml decode") {
+		t.Errorf("unknown field: %d %s", rec.Code, rec.Body)
+	}
+}
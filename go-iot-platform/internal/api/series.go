@@ -0,0 +1,147 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-iot-platform/internal/influx"
+)
+
+// relativeTimeRe — "-24h", "-7d", "-2w" (relativ la now).
+var relativeTimeRe = regexp.MustCompile(`^-(\d+)([smhdw])$`)
+
+// GET /go/series?devices=a,b&fields=power,voltage&start=-24h&stop=now&window=5m&fn=mean
+//
+// Istoric pentru charts: N device-uri × M field-uri, agregate pe `window` cu `fn`
+// (mean / max / min / sum / last). start / stop: relativ ("-24h", "-7d") sau RFC3339.
+// `window` lipsă sau prea mic pentru plan → ales automat (downsampling, vezi
+// influx.LimitsForPlan); fereastra efectivă e întoarsă în răspuns.
+//
+// Răspuns (columnar):
+//
+//	{"tenant_id":2,"start":"…","stop":"…","window":"5m0s","fn":"mean",
+//	 "series":[{"device":"a","field":"power","time":[…],"value":[…]}]}
+func seriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tc, ok := authorize(w, r, PermReadSeries)
+	if !ok {
+		return
+	}
+
+	q, err := parseSeriesQuery(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	q.TenantID = tc.TenantID
+
+	plan, ok := authorizeDevices(w, r, tc, PermReadSeries, q.Devices)
+	if !ok {
+		return
+	}
+	q.Plan = plan
+
+	if err := q.Normalize(influx.LimitsForPlan(q.Plan)); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	series, err := influx.QuerySeries(r.Context(), q)
+	if err != nil {
+		log.Printf("❌ Influx series error tenant=%d: %v", tc.TenantID, err)
+		http.Error(w, "Influx error: "+err.Error(), influxErrorStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"tenant_id": tc.TenantID,
+		"start":     q.Start.UTC().Format(time.RFC3339),
+		"stop":      q.Stop.UTC().Format(time.RFC3339),
+		"window":    q.Window.String(),
+		"fn":        q.Fn,
+		"series":    series,
+	})
+}
+
+// parseSeriesQuery citește parametrii; validarea contra planului e în Normalize.
+func parseSeriesQuery(v url.Values, now time.Time) (influx.SeriesQuery, error) {
+	q := influx.SeriesQuery{
+		Devices: listParam(v, "devices", "device"),
+		Fields:  listParam(v, "fields", "field"),
+		Fn:      strings.ToLower(v.Get("fn")),
+	}
+	var err error
+	start := v.Get("start")
+	if start == "" {
+		start = "-1h"
+	}
+	if q.Start, err = parseTimeParam(start, now); err != nil {
+		return q, fmt.Errorf("start: %w", err)
+	}
+	if q.Stop, err = parseTimeParam(v.Get("stop"), now); err != nil {
+		return q, fmt.Errorf("stop: %w", err)
+	}
+	if w := v.Get("window"); w != "" {
+		if q.Window, err = parseSpan(w); err != nil || q.Window <= 0 {
+			return q, fmt.Errorf("window: invalid duration %q", w)
+		}
+	}
+	return q, nil
+}
+
+// listParam acceptă atât "devices=a,b" cât și "device=a&device=b".
+func listParam(v url.Values, plural, singular string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, raw := range append(v[plural], v[singular]...) {
+		for _, s := range strings.Split(raw, ",") {
+			if s = strings.TrimSpace(s); s != "" && !seen[s] {
+				seen[s] = true
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func parseTimeParam(s string, now time.Time) (time.Time, error) {
+	if s == "" || s == "now" || s == "now()" {
+		return now, nil
+	}
+	if relativeTimeRe.MatchString(s) {
+		d, err := parseSpan(s[1:])
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected -24h / -7d / RFC3339, got %q", s)
+	}
+	return t, nil
+}
+
+// parseSpan — time.ParseDuration + sufixele "d" / "w" folosite în dashboard.
+func parseSpan(s string) (time.Duration, error) {
+	if m := relativeTimeRe.FindStringSubmatch("-" + s); m != nil && (m[2] == "d" || m[2] == "w") {
+		n, _ := strconv.Atoi(m[1])
+		unit := 24 * time.Hour
+		if m[2] == "w" {
+			unit *= 7
+		}
+		return time.Duration(n) * unit, nil
+	}
+	return time.ParseDuration(s)
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseSeriesQuery(t *testing.T) {
+	now := time.Date(2026, 5, 13, 12, 0, 0, 0, time.UTC)
+	v, _ := url.ParseQuery("devices=a,b&device=c&fields=power&start=-7d&window=15m&fn=MAX")
+	q, err := parseSeriesQuery(v, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(q.Devices) != 3 || q.Devices[2] != "c" {
+		t.Errorf("devices=%v", q.Devices)
+	}
+	if !q.Start.Equal(now.Add(-7*24*time.Hour)) || !q.Stop.Equal(now) {
+		t.Errorf("start=%s stop=%s", q.Start, q.Stop)
+	}
+	if q.Window != 15*time.Minute || q.Fn != "max" {
+		t.Errorf("window=%s fn=%s", q.Window, q.Fn)
+	}
+
+	v, _ = url.ParseQuery("devices=a&fields=f&start=2026-05-01T00:00:00Z&stop=2026-05-02T00:00:00Z")
+	q, err = parseSeriesQuery(v, now)
+	if err != nil || q.Stop.Sub(q.Start) != 24*time.Hour {
+		t.Errorf("absolute range: %s-%s err=%v", q.Start, q.Stop, err)
+	}
+
+	for _, bad := range []string{"start=yesterday", "stop=-5x", "window=fast", "window=-5m"} {
+		v, _ := url.ParseQuery("devices=a&fields=f&" + bad)
+		if _, err := parseSeriesQuery(v, now); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"go-iot-platform/internal/django"
+	"go-iot-platform/internal/logging"
+	"go-iot-platform/internal/stream"
+)
+
+const (
+	streamHeartbeat  = 15 * time.Second
+	maxStreamDevices = 200
+)
+
+// streamHub — setat din cmd/main.go (SetStreamHub); nil → /stream răspunde 503.
+var streamHub *stream.Hub
+
+// streamTickets — ticket-urile single-use pentru EventSource (SetStreamTickets).
+var streamTickets *stream.TicketStore
+
+// SetStreamHub conectează endpoint-ul /stream la hub-ul procesului.
+func SetStreamHub(h *stream.Hub) { streamHub = h }
+
+// SetStreamTickets activează POST /stream/ticket și /stream?ticket=.
+func SetStreamTickets(t *stream.TicketStore) { streamTickets = t }
+
+// GET /go/stream?devices=a,b
+//
+// Server-Sent Events cu telemetria acceptată la ingest, în timp real (înlocuiește
+// polling-ul din dashboard). Auth: header Authorization / X-API-Key ca restul
+// API-ului sau, pentru EventSource (care nu poate seta header-e), ?ticket= cerut
+// înainte cu header-ul de la POST /go/stream/ticket. Token-urile nu sunt
+// acceptate în query string — ar ajunge în access log-uri.
+// Fără `devices` → toate device-urile userului din tenant.
+//
+//	event: telemetry
+//	data: {"tenant_id":2,"device":"a","time":"…","fields":{"power":1.5}}
+//
+//	event: dropped
+//	data: {"dropped":12}      ← evenimente pierdute (rate limit / client lent)
+func streamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tc, ok := authorizeWith(w, r, PermReadMetrics, getStreamTokenContext)
+	if !ok {
+		return
+	}
+	if streamHub == nil {
+		http.Error(w, "stream unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	devices, err := userDevices(r.Context(), tc)
+	if err != nil {
+		log.Printf("❌ Django error: %v", err)
+		http.Error(w, "Django error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	serials, err := streamDevices(listParam(r.URL.Query(), "devices", "device"), devices)
+	if err != nil {
+		auditDenied(r, tc, PermReadMetrics, "device", logging.Fields{"error": err.Error()})
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	sub := streamHub.Subscribe(tc.TenantID, serials, stream.SubscriptionConfig{})
+	defer sub.Close()
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no") // nginx / Kong: fără buffering pe răspuns
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: 5000\n: subscribed %d devices\n\n", len(serials))
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	dropTick := time.NewTicker(time.Second)
+	defer dropTick.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-sub.C():
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: telemetry\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-dropTick.C:
+			if n := sub.TakeDropped(); n > 0 {
+				fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", n)
+				flusher.Flush()
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// POST /go/stream/ticket → {"ticket": "…", "expires_in": 30}
+//
+// Auth cu header (JWT sau X-API-Key); ticket-ul poartă contextul de autorizare
+// al cererii, e valabil stream.TicketTTL și se consumă la primul /go/stream.
+func streamTicketHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	tc, ok := authorize(w, r, PermReadMetrics)
+	if !ok {
+		return
+	}
+	if streamTickets == nil {
+		http.Error(w, "stream unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	data, err := json.Marshal(tc)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	ticket, err := streamTickets.Issue(r.Context(), data)
+	if err != nil {
+		log.Printf("❌ stream ticket: %v", err)
+		http.Error(w, "stream ticket unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"ticket":     ticket,
+		"expires_in": int(stream.TicketTTL.Seconds()),
+	})
+}
+
+// getStreamTokenContext — header-ul Authorization / X-API-Key are prioritate;
+// altfel ?ticket= (consumat la prima folosire).
+func getStreamTokenContext(r *http.Request) (tokenContext, error) {
+	if r.Header.Get("Authorization") != "" || r.Header.Get(apiKeyHeader) != "" {
+		return getTokenContext(r)
+	}
+	ticket := r.URL.Query().Get("ticket")
+	if ticket == "" {
+		return tokenContext{}, fmt.Errorf("missing bearer token or stream ticket")
+	}
+	if streamTickets == nil {
+		return tokenContext{}, fmt.Errorf("stream tickets not enabled")
+	}
+	data, err := streamTickets.Redeem(r.Context(), ticket)
+	if err != nil {
+		return tokenContext{}, err
+	}
+	var tc tokenContext
+	if err := json.Unmarshal(data, &tc); err != nil {
+		return tokenContext{}, fmt.Errorf("stream ticket: %w", err)
+	}
+	return tc, nil
+}
+
+// streamDevices verifică device-urile cerute contra celor ale userului;
+// lista goală = toate device-urile userului.
+func streamDevices(requested []string, allowed []django.Device) ([]string, error) {
+	set := make(map[string]bool, len(allowed))
+	for _, d := range allowed {
+		set[d.Serial] = true
+	}
+	if len(requested) == 0 {
+		for _, d := range allowed {
+			requested = append(requested, d.Serial)
+		}
+	}
+	if len(requested) == 0 {
+		return nil, fmt.Errorf("no devices for user/tenant")
+	}
+	if len(requested) > maxStreamDevices {
+		return nil, fmt.Errorf("too many devices: %d (max %d)", len(requested), maxStreamDevices)
+	}
+	for _, s := range requested {
+		if !set[s] {
+			return nil, fmt.Errorf("device not allowed for user/tenant: %s", s)
+		}
+	}
+	return requested, nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-iot-platform/internal/django"
+	"go-iot-platform/internal/stream"
+)
+
+func TestGetStreamTokenContext(t *testing.T) {
+	const secret = "unit-test-secret"
+	t.Setenv("JWT_SECRET", secret)
+	tok := signedToken(t, secret, jwt.MapClaims{
+		"username": "alice", "tenant_id": 3, "role": "VIEWER", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	SetStreamTickets(stream.NewTicketStore(nil))
+	t.Cleanup(func() { SetStreamTickets(nil) })
+
+	// Token-ul în query string nu mai e acceptat (ar ajunge în access log-uri).
+	for _, target := range []string{"/stream?jwt=" + tok, "/stream?access_token=" + tok} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if _, err := getStreamTokenContext(req); err == nil {
+			t.Errorf("%s: expected error for token in query string", target[:12])
+		}
+	}
+
+	// Ticket cerut cu header-ul → folosibil o singură dată pe /stream.
+	req := httptest.NewRequest(http.MethodPost, "/stream/ticket", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	rec := httptest.NewRecorder()
+	streamTicketHandler(rec, req)
+	var resp struct {
+		Ticket    string `json:"ticket"`
+		ExpiresIn int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); rec.Code != http.StatusOK || err != nil || resp.Ticket == "" {
+		t.Fatalf("ticket: code=%d body=%s", rec.Code, rec.Body)
+	}
+	if resp.ExpiresIn != int(stream.TicketTTL.Seconds()) {
+		t.Errorf("expires_in=%d", resp.ExpiresIn)
+	}
+	req = httptest.NewRequest(http.MethodGet, "/stream?ticket="+resp.Ticket, nil)
+	tc, err := getStreamTokenContext(req)
+	if err != nil || tc.TenantID != 3 || tc.Username != "alice" {
+		t.Errorf("ticket: tc=%+v err=%v", tc, err)
+	}
+	if _, err := getStreamTokenContext(req); err == nil {
+		t.Error("expected error when the ticket is reused")
+	}
+
+	// Fără header nu se emite ticket.
+	rec = httptest.NewRecorder()
+	streamTicketHandler(rec, httptest.NewRequest(http.MethodPost, "/stream/ticket", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("ticket without token: code=%d", rec.Code)
+	}
+
+	// Header-ul are prioritate: un header invalid nu e ocolit prin query param.
+	req = httptest.NewRequest(http.MethodGet, "/stream?ticket=x", nil)
+	req.Header.Set("Authorization", "Bearer garbage")
+	if _, err := getStreamTokenContext(req); err == nil {
+		t.Error("expected error for invalid header token")
+	}
+
+	rec = httptest.NewRecorder()
+	streamHandler(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("no token: code=%d", rec.Code)
+	}
+}
+
+func TestStreamDevices(t *testing.T) {
+	allowed := []django.Device{{Serial: "a"}, {Serial: "b"}}
+	if got, err := streamDevices(nil, allowed); err != nil || len(got) != 2 {
+		t.Errorf("all devices: %v %v", got, err)
+	}
+	if got, err := streamDevices([]string{"b"}, allowed); err != nil || len(got) != 1 || got[0] != "b" {
+		t.Errorf("subset: %v %v", got, err)
+	}
+	if _, err := streamDevices([]string{"a", "x"}, allowed); err == nil {
+		t.Error("expected error for foreign device")
+	}
+	if _, err := streamDevices(nil, nil); err == nil {
+		t.Error("expected error for user without devices")
+	}
+}
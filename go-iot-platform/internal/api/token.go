@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-iot-platform/internal/config"
+	"go-iot-platform/internal/jwks"
+)
+
+// TokenVerifierConfig — ce acceptă API-ul Go, independent de Kong.
+//
+//   - Algorithms: whitelist-ul de alg din header (default HS256). HS* folosesc
+//     JWT_SECRET (citit la fiecare verificare); RS* / PS* / ES* cer Keys.
+//   - Keys: JWKS (fișier sau URL) cu selecție după kid; rotația se face în set.
+//   - Issuer / Audience: verificate doar dacă sunt setate.
+//   - Leeway: toleranța de ceas pentru exp / nbf / iat.
+type TokenVerifierConfig struct {
+	Algorithms []string
+	Keys       *jwks.Set
+	Issuer     string
+	Audience   string
+	Leeway     time.Duration
+}
+
+// TokenVerifier validează semnătura și claim-urile standard; claim-urile
+// aplicației (username, tenant_id …) rămân în parseTokenContext.
+type TokenVerifier struct {
+	cfg     TokenVerifierConfig
+	options []jwt.ParserOption
+}
+
+// NewTokenVerifier verifică configurația: algoritmi cunoscuți, JWKS pentru cei asimetrici.
+func NewTokenVerifier(cfg TokenVerifierConfig) (*TokenVerifier, error) {
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = []string{jwt.SigningMethodHS256.Alg()}
+	}
+	for _, alg := range cfg.Algorithms {
+		if jwt.GetSigningMethod(alg) == nil || alg == "none" {
+			return nil, fmt.Errorf("unsupported JWT algorithm %q", alg)
+		}
+		if !isHMAC(alg) && cfg.Keys == nil {
+			return nil, fmt.Errorf("JWT algorithm %s requires a JWKS", alg)
+		}
+	}
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(cfg.Algorithms),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	return &TokenVerifier{cfg: cfg, options: opts}, nil
+}
+
+func isHMAC(alg string) bool { return strings.HasPrefix(alg, "HS") }
+
+// Verify parsează și validează token-ul, întorcând claim-urile.
+func (v *TokenVerifier) Verify(ctx context.Context, tokenStr string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (interface{}, error) {
+		return v.key(ctx, t)
+	}, v.options...)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("token not valid")
+	}
+	return claims, nil
+}
+
+// key — alg-ul e deja în whitelist (WithValidMethods). Fără kid întoarcem toate
+// cheile compatibile; jwt încearcă fiecare cheie din VerificationKeySet.
+func (v *TokenVerifier) key(ctx context.Context, t *jwt.Token) (interface{}, error) {
+	alg := t.Method.Alg()
+	if isHMAC(alg) {
+		secret := config.Get("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("JWT_SECRET not configured")
+		}
+		return []byte(secret), nil
+	}
+	kid, _ := t.Header["kid"].(string)
+	keys, err := v.cfg.Keys.Keys(ctx, kid, alg)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 1 {
+		return keys[0].Pub, nil
+	}
+	set := jwt.VerificationKeySet{}
+	for _, k := range keys {
+		set.Keys = append(set.Keys, k.Pub)
+	}
+	return set, nil
+}
+
+// tokenVerifier — setat din cmd/main.go (SetTokenVerifier); default HS256 + JWT_SECRET.
+var tokenVerifier = mustDefaultVerifier()
+
+func mustDefaultVerifier() *TokenVerifier {
+	v, err := NewTokenVerifier(TokenVerifierConfig{})
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// SetTokenVerifier înlocuiește verificatorul folosit de toate endpoint-urile.
+func SetTokenVerifier(v *TokenVerifier) { tokenVerifier = v }
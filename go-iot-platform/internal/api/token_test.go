@@ -0,0 +1,183 @@
+package api
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"go-iot-platform/internal/jwks"
+)
+
+func testKeys(t *testing.T) (*rsa.PrivateKey, *ecdsa.PrivateKey, *jwks.Set) {
+	t.Helper()
+	rk, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	doc, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "n": enc(rk.N.Bytes()), "e": enc(big.NewInt(int64(rk.E)).Bytes())},
+		{"kty": "EC", "kid": "ec-1", "crv": "P-256",
+			"x": enc(ek.X.FillBytes(make([]byte, 32))), "y": enc(ek.Y.FillBytes(make([]byte, 32)))},
+	}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, doc, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	set, err := jwks.Load(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rk, ek, set
+}
+
+func sign(t *testing.T, m jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(m, claims)
+	if kid != "" {
+		tok.Header["kid"] = kid
+	}
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestTokenVerifierAsymmetric(t *testing.T) {
+	t.Setenv("JWT_SECRET", "unit-test-secret")
+	rk, ek, set := testKeys(t)
+	v, err := NewTokenVerifier(TokenVerifierConfig{
+		Algorithms: []string{"RS256", "ES256"},
+		Keys:       set,
+		Issuer:     "django",
+		Audience:   "go-api",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	base := func(extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{"username": "alice", "tenant_id": 1, "iss": "django", "aud": "go-api", "exp": exp}
+		for k, val := range extra {
+			if val == nil {
+				delete(c, k)
+			} else {
+				c[k] = val
+			}
+		}
+		return c
+	}
+
+	cases := []struct {
+		name  string
+		token string
+		ok    bool
+	}{
+		{"rs256 kid", sign(t, jwt.SigningMethodRS256, "rsa-1", rk, base(nil)), true},
+		{"es256 kid", sign(t, jwt.SigningMethodES256, "ec-1", ek, base(nil)), true},
+		{"es256 no kid", sign(t, jwt.SigningMethodES256, "", ek, base(nil)), true},
+		{"wrong kid", sign(t, jwt.SigningMethodRS256, "ec-1", rk, base(nil)), false},
+		{"alg not allowed", sign(t, jwt.SigningMethodRS512, "rsa-1", rk, base(nil)), false},
+		{"hs256 not allowed", sign(t, jwt.SigningMethodHS256, "", []byte("unit-test-secret"), base(nil)), false},
+		{"wrong issuer", sign(t, jwt.SigningMethodRS256, "rsa-1", rk, base(jwt.MapClaims{"iss": "evil"})), false},
+		{"wrong audience", sign(t, jwt.SigningMethodRS256, "rsa-1", rk, base(jwt.MapClaims{"aud": "other"})), false},
+		{"no exp", sign(t, jwt.SigningMethodRS256, "rsa-1", rk, base(jwt.MapClaims{"exp": nil})), false},
+		{"not yet valid", sign(t, jwt.SigningMethodRS256, "rsa-1", rk, base(jwt.MapClaims{"nbf": time.Now().Add(time.Hour).Unix()})), false},
+	}
+	for _, c := range cases {
+		_, err := v.Verify(context.Background(), c.token)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: err = %v, want ok=%v", c.name, err, c.ok)
+		}
+	}
+}
+
+func TestTokenVerifierConfig(t *testing.T) {
+	if _, err := NewTokenVerifier(TokenVerifierConfig{Algorithms: []string{"RS256"}}); err == nil {
+		t.Error("RS256 without JWKS should fail")
+	}
+	if _, err := NewTokenVerifier(TokenVerifierConfig{Algorithms: []string{"none"}}); err == nil {
+		t.Error("alg none should fail")
+	}
+}
+
+func TestParseTokenContextUsesVerifier(t *testing.T) {
+	rk, _, set := testKeys(t)
+	v, err := NewTokenVerifier(TokenVerifierConfig{Algorithms: []string{"RS256"}, Keys: set})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := tokenVerifier
+	SetTokenVerifier(v)
+	defer SetTokenVerifier(prev)
+
+	tok := sign(t, jwt.SigningMethodRS256, "rsa-1", rk, jwt.MapClaims{
+		"username": "bob", "tenant_id": 5, "role": "VIEWER", "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	tc, err := parseTokenContext(context.Background(), tok)
+	if err != nil || tc.Username != "bob" || tc.TenantID != 5 {
+		t.Fatalf("tc=%+v err=%v", tc, err)
+	}
+}
+
+func TestTokenContextJWKSFetchFollowsRequest(t *testing.T) {
+	rk, _, _ := testKeys(t)
+	enc := base64.RawURLEncoding.EncodeToString
+	doc, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa-1", "n": enc(rk.N.Bytes()), "e": enc(big.NewInt(int64(rk.E)).Bytes())},
+	}})
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			<-r.Context().Done() // refresh-ul pentru kid necunoscut atârnă
+			return
+		}
+		_, _ = w.Write(doc)
+	}))
+	defer srv.Close()
+	set, err := jwks.Load(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewTokenVerifier(TokenVerifierConfig{Algorithms: []string{"RS256"}, Keys: set})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := tokenVerifier
+	SetTokenVerifier(v)
+	defer SetTokenVerifier(prev)
+
+	tok := sign(t, jwt.SigningMethodRS256, "rotated", rk, jwt.MapClaims{
+		"username": "bob", "tenant_id": 5, "exp": time.Now().Add(time.Hour).Unix(),
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(http.MethodGet, "/go/metrics/latest", nil).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	start := time.Now()
+	if _, err := getTokenContext(req); err == nil {
+		t.Fatal("unknown kid accepted")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("JWKS refresh outlived the request: %v", d)
+	}
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Chei API de tenant pentru integrări machine-to-machine (SCADA / BMS): Django
+// (api_keys/signals.py) scrie "apikey:{sha256(plain)}" doar pentru cheile valide,
+// cu expires_at ca TTL; revocarea / ștergerea fac DEL. Go nu vede cheia în clar.
+const apiKeyPrefix = "apikey:"
+
+var (
+	// ErrAPIKeyInvalid — cheie necunoscută, revocată sau expirată.
+	ErrAPIKeyInvalid = errors.New("invalid API key")
+	// ErrAPIKeyUnavailable — Redis indisponibil; cheia nu poate fi verificată.
+	ErrAPIKeyUnavailable = errors.New("API key store unavailable")
+)
+
+// APIKey — înregistrarea sincronizată din Django.
+type APIKey struct {
+	ID        int64      `json:"id"`
+	TenantID  int64      `json:"tenant_id"`
+	Name      string     `json:"name"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	RateLimit int        `json:"rate_limit"` // req/min; 0 → default-ul API-ului
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// HashAPIKey — același hash ca APIKey.hash_key din Django (sha256 hex).
+func HashAPIKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyStore citește cheile din Redis.
+type APIKeyStore struct {
+	get func(ctx context.Context, key string) ([]byte, error)
+}
+
+// NewAPIKeyStore — rdb e același client ca pentru cache-ul de device-uri.
+func NewAPIKeyStore(rdb *redis.Client) *APIKeyStore {
+	return &APIKeyStore{get: func(ctx context.Context, key string) ([]byte, error) {
+		return rdb.Get(ctx, key).Bytes()
+	}}
+}
+
+// Lookup verifică cheia în clar și întoarce înregistrarea.
+func (s *APIKeyStore) Lookup(ctx context.Context, plain string) (APIKey, error) {
+	if plain == "" {
+		return APIKey{}, ErrAPIKeyInvalid
+	}
+	raw, err := s.get(ctx, apiKeyPrefix+HashAPIKey(plain))
+	if errors.Is(err, redis.Nil) {
+		return APIKey{}, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return APIKey{}, fmt.Errorf("%w: %v", ErrAPIKeyUnavailable, err)
+	}
+	return decodeAPIKey(raw, time.Now())
+}
+
+// decodeAPIKey — TTL-ul Redis acoperă expirarea, dar verificăm și aici (ceasuri
+// desincronizate, chei scrise manual fără TTL).
+func decodeAPIKey(raw []byte, now time.Time) (APIKey, error) {
+	var k APIKey
+	if err := json.Unmarshal(raw, &k); err != nil {
+		return APIKey{}, fmt.Errorf("%w: corrupt record: %v", ErrAPIKeyInvalid, err)
+	}
+	if k.TenantID <= 0 {
+		return APIKey{}, fmt.Errorf("%w: no tenant", ErrAPIKeyInvalid)
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		return APIKey{}, fmt.Errorf("%w: expired", ErrAPIKeyInvalid)
+	}
+	return k, nil
+}
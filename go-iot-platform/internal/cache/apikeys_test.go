@@ -0,0 +1,61 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestHashAPIKey(t *testing.T) {
+	// hashlib.sha256(b"abc").hexdigest()
+	if got := HashAPIKey("abc"); got != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("hash = %s", got)
+	}
+}
+
+func TestAPIKeyLookup(t *testing.T) {
+	records := map[string]string{
+		apiKeyPrefix + HashAPIKey("good"):    `{"id":3,"tenant_id":7,"prefix":"good","scopes":["metrics:read"],"rate_limit":60,"expires_at":null}`,
+		apiKeyPrefix + HashAPIKey("expired"): `{"id":4,"tenant_id":7,"expires_at":"2020-01-01T00:00:00+00:00"}`,
+		apiKeyPrefix + HashAPIKey("orphan"):  `{"id":5}`,
+		apiKeyPrefix + HashAPIKey("corrupt"): `{`,
+	}
+	redisDown := errors.New("dial tcp: connection refused")
+	s := &APIKeyStore{get: func(_ context.Context, key string) ([]byte, error) {
+		if key == apiKeyPrefix+HashAPIKey("down") {
+			return nil, redisDown
+		}
+		if v, ok := records[key]; ok {
+			return []byte(v), nil
+		}
+		return nil, redis.Nil
+	}}
+	ctx := context.Background()
+
+	k, err := s.Lookup(ctx, "good")
+	if err != nil || k.ID != 3 || k.TenantID != 7 || k.RateLimit != 60 || len(k.Scopes) != 1 {
+		t.Fatalf("good: %+v %v", k, err)
+	}
+	for _, plain := range []string{"", "unknown", "expired", "orphan", "corrupt"} {
+		if _, err := s.Lookup(ctx, plain); !errors.Is(err, ErrAPIKeyInvalid) {
+			t.Errorf("%q: err = %v, want ErrAPIKeyInvalid", plain, err)
+		}
+	}
+	if _, err := s.Lookup(ctx, "down"); !errors.Is(err, ErrAPIKeyUnavailable) {
+		t.Errorf("down: err = %v", err)
+	}
+}
+
+func TestDecodeAPIKeyExpiry(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	raw := []byte(`{"id":1,"tenant_id":2,"expires_at":"2026-05-01T12:00:01.5+00:00"}`)
+	if _, err := decodeAPIKey(raw, now); err != nil {
+		t.Errorf("not yet expired: %v", err)
+	}
+	if _, err := decodeAPIKey(raw, now.Add(2*time.Second)); !errors.Is(err, ErrAPIKeyInvalid) {
+		t.Errorf("expired: %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
+
+	"go-iot-platform/internal/django"
+)
+
+// Cache de autorizare pentru API-ul de metrici: (user, tenant) → device-urile
+// vizibile, în loc de un apel django.GetDevicesForUserInTenant per request.
+//
+//   - Redis "authz:{tenant}:{user}" cu TTL scurt (default 30s), partajat între instanțe
+//   - miss-urile concurente pentru aceeași cheie fac un singur apel Django (singleflight)
+//   - invalidare push: "device-cache-invalidate" (device mutat / șters — tot tenantul
+//     device-ului) și "authz-membership-invalidate" (membership / tenant schimbat,
+//     publicat de tenants/signals.py)
+//   - generații "authzgen:{tenant}" / "authzgen:0" (tot cache-ul): invalidarea le
+//     incrementează înainte de DEL, iar un fetch pornit înaintea ei nu mai scrie
+//     rezultatul (vechi) peste — SET-ul e condiționat de generațiile citite la start
+const (
+	authzPrefix            = "authz:"
+	authzGenPrefix         = "authzgen:"
+	membershipInvalidateCh = "authz-membership-invalidate"
+	defaultAuthzTTL        = 30 * time.Second
+
+	// authzGenTTL — generațiile trebuie doar să supraviețuiască unui fetch în zbor.
+	authzGenTTL = 24 * time.Hour
+)
+
+// storeIfCurrent: KEYS = authz key, generația tenantului, generația globală;
+// ARGV = valoare, TTL ms, generațiile citite înainte de fetch.
+var storeIfCurrent = redis.NewScript(`
+if (redis.call("GET", KEYS[2]) or "0") ~= ARGV[3] or (redis.call("GET", KEYS[3]) or "0") ~= ARGV[4] then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+return 1
+`)
+
+// AuthzCache — vezi mai sus. rdb nil → doar singleflight, fără stocare.
+type AuthzCache struct {
+	rdb   *redis.Client
+	ttl   time.Duration
+	fetch func(username string, tenantID int64) ([]django.Device, error)
+	group singleflight.Group
+
+	statsMu sync.Mutex
+	hits    uint64
+	misses  uint64
+}
+
+// NewAuthzCache — ttl 0 → 30s; lookup-urile merg la django.GetDevicesForUserInTenant.
+func NewAuthzCache(rdb *redis.Client, ttl time.Duration) *AuthzCache {
+	if ttl <= 0 {
+		ttl = defaultAuthzTTL
+	}
+	return &AuthzCache{rdb: rdb, ttl: ttl, fetch: django.GetDevicesForUserInTenant}
+}
+
+func authzKey(username string, tenantID int64) string {
+	return fmt.Sprintf("%s%d:%s", authzPrefix, tenantID, username)
+}
+
+func authzGenKey(tenantID int64) string {
+	return authzGenPrefix + strconv.FormatInt(tenantID, 10)
+}
+
+// Devices întoarce device-urile vizibile userului în tenant. Erorile Django nu
+// sunt cache-uite; Redis indisponibil → fallback direct pe Django.
+func (a *AuthzCache) Devices(ctx context.Context, username string, tenantID int64) ([]django.Device, error) {
+	key := authzKey(username, tenantID)
+	if a.rdb != nil {
+		if raw, err := a.rdb.Get(ctx, key).Bytes(); err == nil {
+			var devs []django.Device
+			if json.Unmarshal(raw, &devs) == nil {
+				a.bumpStat(true)
+				return devs, nil
+			}
+		}
+	}
+	a.bumpStat(false)
+
+	v, err, _ := a.group.Do(key, func() (interface{}, error) {
+		// Request-ul care a declanșat fetch-ul poate fi deja anulat;
+		// rezultatul servește și celorlalți.
+		ctx := context.WithoutCancel(ctx)
+		var gens []interface{}
+		genErr := errNoRedis
+		if a.rdb != nil {
+			gens, genErr = a.rdb.MGet(ctx, authzGenKey(tenantID), authzGenKey(0)).Result()
+		}
+		devs, err := a.fetch(username, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if genErr == nil {
+			if raw, err := json.Marshal(devs); err == nil {
+				storeIfCurrent.Run(ctx, a.rdb, //nolint:errcheck
+					[]string{key, authzGenKey(tenantID), authzGenKey(0)},
+					raw, a.ttl.Milliseconds(), genValue(gens[0]), genValue(gens[1]))
+			}
+		}
+		return devs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]django.Device), nil
+}
+
+var errNoRedis = errors.New("authz: no redis")
+
+// genValue — o generație din MGET; cheie lipsă = "0", ca în storeIfCurrent.
+func genValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return "0"
+}
+
+// bumpGeneration face ca fetch-urile în zbor pentru tenant (0 = toate) să nu
+// mai fie scrise în cache.
+func (a *AuthzCache) bumpGeneration(ctx context.Context, tenantID int64) error {
+	pipe := a.rdb.Pipeline()
+	pipe.Incr(ctx, authzGenKey(tenantID))
+	pipe.Expire(ctx, authzGenKey(tenantID), authzGenTTL)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// InvalidateUser șterge intrarea unui user dintr-un tenant. Generația e a
+// întregului tenant: un fetch în zbor al altui user pierde doar scrierea în cache.
+func (a *AuthzCache) InvalidateUser(ctx context.Context, username string, tenantID int64) error {
+	if a.rdb == nil {
+		return nil
+	}
+	if err := a.bumpGeneration(ctx, tenantID); err != nil {
+		return err
+	}
+	return a.rdb.Del(ctx, authzKey(username, tenantID)).Err()
+}
+
+// InvalidateTenant șterge intrările tuturor userilor unui tenant; tenantID 0 → tot.
+func (a *AuthzCache) InvalidateTenant(ctx context.Context, tenantID int64) error {
+	if a.rdb == nil {
+		return nil
+	}
+	if err := a.bumpGeneration(ctx, tenantID); err != nil {
+		return err
+	}
+	pattern := authzPrefix + "*"
+	if tenantID > 0 {
+		pattern = fmt.Sprintf("%s%d:*", authzPrefix, tenantID)
+	}
+	iter := a.rdb.Scan(ctx, 0, pattern, 200).Iterator()
+	var keys []string
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return a.rdb.Unlink(ctx, keys...).Err()
+}
+
+// authzInvalidation — payload comun celor două canale; câmpurile lipsă lărgesc
+// invalidarea: fără username → tot tenantul, fără tenant_id → tot cache-ul.
+type authzInvalidation struct {
+	Username string `json:"username"`
+	TenantID int64  `json:"tenant_id"`
+}
+
+func (a *AuthzCache) apply(ctx context.Context, channel string, inv authzInvalidation) error {
+	username, tenantID := invalidationScope(channel, inv)
+	if username != "" {
+		return a.InvalidateUser(ctx, username, tenantID)
+	}
+	return a.InvalidateTenant(ctx, tenantID)
+}
+
+// invalidationScope: username != "" → un singur user; altfel tenantul (0 = tot).
+// Pe canalul de device-uri username-ul e ignorat: device-ul e vizibil oricărui
+// user al tenantului.
+func invalidationScope(channel string, inv authzInvalidation) (string, int64) {
+	if channel == membershipInvalidateCh && inv.Username != "" && inv.TenantID > 0 {
+		return inv.Username, inv.TenantID
+	}
+	return "", inv.TenantID
+}
+
+// SubscribeInvalidations ascultă ambele canale până la anularea ctx.
+func (a *AuthzCache) SubscribeInvalidations(ctx context.Context) <-chan error {
+	errCh := make(chan error, 1)
+	if a.rdb == nil {
+		close(errCh)
+		return errCh
+	}
+	go func() {
+		defer close(errCh)
+		sub := a.rdb.Subscribe(ctx, invalidateChannel, membershipInvalidateCh)
+		defer sub.Close()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				var inv authzInvalidation
+				if err := json.Unmarshal([]byte(msg.Payload), &inv); err != nil {
+					inv = authzInvalidation{} // payload invalid → invalidăm tot, nu ignorăm
+					select {
+					case errCh <- fmt.Errorf("authz invalidation parse: %w", err):
+					default:
+					}
+				}
+				if err := a.apply(ctx, msg.Channel, inv); err != nil {
+					select {
+					case errCh <- fmt.Errorf("authz invalidate: %w", err):
+					default:
+					}
+				}
+			}
+		}
+	}()
+	return errCh
+}
+
+// Stats întoarce (hits, misses).
+func (a *AuthzCache) Stats() (uint64, uint64) {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	return a.hits, a.misses
+}
+
+func (a *AuthzCache) bumpStat(hit bool) {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	if hit {
+		a.hits++
+	} else {
+		a.misses++
+	}
+}
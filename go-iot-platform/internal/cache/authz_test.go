@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"go-iot-platform/internal/django"
+)
+
+func TestAuthzSingleflight(t *testing.T) {
+	a := NewAuthzCache(nil, 0)
+	var calls int32
+	release := make(chan struct{})
+	a.fetch = func(username string, tenantID int64) ([]django.Device, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return []django.Device{{Serial: "dev-1", TenantPlan: "pro"}}, nil
+	}
+
+	// 12 gauge-uri pe același dashboard → un singur apel Django.
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			devs, err := a.Devices(context.Background(), "alice", 2)
+			if err != nil || len(devs) != 1 || devs[0].Serial != "dev-1" {
+				t.Errorf("devs=%v err=%v", devs, err)
+			}
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("django calls = %d, want 1", n)
+	}
+	if _, misses := a.Stats(); misses != 12 {
+		t.Errorf("misses = %d", misses)
+	}
+}
+
+func TestAuthzErrorNotCached(t *testing.T) {
+	a := NewAuthzCache(nil, 0)
+	fail := true
+	a.fetch = func(string, int64) ([]django.Device, error) {
+		if fail {
+			return nil, errors.New("django down")
+		}
+		return []django.Device{}, nil
+	}
+	if _, err := a.Devices(context.Background(), "bob", 1); err == nil {
+		t.Fatal("expected error")
+	}
+	fail = false
+	if _, err := a.Devices(context.Background(), "bob", 1); err != nil {
+		t.Fatalf("error was cached: %v", err)
+	}
+}
+
+func TestInvalidationScope(t *testing.T) {
+	cases := []struct {
+		channel    string
+		inv        authzInvalidation
+		wantUser   string
+		wantTenant int64
+	}{
+		{membershipInvalidateCh, authzInvalidation{Username: "alice", TenantID: 2}, "alice", 2},
+		{membershipInvalidateCh, authzInvalidation{TenantID: 2}, "", 2},
+		{membershipInvalidateCh, authzInvalidation{Username: "alice"}, "", 0},
+		{invalidateChannel, authzInvalidation{Username: "alice", TenantID: 3}, "", 3},
+		{invalidateChannel, authzInvalidation{}, "", 0},
+	}
+	for _, tc := range cases {
+		u, tid := invalidationScope(tc.channel, tc.inv)
+		if u != tc.wantUser || tid != tc.wantTenant {
+			t.Errorf("%s %+v → (%q, %d), want (%q, %d)", tc.channel, tc.inv, u, tid, tc.wantUser, tc.wantTenant)
+		}
+	}
+	if k := authzKey("alice", 2); k != "authz:2:alice" {
+		t.Errorf("key = %s", k)
+	}
+}
+
+// fakeRedis — subsetul de comenzi folosit de AuthzCache, servit dintr-un map
+// printr-un hook (fără conexiune). EVALSHA reproduce storeIfCurrent.
+type fakeRedis struct {
+	mu sync.Mutex
+	kv map[string]string
+}
+
+func newFakeRedis(t *testing.T) (*redis.Client, *fakeRedis) {
+	f := &fakeRedis{kv: map[string]string{}}
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	rdb.AddHook(f)
+	t.Cleanup(func() { rdb.Close() })
+	return rdb, f
+}
+
+func (f *fakeRedis) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (f *fakeRedis) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(_ context.Context, cmd redis.Cmder) error {
+		f.process(cmd)
+		return cmd.Err()
+	}
+}
+
+func (f *fakeRedis) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(_ context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			f.process(cmd)
+		}
+		return nil
+	}
+}
+
+func (f *fakeRedis) process(cmd redis.Cmder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	args := make([]string, len(cmd.Args()))
+	for i, a := range cmd.Args() {
+		if b, ok := a.([]byte); ok {
+			args[i] = string(b)
+		} else {
+			args[i] = fmt.Sprint(a)
+		}
+	}
+	switch c := cmd.(type) {
+	case *redis.StringCmd: // GET
+		if v, ok := f.kv[args[1]]; ok {
+			c.SetVal(v)
+		} else {
+			c.SetErr(redis.Nil)
+		}
+	case *redis.SliceCmd: // MGET
+		vals := make([]interface{}, len(args)-1)
+		for i, k := range args[1:] {
+			if v, ok := f.kv[k]; ok {
+				vals[i] = v
+			}
+		}
+		c.SetVal(vals)
+	case *redis.IntCmd: // INCR / DEL / UNLINK
+		switch cmd.Name() {
+		case "incr":
+			var n int64
+			fmt.Sscan(f.kv[args[1]], &n)
+			n++
+			f.kv[args[1]] = fmt.Sprint(n)
+			c.SetVal(n)
+		default:
+			for _, k := range args[1:] {
+				delete(f.kv, k)
+			}
+		}
+	case *redis.ScanCmd:
+		var keys []string
+		for k := range f.kv {
+			if ok, _ := path.Match(args[3], k); ok {
+				keys = append(keys, k)
+			}
+		}
+		c.SetVal(keys, 0)
+	case *redis.Cmd: // EVALSHA storeIfCurrent
+		// evalsha sha 3 key genTenant genAll value ttl seenTenant seenAll
+		cur := func(k string) string {
+			if v, ok := f.kv[k]; ok {
+				return v
+			}
+			return "0"
+		}
+		if cur(args[4]) == args[8] && cur(args[5]) == args[9] {
+			f.kv[args[3]] = args[6]
+			c.SetVal(int64(1))
+		} else {
+			c.SetVal(int64(0))
+		}
+	}
+}
+
+func (f *fakeRedis) has(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.kv[key]
+	return ok
+}
+
+func TestAuthzInvalidateDuringFetch(t *testing.T) {
+	rdb, fake := newFakeRedis(t)
+	a := NewAuthzCache(rdb, 0)
+	ctx := context.Background()
+
+	started, release := make(chan struct{}), make(chan struct{})
+	a.fetch = func(string, int64) ([]django.Device, error) {
+		close(started)
+		<-release
+		return []django.Device{{Serial: "moved-away"}}, nil
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		a.Devices(ctx, "alice", 2) //nolint:errcheck
+	}()
+	<-started
+	// Device-ul e mutat în alt tenant cât timp fetch-ul e în zbor.
+	if err := a.InvalidateTenant(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	<-done
+	if fake.has(authzKey("alice", 2)) {
+		t.Fatal("stale fetch stored after invalidation")
+	}
+
+	// Un fetch pornit după invalidare e cache-uit normal.
+	a.fetch = func(string, int64) ([]django.Device, error) { return []django.Device{{Serial: "dev-1"}}, nil }
+	if _, err := a.Devices(ctx, "alice", 2); err != nil {
+		t.Fatal(err)
+	}
+	if !fake.has(authzKey("alice", 2)) {
+		t.Fatal("fresh fetch not stored")
+	}
+	if devs, err := a.Devices(ctx, "alice", 2); err != nil || len(devs) != 1 {
+		t.Fatalf("cached devs=%v err=%v", devs, err)
+	}
+	if hits, _ := a.Stats(); hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+
+	// Invalidarea globală (tenant 0) oprește și ea scrierea.
+	a.fetch = func(string, int64) ([]django.Device, error) {
+		a.InvalidateTenant(ctx, 0) //nolint:errcheck
+		return []django.Device{}, nil
+	}
+	if _, err := a.Devices(ctx, "bob", 3); err != nil {
+		t.Fatal(err)
+	}
+	if fake.has(authzKey("bob", 3)) || fake.has(authzKey("alice", 2)) {
+		t.Error("global invalidation: entries still cached")
+	}
+}
@@ -259,6 +259,12 @@ func (c *Cache) bumpStat(hit bool) {
 	}
 }
 
+// Client expune conexiunea Redis, ca alte componente ale procesului (ex:
+// stream pub/sub) să nu deschidă un pool separat.
+func (c *Cache) Client() *redis.Client {
+	return c.rdb
+}
+
 // Close închide conexiunea Redis.
 func (c *Cache) Close() error {
 	return c.rdb.Close()
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Token-urile CoAP (?t= pe coap://…/t/{serial}/{stream}) circulă în clar pe
+// UDP, deci sunt separate de secretul HMAC de ingest: Django (clients/signals.py)
+// scrie "coaptoken:{serial}" → {"tenant_id", "token_sha256"} cât timp
+// Device.coap_token_hash e setat. Se ține doar hash-ul — serverul compară, nu
+// semnează — iar rotirea (POST /api/devices/{id}/coap-token/rotate/) nu atinge
+// secretul de ingest. Erorile sunt cele ale IngestSecretStore.
+const coapTokenPrefix = "coaptoken:"
+
+// CoAPToken — înregistrarea sincronizată din Django.
+type CoAPToken struct {
+	TenantID    int64  `json:"tenant_id"`
+	TokenSHA256 string `json:"token_sha256"` // hex
+}
+
+// Matches compară token-ul primit cu hash-ul, în timp constant.
+func (t CoAPToken) Matches(token string) bool {
+	want, err := hex.DecodeString(t.TokenSHA256)
+	if err != nil || len(want) != sha256.Size {
+		return false
+	}
+	got := sha256.Sum256([]byte(token))
+	return token != "" && subtle.ConstantTimeCompare(got[:], want) == 1
+}
+
+// CoAPTokenStore citește token-urile din Redis.
+type CoAPTokenStore struct {
+	get func(ctx context.Context, key string) ([]byte, error)
+}
+
+// NewCoAPTokenStore — rdb e același client ca pentru cache-ul de device-uri.
+func NewCoAPTokenStore(rdb *redis.Client) *CoAPTokenStore {
+	return &CoAPTokenStore{get: func(ctx context.Context, key string) ([]byte, error) {
+		return rdb.Get(ctx, key).Bytes()
+	}}
+}
+
+// Lookup întoarce token-ul (hash-ul) device-ului.
+func (s *CoAPTokenStore) Lookup(ctx context.Context, serial string) (CoAPToken, error) {
+	if serial == "" {
+		return CoAPToken{}, ErrIngestSecretUnknown
+	}
+	raw, err := s.get(ctx, coapTokenPrefix+serial)
+	if errors.Is(err, redis.Nil) {
+		return CoAPToken{}, ErrIngestSecretUnknown
+	}
+	if err != nil {
+		return CoAPToken{}, fmt.Errorf("%w: %v", ErrIngestSecretUnavailable, err)
+	}
+	var rec CoAPToken
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return CoAPToken{}, fmt.Errorf("%w: corrupt record: %v", ErrIngestSecretUnknown, err)
+	}
+	if rec.TenantID <= 0 || rec.TokenSHA256 == "" {
+		return CoAPToken{}, fmt.Errorf("%w: incomplete record", ErrIngestSecretUnknown)
+	}
+	return rec, nil
+}
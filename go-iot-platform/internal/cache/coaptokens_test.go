@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestCoAPTokenLookup(t *testing.T) {
+	// python3: hashlib.sha256(b"tok-1").hexdigest()
+	const tok1 = "65dcf16ea3dfa49069628089eb4a75483070f5584b2a21ee64912b5f621f12da"
+	records := map[string]string{
+		coapTokenPrefix + "NB1":     `{"tenant_id":7,"token_sha256":"` + tok1 + `"}`,
+		coapTokenPrefix + "ORPHAN":  `{"token_sha256":"` + tok1 + `"}`,
+		coapTokenPrefix + "CORRUPT": `{`,
+		// secretul de ingest nu e un token CoAP
+		ingestSecretPrefix + "NB2": `{"tenant_id":7,"secret":"s3cr3t"}`,
+	}
+	s := &CoAPTokenStore{get: func(_ context.Context, key string) ([]byte, error) {
+		if key == coapTokenPrefix+"DOWN" {
+			return nil, errors.New("dial tcp: connection refused")
+		}
+		if v, ok := records[key]; ok {
+			return []byte(v), nil
+		}
+		return nil, redis.Nil
+	}}
+	ctx := context.Background()
+
+	rec, err := s.Lookup(ctx, "NB1")
+	if err != nil || rec.TenantID != 7 {
+		t.Fatalf("NB1: %+v %v", rec, err)
+	}
+	if !rec.Matches("tok-1") {
+		t.Error("tok-1 should match its hash")
+	}
+	for _, bad := range []string{"", "tok-2", tok1} {
+		if rec.Matches(bad) {
+			t.Errorf("%q matched", bad)
+		}
+	}
+	for _, serial := range []string{"", "NB2", "ORPHAN", "CORRUPT"} {
+		if _, err := s.Lookup(ctx, serial); !errors.Is(err, ErrIngestSecretUnknown) {
+			t.Errorf("%q: want ErrIngestSecretUnknown, got %v", serial, err)
+		}
+	}
+	if _, err := s.Lookup(ctx, "DOWN"); !errors.Is(err, ErrIngestSecretUnavailable) {
+		t.Errorf("DOWN: want ErrIngestSecretUnavailable, got %v", err)
+	}
+}
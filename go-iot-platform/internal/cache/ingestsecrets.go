@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Secretele HMAC ale device-urilor care trimit prin POST /go/ingest fără cheie
+// API: Django (clients/signals.py) scrie "ingestsecret:{serial}" cât timp
+// Device.ingest_secret e setat și face DEL la rotire pe gol / ștergere.
+//
+// Semnăturile deja acceptate sunt ținute ca "ingestsig:{serial}:{signature}"
+// (SET NX EX, TTL = fereastra în care timestamp-ul semnat e valid), ca o cerere
+// capturată să nu poată fi reluată în fereastră.
+const (
+	ingestSecretPrefix = "ingestsecret:"
+	ingestSigPrefix    = "ingestsig:"
+)
+
+var (
+	// ErrIngestSecretUnknown — device fără secret de ingest.
+	ErrIngestSecretUnknown = errors.New("no ingest secret for device")
+	// ErrIngestSecretUnavailable — Redis indisponibil.
+	ErrIngestSecretUnavailable = errors.New("ingest secret store unavailable")
+)
+
+// IngestSecret — înregistrarea sincronizată din Django.
+type IngestSecret struct {
+	TenantID int64  `json:"tenant_id"`
+	Secret   string `json:"secret"`
+}
+
+// IngestSecretStore citește secretele din Redis.
+type IngestSecretStore struct {
+	get   func(ctx context.Context, key string) ([]byte, error)
+	setNX func(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// NewIngestSecretStore — rdb e același client ca pentru cache-ul de device-uri.
+func NewIngestSecretStore(rdb *redis.Client) *IngestSecretStore {
+	return &IngestSecretStore{
+		get: func(ctx context.Context, key string) ([]byte, error) {
+			return rdb.Get(ctx, key).Bytes()
+		},
+		setNX: func(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+			return rdb.SetNX(ctx, key, 1, ttl).Result()
+		},
+	}
+}
+
+// ClaimSignature marchează semnătura ca folosită pentru ttl; fresh=false →
+// a mai fost acceptată (replay). Redis căzut → ErrIngestSecretUnavailable.
+func (s *IngestSecretStore) ClaimSignature(ctx context.Context, serial, signature string, ttl time.Duration) (bool, error) {
+	fresh, err := s.setNX(ctx, ingestSigPrefix+serial+":"+signature, ttl)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrIngestSecretUnavailable, err)
+	}
+	return fresh, nil
+}
+
+// Lookup întoarce secretul device-ului.
+func (s *IngestSecretStore) Lookup(ctx context.Context, serial string) (IngestSecret, error) {
+	if serial == "" {
+		return IngestSecret{}, ErrIngestSecretUnknown
+	}
+	raw, err := s.get(ctx, ingestSecretPrefix+serial)
+	if errors.Is(err, redis.Nil) {
+		return IngestSecret{}, ErrIngestSecretUnknown
+	}
+	if err != nil {
+		return IngestSecret{}, fmt.Errorf("%w: %v", ErrIngestSecretUnavailable, err)
+	}
+	var rec IngestSecret
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return IngestSecret{}, fmt.Errorf("%w: corrupt record: %v", ErrIngestSecretUnknown, err)
+	}
+	if rec.TenantID <= 0 || rec.Secret == "" {
+		return IngestSecret{}, fmt.Errorf("%w: incomplete record", ErrIngestSecretUnknown)
+	}
+	return rec, nil
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestIngestSecretLookup(t *testing.T) {
+	records := map[string]string{
+		ingestSecretPrefix + "DEV1":    `{"tenant_id":7,"secret":"s3cr3t"}`,
+		ingestSecretPrefix + "ORPHAN":  `{"secret":"x"}`,
+		ingestSecretPrefix + "CORRUPT": `{`,
+	}
+	s := &IngestSecretStore{get: func(_ context.Context, key string) ([]byte, error) {
+		if key == ingestSecretPrefix+"DOWN" {
+			return nil, errors.New("dial tcp: connection refused")
+		}
+		if v, ok := records[key]; ok {
+			return []byte(v), nil
+		}
+		return nil, redis.Nil
+	}}
+	ctx := context.Background()
+
+	rec, err := s.Lookup(ctx, "DEV1")
+	if err != nil || rec.TenantID != 7 || rec.Secret != "s3cr3t" {
+		t.Fatalf("DEV1: %+v %v", rec, err)
+	}
+	for _, serial := range []string{"", "MISSING", "ORPHAN", "CORRUPT"} {
+		if _, err := s.Lookup(ctx, serial); !errors.Is(err, ErrIngestSecretUnknown) {
+			t.Errorf("%q: want ErrIngestSecretUnknown, got %v", serial, err)
+		}
+	}
+	if _, err := s.Lookup(ctx, "DOWN"); !errors.Is(err, ErrIngestSecretUnavailable) {
+		t.Errorf("DOWN: want ErrIngestSecretUnavailable, got %v", err)
+	}
+}
+
+func TestIngestClaimSignature(t *testing.T) {
+	seen := map[string]time.Duration{}
+	down := false
+	s := &IngestSecretStore{setNX: func(_ context.Context, key string, ttl time.Duration) (bool, error) {
+		if down {
+			return false, errors.New("dial tcp: connection refused")
+		}
+		if _, ok := seen[key]; ok {
+			return false, nil
+		}
+		seen[key] = ttl
+		return true, nil
+	}}
+	ctx := context.Background()
+
+	if fresh, err := s.ClaimSignature(ctx, "DEV1", "ab12", 10*time.Minute); !fresh || err != nil {
+		t.Fatalf("first claim: %v %v", fresh, err)
+	}
+	if seen[ingestSigPrefix+"DEV1:ab12"] != 10*time.Minute {
+		t.Errorf("keys = %v", seen)
+	}
+	if fresh, err := s.ClaimSignature(ctx, "DEV1", "ab12", 10*time.Minute); fresh || err != nil {
+		t.Errorf("replay: %v %v", fresh, err)
+	}
+	if fresh, _ := s.ClaimSignature(ctx, "DEV2", "ab12", 10*time.Minute); !fresh {
+		t.Error("same signature on another device must not collide")
+	}
+	down = true
+	if _, err := s.ClaimSignature(ctx, "DEV1", "cd34", time.Minute); !errors.Is(err, ErrIngestSecretUnavailable) {
+		t.Errorf("DOWN: want ErrIngestSecretUnavailable, got %v", err)
+	}
+}
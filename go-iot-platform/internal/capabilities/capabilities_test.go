@@ -0,0 +1,100 @@
+package capabilities
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-iot-platform/internal/registry"
+)
+
+func TestProductionDDsSatisfyVocabulary(t *testing.T) {
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("load DDs: %v %v", err, errs)
+	}
+	for _, e := range ValidateRegistry(reg) {
+		t.Error(e)
+	}
+}
+
+func TestExpand(t *testing.T) {
+	got := Expand([]string{"smart_plug", "power_meter", "smart_meter"})
+	want := []string{"smart_plug", "relay", "power_meter", "smart_meter"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expand = %v, want %v", got, want)
+	}
+	if got := Expand([]string{"teleporter"}); !reflect.DeepEqual(got, []string{"teleporter"}) {
+		t.Errorf("unknown kept: %v", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dd := &registry.DeviceDefinition{
+		ID:           "bad_plug",
+		Capabilities: []string{"smart_plug", "teleporter"},
+		NormalizedFields: map[string]registry.NormSpec{
+			"active_power_w":  {Source: "power", Unit: "kW"},
+			"relay_state_str": {Source: "POWER"},
+		},
+		Commands: map[string]registry.CommandSpec{"relay_on": {Topic: "cmnd/{device_id}/POWER"}},
+	}
+	var msgs []string
+	for _, e := range Validate(dd) {
+		msgs = append(msgs, e.Error())
+	}
+	all := strings.Join(msgs, "\n")
+	for _, want := range []string{
+		`capability "teleporter" unknown`,
+		"capability relay: commands.relay_off required",
+		`capability power_meter: normalized_fields.active_power_w unit "kW", expected "W"`,
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing %q in:\n%s", want, all)
+		}
+	}
+	if len(msgs) != 3 {
+		t.Errorf("got %d errors:\n%s", len(msgs), all)
+	}
+
+	delete(dd.NormalizedFields, "active_power_w")
+	if errs := Validate(dd); !strings.Contains(fmt.Sprint(errs), "capability power_meter: normalized_fields.active_power_w required") {
+		t.Errorf("required field not reported: %v", errs)
+	}
+}
+
+func TestForDevice(t *testing.T) {
+	dd := &registry.DeviceDefinition{
+		ID:           "plug",
+		Capabilities: []string{"smart_plug"},
+		NormalizedFields: map[string]registry.NormSpec{
+			"active_power_w":  {Source: "ENERGY.Power", Unit: "W"},
+			"voltage_v":       {Source: "ENERGY.Voltage", Unit: "V"},
+			"relay_state_str": {Source: "POWER"},
+		},
+		Commands: map[string]registry.CommandSpec{
+			"relay_on": {Topic: "t"}, "relay_off": {Topic: "t"}, "request_state": {Topic: "t"},
+		},
+	}
+	now := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+	caps := ForDevice(dd, map[string]Value{"active_power_w": {Value: 1500.0, Unit: "W", Time: now}})
+	if len(caps) != 3 || caps[0].Name != "smart_plug" || caps[1].Name != "relay" || caps[2].Name != "power_meter" {
+		t.Fatalf("caps = %+v", caps)
+	}
+	if !reflect.DeepEqual(caps[1].Commands, []string{"relay_on", "relay_off"}) {
+		t.Errorf("relay commands = %v", caps[1].Commands)
+	}
+	pm := caps[2].Fields
+	if len(pm) != 2 || pm["active_power_w"] == nil || pm["active_power_w"].Value != 1500.0 {
+		t.Errorf("power_meter fields = %+v", pm)
+	}
+	if v, ok := pm["voltage_v"]; !ok || v != nil {
+		t.Errorf("voltage_v should be present with no value, got %+v", v)
+	}
+	if !Has(dd, "relay") || Has(dd, "battery") {
+		t.Error("Has mismatch")
+	}
+}
@@ -0,0 +1,67 @@
+package capabilities
+
+import (
+	"time"
+
+	"go-iot-platform/internal/registry"
+)
+
+// Value — ultima valoare a unui field canonic (normalizată cu multiplier / decimals).
+type Value struct {
+	Value interface{} `json:"value"`
+	Unit  string      `json:"unit"`
+	Time  time.Time   `json:"time"`
+}
+
+// DeviceCapability — o capability a unui device concret, cu valorile curente.
+//
+// Fields conține field-urile capability-ului pe care DD-ul le definește; nil
+// (JSON null) = fără date în fereastra cerută. Commands — comenzile
+// capability-ului disponibile în DD.
+type DeviceCapability struct {
+	Name     string            `json:"name"`
+	Fields   map[string]*Value `json:"fields"`
+	Commands []string          `json:"commands,omitempty"`
+}
+
+// ForDevice construiește vederea pe capabilities a unui device cu DD-ul dd.
+// values: field canonic → ultima valoare. Capabilities necunoscute sunt omise
+// (Validate le raportează la încărcarea registry-ului).
+func ForDevice(dd *registry.DeviceDefinition, values map[string]Value) []DeviceCapability {
+	out := []DeviceCapability{}
+	for _, name := range Expand(dd.Capabilities) {
+		c, ok := Lookup(name)
+		if !ok {
+			continue
+		}
+		dc := DeviceCapability{Name: name, Fields: map[string]*Value{}}
+		for _, f := range c.Fields {
+			if _, ok := dd.NormalizedFields[f.Name]; !ok {
+				continue
+			}
+			if v, ok := values[f.Name]; ok {
+				v := v
+				dc.Fields[f.Name] = &v
+			} else {
+				dc.Fields[f.Name] = nil
+			}
+		}
+		for _, cmd := range c.Commands {
+			if _, ok := dd.Commands[cmd.Name]; ok {
+				dc.Commands = append(dc.Commands, cmd.Name)
+			}
+		}
+		out = append(out, dc)
+	}
+	return out
+}
+
+// Has — true dacă DD-ul declară capability-ul, direct sau prin Implies.
+func Has(dd *registry.DeviceDefinition, name string) bool {
+	for _, c := range Expand(dd.Capabilities) {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
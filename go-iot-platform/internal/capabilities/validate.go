@@ -0,0 +1,62 @@
+package capabilities
+
+import (
+	"fmt"
+	"sort"
+
+	"go-iot-platform/internal/registry"
+)
+
+// Validate verifică un DD contra vocabularului și întoarce toate încălcările
+// (nu fail-fast, ca un DD nou să fie corectat dintr-o singură trecere):
+//
+//   - capability necunoscută
+//   - field obligatoriu lipsă din normalized_fields (inclusiv pentru Implies)
+//   - unit diferit de cel din vocabular, pentru orice field al capability-ului
+//   - comandă obligatorie lipsă din commands
+func Validate(dd *registry.DeviceDefinition) []error {
+	var errs []error
+	for _, name := range Expand(dd.Capabilities) {
+		c, ok := Lookup(name)
+		if !ok {
+			errs = append(errs, fmt.Errorf("capability %q unknown (valid: %v)", name, Names()))
+			continue
+		}
+		for _, f := range c.Fields {
+			nf, ok := dd.NormalizedFields[f.Name]
+			if !ok {
+				if f.Required {
+					errs = append(errs, fmt.Errorf("capability %s: normalized_fields.%s required", name, f.Name))
+				}
+				continue
+			}
+			if nf.Unit != f.Unit {
+				errs = append(errs, fmt.Errorf("capability %s: normalized_fields.%s unit %q, expected %q",
+					name, f.Name, nf.Unit, f.Unit))
+			}
+		}
+		for _, cmd := range c.Commands {
+			if _, ok := dd.Commands[cmd.Name]; !ok && cmd.Required {
+				errs = append(errs, fmt.Errorf("capability %s: commands.%s required", name, cmd.Name))
+			}
+		}
+	}
+	return errs
+}
+
+// ValidateRegistry rulează Validate pe toate DD-urile; erorile sunt prefixate
+// cu id-ul DD-ului și ordonate după id (output stabil pentru log / CI).
+func ValidateRegistry(reg *registry.Registry) []error {
+	if reg == nil {
+		return nil
+	}
+	all := reg.All()
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	var errs []error
+	for _, dd := range all {
+		for _, err := range Validate(dd) {
+			errs = append(errs, fmt.Errorf("dd=%s: %w", dd.ID, err))
+		}
+	}
+	return errs
+}
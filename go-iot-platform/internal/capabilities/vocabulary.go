@@ -0,0 +1,176 @@
+// Package capabilities — Faza 5 Capability Engine: vocabularul canonical de
+// capabilities (power_meter, relay, temperature_sensor …) peste Device Definitions.
+//
+// O capability definește field-urile canonice (numele din normalized_fields,
+// cu unit) și comenzile pe care un DD trebuie să le aibă ca s-o declare.
+// Frontend-ul și regulile țintesc "orice power_meter" → active_power_w, indiferent
+// de vendor (Shelly "power", Tasmota "ENERGY.Power", Huawei "active_power").
+//
+// Vocabularul e în cod (nu YAML) ca o capability nouă să treacă prin review —
+// vezi R-5.1 în docs/upgrade_md_iot_platform_refactor_ai_ready.md.
+package capabilities
+
+import "sort"
+
+// Field — un field canonic al unei capability. Unit trebuie să coincidă cu
+// normalized_fields[name].unit din DD ("" = adimensional).
+type Field struct {
+	Name     string `json:"name"`
+	Unit     string `json:"unit"`
+	Required bool   `json:"required"`
+}
+
+// Command — o comandă downlink (cheie din DD.commands).
+type Command struct {
+	Name     string `json:"name"`
+	Required bool   `json:"required"`
+}
+
+// Capability — definiția unei capability din vocabular.
+//
+// Implies — capabilities incluse automat (moștenire): un DD care declară
+// smart_plug trebuie să satisfacă și relay + power_meter.
+type Capability struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Implies     []string  `json:"implies,omitempty"`
+	Fields      []Field   `json:"fields"`
+	Commands    []Command `json:"commands,omitempty"`
+}
+
+// Vocabulary — capabilities acceptate în DD.capabilities.
+var Vocabulary = map[string]Capability{
+	"power_meter": {
+		Description: "Putere activă instantanee (W) + mărimi electrice opționale",
+		Fields: []Field{
+			{"active_power_w", "W", true},
+			{"voltage_v", "V", false},
+			{"current_a", "A", false},
+			{"power_factor", "", false},
+			{"apparent_power_va", "VA", false},
+			{"reactive_power_var", "var", false},
+		},
+	},
+	"smart_meter": {
+		Description: "Contor de energie cu index consumat / injectat",
+		Implies:     []string{"power_meter"},
+		Fields: []Field{
+			{"total_consumed_kwh", "kWh", true},
+			{"total_returned_kwh", "kWh", false},
+		},
+	},
+	"relay": {
+		Description: "Releu comandabil ON / OFF",
+		Fields: []Field{
+			{"relay_state_str", "", true},
+		},
+		Commands: []Command{
+			{"relay_on", true},
+			{"relay_off", true},
+			{"relay_toggle", false},
+		},
+	},
+	"smart_plug": {
+		Description: "Priză inteligentă: releu + măsurare energie",
+		Implies:     []string{"relay", "power_meter"},
+		Fields: []Field{
+			{"total_energy_kwh", "kWh", false},
+			{"today_kwh", "kWh", false},
+			{"yesterday_kwh", "kWh", false},
+		},
+	},
+	"solar_pv": {
+		Description: "Producție fotovoltaică",
+		Fields: []Field{
+			{"solar_power_kw", "kW", true},
+			{"daily_yield_kwh", "kWh", false},
+		},
+	},
+	"inverter": {
+		Description: "Invertor grid-tied: schimb cu rețeaua + consum casă",
+		Fields: []Field{
+			{"grid_power_w", "W", true},
+			{"house_load_kw", "kW", false},
+			{"inverter_temp_c", "°C", false},
+		},
+	},
+	"battery": {
+		Description: "Stocare în baterie (stare de încărcare, putere)",
+		Fields: []Field{
+			{"battery_soc_pct", "%", true},
+			{"battery_power_kw", "kW", false},
+			{"battery_temp_c", "°C", false},
+		},
+	},
+	"battery_powered": {
+		Description: "Device alimentat din baterie (nivel baterie)",
+		Fields: []Field{
+			{"battery_pct", "%", true},
+			{"battery_voltage_mv", "mV", false},
+		},
+	},
+	"temperature_sensor": {
+		Description: "Senzor de temperatură",
+		Fields: []Field{
+			{"temperature_c", "°C", true},
+		},
+	},
+	"humidity_sensor": {
+		Description: "Senzor de umiditate relativă",
+		Fields: []Field{
+			{"humidity_pct", "%", true},
+		},
+	},
+	"contact_sensor": {
+		Description: "Senzor de contact ușă / fereastră (true = închis)",
+		Fields: []Field{
+			{"contact_closed", "", true},
+		},
+	},
+}
+
+func init() {
+	for name, c := range Vocabulary {
+		c.Name = name
+		Vocabulary[name] = c
+	}
+}
+
+// Lookup întoarce definiția capability-ului, ok=false dacă nu e în vocabular.
+func Lookup(name string) (Capability, bool) {
+	c, ok := Vocabulary[name]
+	return c, ok
+}
+
+// Names — capabilities din vocabular, sortate.
+func Names() []string {
+	out := make([]string, 0, len(Vocabulary))
+	for name := range Vocabulary {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Expand întoarce capabilities declarate + cele implicate (Implies), fără
+// duplicate, în ordinea declarării (implicatele după capability-ul care le aduce).
+// Numele necunoscute rămân în listă — Validate le raportează.
+func Expand(names []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	var visit func(string)
+	visit = func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		out = append(out, name)
+		for _, implied := range Vocabulary[name].Implies {
+			visit(implied)
+		}
+	}
+	for _, name := range names {
+		visit(name)
+	}
+	return out
+}
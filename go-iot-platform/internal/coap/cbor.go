@@ -0,0 +1,253 @@
+package coap
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// CBORToJSON convertește un payload CBOR (RFC 8949) în JSON, ca parserele din
+// DD (`json`, `json_with_measurements_array`) să-l citească la fel ca pe unul
+// trimis direct în JSON. Acoperă ce produc firmware-urile de senzori: întregi,
+// float16/32/64, text, byte strings (→ base64), array-uri, map-uri cu chei
+// text sau întregi (→ "7"), bool / null, lungimi nedefinite. Tag-urile sunt
+// ignorate (se păstrează conținutul), cu excepția bignum-urilor (2 / 3).
+func CBORToJSON(b []byte) ([]byte, error) {
+	d := cborDecoder{buf: b}
+	v, err := d.value(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(b) {
+		return nil, fmt.Errorf("cbor: %d trailing bytes", len(b)-d.off)
+	}
+	return json.Marshal(v)
+}
+
+// maxCBORDepth — imbricarea maximă acceptată (array / map / tag).
+const maxCBORDepth = 16
+
+var (
+	errCBORShort = errors.New("cbor: unexpected end of data")
+	errCBORBreak = errors.New("cbor: unexpected break")
+)
+
+type cborDecoder struct {
+	buf []byte
+	off int
+}
+
+// head citește octetul inițial și argumentul. indefinite = informația
+// adițională 31 (lungime nedefinită / break).
+func (d *cborDecoder) head() (major byte, arg uint64, indefinite bool, err error) {
+	if d.off >= len(d.buf) {
+		return 0, 0, false, errCBORShort
+	}
+	ib := d.buf[d.off]
+	d.off++
+	major, ai := ib>>5, ib&0x1f
+	switch {
+	case ai < 24:
+		return major, uint64(ai), false, nil
+	case ai <= 27:
+		n := 1 << (ai - 24)
+		if len(d.buf)-d.off < n {
+			return 0, 0, false, errCBORShort
+		}
+		p := d.buf[d.off : d.off+n]
+		d.off += n
+		switch n {
+		case 1:
+			arg = uint64(p[0])
+		case 2:
+			arg = uint64(binary.BigEndian.Uint16(p))
+		case 4:
+			arg = uint64(binary.BigEndian.Uint32(p))
+		default:
+			arg = binary.BigEndian.Uint64(p)
+		}
+		return major, arg, false, nil
+	case ai == 31:
+		return major, 0, true, nil
+	}
+	return 0, 0, false, fmt.Errorf("cbor: reserved additional info %d", ai)
+}
+
+func (d *cborDecoder) value(depth int) (interface{}, error) {
+	if depth > maxCBORDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+	start := d.off
+	major, arg, indefinite, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if indefinite && (major < 2 || major == 6) {
+		return nil, fmt.Errorf("cbor: indefinite length on major type %d", major)
+	}
+
+	switch major {
+	case 0:
+		return arg, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer out of range")
+		}
+		return -1 - int64(arg), nil
+	case 2, 3:
+		s, err := d.str(major, arg, indefinite)
+		if err != nil {
+			return nil, err
+		}
+		if major == 2 {
+			return s, nil // []byte → base64 în JSON
+		}
+		if !utf8.Valid(s) {
+			return nil, errors.New("cbor: invalid UTF-8 in text string")
+		}
+		return string(s), nil
+	case 4:
+		if !indefinite && arg > uint64(len(d.buf)-d.off) {
+			return nil, errCBORShort
+		}
+		out := []interface{}{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && d.isBreak() {
+				break
+			}
+			v, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	case 5:
+		if !indefinite && arg > uint64(len(d.buf)-d.off) {
+			return nil, errCBORShort
+		}
+		out := map[string]interface{}{}
+		for i := uint64(0); indefinite || i < arg; i++ {
+			if indefinite && d.isBreak() {
+				break
+			}
+			k, err := d.value(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			var key string
+			switch k := k.(type) {
+			case string:
+				key = k
+			case uint64:
+				key = strconv.FormatUint(k, 10)
+			case int64:
+				key = strconv.FormatInt(k, 10)
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", k)
+			}
+			if _, dup := out[key]; dup {
+				return nil, fmt.Errorf("cbor: duplicate map key %q", key)
+			}
+			if out[key], err = d.value(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	case 6:
+		if arg == 2 || arg == 3 {
+			return nil, errors.New("cbor: bignums not supported")
+		}
+		return d.value(depth + 1)
+	}
+
+	// major 7: simple values și float-uri
+	var f float64
+	switch ai := d.buf[start] & 0x1f; {
+	case indefinite:
+		return nil, errCBORBreak
+	case ai <= 24:
+		switch arg {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cbor: unsupported simple value %d", arg)
+	case ai == 25:
+		f = float16(uint16(arg))
+	case ai == 26:
+		f = float64(math.Float32frombits(uint32(arg)))
+	default:
+		f = math.Float64frombits(arg)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, errors.New("cbor: NaN / Infinity has no JSON form")
+	}
+	return f, nil
+}
+
+// str citește un byte / text string, eventual din fragmente (lungime nedefinită).
+func (d *cborDecoder) str(major byte, arg uint64, indefinite bool) ([]byte, error) {
+	if !indefinite {
+		if arg > uint64(len(d.buf)-d.off) {
+			return nil, errCBORShort
+		}
+		s := d.buf[d.off : d.off+int(arg)]
+		d.off += int(arg)
+		return s, nil
+	}
+	var out []byte
+	for !d.isBreak() {
+		m, n, ind, err := d.head()
+		if err != nil {
+			return nil, err
+		}
+		if m != major || ind {
+			return nil, errors.New("cbor: invalid chunk in indefinite-length string")
+		}
+		chunk, err := d.str(major, n, false)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+	return out, nil
+}
+
+// isBreak consumă marcajul 0xff dacă urmează.
+func (d *cborDecoder) isBreak() bool {
+	if d.off < len(d.buf) && d.buf[d.off] == 0xff {
+		d.off++
+		return true
+	}
+	return false
+}
+
+// float16 — IEEE 754 half precision.
+func float16(h uint16) float64 {
+	exp, mant := int(h>>10&0x1f), float64(h&0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
@@ -0,0 +1,70 @@
+package coap
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestCBORToJSON(t *testing.T) {
+	cases := []struct {
+		name, hex, want string
+	}{
+		{"uint", "1903e8", `1000`},
+		{"negint", "3863", `-100`},
+		{"float16", "f93e00", `1.5`},
+		{"float32", "fa47c35000", `100000`},
+		{"float64", "fb3ff199999999999a", `1.1`},
+		{"simple", "83f5f4f6", `[true,false,null]`},
+		{"text", "6449455446", `"IETF"`},
+		{"bytes", "4401020304", `"AQIDBA=="`},
+		{"tag ignored", "c11a514b67b0", `1363896240`},
+		// {"temp": 21.4, "rh": 48, "bat": 87}
+		{"sensor map", "a36474656d70fb40356666666666666272681830636261741857", `{"bat":87,"rh":48,"temp":21.4}`},
+		{"int keys", "a2011864026161", `{"1":100,"2":"a"}`},
+		{"indefinite", "9f018202039f0405ffff", `[1,[2,3],[4,5]]`},
+		{"indefinite text", "7f657374726561646d696e67ff", `"streaming"`},
+		{"indefinite map", "bf6346756ef563416d7421ff", `{"Amt":-2,"Fun":true}`},
+	}
+	for _, c := range cases {
+		b, err := hex.DecodeString(c.hex)
+		if err != nil {
+			t.Fatalf("%s: bad hex: %v", c.name, err)
+		}
+		got, err := CBORToJSON(b)
+		if err != nil || string(got) != c.want {
+			t.Errorf("%s: got %s, %v; want %s", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestCBORToJSONRejects(t *testing.T) {
+	cases := map[string]string{
+		"truncated":      "1903",
+		"trailing":       "0101",
+		"short string":   "6549455446",
+		"huge array":     "9bffffffffffffffff",
+		"bignum":         "c249010000000000000000",
+		"NaN":            "f97e00",
+		"float key":      "a1f93e0001",
+		"duplicate key":  "a2616101616102",
+		"bad utf8":       "62c328",
+		"stray break":    "ff",
+		"reserved ai":    "1c",
+		"indefinite int": "1f",
+		"mixed chunks":   "7f4161ff",
+	}
+	for name, h := range cases {
+		b, _ := hex.DecodeString(h)
+		if got, err := CBORToJSON(b); err == nil {
+			t.Errorf("%s: expected error, got %s", name, got)
+		}
+	}
+
+	deep := make([]byte, maxCBORDepth+2)
+	for i := range deep {
+		deep[i] = 0x81 // array(1)
+	}
+	if _, err := CBORToJSON(append(deep, 0x00)); err == nil {
+		t.Error("nesting: expected error")
+	}
+}
@@ -0,0 +1,248 @@
+// Package coap — listener CoAP (RFC 7252, UDP) pentru device-urile pe baterie
+// (NB-IoT) care nu își permit keepalive-urile MQTT: POST confirmabil sau
+// neconfirmabil pe /t/{serial}/{stream}, payload JSON / CBOR / text, token
+// pre-partajat; citirile intră în ingest.Pipeline, ca la HTTP.
+//
+// Implementare minimă, fără dependențe: un singur mesaj per datagramă, fără
+// block-wise transfer (RFC 7959), observe sau DTLS.
+package coap
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Type — tipul mesajului (RFC 7252 §3).
+type Type uint8
+
+const (
+	Confirmable     Type = 0
+	NonConfirmable  Type = 1
+	Acknowledgement Type = 2
+	Reset           Type = 3
+)
+
+// Code — clasa.detaliu pe un octet (c.dd → c<<5 | dd).
+type Code uint8
+
+const (
+	Empty Code = 0x00
+	GET   Code = 0x01
+	POST  Code = 0x02
+
+	Changed                  Code = 0x44 // 2.04
+	BadRequest               Code = 0x80 // 4.00
+	Unauthorized             Code = 0x81 // 4.01
+	BadOption                Code = 0x82 // 4.02
+	Forbidden                Code = 0x83 // 4.03
+	NotFound                 Code = 0x84 // 4.04
+	MethodNotAllowed         Code = 0x85 // 4.05
+	RequestEntityTooLarge    Code = 0x8D // 4.13
+	UnsupportedContentFormat Code = 0x8F // 4.15
+	TooManyRequests          Code = 0x9D // 4.29, RFC 8516
+	InternalServerError      Code = 0xA0 // 5.00
+	ServiceUnavailable       Code = 0xA3 // 5.03
+)
+
+func (c Code) String() string { return fmt.Sprintf("%d.%02d", c>>5, c&0x1f) }
+
+// IsRequest — clasa 0, fără mesajul gol.
+func (c Code) IsRequest() bool { return c != Empty && c>>5 == 0 }
+
+// Numere de opțiuni folosite de listener.
+const (
+	OptURIHost       uint16 = 3
+	OptURIPort       uint16 = 7
+	OptURIPath       uint16 = 11
+	OptContentFormat uint16 = 12
+	OptMaxAge        uint16 = 14
+	OptURIQuery      uint16 = 15
+	OptAccept        uint16 = 17
+	OptSize1         uint16 = 60
+)
+
+// Content-Format (registrul IANA).
+const (
+	FormatTextPlain = 0
+	FormatJSON      = 50
+	FormatCBOR      = 60
+)
+
+// Option — o opțiune; valorile repetate (Uri-Path) apar ca opțiuni separate.
+type Option struct {
+	Number uint16
+	Value  []byte
+}
+
+// Message — un mesaj CoAP decodat.
+type Message struct {
+	Type      Type
+	Code      Code
+	MessageID uint16
+	Token     []byte
+	Options   []Option
+	Payload   []byte
+}
+
+var (
+	errShort   = errors.New("coap: message too short")
+	errVersion = errors.New("coap: unsupported version")
+)
+
+// Parse decodează o datagramă. Erorile după header (token, opțiuni) lasă
+// Type / Code / MessageID completate, ca apelantul să poată răspunde cu RST.
+func Parse(b []byte) (Message, error) {
+	var m Message
+	if len(b) < 4 {
+		return m, errShort
+	}
+	if b[0]>>6 != 1 {
+		return m, errVersion
+	}
+	m.Type = Type(b[0] >> 4 & 0x3)
+	m.Code = Code(b[1])
+	m.MessageID = binary.BigEndian.Uint16(b[2:4])
+	tkl := int(b[0] & 0xf)
+	if tkl > 8 {
+		return m, errors.New("coap: token length > 8")
+	}
+	if m.Code == Empty && (tkl != 0 || len(b) != 4) {
+		return m, errors.New("coap: empty message with content")
+	}
+	b = b[4:]
+	if len(b) < tkl {
+		return m, errShort
+	}
+	m.Token = append([]byte(nil), b[:tkl]...)
+	b = b[tkl:]
+
+	var number uint16
+	for len(b) > 0 {
+		if b[0] == 0xff {
+			if len(b) == 1 {
+				return m, errors.New("coap: payload marker without payload")
+			}
+			m.Payload = append([]byte(nil), b[1:]...)
+			break
+		}
+		delta, length := int(b[0]>>4), int(b[0]&0xf)
+		b = b[1:]
+		var err error
+		if delta, b, err = optionNibble(delta, b); err != nil {
+			return m, err
+		}
+		if length, b, err = optionNibble(length, b); err != nil {
+			return m, err
+		}
+		if int(number)+delta > 0xffff {
+			return m, errors.New("coap: option number overflow")
+		}
+		number += uint16(delta)
+		if len(b) < length {
+			return m, errShort
+		}
+		m.Options = append(m.Options, Option{Number: number, Value: append([]byte(nil), b[:length]...)})
+		b = b[length:]
+	}
+	return m, nil
+}
+
+// optionNibble extinde un delta / length de 4 biți (13 → +1 octet, 14 → +2).
+func optionNibble(v int, b []byte) (int, []byte, error) {
+	switch v {
+	case 13:
+		if len(b) < 1 {
+			return 0, nil, errShort
+		}
+		return int(b[0]) + 13, b[1:], nil
+	case 14:
+		if len(b) < 2 {
+			return 0, nil, errShort
+		}
+		return int(binary.BigEndian.Uint16(b)) + 269, b[2:], nil
+	case 15:
+		return 0, nil, errors.New("coap: reserved option nibble 15")
+	}
+	return v, b, nil
+}
+
+// Marshal encodează mesajul; opțiunile sunt sortate stabil după număr.
+func (m Message) Marshal() ([]byte, error) {
+	if len(m.Token) > 8 {
+		return nil, errors.New("coap: token length > 8")
+	}
+	out := []byte{1<<6 | byte(m.Type&0x3)<<4 | byte(len(m.Token)), byte(m.Code), 0, 0}
+	binary.BigEndian.PutUint16(out[2:], m.MessageID)
+	out = append(out, m.Token...)
+
+	opts := append([]Option(nil), m.Options...)
+	sort.SliceStable(opts, func(i, j int) bool { return opts[i].Number < opts[j].Number })
+	var prev uint16
+	for _, o := range opts {
+		if len(o.Value) > 0xffff+269 {
+			return nil, fmt.Errorf("coap: option %d too long", o.Number)
+		}
+		dn, dext := nibble(int(o.Number - prev))
+		ln, lext := nibble(len(o.Value))
+		out = append(out, byte(dn<<4|ln))
+		out = append(out, dext...)
+		out = append(out, lext...)
+		out = append(out, o.Value...)
+		prev = o.Number
+	}
+	if len(m.Payload) > 0 {
+		out = append(out, 0xff)
+		out = append(out, m.Payload...)
+	}
+	return out, nil
+}
+
+func nibble(v int) (int, []byte) {
+	switch {
+	case v < 13:
+		return v, nil
+	case v < 269:
+		return 13, []byte{byte(v - 13)}
+	default:
+		return 14, []byte{byte((v - 269) >> 8), byte(v - 269)}
+	}
+}
+
+// Strings întoarce valorile opțiunii n (Uri-Path, Uri-Query) în ordine.
+func (m Message) Strings(n uint16) []string {
+	var out []string
+	for _, o := range m.Options {
+		if o.Number == n {
+			out = append(out, string(o.Value))
+		}
+	}
+	return out
+}
+
+// Uint întoarce valoarea uint a primei opțiuni n (Content-Format, Max-Age).
+func (m Message) Uint(n uint16) (uint32, bool) {
+	for _, o := range m.Options {
+		if o.Number == n {
+			if len(o.Value) > 4 {
+				return 0, false
+			}
+			var v uint32
+			for _, c := range o.Value {
+				v = v<<8 | uint32(c)
+			}
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// UintOption encodează v pe numărul minim de octeți (0 → valoare goală).
+func UintOption(n uint16, v uint32) Option {
+	var b []byte
+	for ; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	return Option{Number: n, Value: b}
+}
@@ -0,0 +1,311 @@
+package coap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-iot-platform/internal/cache"
+	"go-iot-platform/internal/ingest"
+)
+
+// Cererea acceptată:
+//
+//	POST coap://<host>/t/<serial>/<stream>?t=<token>
+//	Content-Format: 50 (JSON) | 60 (CBOR) | 0 / absent (text, ex. keyvalue)
+//
+// Token-ul pre-partajat e propriu CoAP-ului (Django, POST /api/devices/{id}/
+// coap-token/rotate/ → Redis "coaptoken:{serial}", doar hash-ul SHA-256), separat
+// de secretul HMAC de pe /go/ingest: fără DTLS token-ul circulă în clar, iar o
+// captură nu trebuie să permită semnarea cererilor HTTP. Tenantul vine din
+// înregistrare. Listener-ul se expune doar pe APN-ul privat / VPN-ul operatorului
+// NB-IoT; un token expus se rotește fără să atingă secretul de ingest.
+//
+// Răspunsuri: 2.04 la scriere; 4.00 payload invalid / respins de parser;
+// 4.01 token lipsă sau greșit; 4.03 device necunoscut / alt tenant; 4.29 rate
+// limit (Max-Age = secunde până la retry); 5.03 Redis indisponibil. CON →
+// ACK cu răspunsul piggybacked; NON → răspuns NON.
+
+// MaxPayload — payload-ul maxim acceptat (fără block-wise; un mesaj NB-IoT
+// încape oricum într-o datagramă).
+const MaxPayload = 1024
+
+const (
+	// exchangeLifetime — cât ține minte serverul un Message ID (RFC 7252
+	// §4.8.2, EXCHANGE_LIFETIME); retransmisiile CON primesc răspunsul salvat,
+	// fără să fie scrise din nou.
+	exchangeLifetime = 247 * time.Second
+	maxExchanges     = 1 << 16
+	maxInFlight      = 64
+	pipelineTimeout  = 10 * time.Second
+)
+
+// TokenLookup — implementat de cache.CoAPTokenStore.
+type TokenLookup interface {
+	Lookup(ctx context.Context, serial string) (cache.CoAPToken, error)
+}
+
+// Server — listener-ul CoAP; un Server poate servi un singur PacketConn.
+type Server struct {
+	pipeline ingest.Pipeline
+	tokens   TokenLookup
+	now      func() time.Time
+
+	mid uint32 // Message ID pentru răspunsurile NON
+
+	mu        sync.Mutex
+	exchanges map[exchangeKey]*exchange
+	lastSweep time.Time
+}
+
+type exchangeKey struct {
+	peer string
+	mid  uint16
+}
+
+// exchange — resp nil cât timp cererea e încă procesată.
+type exchange struct {
+	resp    []byte
+	expires time.Time
+}
+
+// NewServer — pipeline e processMessage din cmd/main.go.
+func NewServer(pipeline ingest.Pipeline, tokens TokenLookup) *Server {
+	return &Server{
+		pipeline:  pipeline,
+		tokens:    tokens,
+		now:       time.Now,
+		mid:       uint32(rand.Intn(1 << 16)),
+		exchanges: map[exchangeKey]*exchange{},
+	}
+}
+
+// ListenAndServe ascultă pe addr (UDP, ex. ":5683") până la anularea ctx.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, pc)
+}
+
+// Serve procesează datagramele de pe pc până la anularea ctx (care închide pc).
+func (s *Server) Serve(ctx context.Context, pc net.PacketConn) error {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stop:
+		}
+		pc.Close()
+	}()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	sem := make(chan struct{}, maxInFlight)
+	buf := make([]byte, 64<<10)
+	for {
+		n, peer, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+		pkt := append([]byte(nil), buf[:n]...)
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			if resp := s.handle(ctx, pkt, peer.String()); resp != nil {
+				if _, err := pc.WriteTo(resp, peer); err != nil && ctx.Err() == nil {
+					log.Printf("⚠️ coap: răspuns către %s: %v", peer, err)
+				}
+			}
+		}()
+	}
+}
+
+// handle întoarce datagrama de răspuns (nil = nimic de trimis).
+func (s *Server) handle(ctx context.Context, pkt []byte, peer string) []byte {
+	req, err := Parse(pkt)
+	if err != nil {
+		// CON malformat → RST (§4.2); restul se ignoră.
+		if len(pkt) >= 4 && pkt[0]>>6 == 1 && req.Type == Confirmable {
+			return s.reset(req.MessageID)
+		}
+		return nil
+	}
+	switch {
+	case req.Type == Acknowledgement || req.Type == Reset:
+		return nil
+	case !req.Code.IsRequest():
+		// Mesaj gol CON = "CoAP ping" → RST; răspunsuri nesolicitate la fel.
+		if req.Type == Confirmable {
+			return s.reset(req.MessageID)
+		}
+		return nil
+	}
+
+	key := exchangeKey{peer: peer, mid: req.MessageID}
+	if resp, dup := s.begin(key); dup {
+		return resp // nil dacă originalul e încă în lucru — clientul retransmite
+	}
+	code, opts, payload := s.serve(ctx, req, peer)
+	resp := Message{Code: code, Token: req.Token, Options: opts, Payload: payload}
+	if req.Type == Confirmable {
+		resp.Type, resp.MessageID = Acknowledgement, req.MessageID
+	} else {
+		resp.Type, resp.MessageID = NonConfirmable, uint16(atomic.AddUint32(&s.mid, 1))
+	}
+	b, err := resp.Marshal()
+	if err != nil {
+		log.Printf("❌ coap: encode răspuns: %v", err)
+		return nil
+	}
+	s.finish(key, b)
+	return b
+}
+
+func (s *Server) reset(mid uint16) []byte {
+	b, _ := Message{Type: Reset, Code: Empty, MessageID: mid}.Marshal()
+	return b
+}
+
+// knownCritical — opțiunile critice (număr impar) pe care serverul le înțelege;
+// orice altă opțiune critică → 4.02 (§5.4.1).
+var knownCritical = map[uint16]bool{OptURIHost: true, OptURIPort: true, OptURIPath: true, OptURIQuery: true, OptAccept: true}
+
+func (s *Server) serve(ctx context.Context, req Message, peer string) (Code, []Option, []byte) {
+	if req.Code != POST {
+		return MethodNotAllowed, nil, nil
+	}
+	for _, o := range req.Options {
+		if o.Number&1 == 1 && !knownCritical[o.Number] {
+			return BadOption, nil, diagnostic("unsupported critical option %d", o.Number)
+		}
+	}
+	path := req.Strings(OptURIPath)
+	if len(path) != 3 || path[0] != "t" || path[1] == "" || path[2] == "" {
+		return NotFound, nil, nil
+	}
+	serial, streamName := path[1], path[2]
+	if len(req.Payload) == 0 {
+		return BadRequest, nil, diagnostic("empty payload")
+	}
+	if len(req.Payload) > MaxPayload {
+		return RequestEntityTooLarge, []Option{UintOption(OptSize1, MaxPayload)}, nil
+	}
+
+	tenantID, code, diag := s.authenticate(ctx, req, serial, peer)
+	if code != 0 {
+		return code, nil, diag
+	}
+
+	payload := req.Payload
+	switch cf, ok := req.Uint(OptContentFormat); {
+	case !ok, cf == FormatTextPlain, cf == FormatJSON:
+	case cf == FormatCBOR:
+		var err error
+		if payload, err = CBORToJSON(req.Payload); err != nil {
+			return BadRequest, nil, diagnostic("%v", err)
+		}
+	default:
+		return UnsupportedContentFormat, nil, nil
+	}
+
+	topic, err := ingest.Topic(tenantID, serial, streamName)
+	if err != nil {
+		return BadRequest, nil, diagnostic("%v", err)
+	}
+	pctx, cancel := context.WithTimeout(ctx, pipelineTimeout)
+	defer cancel()
+	errs, err := s.pipeline(pctx, topic, []ingest.Reading{{Payload: payload}})
+	switch {
+	case errors.Is(err, ingest.ErrUnknownDevice), errors.Is(err, ingest.ErrTenantMismatch):
+		log.Printf("⚠️ coap %s: %s respins: %v", peer, topic, err)
+		return Forbidden, nil, diagnostic("%v", err)
+	case errors.Is(err, ingest.ErrRateLimited):
+		return TooManyRequests, []Option{UintOption(OptMaxAge, 1)}, nil
+	case err != nil:
+		log.Printf("❌ coap ingest %s: %v", topic, err)
+		return InternalServerError, nil, nil
+	case len(errs) > 0 && errs[0] != nil:
+		return BadRequest, nil, diagnostic("%v", errs[0])
+	}
+	return Changed, nil, nil
+}
+
+// authenticate verifică token-ul din Uri-Query "t=" și întoarce tenantul
+// device-ului; code != 0 la refuz.
+func (s *Server) authenticate(ctx context.Context, req Message, serial, peer string) (int64, Code, []byte) {
+	var token string
+	for _, q := range req.Strings(OptURIQuery) {
+		if strings.HasPrefix(q, "t=") {
+			token = q[2:]
+		}
+	}
+	if token == "" {
+		return 0, Unauthorized, diagnostic("token required")
+	}
+	if s.tokens == nil {
+		return 0, Unauthorized, diagnostic("token auth not enabled")
+	}
+	rec, err := s.tokens.Lookup(ctx, serial)
+	if errors.Is(err, cache.ErrIngestSecretUnavailable) {
+		log.Printf("❌ coap: token store: %v", err)
+		return 0, ServiceUnavailable, nil
+	}
+	if err != nil || !rec.Matches(token) {
+		log.Printf("⚠️ coap %s: token invalid pentru %s", peer, serial)
+		return 0, Unauthorized, diagnostic("invalid token")
+	}
+	return rec.TenantID, 0, nil
+}
+
+// diagnostic — payload text pentru răspunsurile de eroare (§5.5.2).
+func diagnostic(format string, args ...interface{}) []byte {
+	return []byte(fmt.Sprintf(format, args...))
+}
+
+// begin înregistrează un exchange nou; dup = Message ID deja văzut de la peer.
+func (s *Server) begin(key exchangeKey) (resp []byte, dup bool) {
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.exchanges[key]; ok && now.Before(e.expires) {
+		return e.resp, true
+	}
+	if now.Sub(s.lastSweep) > exchangeLifetime/4 {
+		for k, e := range s.exchanges {
+			if !now.Before(e.expires) {
+				delete(s.exchanges, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	if len(s.exchanges) < maxExchanges {
+		s.exchanges[key] = &exchange{expires: now.Add(exchangeLifetime)}
+	}
+	return nil, false
+}
+
+func (s *Server) finish(key exchangeKey, resp []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.exchanges[key]; ok {
+		e.resp = resp
+	}
+}
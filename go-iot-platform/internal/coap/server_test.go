@@ -0,0 +1,314 @@
+package coap
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-iot-platform/internal/cache"
+	"go-iot-platform/internal/ingest"
+)
+
+// stubTokens — serial → token în clar; Lookup întoarce doar hash-ul, ca Redis.
+type stubTokens map[string]struct {
+	tenant int64
+	token  string
+}
+
+func (s stubTokens) Lookup(_ context.Context, serial string) (cache.CoAPToken, error) {
+	if serial == "down" {
+		return cache.CoAPToken{}, cache.ErrIngestSecretUnavailable
+	}
+	rec, ok := s[serial]
+	if !ok {
+		return cache.CoAPToken{}, cache.ErrIngestSecretUnknown
+	}
+	sum := sha256.Sum256([]byte(rec.token))
+	return cache.CoAPToken{TenantID: rec.tenant, TokenSHA256: hex.EncodeToString(sum[:])}, nil
+}
+
+// recorder — pipeline fals: reține apelurile; err / readingErr simulează refuzurile.
+type recorder struct {
+	mu         sync.Mutex
+	topics     []string
+	payloads   []string
+	err        error
+	readingErr error
+}
+
+func (r *recorder) pipeline(_ context.Context, topic string, readings []ingest.Reading) ([]error, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.topics = append(r.topics, topic)
+	for _, rd := range readings {
+		r.payloads = append(r.payloads, string(rd.Payload))
+	}
+	return []error{r.readingErr}, nil
+}
+
+func (r *recorder) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.topics)
+}
+
+func newTestServer() (*Server, *recorder) {
+	rec := &recorder{}
+	return NewServer(rec.pipeline, stubTokens{"NB1": {7, "s3cr3t"}}), rec
+}
+
+// post construiește POST /t/{serial}/{stream}?query.
+func post(typ Type, mid uint16, serial, stream, query string, format int, payload []byte) Message {
+	m := Message{Type: typ, Code: POST, MessageID: mid, Token: []byte{0xca, 0xfe}, Payload: payload}
+	for _, p := range []string{"t", serial, stream} {
+		m.Options = append(m.Options, Option{Number: OptURIPath, Value: []byte(p)})
+	}
+	if query != "" {
+		m.Options = append(m.Options, Option{Number: OptURIQuery, Value: []byte(query)})
+	}
+	if format >= 0 {
+		m.Options = append(m.Options, UintOption(OptContentFormat, uint32(format)))
+	}
+	return m
+}
+
+func handle(t *testing.T, s *Server, m Message) Message {
+	t.Helper()
+	b, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := s.handle(context.Background(), b, "10.0.0.1:5683")
+	if out == nil {
+		t.Fatal("no response")
+	}
+	resp, err := Parse(out)
+	if err != nil {
+		t.Fatalf("parse response: %v", err)
+	}
+	return resp
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := Message{
+		Type: Confirmable, Code: POST, MessageID: 0xbeef, Token: []byte{1, 2, 3},
+		Options: []Option{
+			UintOption(OptSize1, 1024), // delta 60 → extensie pe un octet
+			{Number: OptURIPath, Value: []byte("t")},
+			{Number: OptURIPath, Value: bytes.Repeat([]byte("x"), 300)}, // lungime pe doi octeți
+			{Number: 2048, Value: []byte{1}},                            // delta pe doi octeți
+		},
+		Payload: []byte(`{"temp":21.4}`),
+	}
+	b, err := m.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != m.Type || got.Code != m.Code || got.MessageID != m.MessageID || !bytes.Equal(got.Token, m.Token) || string(got.Payload) != string(m.Payload) {
+		t.Fatalf("header/payload mismatch: %+v", got)
+	}
+	if p := got.Strings(OptURIPath); len(p) != 2 || p[0] != "t" || len(p[1]) != 300 {
+		t.Errorf("uri-path = %q", p)
+	}
+	if v, ok := got.Uint(OptSize1); !ok || v != 1024 {
+		t.Errorf("size1 = %d %v", v, ok)
+	}
+	if len(got.Options) != 4 || got.Options[3].Number != 2048 {
+		t.Errorf("options = %+v", got.Options)
+	}
+	if POST.String() != "0.02" || TooManyRequests.String() != "4.29" {
+		t.Errorf("code strings: %s %s", POST, TooManyRequests)
+	}
+
+	for _, bad := range [][]byte{
+		{0x40, 0x02},                  // prea scurt
+		{0x80, 0x02, 0, 1},            // versiunea 2
+		{0x49, 0x02, 0, 1},            // TKL 9
+		{0x40, 0x02, 0, 1, 0xff},      // marcaj fără payload
+		{0x40, 0x02, 0, 1, 0xf1, 0x0}, // nibble rezervat
+		{0x40, 0x00, 0, 1, 0xff, 'x'}, // mesaj gol cu conținut
+	} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(% x): expected error", bad)
+		}
+	}
+}
+
+func TestHandle(t *testing.T) {
+	cbor := []byte{0xa2, 0x64, 't', 'e', 'm', 'p', 0xf9, 0x4d, 0x5a, 0x62, 'r', 'h', 0x18, 0x30} // {"temp": 21.40625, "rh": 48}
+	cases := []struct {
+		name        string
+		req         Message
+		pipeErr     error
+		readingErr  error
+		want        Code
+		wantPayload string // payload-ul ajuns în pipeline
+		wantDiag    string
+	}{
+		{name: "json", req: post(Confirmable, 1, "NB1", "env", "t=s3cr3t", FormatJSON, []byte(`{"temp":21.4}`)), want: Changed, wantPayload: `{"temp":21.4}`},
+		{name: "cbor", req: post(Confirmable, 2, "NB1", "env", "t=s3cr3t", FormatCBOR, cbor), want: Changed, wantPayload: `{"rh":48,"temp":21.40625}`},
+		{name: "no format", req: post(Confirmable, 3, "NB1", "meter", "t=s3cr3t", -1, []byte("power=1")), want: Changed, wantPayload: "power=1"},
+		{name: "bad cbor", req: post(Confirmable, 4, "NB1", "env", "t=s3cr3t", FormatCBOR, []byte{0xa1}), want: BadRequest, wantDiag: "cbor"},
+		{name: "xml", req: post(Confirmable, 5, "NB1", "env", "t=s3cr3t", 41, []byte("<x/>")), want: UnsupportedContentFormat},
+		{name: "no token", req: post(Confirmable, 6, "NB1", "env", "", FormatJSON, []byte("{}")), want: Unauthorized, wantDiag: "token required"},
+		{name: "wrong token", req: post(Confirmable, 7, "NB1", "env", "t=guess", FormatJSON, []byte("{}")), want: Unauthorized, wantDiag: "invalid token"},
+		{name: "unknown serial", req: post(Confirmable, 8, "NB2", "env", "t=s3cr3t", FormatJSON, []byte("{}")), want: Unauthorized},
+		{name: "store down", req: post(Confirmable, 9, "down", "env", "t=x", FormatJSON, []byte("{}")), want: ServiceUnavailable},
+		{name: "bad stream", req: post(Confirmable, 10, "NB1", "Env!", "t=s3cr3t", FormatJSON, []byte("{}")), want: BadRequest},
+		{name: "empty", req: post(Confirmable, 11, "NB1", "env", "t=s3cr3t", FormatJSON, nil), want: BadRequest},
+		{name: "too large", req: post(Confirmable, 12, "NB1", "env", "t=s3cr3t", -1, make([]byte, MaxPayload+1)), want: RequestEntityTooLarge},
+		{name: "unregistered", req: post(Confirmable, 13, "NB1", "env", "t=s3cr3t", FormatJSON, []byte("{}")), pipeErr: ingest.ErrUnknownDevice, want: Forbidden},
+		{name: "rate limited", req: post(Confirmable, 14, "NB1", "env", "t=s3cr3t", FormatJSON, []byte("{}")), pipeErr: ingest.ErrRateLimited, want: TooManyRequests},
+		{name: "pipeline error", req: post(Confirmable, 15, "NB1", "env", "t=s3cr3t", FormatJSON, []byte("{}")), pipeErr: errors.New("boom"), want: InternalServerError},
+		{name: "parser rejects", req: post(Confirmable, 16, "NB1", "env", "t=s3cr3t", FormatJSON, []byte("nope")), readingErr: errors.New("invalid JSON"), want: BadRequest, wantDiag: "invalid JSON"},
+	}
+	for _, c := range cases {
+		s, rec := newTestServer()
+		rec.err, rec.readingErr = c.pipeErr, c.readingErr
+		resp := handle(t, s, c.req)
+		if resp.Code != c.want || resp.Type != Acknowledgement || resp.MessageID != c.req.MessageID || !bytes.Equal(resp.Token, c.req.Token) {
+			t.Errorf("%s: got %s type %d mid %d, want %s ACK", c.name, resp.Code, resp.Type, resp.MessageID, c.want)
+		}
+		if c.wantDiag != "" && !strings.Contains(string(resp.Payload), c.wantDiag) {
+			t.Errorf("%s: diagnostic %q, want %q", c.name, resp.Payload, c.wantDiag)
+		}
+		if c.want == Changed {
+			stream := c.req.Strings(OptURIPath)[2]
+			if rec.calls() != 1 || rec.topics[0] != "tenants/7/devices/NB1/up/"+stream || rec.payloads[0] != c.wantPayload {
+				t.Errorf("%s: pipeline got %v %q", c.name, rec.topics, rec.payloads)
+			}
+		}
+		if c.want == TooManyRequests {
+			if v, ok := resp.Uint(OptMaxAge); !ok || v != 1 {
+				t.Errorf("%s: Max-Age = %d %v", c.name, v, ok)
+			}
+		}
+		if c.want == RequestEntityTooLarge {
+			if v, ok := resp.Uint(OptSize1); !ok || v != MaxPayload {
+				t.Errorf("%s: Size1 = %d %v", c.name, v, ok)
+			}
+		}
+	}
+}
+
+func TestHandleProtocol(t *testing.T) {
+	s, rec := newTestServer()
+
+	// Retransmisia unui CON (același Message ID, același peer) primește
+	// răspunsul salvat; citirea nu e scrisă de două ori.
+	req := post(Confirmable, 100, "NB1", "env", "t=s3cr3t", FormatJSON, []byte(`{"temp":1}`))
+	first, second := handle(t, s, req), handle(t, s, req)
+	if first.Code != Changed || second.Code != Changed || rec.calls() != 1 {
+		t.Errorf("retransmission: %s %s, %d pipeline calls", first.Code, second.Code, rec.calls())
+	}
+	// Același Message ID după EXCHANGE_LIFETIME e o cerere nouă.
+	s.now = func() time.Time { return time.Now().Add(exchangeLifetime + time.Second) }
+	if handle(t, s, req); rec.calls() != 2 {
+		t.Errorf("after lifetime: %d pipeline calls, want 2", rec.calls())
+	}
+
+	// NON → răspuns NON cu Message ID propriu, același token.
+	non := post(NonConfirmable, 200, "NB1", "env", "t=s3cr3t", FormatJSON, []byte(`{"temp":2}`))
+	if resp := handle(t, s, non); resp.Type != NonConfirmable || resp.Code != Changed || !bytes.Equal(resp.Token, non.Token) {
+		t.Errorf("NON: %+v", resp)
+	}
+
+	other := []struct {
+		name string
+		req  Message
+		want Code
+	}{
+		{"get", Message{Type: Confirmable, Code: GET, MessageID: 300, Options: []Option{{Number: OptURIPath, Value: []byte("t")}}}, MethodNotAllowed},
+		{"path", post(Confirmable, 301, "NB1", "", "t=s3cr3t", FormatJSON, []byte("{}")), NotFound},
+		{"critical option", func() Message {
+			m := post(Confirmable, 302, "NB1", "env", "t=s3cr3t", FormatJSON, []byte("{}"))
+			m.Options = append(m.Options, Option{Number: 27, Value: []byte{0x0e}}) // Block1
+			return m
+		}(), BadOption},
+	}
+	for _, c := range other {
+		if resp := handle(t, s, c.req); resp.Code != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, resp.Code, c.want)
+		}
+	}
+
+	// "CoAP ping" (CON gol) și CON malformat → RST; ACK / NON malformat → nimic.
+	for name, pkt := range map[string][]byte{
+		"ping":          {0x40, 0x00, 0x12, 0x34},
+		"malformed CON": {0x40, 0x02, 0x12, 0x34, 0xf1},
+	} {
+		out := s.handle(context.Background(), pkt, "10.0.0.1:5683")
+		if resp, err := Parse(out); err != nil || resp.Type != Reset || resp.MessageID != 0x1234 {
+			t.Errorf("%s: got % x (%v)", name, out, err)
+		}
+	}
+	for name, pkt := range map[string][]byte{
+		"ack":           {0x60, 0x00, 0x12, 0x34},
+		"malformed NON": {0x50, 0x02, 0x12, 0x34, 0xf1},
+	} {
+		if out := s.handle(context.Background(), pkt, "10.0.0.1:5683"); out != nil {
+			t.Errorf("%s: unexpected response % x", name, out)
+		}
+	}
+}
+
+func TestServeUDP(t *testing.T) {
+	s, rec := newTestServer()
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp loopback unavailable: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(ctx, pc) }()
+
+	conn, err := net.Dial("udp", pc.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	for i := 0; i < 3; i++ {
+		b, _ := post(Confirmable, uint16(500+i), "NB1", "env", "t=s3cr3t", FormatJSON, []byte(fmt.Sprintf(`{"seq":%d}`, i))).Marshal()
+		if _, err := conn.Write(b); err != nil {
+			t.Fatal(err)
+		}
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		buf := make([]byte, 1500)
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		resp, err := Parse(buf[:n])
+		if err != nil || resp.Type != Acknowledgement || resp.Code != Changed || resp.MessageID != uint16(500+i) {
+			t.Fatalf("response %d: %+v %v", i, resp, err)
+		}
+	}
+	if rec.calls() != 3 {
+		t.Errorf("pipeline calls = %d, want 3", rec.calls())
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve did not stop on cancel")
+	}
+}
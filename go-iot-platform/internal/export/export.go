@@ -0,0 +1,233 @@
+// Package export serializează istoricul unui tenant pentru download (/go/export):
+// CSV, NDJSON, InfluxDB line protocol și Parquet.
+//
+// Formatul e "lung" — un rând per (timestamp, device, field) — ca writer-ele să
+// poată scrie pe măsură ce vin punctele din Influx, fără pivot în memorie.
+// Parquet e singura excepție parțială: ține un row group (RowGroupSize rânduri)
+// înainte să-l scrie.
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format — formatul de export cerut prin ?format=.
+type Format string
+
+const (
+	FormatCSV          Format = "csv"
+	FormatNDJSON       Format = "ndjson"
+	FormatLineProtocol Format = "lp"
+	FormatParquet      Format = "parquet"
+)
+
+// ParseFormat acceptă și aliasurile uzuale (jsonl, line, influx).
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "csv":
+		return FormatCSV, nil
+	case "ndjson", "jsonl":
+		return FormatNDJSON, nil
+	case "lp", "line", "influx":
+		return FormatLineProtocol, nil
+	case "parquet":
+		return FormatParquet, nil
+	}
+	return "", fmt.Errorf("unknown format %q (csv, ndjson, lp, parquet)", s)
+}
+
+// ContentType pentru header-ul răspunsului.
+func (f Format) ContentType() string {
+	switch f {
+	case FormatNDJSON:
+		return "application/x-ndjson"
+	case FormatLineProtocol:
+		return "text/plain; charset=utf-8"
+	case FormatParquet:
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Extension pentru numele fișierului din Content-Disposition.
+func (f Format) Extension() string {
+	switch f {
+	case FormatNDJSON:
+		return "ndjson"
+	case FormatLineProtocol:
+		return "lp"
+	case FormatParquet:
+		return "parquet"
+	}
+	return "csv"
+}
+
+// Row — un punct exportat. Value: float64, int64, bool sau string. Unit e
+// completat doar în modul normalizat. TenantID / Source / DD sunt tag-urile
+// punctului, păstrate doar de line protocol (reimport cu aceleași serii).
+type Row struct {
+	Time     time.Time
+	Device   string
+	Field    string
+	Value    interface{}
+	Unit     string
+	TenantID int64
+	Source   string
+	DD       string
+}
+
+// Writer scrie rânduri în formatul ales. Close finalizează fișierul (footer-ul
+// Parquet, buffer-ele) fără să închidă io.Writer-ul de dedesubt.
+type Writer interface {
+	Write(Row) error
+	Close() error
+}
+
+// NewWriter creează writer-ul pentru format.
+func NewWriter(f Format, w io.Writer) (Writer, error) {
+	switch f {
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatNDJSON:
+		return &ndjsonWriter{w: bufio.NewWriter(w)}, nil
+	case FormatLineProtocol:
+		return &lpWriter{w: bufio.NewWriter(w)}, nil
+	case FormatParquet:
+		return newParquetWriter(w), nil
+	}
+	return nil, fmt.Errorf("unknown format %q", f)
+}
+
+// FormatValue — reprezentarea text a valorii (CSV); float-urile fără exponent inutil.
+func FormatValue(v interface{}) string {
+	switch t := v.(type) {
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case uint64:
+		return strconv.FormatUint(t, 10)
+	case bool:
+		return strconv.FormatBool(t)
+	case string:
+		return t
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// ─── CSV ───
+
+type csvWriter struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVWriter(w io.Writer) *csvWriter { return &csvWriter{w: csv.NewWriter(w)} }
+
+func (c *csvWriter) Write(r Row) error {
+	if !c.header {
+		c.header = true
+		if err := c.w.Write([]string{"time", "device", "field", "value", "unit"}); err != nil {
+			return err
+		}
+	}
+	return c.w.Write([]string{
+		r.Time.UTC().Format(time.RFC3339Nano), r.Device, r.Field, FormatValue(r.Value), r.Unit,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if !c.header {
+		// export gol: tot scriem header-ul, ca fișierul să fie valid
+		if err := c.w.Write([]string{"time", "device", "field", "value", "unit"}); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ─── NDJSON ───
+
+type ndjsonWriter struct{ w *bufio.Writer }
+
+type ndjsonRow struct {
+	Time   string      `json:"time"`
+	Device string      `json:"device"`
+	Field  string      `json:"field"`
+	Value  interface{} `json:"value"`
+	Unit   string      `json:"unit,omitempty"`
+}
+
+func (n *ndjsonWriter) Write(r Row) error {
+	v := r.Value
+	if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+		v = nil // JSON nu are NaN / Inf
+	}
+	b, err := json.Marshal(ndjsonRow{r.Time.UTC().Format(time.RFC3339Nano), r.Device, r.Field, v, r.Unit})
+	if err != nil {
+		return err
+	}
+	if _, err := n.w.Write(b); err != nil {
+		return err
+	}
+	return n.w.WriteByte('\n')
+}
+
+func (n *ndjsonWriter) Close() error { return n.w.Flush() }
+
+// ─── Line protocol ───
+
+// lpWriter — `devices,device=<id>,dd_id=…,source=…,tenant_id=… <field>=<value> <ns>`,
+// reimportabil cu `influx write` în aceleași serii (tag-urile în ordinea cheilor,
+// cele goale omise). Unit-ul nu are loc în line protocol și e omis.
+type lpWriter struct{ w *bufio.Writer }
+
+var (
+	lpTagEscaper   = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+	lpFieldEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+func (l *lpWriter) Write(r Row) error {
+	var val string
+	switch t := r.Value.(type) {
+	case float64:
+		if math.IsNaN(t) || math.IsInf(t, 0) {
+			return nil // nereprezentabil în line protocol
+		}
+		val = strconv.FormatFloat(t, 'g', -1, 64)
+	case int64:
+		val = strconv.FormatInt(t, 10) + "i"
+	case uint64:
+		val = strconv.FormatUint(t, 10) + "u"
+	case bool:
+		val = strconv.FormatBool(t)
+	default:
+		val = `"` + lpFieldEscaper.Replace(FormatValue(t)) + `"`
+	}
+	tags := "device=" + lpTagEscaper.Replace(r.Device)
+	if r.DD != "" {
+		tags += ",dd_id=" + lpTagEscaper.Replace(r.DD)
+	}
+	if r.Source != "" {
+		tags += ",source=" + lpTagEscaper.Replace(r.Source)
+	}
+	if r.TenantID != 0 {
+		tags += ",tenant_id=" + strconv.FormatInt(r.TenantID, 10)
+	}
+	_, err := fmt.Fprintf(l.w, "devices,%s %s=%s %d\n",
+		tags, lpTagEscaper.Replace(r.Field), val, r.Time.UnixNano())
+	return err
+}
+
+func (l *lpWriter) Close() error { return l.w.Flush() }
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+var ts = time.Date(2026, 5, 13, 10, 0, 0, 500_000_000, time.UTC)
+
+func sampleRows() []Row {
+	return []Row{
+		{Time: ts, Device: "sh-1", Field: "power", Value: 12.5, Unit: "W", TenantID: 7, Source: "shelly", DD: "shelly_em"},
+		{Time: ts, Device: "sh-1", Field: "relay_on", Value: int64(1), TenantID: 7, Source: "shelly"},
+		{Time: ts, Device: "dev, 2", Field: "relay state", Value: `ON "x"`},
+		{Time: ts, Device: "z", Field: "ok", Value: true},
+	}
+}
+
+func render(t *testing.T, f Format, rows []Row) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(f, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range rows {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestParseFormat(t *testing.T) {
+	for in, want := range map[string]Format{"": FormatCSV, "JSONL": FormatNDJSON, "line": FormatLineProtocol, "parquet": FormatParquet} {
+		if got, err := ParseFormat(in); err != nil || got != want {
+			t.Errorf("%q → %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("xlsx should be rejected")
+	}
+}
+
+func TestCSV(t *testing.T) {
+	recs, err := csv.NewReader(strings.NewReader(render(t, FormatCSV, sampleRows()))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 5 || strings.Join(recs[0], ",") != "time,device,field,value,unit" {
+		t.Fatalf("records = %v", recs)
+	}
+	if got := strings.Join(recs[1], "|"); got != "2026-05-13T10:00:00.5Z|sh-1|power|12.5|W" {
+		t.Errorf("row 1 = %s", got)
+	}
+	if recs[3][1] != "dev, 2" || recs[3][3] != `ON "x"` || recs[4][3] != "true" {
+		t.Errorf("quoting: %v %v", recs[3], recs[4])
+	}
+	if got := render(t, FormatCSV, nil); got != "time,device,field,value,unit\n" {
+		t.Errorf("empty export = %q", got)
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	rows := append(sampleRows(), Row{Time: ts, Device: "n", Field: "bad", Value: math.NaN()})
+	lines := strings.Split(strings.TrimSpace(render(t, FormatNDJSON, rows)), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("lines = %d", len(lines))
+	}
+	var first map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatal(err)
+	}
+	if first["value"] != 12.5 || first["unit"] != "W" || first["time"] != "2026-05-13T10:00:00.5Z" {
+		t.Errorf("first = %v", first)
+	}
+	if strings.Contains(lines[1], "unit") {
+		t.Errorf("empty unit should be omitted: %s", lines[1])
+	}
+	if !strings.Contains(lines[4], `"value":null`) {
+		t.Errorf("NaN → null: %s", lines[4])
+	}
+}
+
+func TestLineProtocol(t *testing.T) {
+	got := render(t, FormatLineProtocol, sampleRows())
+	ns := "1778666400500000000"
+	want := "devices,device=sh-1,dd_id=shelly_em,source=shelly,tenant_id=7 power=12.5 " + ns + "\n" +
+		"devices,device=sh-1,source=shelly,tenant_id=7 relay_on=1i " + ns + "\n" +
+		`devices,device=dev\,\ 2 relay\ state="ON \"x\"" ` + ns + "\n" +
+		"devices,device=z ok=true " + ns + "\n"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
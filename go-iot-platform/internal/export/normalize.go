@@ -0,0 +1,134 @@
+package export
+
+import (
+	"strings"
+	"unicode"
+
+	"go-iot-platform/internal/parsers"
+	"go-iot-platform/internal/registry"
+)
+
+// LegacySources — tag-ul "source" → ID-ul Device Definition-ului, doar pentru
+// punctele scrise înainte ca ingest-ul să pună tag-ul dd_id (cmd/main.go
+// writeMessage). Punctele noi poartă DD-ul identificat de matcher.
+var LegacySources = map[string]string{
+	"shelly":      "shelly_em",
+	"nousat":      "nous_a1t",
+	"sun2000":     "huawei_sun2000_3phase",
+	"zigbee2mqtt": "zigbee_temperature",
+}
+
+// Normalizer mapează field-urile stocate în Influx (nume vendor: "Power",
+// "nousat_power", "pv_input_power") pe numele canonice din normalized_fields
+// ("active_power_w", "solar_power_kw" …), cu unit, multiplier și decimals din DD.
+//
+// DD-ul unui punct vine din tag-ul dd_id scris la ingest; punctele mai vechi nu
+// îl au și îl deducem din tag-ul source (sources).
+// Potrivirea field-ului e pe ultimul segment din normalized_fields[*].source, în
+// snake_case ("ENERGY.ApparentPower" → "apparent_power"), case-insensitive, după
+// eliminarea prefixului "<source>_" pus la ingest ("nousat_power" → "power").
+// Dacă două surse ale aceluiași DD dau același alias (Tasmota: "ENERGY.Power" și
+// releul "POWER"), câștigă cea imbricată — așa le scrie ingest-ul. Pentru surse necunoscute
+// (generic) se folosesc doar aliasurile care duc la același canonic în toate DD-urile.
+type Normalizer struct {
+	sources map[string]string
+	byDD    map[string]map[string]normTarget
+	global  map[string]normTarget
+}
+
+type normTarget struct {
+	name  string
+	spec  registry.NormSpec
+	depth int // segmente în spec.Source
+}
+
+// NewNormalizer construiește tabelele de aliasuri din registry; sources nil → LegacySources.
+func NewNormalizer(reg *registry.Registry, sources map[string]string) *Normalizer {
+	if sources == nil {
+		sources = LegacySources
+	}
+	n := &Normalizer{sources: sources, byDD: map[string]map[string]normTarget{}, global: map[string]normTarget{}}
+	if reg == nil {
+		return n
+	}
+	ambiguous := map[string]bool{}
+	for _, dd := range reg.All() {
+		table := map[string]normTarget{}
+		for name, spec := range dd.NormalizedFields {
+			alias := fieldAlias(spec.Source)
+			if alias == "" {
+				continue
+			}
+			t := normTarget{name: name, spec: spec, depth: strings.Count(spec.Source, ".")}
+			if prev, ok := table[alias]; ok && (prev.depth > t.depth || prev.depth == t.depth && prev.name < t.name) {
+				continue
+			}
+			table[alias] = t
+		}
+		for alias, t := range table {
+			if ambiguous[alias] {
+				continue
+			}
+			if prev, ok := n.global[alias]; ok && !sameTarget(prev, t) {
+				delete(n.global, alias)
+				ambiguous[alias] = true
+				continue
+			}
+			n.global[alias] = t
+		}
+		n.byDD[dd.ID] = table
+	}
+	return n
+}
+
+func sameTarget(a, b normTarget) bool {
+	return a.name == b.name && a.spec.Unit == b.spec.Unit && a.spec.Multiplier == b.spec.Multiplier &&
+		(a.spec.Decimals == nil) == (b.spec.Decimals == nil) &&
+		(a.spec.Decimals == nil || *a.spec.Decimals == *b.spec.Decimals)
+}
+
+func fieldAlias(source string) string {
+	source = strings.TrimSpace(source)
+	if i := strings.LastIndex(source, "."); i >= 0 {
+		source = source[i+1:]
+	}
+	var b strings.Builder
+	for i, r := range source {
+		if unicode.IsUpper(r) && i > 0 {
+			if prev := rune(source[i-1]); unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// Apply întoarce rândul normalizat; ok=false → field-ul nu are nume canonic
+// și nu apare în exportul normalizat. ddID e tag-ul dd_id al punctului; gol
+// (punct scris înainte de tag) → DD-ul dedus din source.
+func (n *Normalizer) Apply(ddID, source string, r Row) (Row, bool) {
+	if ddID == "" {
+		ddID = n.sources[source]
+	}
+	return n.ApplyDefinition(ddID, source, r)
+}
+
+// ApplyDefinition — ca Apply, dar cu DD-ul deja cunoscut (ex. din topicurile
+// device-ului); ddID gol sau necunoscut → tabela globală.
+func (n *Normalizer) ApplyDefinition(ddID, source string, r Row) (Row, bool) {
+	alias := strings.ToLower(r.Field)
+	if source != "" {
+		alias = strings.TrimPrefix(alias, strings.ToLower(source)+"_")
+	}
+	table := n.global
+	if t := n.byDD[ddID]; t != nil {
+		table = t
+	}
+	t, ok := table[alias]
+	if !ok {
+		return r, false
+	}
+	r.Field, r.Value, r.Unit = t.name, parsers.NormalizeValue(r.Value, t.spec), t.spec.Unit
+	return r, true
+}
@@ -0,0 +1,68 @@
+package export
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go-iot-platform/internal/registry"
+)
+
+func prodNormalizer(t *testing.T) *Normalizer {
+	t.Helper()
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("load DDs: %v %v", err, errs)
+	}
+	return NewNormalizer(reg, nil)
+}
+
+func TestNormalizerProductionDDs(t *testing.T) {
+	n := prodNormalizer(t)
+	cases := []struct {
+		source, field string
+		value         interface{}
+		want          string
+		wantVal       interface{}
+		unit          string
+	}{
+		{"shelly", "Power", 12.34, "active_power_w", 12.3, "W"},
+		{"shelly", "Total", 12345.0, "total_consumed_kwh", 12.345, "kWh"},
+		{"nousat", "nousat_power", 7.4, "active_power_w", 7.0, "W"},
+		{"nousat", "nousat_apparent_power", 10.4, "apparent_power_va", 10.0, "VA"},
+		{"nousat", "nousat_total", 3.5, "total_energy_kwh", 3.5, "kWh"},
+		{"sun2000", "pv_input_power", 2.5, "solar_power_kw", 2.5, "kW"},
+		{"zigbee2mqtt", "voltage", 2980.0, "battery_voltage_mv", 2980.0, "mV"},
+		// sursă necunoscută: doar aliasurile neambigue
+		{"generic", "temperature", 21.26, "temperature_c", 21.3, "°C"},
+	}
+	for _, c := range cases {
+		r, ok := n.Apply("", c.source, Row{Device: "d", Field: c.field, Value: c.value})
+		if !ok || r.Field != c.want || r.Value != c.wantVal || r.Unit != c.unit {
+			t.Errorf("%s/%s → %+v ok=%v", c.source, c.field, r, ok)
+		}
+	}
+	for _, c := range [][2]string{{"generic", "voltage"}, {"generic", "total"}, {"shelly", "relay_on"}, {"generic", "value"}} {
+		if r, ok := n.Apply("", c[0], Row{Field: c[1], Value: 1.0}); ok {
+			t.Errorf("%s/%s should not normalize, got %+v", c[0], c[1], r)
+		}
+	}
+}
+
+func TestNormalizerDefinitionTag(t *testing.T) {
+	n := prodNormalizer(t)
+	// source=zigbee2mqtt → zigbee_temperature doar pentru punctele vechi; cu
+	// tag-ul dd_id un senzor de contact își primește propriile normalized_fields.
+	if _, ok := n.Apply("", "zigbee2mqtt", Row{Field: "contact", Value: true}); ok {
+		t.Error("legacy source mapping should not know contact")
+	}
+	r, ok := n.Apply("zigbee_contact", "zigbee2mqtt", Row{Field: "contact", Value: true})
+	if !ok || r.Field != "contact_closed" {
+		t.Errorf("dd_id=zigbee_contact → %+v ok=%v", r, ok)
+	}
+}
+
+func TestNormalizerNoRegistry(t *testing.T) {
+	if _, ok := NewNormalizer(nil, nil).Apply("", "shelly", Row{Field: "power", Value: 1.0}); ok {
+		t.Error("empty normalizer must not map anything")
+	}
+}
@@ -0,0 +1,396 @@
+package export
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// Writer Parquet minimal, fără dependențe: un singur data page (v1) per coloană
+// per row group, encoding PLAIN, fără compresie. Schema e fixă (formatul lung):
+//
+//	time          INT64       TIMESTAMP_MICROS  required
+//	device        BYTE_ARRAY  UTF8              required
+//	field         BYTE_ARRAY  UTF8              required
+//	value         DOUBLE                        optional  (numeric / bool → 1/0)
+//	value_string  BYTE_ARRAY  UTF8              optional  (valori text)
+//	unit          BYTE_ARRAY  UTF8              optional
+//
+// Metadatele (PageHeader, FileMetaData) sunt Thrift compact protocol, scrise de
+// thriftWriter de mai jos — doar subsetul de tipuri folosit aici.
+
+// RowGroupSize — rânduri ținute în memorie înainte de a scrie un row group.
+const RowGroupSize = 50_000
+
+const parquetMagic = "PAR1"
+
+// Tipuri fizice, repetiții, converted types, encodings (parquet.thrift).
+const (
+	ptInt64     = 2
+	ptDouble    = 5
+	ptByteArray = 6
+
+	repRequired = 0
+	repOptional = 1
+
+	ctUTF8            = 0
+	ctTimestampMicros = 10
+
+	encPlain = 0
+	encRLE   = 3
+)
+
+type parquetColumn struct {
+	name      string
+	physical  int32
+	converted int32 // -1 = fără
+	optional  bool
+}
+
+var parquetSchema = []parquetColumn{
+	{"time", ptInt64, ctTimestampMicros, false},
+	{"device", ptByteArray, ctUTF8, false},
+	{"field", ptByteArray, ctUTF8, false},
+	{"value", ptDouble, -1, true},
+	{"value_string", ptByteArray, ctUTF8, true},
+	{"unit", ptByteArray, ctUTF8, true},
+}
+
+// columnBuf — valorile PLAIN + nivelurile de definiție ale unei coloane.
+type columnBuf struct {
+	values []byte
+	defs   []bool // doar pentru coloanele optional
+}
+
+type chunkMeta struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+type rowGroupMeta struct {
+	rows   int64
+	size   int64
+	chunks []chunkMeta
+}
+
+type parquetWriter struct {
+	w      *countingWriter
+	cols   []columnBuf
+	rows   int
+	groups []rowGroupMeta
+	total  int64
+	err    error
+}
+
+type countingWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func newParquetWriter(w io.Writer) *parquetWriter {
+	return &parquetWriter{
+		w:    &countingWriter{w: bufio.NewWriterSize(w, 64<<10)},
+		cols: make([]columnBuf, len(parquetSchema)),
+	}
+}
+
+func (p *parquetWriter) Write(r Row) error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.rows == 0 && p.w.n == 0 {
+		if _, p.err = io.WriteString(p.w, parquetMagic); p.err != nil {
+			return p.err
+		}
+	}
+	p.cols[0].values = binary.LittleEndian.AppendUint64(p.cols[0].values, uint64(r.Time.UnixMicro()))
+	p.cols[1].values = appendByteArray(p.cols[1].values, r.Device)
+	p.cols[2].values = appendByteArray(p.cols[2].values, r.Field)
+
+	num, isNum := parquetNumber(r.Value)
+	p.cols[3].defs = append(p.cols[3].defs, isNum)
+	if isNum {
+		p.cols[3].values = binary.LittleEndian.AppendUint64(p.cols[3].values, math.Float64bits(num))
+	}
+	str, isStr := r.Value.(string)
+	p.cols[4].defs = append(p.cols[4].defs, isStr)
+	if isStr {
+		p.cols[4].values = appendByteArray(p.cols[4].values, str)
+	}
+	p.cols[5].defs = append(p.cols[5].defs, r.Unit != "")
+	if r.Unit != "" {
+		p.cols[5].values = appendByteArray(p.cols[5].values, r.Unit)
+	}
+
+	p.rows++
+	if p.rows >= RowGroupSize {
+		p.err = p.flushRowGroup()
+	}
+	return p.err
+}
+
+func parquetNumber(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int64:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case bool:
+		if t {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+func appendByteArray(b []byte, s string) []byte {
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func (p *parquetWriter) flushRowGroup() error {
+	if p.rows == 0 {
+		return nil
+	}
+	rg := rowGroupMeta{rows: int64(p.rows)}
+	for i, col := range parquetSchema {
+		buf := &p.cols[i]
+		var page []byte
+		if col.optional {
+			levels := encodeDefLevels(buf.defs)
+			page = binary.LittleEndian.AppendUint32(page, uint32(len(levels)))
+			page = append(page, levels...)
+		}
+		page = append(page, buf.values...)
+
+		var hdr thriftWriter
+		hdr.i32(1, 0) // type = DATA_PAGE
+		hdr.i32(2, int32(len(page)))
+		hdr.i32(3, int32(len(page)))
+		hdr.structBegin(5) // data_page_header
+		hdr.i32(1, int32(p.rows))
+		hdr.i32(2, encPlain)
+		hdr.i32(3, encRLE)
+		hdr.i32(4, encRLE)
+		hdr.structEnd()
+		hdr.stop()
+
+		offset := p.w.n
+		if _, err := p.w.Write(hdr.buf); err != nil {
+			return err
+		}
+		if _, err := p.w.Write(page); err != nil {
+			return err
+		}
+		size := int64(len(hdr.buf) + len(page))
+		rg.chunks = append(rg.chunks, chunkMeta{offset: offset, size: size, values: int64(p.rows)})
+		rg.size += size
+		buf.values, buf.defs = buf.values[:0], buf.defs[:0]
+	}
+	p.groups = append(p.groups, rg)
+	p.total += int64(p.rows)
+	p.rows = 0
+	return nil
+}
+
+// encodeDefLevels — hibridul RLE / bit-packed cu bit width 1, doar run-uri RLE:
+// header varint (lungime << 1), apoi valoarea pe un byte.
+func encodeDefLevels(defs []bool) []byte {
+	var out []byte
+	for i := 0; i < len(defs); {
+		j := i
+		for j < len(defs) && defs[j] == defs[i] {
+			j++
+		}
+		out = binary.AppendUvarint(out, uint64(j-i)<<1)
+		if defs[i] {
+			out = append(out, 1)
+		} else {
+			out = append(out, 0)
+		}
+		i = j
+	}
+	return out
+}
+
+func (p *parquetWriter) Close() error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.w.n == 0 {
+		if _, err := io.WriteString(p.w, parquetMagic); err != nil {
+			return err
+		}
+	}
+	if err := p.flushRowGroup(); err != nil {
+		return err
+	}
+	meta := p.fileMetaData()
+	if _, err := p.w.Write(meta); err != nil {
+		return err
+	}
+	var tail [4]byte
+	binary.LittleEndian.PutUint32(tail[:], uint32(len(meta)))
+	if _, err := p.w.Write(tail[:]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(p.w, parquetMagic); err != nil {
+		return err
+	}
+	return p.w.w.Flush()
+}
+
+func (p *parquetWriter) fileMetaData() []byte {
+	var t thriftWriter
+	t.i32(1, 1) // version
+
+	t.listBegin(2, thriftStruct, len(parquetSchema)+1)
+	t.elemBegin() // root
+	t.binary(4, "schema")
+	t.i32(5, int32(len(parquetSchema)))
+	t.elemEnd()
+	for _, col := range parquetSchema {
+		t.elemBegin()
+		t.i32(1, col.physical)
+		rep := int32(repRequired)
+		if col.optional {
+			rep = repOptional
+		}
+		t.i32(3, rep)
+		t.binary(4, col.name)
+		if col.converted >= 0 {
+			t.i32(6, col.converted)
+		}
+		t.elemEnd()
+	}
+
+	t.i64(3, p.total)
+
+	t.listBegin(4, thriftStruct, len(p.groups))
+	for _, rg := range p.groups {
+		t.elemBegin()
+		t.listBegin(1, thriftStruct, len(rg.chunks))
+		for i, ch := range rg.chunks {
+			col := parquetSchema[i]
+			t.elemBegin()
+			t.i64(2, ch.offset) // file_offset
+			t.structBegin(3)    // meta_data
+			t.i32(1, col.physical)
+			encs := []int32{encPlain}
+			if col.optional {
+				encs = append(encs, encRLE)
+			}
+			t.listBegin(2, thriftI32, len(encs))
+			for _, e := range encs {
+				t.elemI32(e)
+			}
+			t.listBegin(3, thriftBinary, 1)
+			t.elemBinary(col.name)
+			t.i32(4, 0) // UNCOMPRESSED
+			t.i64(5, ch.values)
+			t.i64(6, ch.size)
+			t.i64(7, ch.size)
+			t.i64(9, ch.offset) // data_page_offset
+			t.structEnd()
+			t.elemEnd()
+		}
+		t.i64(2, rg.size)
+		t.i64(3, rg.rows)
+		t.elemEnd()
+	}
+	t.binary(6, "go-iot-platform export")
+	t.stop()
+	return t.buf
+}
+
+// ─── Thrift compact protocol (doar scriere) ───
+
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter ține ultimul field id per nivel de struct (delta encoding).
+type thriftWriter struct {
+	buf  []byte
+	last []int16
+	cur  int16
+}
+
+func (t *thriftWriter) fieldHeader(id int16, typ byte) {
+	if d := id - t.cur; d > 0 && d <= 15 {
+		t.buf = append(t.buf, byte(d)<<4|typ)
+	} else {
+		t.buf = append(t.buf, typ)
+		t.buf = binary.AppendVarint(t.buf, int64(id))
+	}
+	t.cur = id
+}
+
+func (t *thriftWriter) i32(id int16, v int32) {
+	t.fieldHeader(id, thriftI32)
+	t.buf = binary.AppendVarint(t.buf, int64(v))
+}
+
+func (t *thriftWriter) i64(id int16, v int64) {
+	t.fieldHeader(id, thriftI64)
+	t.buf = binary.AppendVarint(t.buf, v)
+}
+
+func (t *thriftWriter) binary(id int16, s string) {
+	t.fieldHeader(id, thriftBinary)
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func (t *thriftWriter) structBegin(id int16) {
+	t.fieldHeader(id, thriftStruct)
+	t.elemBegin()
+}
+
+func (t *thriftWriter) structEnd() { t.elemEnd() }
+
+func (t *thriftWriter) listBegin(id int16, elem byte, n int) {
+	t.fieldHeader(id, thriftList)
+	if n < 15 {
+		t.buf = append(t.buf, byte(n)<<4|elem)
+	} else {
+		t.buf = append(t.buf, 0xF0|elem)
+		t.buf = binary.AppendUvarint(t.buf, uint64(n))
+	}
+}
+
+// elemBegin / elemEnd — un struct fără field header (element de listă sau câmp struct).
+func (t *thriftWriter) elemBegin() {
+	t.last = append(t.last, t.cur)
+	t.cur = 0
+}
+
+func (t *thriftWriter) elemEnd() {
+	t.stop()
+	t.cur = t.last[len(t.last)-1]
+	t.last = t.last[:len(t.last)-1]
+}
+
+func (t *thriftWriter) elemI32(v int32) { t.buf = binary.AppendVarint(t.buf, int64(v)) }
+
+func (t *thriftWriter) elemBinary(s string) {
+	t.buf = binary.AppendUvarint(t.buf, uint64(len(s)))
+	t.buf = append(t.buf, s...)
+}
+
+func (t *thriftWriter) stop() { t.buf = append(t.buf, 0) }
@@ -0,0 +1,363 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// thriftReader — decodor compact protocol minimal, doar pentru verificarea
+// footer-ului și a page header-elor scrise de parquetWriter.
+type thriftReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *thriftReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) varint() int64 {
+	v, n := binary.Varint(r.b[r.pos:])
+	r.pos += n
+	return v
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n := int(r.uvarint())
+		s := string(r.b[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case thriftList:
+		h := r.b[r.pos]
+		r.pos++
+		n, elem := int(h>>4), h&0x0F
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		out := make([]interface{}, n)
+		for i := range out {
+			out[i] = r.value(elem)
+		}
+		return out
+	case thriftStruct:
+		return r.structure()
+	}
+	panic("unsupported thrift type")
+}
+
+func (r *thriftReader) structure() map[int16]interface{} {
+	out := map[int16]interface{}{}
+	var last int16
+	for {
+		h := r.b[r.pos]
+		r.pos++
+		if h == 0 {
+			return out
+		}
+		id := last + int16(h>>4)
+		if h>>4 == 0 {
+			id = int16(r.varint())
+		}
+		out[id] = r.value(h & 0x0F)
+		last = id
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	base := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+	rows := []Row{
+		{Time: base, Device: "shelly-1", Field: "active_power_w", Value: 12.5, Unit: "W"},
+		{Time: base.Add(time.Second), Device: "shelly-1", Field: "relay", Value: "ON"},
+		{Time: base.Add(2 * time.Second), Device: "nous-1", Field: "count", Value: int64(7)},
+	}
+	var buf bytes.Buffer
+	w, _ := NewWriter(FormatParquet, &buf)
+	for _, r := range rows {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	if string(b[:4]) != parquetMagic || string(b[len(b)-4:]) != parquetMagic {
+		t.Fatalf("missing PAR1 magic")
+	}
+	metaLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	meta := (&thriftReader{b: b[len(b)-8-metaLen : len(b)-8]}).structure()
+
+	if meta[3].(int64) != 3 {
+		t.Errorf("num_rows = %v", meta[3])
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != len(parquetSchema)+1 || schema[1].(map[int16]interface{})[4] != "time" {
+		t.Fatalf("schema = %v", schema)
+	}
+	groups := meta[4].([]interface{})
+	if len(groups) != 1 {
+		t.Fatalf("row groups = %d", len(groups))
+	}
+	chunks := groups[0].(map[int16]interface{})[1].([]interface{})
+
+	// coloana "value" (optional DOUBLE): def levels 1,0,1 apoi 12.5, 7
+	cm := chunks[3].(map[int16]interface{})[3].(map[int16]interface{})
+	off := int(cm[9].(int64))
+	pr := &thriftReader{b: b, pos: off}
+	ph := pr.structure()
+	if ph[5].(map[int16]interface{})[1].(int64) != 3 {
+		t.Errorf("page num_values = %v", ph)
+	}
+	page := b[pr.pos : pr.pos+int(ph[2].(int64))]
+	levels := int(binary.LittleEndian.Uint32(page))
+	if want := encodeDefLevels([]bool{true, false, true}); !bytes.Equal(page[4:4+levels], want) {
+		t.Errorf("def levels = %x, want %x", page[4:4+levels], want)
+	}
+	vals := page[4+levels:]
+	if len(vals) != 16 ||
+		math.Float64frombits(binary.LittleEndian.Uint64(vals)) != 12.5 ||
+		math.Float64frombits(binary.LittleEndian.Uint64(vals[8:])) != 7 {
+		t.Errorf("values = %x", vals)
+	}
+}
+
+func TestParquetEmptyAndRowGroups(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := NewWriter(FormatParquet, &buf)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	metaLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	meta := (&thriftReader{b: b[len(b)-8-metaLen : len(b)-8]}).structure()
+	if string(b[:4]) != parquetMagic || meta[3].(int64) != 0 || len(meta[4].([]interface{})) != 0 {
+		t.Errorf("empty file meta = %v", meta)
+	}
+
+	buf.Reset()
+	w, _ = NewWriter(FormatParquet, &buf)
+	for i := 0; i < RowGroupSize+10; i++ {
+		w.Write(Row{Time: time.Unix(int64(i), 0), Device: "d", Field: "f", Value: float64(i)})
+	}
+	w.Close()
+	b = buf.Bytes()
+	metaLen = int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	meta = (&thriftReader{b: b[len(b)-8-metaLen : len(b)-8]}).structure()
+	if groups := meta[4].([]interface{}); len(groups) != 2 || meta[3].(int64) != RowGroupSize+10 {
+		t.Errorf("groups=%d rows=%v", len(groups), meta[3])
+	}
+}
+
+// readParquet — cititor independent de parquetWriter: pornește doar de la
+// footer (schema, row groups, offset-urile paginilor) și decodează după spec
+// — hibridul RLE / bit-packed pentru definition levels, PLAIN pe tip fizic —
+// nu după ce știm că scrie writer-ul. Întoarce coloanele după nume.
+func readParquet(t *testing.T, b []byte) map[string][]interface{} {
+	t.Helper()
+	if len(b) < 12 || string(b[:4]) != "PAR1" || string(b[len(b)-4:]) != "PAR1" {
+		t.Fatalf("not a parquet file")
+	}
+	metaLen := int(binary.LittleEndian.Uint32(b[len(b)-8:]))
+	meta := (&thriftReader{b: b[len(b)-8-metaLen : len(b)-8]}).structure()
+
+	type leaf struct {
+		name      string
+		physical  int64
+		optional  bool
+		converted int64
+	}
+	var leaves []leaf
+	for _, e := range meta[2].([]interface{})[1:] { // [0] = rădăcina
+		el := e.(map[int16]interface{})
+		l := leaf{name: el[4].(string), physical: el[1].(int64), optional: el[3].(int64) == 1, converted: -1}
+		if c, ok := el[6].(int64); ok {
+			l.converted = c
+		}
+		leaves = append(leaves, l)
+	}
+
+	cols := map[string][]interface{}{}
+	for _, g := range meta[4].([]interface{}) {
+		rg := g.(map[int16]interface{})
+		for i, c := range rg[1].([]interface{}) {
+			cm := c.(map[int16]interface{})[3].(map[int16]interface{})
+			if cm[4].(int64) != 0 {
+				t.Fatalf("column %d: codec %v", i, cm[4])
+			}
+			col := leaves[i]
+			pr := &thriftReader{b: b, pos: int(cm[9].(int64))}
+			ph := pr.structure()
+			if ph[1].(int64) != 0 {
+				t.Fatalf("column %s: page type %v", col.name, ph[1])
+			}
+			n := int(ph[5].(map[int16]interface{})[1].(int64))
+			page := b[pr.pos : pr.pos+int(ph[2].(int64))]
+
+			present := make([]bool, n)
+			for j := range present {
+				present[j] = true
+			}
+			if col.optional {
+				size := int(binary.LittleEndian.Uint32(page))
+				present = decodeHybridBits(t, page[4:4+size], n)
+				page = page[4+size:]
+			}
+			for _, ok := range present {
+				if !ok {
+					cols[col.name] = append(cols[col.name], nil)
+					continue
+				}
+				var v interface{}
+				switch col.physical {
+				case 2: // INT64
+					x := int64(binary.LittleEndian.Uint64(page))
+					page = page[8:]
+					v = x
+					if col.converted == 10 { // TIMESTAMP_MICROS
+						v = time.UnixMicro(x).UTC()
+					}
+				case 5: // DOUBLE
+					v = math.Float64frombits(binary.LittleEndian.Uint64(page))
+					page = page[8:]
+				case 6: // BYTE_ARRAY
+					l := int(binary.LittleEndian.Uint32(page))
+					v = string(page[4 : 4+l])
+					page = page[4+l:]
+				default:
+					t.Fatalf("column %s: physical type %d", col.name, col.physical)
+				}
+				cols[col.name] = append(cols[col.name], v)
+			}
+			if len(page) != 0 {
+				t.Errorf("column %s: %d trailing bytes", col.name, len(page))
+			}
+		}
+	}
+	return cols
+}
+
+// decodeHybridBits — RLE / bit-packed hybrid cu bit width 1 (max def level 1).
+func decodeHybridBits(t *testing.T, b []byte, n int) []bool {
+	t.Helper()
+	var out []bool
+	for len(out) < n {
+		h, k := binary.Uvarint(b)
+		if k <= 0 {
+			t.Fatalf("bad run header")
+		}
+		b = b[k:]
+		if h&1 == 1 { // bit-packed: (h>>1) grupuri de 8 valori
+			for _, x := range b[:h>>1] {
+				for bit := 0; bit < 8; bit++ {
+					out = append(out, x>>bit&1 == 1)
+				}
+			}
+			b = b[h>>1:]
+			continue
+		}
+		for i := uint64(0); i < h>>1; i++ {
+			out = append(out, b[0] == 1)
+		}
+		b = b[1:]
+	}
+	return out[:n]
+}
+
+func TestParquetRoundTrip(t *testing.T) {
+	base := time.Date(2026, 5, 13, 10, 0, 0, 123456000, time.UTC)
+	rows := []Row{
+		{Time: base, Device: "shelly-1", Field: "active_power_w", Value: 12.5, Unit: "W"},
+		{Time: base.Add(time.Second), Device: "shelly-1", Field: "relay", Value: "ON"},
+		{Time: base.Add(2 * time.Second), Device: "nous-1", Field: "count", Value: int64(7)},
+		{Time: base.Add(3 * time.Second), Device: "z-ă", Field: "contact", Value: true},
+		{Time: base.Add(4 * time.Second), Device: "z-ă", Field: "contact", Value: false, Unit: ""},
+		{Time: base.Add(5 * time.Second), Device: "x", Field: "note", Value: ""},
+	}
+	var buf bytes.Buffer
+	w, _ := NewWriter(FormatParquet, &buf)
+	for _, r := range rows {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	cols := readParquet(t, buf.Bytes())
+
+	want := map[string][]interface{}{
+		"time":         {base, base.Add(time.Second), base.Add(2 * time.Second), base.Add(3 * time.Second), base.Add(4 * time.Second), base.Add(5 * time.Second)},
+		"device":       {"shelly-1", "shelly-1", "nous-1", "z-ă", "z-ă", "x"},
+		"field":        {"active_power_w", "relay", "count", "contact", "contact", "note"},
+		"value":        {12.5, nil, 7.0, 1.0, 0.0, nil},
+		"value_string": {nil, "ON", nil, nil, nil, ""},
+		"unit":         {"W", nil, nil, nil, nil, nil},
+	}
+	for name, vals := range want {
+		got := cols[name]
+		if len(got) != len(vals) {
+			t.Errorf("%s: %d values, want %d", name, len(got), len(vals))
+			continue
+		}
+		for i := range vals {
+			if tv, ok := vals[i].(time.Time); ok {
+				if gt, _ := got[i].(time.Time); !gt.Equal(tv) {
+					t.Errorf("%s[%d] = %v, want %v", name, i, got[i], tv)
+				}
+			} else if got[i] != vals[i] {
+				t.Errorf("%s[%d] = %#v, want %#v", name, i, got[i], vals[i])
+			}
+		}
+	}
+	if len(cols) != len(want) {
+		t.Errorf("columns = %d, want %d", len(cols), len(want))
+	}
+}
+
+// TestParquetPyArrow — citire cu pyarrow, când e instalat (CI-ul cu Python);
+// altfel skip, round-trip-ul de mai sus rămâne verificarea de bază.
+func TestParquetPyArrow(t *testing.T) {
+	if err := exec.Command("python3", "-c", "import pyarrow.parquet").Run(); err != nil {
+		t.Skip("pyarrow not available")
+	}
+	path := filepath.Join(t.TempDir(), "export.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, _ := NewWriter(FormatParquet, f)
+	base := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+	w.Write(Row{Time: base, Device: "shelly-1", Field: "active_power_w", Value: 12.5, Unit: "W"})
+	w.Write(Row{Time: base.Add(time.Second), Device: "shelly-1", Field: "relay", Value: "ON"})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	script := `import json, sys, pyarrow.parquet as pq
+t = pq.read_table(sys.argv[1])
+print(json.dumps({c: [str(v) if c == "time" else v for v in t.column(c).to_pylist()] for c in t.column_names}, sort_keys=True))`
+	out, err := exec.Command("python3", "-c", script, path).CombinedOutput()
+	if err != nil {
+		t.Fatalf("pyarrow: %v\n%s", err, out)
+	}
+	want := `{"device": ["shelly-1", "shelly-1"], "field": ["active_power_w", "relay"], ` +
+		`"time": ["2026-05-13 10:00:00+00:00", "2026-05-13 10:00:01+00:00"], "unit": ["W", null], ` +
+		`"value": [12.5, null], "value_string": [null, "ON"]}`
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Errorf("pyarrow read:\n%s\nwant:\n%s", got, want)
+	}
+}
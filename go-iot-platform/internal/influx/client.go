@@ -2,12 +2,12 @@ package influx
 
 import (
 	"context"
-	"fmt"
 	"regexp"
+	"time"
 
 	"go-iot-platform/internal/config"
 
-	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/influxdata/influxdb-client-go/v2/api/query"
 )
 
 var (
@@ -42,52 +42,55 @@ func bucketsToTry(plan string) []string {
 	return out
 }
 
-func GetFieldForDevice(device, field, rangeStr string, tenantID int64, plan string) (float64, error) {
+// GetFieldForDevice — ultima valoare a unui field, prin clientul partajat.
+func GetFieldForDevice(ctx context.Context, device, field, rangeStr string, tenantID int64, plan string) (float64, error) {
+	return DefaultQueryClient().FieldForDevice(ctx, device, field, rangeStr, tenantID, plan)
+}
+
+// FieldForDevice întoarce ultima valoare din range, căutând în bucket-urile
+// planului în ordinea din bucketsToTry. ErrNoData dacă niciun bucket nu are
+// date; ErrUnavailable dacă Influx nu răspunde — o eroare pe bucket-ul
+// prioritar nu e mascată de un bucket secundar gol.
+func (c *QueryClient) FieldForDevice(ctx context.Context, device, field, rangeStr string, tenantID int64, plan string) (float64, error) {
 	if rangeStr == "" {
 		rangeStr = DefaultRange
 	}
-	if !rangeRe.MatchString(rangeStr) {
-		return 0, fmt.Errorf("invalid range %q (expected like -5m, -1h, -2d)", rangeStr)
+	since, err := ParseRelativeRange(rangeStr)
+	if err != nil {
+		return 0, err
 	}
 
-	client := influxdb2.NewClient(URL, Token)
-	defer client.Close()
-	q := client.QueryAPI(Org)
-
-	// Filtrul pe tenant_id se aplică strict: doar date cu tenant_id corect.
+	// Filtrul pe tenant_id se aplică strict de DeviceQuery: doar date cu tenant_id corect.
 	// Legacy data fără tenant_id e RESPINSĂ (multi-tenant isolation).
-	tenantFilter := ""
-	if tenantID > 0 {
-		tenantFilter = fmt.Sprintf(` and r.tenant_id == "%d"`, tenantID)
-	}
-
 	for _, bucket := range bucketsToTry(plan) {
-		flux := fmt.Sprintf(`
-            from(bucket: "%s")
-            |> range(start: %s)
-            |> filter(fn: (r) => r._measurement == "devices" and r.device == "%s" and r._field == "%s"%s)
-            |> last()
-        `, bucket, rangeStr, device, field, tenantFilter)
-
-		result, err := q.Query(context.Background(), flux)
+		flux, err := DeviceQuery(bucket, tenantID, Since(since)).
+			Filter(Eq("device", device), Eq("_field", field)).
+			Last().
+			Build()
 		if err != nil {
-			continue
+			return 0, err
 		}
-		for result.Next() {
-			switch v := result.Record().Value().(type) {
-			case float64:
-				return v, nil
-			case int64:
-				return float64(v), nil
-			case uint64:
-				return float64(v), nil
-			case bool:
-				if v {
-					return 1, nil
-				}
-				return 0, nil
+
+		// last() întoarce câte un rând per serie (ex. cu și fără dd_id) —
+		// câștigă cel mai nou, ca în Latest.
+		var (
+			val float64
+			at  time.Time
+			got bool
+		)
+		err = c.run(ctx, bucket, flux, func(rec *query.FluxRecord) {
+			v, ok := toFloat(rec.Value())
+			if !ok || (got && !rec.Time().After(at)) {
+				return
 			}
+			val, at, got = v, rec.Time(), true
+		})
+		if err != nil {
+			return 0, err
+		}
+		if got {
+			return val, nil
 		}
 	}
-	return 0, fmt.Errorf("no data")
+	return 0, ErrNoData
 }
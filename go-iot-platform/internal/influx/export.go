@@ -0,0 +1,115 @@
+package influx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/query"
+)
+
+// DefaultExportTimeout — plafonul unui query de export per bucket (vs.
+// DefaultQueryTimeout pentru dashboard).
+const DefaultExportTimeout = 5 * time.Minute
+
+// ExportMaxRange — cea mai lungă fereastră exportabilă într-un request, per plan.
+// Sub retention-ul bucket-ului (LimitsForPlan): un export mai lung se face în bucăți.
+func ExportMaxRange(plan string) time.Duration {
+	switch plan {
+	case "enterprise":
+		return 366 * 24 * time.Hour
+	case "pro":
+		return 31 * 24 * time.Hour
+	default:
+		return 7 * 24 * time.Hour
+	}
+}
+
+// ExportQuery — istoricul brut (neagregat) al unor device-uri dintr-un tenant.
+type ExportQuery struct {
+	TenantID int64
+	Plan     string
+	Devices  []string
+	Fields   []string // gol → toate field-urile
+	Start    time.Time
+	Stop     time.Time
+}
+
+// ExportRecord — un punct (device, field) așa cum e stocat.
+type ExportRecord struct {
+	Time   time.Time
+	Device string
+	Source string // tag-ul "source" (shelly / nousat / …)
+	DD     string // tag-ul "dd_id" — DD-ul identificat la ingest; gol pe punctele vechi
+	Field  string
+	Value  interface{}
+}
+
+// Validate verifică query-ul contra ExportMaxRange(plan).
+func (q ExportQuery) Validate() error {
+	if q.TenantID <= 0 {
+		return fmt.Errorf("tenant_id required")
+	}
+	if len(q.Devices) == 0 {
+		return fmt.Errorf("no devices to export")
+	}
+	for _, s := range append(append([]string{}, q.Devices...), q.Fields...) {
+		if s == "" || len(s) > maxIdentLen {
+			return fmt.Errorf("invalid device/field name %q", s)
+		}
+	}
+	if !q.Stop.After(q.Start) {
+		return fmt.Errorf("stop must be after start")
+	}
+	if span, max := q.Stop.Sub(q.Start), ExportMaxRange(q.Plan); span > max {
+		return fmt.Errorf("range %s exceeds export limit %s for plan", span, max)
+	}
+	return nil
+}
+
+func buildExportFlux(bucket string, q ExportQuery) (string, error) {
+	preds := []Pred{In("device", q.Devices)}
+	if len(q.Fields) > 0 {
+		preds = append(preds, In("_field", q.Fields))
+	}
+	return DeviceQuery(bucket, q.TenantID, Between(q.Start, q.Stop)).
+		Filter(preds...).
+		Keep("_time", "_value", "_field", "device", "source", "dd_id").
+		Build()
+}
+
+// Export trimite fiecare punct către fn, pe măsură ce vine de la Influx — nimic
+// nu e ținut în memorie. Ordinea: bucket-urile planului (bucketsToTry), apoi
+// serie cu serie (device × field), cronologic în interiorul seriei. O eroare din
+// fn (clientul a închis conexiunea) oprește exportul.
+func (c *QueryClient) Export(ctx context.Context, q ExportQuery, timeout time.Duration, fn func(ExportRecord) error) error {
+	if err := q.Validate(); err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		timeout = DefaultExportTimeout
+	}
+	for _, bucket := range bucketsToTry(q.Plan) {
+		flux, err := buildExportFlux(bucket, q)
+		if err != nil {
+			return err
+		}
+		err = c.stream(ctx, bucket, flux, timeout, func(rec *query.FluxRecord) error {
+			device, _ := rec.ValueByKey("device").(string)
+			source, _ := rec.ValueByKey("source").(string)
+			ddID, _ := rec.ValueByKey("dd_id").(string)
+			return fn(ExportRecord{
+				Time:   rec.Time(),
+				Device: device,
+				Source: source,
+				DD:     ddID,
+				Field:  rec.Field(),
+				Value:  rec.Value(),
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package influx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportQueryValidate(t *testing.T) {
+	stop := time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC)
+	base := ExportQuery{TenantID: 1, Plan: "pro", Devices: []string{"d"}, Start: stop.Add(-24 * time.Hour), Stop: stop}
+	if err := base.Validate(); err != nil {
+		t.Fatalf("valid query: %v", err)
+	}
+	cases := map[string]func(q *ExportQuery){
+		"no tenant":      func(q *ExportQuery) { q.TenantID = 0 },
+		"no devices":     func(q *ExportQuery) { q.Devices = nil },
+		"reversed":       func(q *ExportQuery) { q.Start = q.Stop.Add(time.Hour) },
+		"over plan":      func(q *ExportQuery) { q.Start = q.Stop.Add(-32 * 24 * time.Hour) },
+		"free over plan": func(q *ExportQuery) { q.Plan = "free"; q.Start = q.Stop.Add(-8 * 24 * time.Hour) },
+		"long name":      func(q *ExportQuery) { q.Fields = []string{strings.Repeat("x", maxIdentLen+1)} },
+	}
+	for name, mut := range cases {
+		q := base
+		mut(&q)
+		if err := q.Validate(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestBuildExportFlux(t *testing.T) {
+	q := ExportQuery{TenantID: 4, Devices: []string{"a"}, Fields: []string{"power"},
+		Start: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), Stop: time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC)}
+	flux, err := buildExportFlux("iot-free", q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`r.tenant_id == "4"`, `r.device == "a"`, `r._field == "power"`, `"source"`} {
+		if !strings.Contains(flux, want) {
+			t.Errorf("flux missing %s:\n%s", want, flux)
+		}
+	}
+	if strings.Contains(flux, "aggregateWindow") {
+		t.Error("export must not aggregate")
+	}
+}
+
+func TestExportStreams(t *testing.T) {
+	c, _ := fakeInflux(t, func(bucket string, w http.ResponseWriter) {
+		switch bucket {
+		case "iot-pro":
+			writeCSV(w, csvHeader+
+				csvRow("2026-05-13T10:00:00Z", "1", "power", "a")+
+				csvRow("2026-05-13T10:01:00Z", "2", "power", "a"))
+		case "iot-free":
+			writeCSV(w, csvHeader+csvRow("2026-05-01T10:00:00Z", "9", "power", "a"))
+		default:
+			writeCSV(w, "")
+		}
+	})
+	q := ExportQuery{TenantID: 1, Plan: "pro", Devices: []string{"a"},
+		Start: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), Stop: time.Date(2026, 5, 14, 0, 0, 0, 0, time.UTC)}
+
+	var got []float64
+	err := c.Export(context.Background(), q, time.Second, func(r ExportRecord) error {
+		got = append(got, r.Value.(float64))
+		return nil
+	})
+	if err != nil || len(got) != 3 || got[0] != 1 || got[2] != 9 {
+		t.Fatalf("got %v err=%v (plan bucket first, then others)", got, err)
+	}
+
+	stop := errors.New("client gone")
+	n := 0
+	err = c.Export(context.Background(), q, time.Second, func(ExportRecord) error {
+		n++
+		return stop
+	})
+	if !errors.Is(err, stop) || n != 1 {
+		t.Errorf("write error must stop export: n=%d err=%v", n, err)
+	}
+}
@@ -0,0 +1,266 @@
+package influx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Builder tipizat pentru query-urile Flux de citire. Toate endpoint-urile de
+// read (metrics, series, ...) construiesc query-ul prin DeviceQuery, nu prin
+// fmt.Sprintf cu valori din URL:
+//
+//   - string-urile (bucket, device, field, tenant) trec prin StringLit —
+//     escape pentru \ " și interpolarea ${…}, deci nu pot ieși din literal
+//   - numele de coloane / funcții sunt validate (identRe / AggFunc)
+//   - durate și timestamp-uri sunt formatate din time.Duration / time.Time
+//   - filtrul _measurement + tenant_id e pus de DeviceQuery, înaintea oricărui
+//     filtru cerut de caller: un query nu poate fi construit fără izolarea pe tenant
+
+// identRe — nume de coloană Flux acceptate în r.<col>.
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// AggFunc — funcțiile de agregare permise în aggregateWindow.
+type AggFunc string
+
+const (
+	AggMean AggFunc = "mean"
+	AggMax  AggFunc = "max"
+	AggMin  AggFunc = "min"
+	AggSum  AggFunc = "sum"
+	AggLast AggFunc = "last"
+)
+
+func (f AggFunc) valid() bool {
+	switch f {
+	case AggMean, AggMax, AggMin, AggSum, AggLast:
+		return true
+	}
+	return false
+}
+
+// StringLit întoarce s ca string literal Flux, cu ghilimele.
+func StringLit(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			b.WriteString(`\\`)
+		case '"':
+			b.WriteString(`\"`)
+		case '$':
+			// "${" deschide interpolare în Flux → escape-ul din spec e `\${`.
+			if i+1 < len(s) && s[i+1] == '{' {
+				b.WriteString(`\${`)
+				i++
+			} else {
+				b.WriteByte(c)
+			}
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// DurationLit — durată pozitivă ca literal Flux în secunde ("300s").
+func DurationLit(d time.Duration) (string, error) {
+	if d < time.Second {
+		return "", fmt.Errorf("flux: duration %s below 1s", d)
+	}
+	return strconv.FormatInt(int64(d/time.Second), 10) + "s", nil
+}
+
+// TimeLit — timestamp ca literal Flux RFC3339 (UTC).
+func TimeLit(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// ParseRelativeRange convertește "-5m" / "5m" / "-2d" într-o durată pozitivă.
+func ParseRelativeRange(s string) (time.Duration, error) {
+	if !rangeRe.MatchString(s) {
+		return 0, fmt.Errorf("invalid range %q (expected like -5m, -1h, -2d)", s)
+	}
+	s = strings.TrimPrefix(s, "-")
+	n, _ := strconv.ParseInt(s[:len(s)-1], 10, 64)
+	unit := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour}[s[len(s)-1]]
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid range %q: must be non-zero", s)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// Pred — un predicat din filter(fn: (r) => …), deja escapat.
+type Pred struct {
+	expr string
+	err  error
+}
+
+// Eq — r.<col> == "<val>".
+func Eq(col, val string) Pred {
+	if !identRe.MatchString(col) {
+		return Pred{err: fmt.Errorf("flux: invalid column %q", col)}
+	}
+	return Pred{expr: "r." + col + " == " + StringLit(val)}
+}
+
+// In — (r.<col> == "a" or r.<col> == "b" …); lista goală e o eroare, nu "true".
+func In(col string, vals []string) Pred {
+	if len(vals) == 0 {
+		return Pred{err: fmt.Errorf("flux: empty value list for %q", col)}
+	}
+	parts := make([]string, 0, len(vals))
+	for _, v := range vals {
+		p := Eq(col, v)
+		if p.err != nil {
+			return p
+		}
+		parts = append(parts, p.expr)
+	}
+	return Pred{expr: "(" + strings.Join(parts, " or ") + ")"}
+}
+
+// Range — fereastra de timp: relativă la now (Since) sau absolută (Between).
+type Range struct {
+	since       time.Duration
+	start, stop time.Time
+}
+
+// Since — range(start: -d).
+func Since(d time.Duration) Range { return Range{since: d} }
+
+// Between — range(start: a, stop: b).
+func Between(start, stop time.Time) Range { return Range{start: start, stop: stop} }
+
+// Query — pipeline Flux construit pas cu pas; prima eroare oprește construcția.
+type Query struct {
+	steps []string
+	err   error
+}
+
+// DeviceQuery pornește un query pe measurement-ul "devices" al unui tenant:
+//
+//	from(bucket) |> range(…) |> filter(r._measurement == "devices" and r.tenant_id == "N")
+func DeviceQuery(bucket string, tenantID int64, rng Range) *Query {
+	q := &Query{}
+	if tenantID <= 0 {
+		q.err = fmt.Errorf("flux: tenant_id required")
+		return q
+	}
+	if bucket == "" {
+		q.err = fmt.Errorf("flux: bucket required")
+		return q
+	}
+	q.steps = append(q.steps, "from(bucket: "+StringLit(bucket)+")")
+	switch {
+	case rng.since > 0:
+		dur, err := DurationLit(rng.since)
+		if err != nil {
+			q.err = err
+			return q
+		}
+		q.steps = append(q.steps, "range(start: -"+dur+")")
+	case !rng.start.IsZero() && rng.stop.After(rng.start):
+		q.steps = append(q.steps, "range(start: "+TimeLit(rng.start)+", stop: "+TimeLit(rng.stop)+")")
+	default:
+		q.err = fmt.Errorf("flux: invalid range")
+		return q
+	}
+	return q.Filter(Eq("_measurement", "devices"), Eq("tenant_id", strconv.FormatInt(tenantID, 10)))
+}
+
+// Filter adaugă filter(fn: (r) => p1 and p2 …).
+func (q *Query) Filter(preds ...Pred) *Query {
+	if q.err != nil {
+		return q
+	}
+	parts := make([]string, 0, len(preds))
+	for _, p := range preds {
+		if p.err != nil {
+			q.err = p.err
+			return q
+		}
+		parts = append(parts, p.expr)
+	}
+	if len(parts) > 0 {
+		q.steps = append(q.steps, "filter(fn: (r) => "+strings.Join(parts, " and ")+")")
+	}
+	return q
+}
+
+// AggregateWindow adaugă aggregateWindow(every, fn, createEmpty: false).
+func (q *Query) AggregateWindow(every time.Duration, fn AggFunc) *Query {
+	if q.err != nil {
+		return q
+	}
+	if !fn.valid() {
+		q.err = fmt.Errorf("flux: invalid aggregate fn %q", fn)
+		return q
+	}
+	dur, err := DurationLit(every)
+	if err != nil {
+		q.err = err
+		return q
+	}
+	q.steps = append(q.steps, "aggregateWindow(every: "+dur+", fn: "+string(fn)+", createEmpty: false)")
+	return q
+}
+
+// Last adaugă last().
+func (q *Query) Last() *Query {
+	if q.err == nil {
+		q.steps = append(q.steps, "last()")
+	}
+	return q
+}
+
+// Group adaugă group(columns: […]) — o singură tabelă per combinație, oricâte
+// tag-uri în plus ar avea punctele (ex. dd_id, scris doar de ingest-ul nou).
+func (q *Query) Group(cols ...string) *Query {
+	return q.columnsStep("group", cols)
+}
+
+// Sort adaugă sort(columns: […]).
+func (q *Query) Sort(cols ...string) *Query {
+	return q.columnsStep("sort", cols)
+}
+
+// Keep adaugă keep(columns: […]).
+func (q *Query) Keep(cols ...string) *Query {
+	return q.columnsStep("keep", cols)
+}
+
+func (q *Query) columnsStep(fn string, cols []string) *Query {
+	if q.err != nil {
+		return q
+	}
+	lits := make([]string, 0, len(cols))
+	for _, c := range cols {
+		if !identRe.MatchString(c) {
+			q.err = fmt.Errorf("flux: invalid column %q", c)
+			return q
+		}
+		lits = append(lits, StringLit(c))
+	}
+	q.steps = append(q.steps, fn+"(columns: ["+strings.Join(lits, ", ")+"])")
+	return q
+}
+
+// Build întoarce textul query-ului sau prima eroare de construcție.
+func (q *Query) Build() (string, error) {
+	if q.err != nil {
+		return "", q.err
+	}
+	return strings.Join(q.steps, "\n  |> "), nil
+}
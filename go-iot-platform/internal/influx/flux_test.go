@@ -0,0 +1,191 @@
+package influx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// splitFlux separă un query în scheletul de cod (literalii înlocuiți cu "?") și
+// valorile decodate ale string literal-ilor. Dacă scheletul e identic pentru
+// orice input, input-ul nu a putut ieși din literal.
+func splitFlux(t testing.TB, q string) (string, []string) {
+	t.Helper()
+	var skel strings.Builder
+	var lits []string
+	for i := 0; i < len(q); i++ {
+		if q[i] != '"' {
+			skel.WriteByte(q[i])
+			continue
+		}
+		var lit strings.Builder
+		i++
+		for ; i < len(q) && q[i] != '"'; i++ {
+			switch {
+			case q[i] == '\\' && i+1 < len(q):
+				i++
+				switch q[i] {
+				case 'n':
+					lit.WriteByte('\n')
+				case 'r':
+					lit.WriteByte('\r')
+				case 't':
+					lit.WriteByte('\t')
+				case '\\', '"':
+					lit.WriteByte(q[i])
+				case '$':
+					if i+1 >= len(q) || q[i+1] != '{' {
+						t.Fatalf("invalid escape \\$ at %d in %s", i, q)
+					}
+					lit.WriteString("${")
+					i++
+				default:
+					t.Fatalf("invalid escape \\%c at %d in %s", q[i], i, q)
+				}
+			case q[i] == '$' && i+1 < len(q) && q[i+1] == '{':
+				t.Fatalf("unescaped interpolation at %d in %s", i, q)
+			default:
+				lit.WriteByte(q[i])
+			}
+		}
+		if i >= len(q) {
+			t.Fatalf("unterminated string literal in %s", q)
+		}
+		skel.WriteString(`"?"`)
+		lits = append(lits, lit.String())
+	}
+	return skel.String(), lits
+}
+
+func TestStringLit(t *testing.T) {
+	cases := map[string]string{
+		`dev-1`:          `"dev-1"`,
+		`a"b`:            `"a\"b"`,
+		`a\b`:            `"a\\b"`,
+		`${r.tenant_id}`: `"\${r.tenant_id}"`,
+		`cost $5`:        `"cost $5"`,
+		"line\nbreak":    `"line\nbreak"`,
+	}
+	for in, want := range cases {
+		if got := StringLit(in); got != want {
+			t.Errorf("StringLit(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestDeviceQuery(t *testing.T) {
+	q, err := DeviceQuery("iot-pro", 2, Since(5*time.Minute)).
+		Filter(Eq("device", "dev-1"), Eq("_field", "power")).
+		Last().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `from(bucket: "iot-pro")
+  |> range(start: -300s)
+  |> filter(fn: (r) => r._measurement == "devices" and r.tenant_id == "2")
+  |> filter(fn: (r) => r.device == "dev-1" and r._field == "power")
+  |> last()`
+	if q != want {
+		t.Errorf("query:\n%s\nwant:\n%s", q, want)
+	}
+}
+
+func TestDeviceQueryErrors(t *testing.T) {
+	cases := map[string]*Query{
+		"no tenant":      DeviceQuery("b", 0, Since(time.Minute)),
+		"no bucket":      DeviceQuery("", 2, Since(time.Minute)),
+		"no range":       DeviceQuery("b", 2, Range{}),
+		"bad column":     DeviceQuery("b", 2, Since(time.Minute)).Filter(Eq(`device == "x" or true`, "x")),
+		"empty in":       DeviceQuery("b", 2, Since(time.Minute)).Filter(In("device", nil)),
+		"bad fn":         DeviceQuery("b", 2, Since(time.Minute)).AggregateWindow(time.Minute, "yield"),
+		"sub-second win": DeviceQuery("b", 2, Since(time.Minute)).AggregateWindow(time.Millisecond, AggMean),
+		"bad keep":       DeviceQuery("b", 2, Since(time.Minute)).Keep("_time", `"x"`),
+	}
+	for name, q := range cases {
+		if _, err := q.Build(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseRelativeRange(t *testing.T) {
+	cases := map[string]time.Duration{"-5m": 5 * time.Minute, "1h": time.Hour, "-2d": 48 * time.Hour, "-30s": 30 * time.Second}
+	for in, want := range cases {
+		if got, err := ParseRelativeRange(in); err != nil || got != want {
+			t.Errorf("%q → %s, %v; want %s", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "-0m", "5min", `-5m) |> drop(`} {
+		if _, err := ParseRelativeRange(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+// FuzzDeviceQuery — device / field arbitrare nu schimbă structura query-ului
+// și nu pot atinge filtrul de tenant.
+func FuzzDeviceQuery(f *testing.F) {
+	for _, seed := range [][2]string{
+		{"dev-1", "power"},
+		{`x" or r.tenant_id != "2`, "power"},
+		{`x\`, `" or true or "`},
+		{"${r.tenant_id}", "$"},
+		{"a\") |> drop(columns: [\"tenant_id\"]) //", "\n"},
+		{`\"`, `\\"`},
+	} {
+		f.Add(seed[0], seed[1])
+	}
+	build := func(t testing.TB, device, field string) string {
+		q, err := DeviceQuery("iot-free", 2, Since(5*time.Minute)).
+			Filter(Eq("device", device), Eq("_field", field)).
+			Last().
+			Build()
+		if err != nil {
+			t.Fatalf("build: %v", err)
+		}
+		return q
+	}
+	wantSkel, _ := splitFlux(f, build(f, "d", "f"))
+
+	f.Fuzz(func(t *testing.T, device, field string) {
+		skel, lits := splitFlux(t, build(t, device, field))
+		if skel != wantSkel {
+			t.Fatalf("query structure changed for device=%q field=%q:\n%s", device, field, skel)
+		}
+		want := []string{"iot-free", "devices", "2", device, field}
+		if !reflect.DeepEqual(lits, want) {
+			t.Fatalf("literals=%q want %q", lits, want)
+		}
+	})
+}
+
+// FuzzSeriesQuery — la fel pentru filtrul In (series cu mai multe device-uri).
+func FuzzSeriesQuery(f *testing.F) {
+	f.Add("a", `b" or r.tenant_id == "3`, "power")
+	f.Add("${x}", "\\", `"`)
+	start := time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC)
+	build := func(t testing.TB, d1, d2, field string) string {
+		q, err := buildSeriesFlux("iot-pro", SeriesQuery{
+			TenantID: 7, Devices: []string{d1, d2}, Fields: []string{field},
+			Start: start, Stop: start.Add(time.Hour), Window: time.Minute, Fn: "mean",
+		})
+		if err != nil {
+			t.Fatalf("build: %v", err)
+		}
+		return q
+	}
+	wantSkel, _ := splitFlux(f, build(f, "a", "b", "c"))
+
+	f.Fuzz(func(t *testing.T, d1, d2, field string) {
+		skel, lits := splitFlux(t, build(t, d1, d2, field))
+		if skel != wantSkel {
+			t.Fatalf("query structure changed:\n%s", skel)
+		}
+		// bucket, measurement, tenant, d1, d2, field, apoi coloanele din keep()
+		if len(lits) < 6 || lits[2] != "7" || lits[3] != d1 || lits[4] != d2 || lits[5] != field {
+			t.Fatalf("literals=%q", lits)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package influx
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/query"
+)
+
+// MaxLatestPairs — limită de (device, field) per request batch.
+const MaxLatestPairs = 200
+
+// LatestQuery — ultimele valori pentru mai multe device-uri; fiecare device
+// are lista lui de field-uri.
+type LatestQuery struct {
+	TenantID int64
+	Plan     string
+	Fields   map[string][]string // device → fields
+	Since    time.Duration       // 0 = DefaultRange
+}
+
+// LatestValue — o valoare plus timestamp-ul ei (UI-ul arată vechimea).
+// Value e float64 pentru câmpurile numerice / bool, string altfel.
+type LatestValue struct {
+	Value interface{} `json:"value"`
+	Time  time.Time   `json:"time"`
+}
+
+// Latest rulează un singur query per bucket — last() pe fiecare serie a
+// device-urilor cerute — și întoarce device → field → valoare. Perechile fără
+// date lipsesc din map. Între bucket-uri câștigă punctul cel mai nou.
+func (c *QueryClient) Latest(ctx context.Context, q LatestQuery) (map[string]map[string]LatestValue, error) {
+	if q.TenantID <= 0 {
+		return nil, fmt.Errorf("tenant_id required")
+	}
+	if q.Since <= 0 {
+		q.Since, _ = ParseRelativeRange(DefaultRange)
+	}
+	var devices, fields []string
+	seenField := map[string]bool{}
+	pairs := 0
+	for device, fs := range q.Fields {
+		if device == "" || len(device) > maxIdentLen {
+			return nil, fmt.Errorf("invalid device name %q", device)
+		}
+		devices = append(devices, device)
+		for _, f := range fs {
+			if f == "" || len(f) > maxIdentLen {
+				return nil, fmt.Errorf("invalid field name %q", f)
+			}
+			pairs++
+			if !seenField[f] {
+				seenField[f] = true
+				fields = append(fields, f)
+			}
+		}
+	}
+	if pairs == 0 {
+		return nil, fmt.Errorf("at least one device and one field required")
+	}
+	if pairs > MaxLatestPairs {
+		return nil, fmt.Errorf("too many device/field pairs: %d (max %d)", pairs, MaxLatestPairs)
+	}
+	wanted := make(map[string]map[string]bool, len(q.Fields))
+	for device, fs := range q.Fields {
+		wanted[device] = map[string]bool{}
+		for _, f := range fs {
+			wanted[device][f] = true
+		}
+	}
+
+	// Filtrul e device IN (…) and _field IN (…); combinațiile necerute
+	// (alt device cu același field) sunt aruncate la citire.
+	out := map[string]map[string]LatestValue{}
+	for _, bucket := range bucketsToTry(q.Plan) {
+		flux, err := DeviceQuery(bucket, q.TenantID, Since(q.Since)).
+			Filter(In("device", devices), In("_field", fields)).
+			Last().
+			Build()
+		if err != nil {
+			return nil, err
+		}
+		err = c.run(ctx, bucket, flux, func(rec *query.FluxRecord) {
+			device, _ := rec.ValueByKey("device").(string)
+			field := rec.Field()
+			if !wanted[device][field] {
+				return
+			}
+			v, ok := latestValue(rec.Value())
+			if !ok {
+				return
+			}
+			if prev, seen := out[device][field]; seen && !rec.Time().After(prev.Time) {
+				return
+			}
+			if out[device] == nil {
+				out[device] = map[string]LatestValue{}
+			}
+			out[device][field] = LatestValue{Value: v, Time: rec.Time()}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func latestValue(v interface{}) (interface{}, bool) {
+	if f, ok := toFloat(v); ok {
+		return f, true
+	}
+	if s, ok := v.(string); ok {
+		return s, true
+	}
+	return nil, false
+}
+
+// Snapshot — ultima valoare a fiecărui field al unui device (toate field-urile,
+// cu tag-ul source), pentru vederea pe capabilities. Un record per field; între
+// bucket-uri câștigă punctul cel mai nou. since 0 = DefaultRange.
+func (c *QueryClient) Snapshot(ctx context.Context, tenantID int64, plan, device string, since time.Duration) ([]ExportRecord, error) {
+	if device == "" || len(device) > maxIdentLen {
+		return nil, fmt.Errorf("invalid device name %q", device)
+	}
+	if since <= 0 {
+		since, _ = ParseRelativeRange(DefaultRange)
+	}
+	byField := map[string]int{}
+	var out []ExportRecord
+	for _, bucket := range bucketsToTry(plan) {
+		flux, err := buildSnapshotFlux(bucket, tenantID, device, since)
+		if err != nil {
+			return nil, err
+		}
+		err = c.run(ctx, bucket, flux, func(rec *query.FluxRecord) {
+			v, ok := latestValue(rec.Value())
+			if !ok {
+				return
+			}
+			source, _ := rec.ValueByKey("source").(string)
+			ddID, _ := rec.ValueByKey("dd_id").(string)
+			r := ExportRecord{Time: rec.Time(), Device: device, Source: source, DD: ddID, Field: rec.Field(), Value: v}
+			if i, seen := byField[r.Field]; seen {
+				if r.Time.After(out[i].Time) {
+					out[i] = r
+				}
+				return
+			}
+			byField[r.Field] = len(out)
+			out = append(out, r)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func buildSnapshotFlux(bucket string, tenantID int64, device string, since time.Duration) (string, error) {
+	return DeviceQuery(bucket, tenantID, Since(since)).
+		Filter(Eq("device", device)).
+		Last().
+		Build()
+}
@@ -0,0 +1,205 @@
+package influx
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	influxdb2api "github.com/influxdata/influxdb-client-go/v2/api"
+	influxhttp "github.com/influxdata/influxdb-client-go/v2/api/http"
+	"github.com/influxdata/influxdb-client-go/v2/api/query"
+)
+
+// Erorile de citire, ca API-ul să poată răspunde corect:
+//
+//	ErrNoData      → 404 (query-ul a mers, dar nu există puncte în range)
+//	ErrUnavailable → 503 (Influx down / timeout / eroare de server)
+//
+// Erorile de construcție a query-ului (flux.go) rămân erori simple → 400.
+var (
+	ErrNoData      = errors.New("influx: no data")
+	ErrUnavailable = errors.New("influx: unavailable")
+)
+
+// DefaultQueryTimeout — plafonul per query; deadline-ul din ctx-ul request-ului
+// câștigă dacă e mai scurt.
+const DefaultQueryTimeout = 10 * time.Second
+
+// QueryClient — client de citire long-lived, partajat între request-uri:
+// un singur influxdb2.Client cu transport HTTP keep-alive (conexiuni refolosite),
+// în loc de NewClient / Close la fiecare request.
+type QueryClient struct {
+	client  influxdb2.Client
+	qapi    influxdb2api.QueryAPI
+	timeout time.Duration
+}
+
+// NewQueryClient creează clientul; timeout <= 0 → DefaultQueryTimeout.
+func NewQueryClient(url, token, org string, timeout time.Duration) *QueryClient {
+	if timeout <= 0 {
+		timeout = DefaultQueryTimeout
+	}
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		DialContext:         (&net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+		MaxIdleConns:        64,
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	// Fără http.Client.Timeout: timeout-ul vine din ctx-ul fiecărui query.
+	opts := influxdb2.DefaultOptions().SetHTTPClient(&http.Client{Transport: transport})
+	client := influxdb2.NewClientWithOptions(url, token, opts)
+	return &QueryClient{client: client, qapi: client.QueryAPI(org), timeout: timeout}
+}
+
+// Close închide conexiunile idle.
+func (c *QueryClient) Close() { c.client.Close() }
+
+var (
+	defaultQueryOnce   sync.Once
+	defaultQueryClient *QueryClient
+)
+
+// DefaultQueryClient — clientul partajat al procesului, din INFLUX_URL /
+// INFLUX_TOKEN / INFLUX_ORG; INFLUX_QUERY_TIMEOUT (ex: "5s") suprascrie timeout-ul.
+func DefaultQueryClient() *QueryClient {
+	defaultQueryOnce.Do(func() {
+		timeout, _ := time.ParseDuration(os.Getenv("INFLUX_QUERY_TIMEOUT"))
+		defaultQueryClient = NewQueryClient(URL, Token, Org, timeout)
+	})
+	return defaultQueryClient
+}
+
+// run execută un query pe un bucket și apelează fn pentru fiecare record.
+// Un bucket inexistent nu e o eroare (zero records) — bucket-urile legacy / ale
+// altor planuri pot lipsi într-un deployment.
+func (c *QueryClient) run(ctx context.Context, bucket, flux string, fn func(*query.FluxRecord)) error {
+	return c.stream(ctx, bucket, flux, c.timeout, func(rec *query.FluxRecord) error {
+		fn(rec)
+		return nil
+	})
+}
+
+// stream — ca run, cu timeout explicit (export-urile durează mai mult decât un
+// query de dashboard); o eroare din fn oprește citirea și e întoarsă ca atare.
+func (c *QueryClient) stream(ctx context.Context, bucket, flux string, timeout time.Duration, fn func(*query.FluxRecord) error) (err error) {
+	qctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	defer func() { queryStats.observe(bucket, time.Since(start), err) }()
+
+	result, err := c.qapi.Query(qctx, flux)
+	if err != nil {
+		if isBucketNotFound(err) {
+			return nil
+		}
+		return c.classify(ctx, err)
+	}
+	defer result.Close()
+	for result.Next() {
+		if err := fn(result.Record()); err != nil {
+			return err
+		}
+	}
+	if err := result.Err(); err != nil {
+		return c.classify(ctx, err)
+	}
+	return nil
+}
+
+// classify: clientul HTTP a plecat (ctx anulat de caller) → ctx.Err() neîmpachetat;
+// orice altceva (timeout-ul nostru, conexiune refuzată, 5xx) → ErrUnavailable.
+func (c *QueryClient) classify(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
+
+// isBucketNotFound — doar 404-ul pentru bucket inexistent (planul nu are încă
+// bucket); un 404 pentru org greșit sau de la un proxy rămâne eroare. Mesajele
+// Influx 2.x: `bucket "iot-pro" not found`, `failed to initialize execute
+// state: could not find bucket "iot-pro"`.
+func isBucketNotFound(err error) bool {
+	var he *influxhttp.Error
+	if !errors.As(err, &he) || he.StatusCode != http.StatusNotFound {
+		return false
+	}
+	msg := strings.ToLower(he.Message)
+	return strings.Contains(msg, "bucket") &&
+		(strings.Contains(msg, "not found") || strings.Contains(msg, "could not find"))
+}
+
+// ─── metrici de latență per bucket (expvar "influx_query") ───
+
+// latencyBoundsMs — limitele histogramei (cumulative, ca la Prometheus "le").
+var latencyBoundsMs = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+// BucketQueryStats — contoare per bucket, expuse pe /debug/vars.
+type BucketQueryStats struct {
+	Count   int64            `json:"count"`
+	Errors  int64            `json:"errors"`
+	TotalMs float64          `json:"total_ms"`
+	MaxMs   float64          `json:"max_ms"`
+	Le      map[string]int64 `json:"le_ms"`
+}
+
+type queryStatsRegistry struct {
+	mu      sync.Mutex
+	buckets map[string]*BucketQueryStats
+}
+
+var queryStats = &queryStatsRegistry{buckets: map[string]*BucketQueryStats{}}
+
+func init() {
+	expvar.Publish("influx_query", expvar.Func(func() interface{} { return QueryStats() }))
+}
+
+func (r *queryStatsRegistry) observe(bucket string, d time.Duration, err error) {
+	ms := float64(d.Microseconds()) / 1000
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.buckets[bucket]
+	if s == nil {
+		s = &BucketQueryStats{Le: map[string]int64{}}
+		r.buckets[bucket] = s
+	}
+	s.Count++
+	if err != nil {
+		s.Errors++
+	}
+	s.TotalMs += ms
+	if ms > s.MaxMs {
+		s.MaxMs = ms
+	}
+	for _, b := range latencyBoundsMs {
+		if ms <= float64(b) {
+			s.Le[fmt.Sprint(b)]++
+		}
+	}
+}
+
+// QueryStats întoarce o copie a contoarelor per bucket.
+func QueryStats() map[string]BucketQueryStats {
+	queryStats.mu.Lock()
+	defer queryStats.mu.Unlock()
+	out := make(map[string]BucketQueryStats, len(queryStats.buckets))
+	for b, s := range queryStats.buckets {
+		cp := *s
+		cp.Le = make(map[string]int64, len(s.Le))
+		for k, v := range s.Le {
+			cp.Le[k] = v
+		}
+		out[b] = cp
+	}
+	return out
+}
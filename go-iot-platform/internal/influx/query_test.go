@@ -0,0 +1,270 @@
+package influx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeInflux răspunde la /api/v2/query per bucket: bucket-ul e extras din
+// textul query-ului (from(bucket: "…")).
+func fakeInflux(t *testing.T, handler func(bucket string, w http.ResponseWriter)) (*QueryClient, *int32) {
+	t.Helper()
+	var conns int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query string `json:"query"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		i := strings.Index(body.Query, `from(bucket: "`)
+		if i < 0 {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		rest := body.Query[i+len(`from(bucket: "`):]
+		handler(rest[:strings.Index(rest, `"`)], w)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, s http.ConnState) {
+		if s == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	t.Cleanup(srv.Close)
+	c := NewQueryClient(srv.URL, "token", "org", time.Second)
+	t.Cleanup(c.Close)
+	return c, &conns
+}
+
+const csvHeader = "#datatype,string,long,dateTime:RFC3339,double,string,string\n" +
+	"#group,false,false,false,false,true,true\n" +
+	"#default,_result,,,,,\n" +
+	",result,table,_time,_value,_field,device\n"
+
+func csvRow(ts, value, field, device string) string {
+	return ",,0," + ts + "," + value + "," + field + "," + device + "\n"
+}
+
+func writeCSV(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "text/csv")
+	_, _ = w.Write([]byte(body))
+}
+
+func TestFieldForDevice(t *testing.T) {
+	c, conns := fakeInflux(t, func(bucket string, w http.ResponseWriter) {
+		switch bucket {
+		case "iot-pro":
+			writeCSV(w, "") // fără date în bucket-ul planului
+		case "iot-enterprise":
+			w.WriteHeader(http.StatusNotFound) // bucket inexistent → sărit
+			_, _ = w.Write([]byte(`{"code":"not found","message":"failed to initialize execute state: could not find bucket \"iot-enterprise\""}`))
+		case "iot-free":
+			writeCSV(w, csvHeader+csvRow("2026-05-13T10:00:00Z", "42.5", "power", "dev-1"))
+		}
+	})
+	for i := 0; i < 3; i++ {
+		v, err := c.FieldForDevice(context.Background(), "dev-1", "power", "-5m", 2, "pro")
+		if err != nil || v != 42.5 {
+			t.Fatalf("got %v, %v; want 42.5", v, err)
+		}
+	}
+	if n := atomic.LoadInt32(conns); n != 1 {
+		t.Errorf("connections opened = %d, want 1 (keep-alive reuse)", n)
+	}
+	st := QueryStats()["iot-free"]
+	if st.Count < 3 || st.Le["10000"] < 3 {
+		t.Errorf("stats for iot-free = %+v", st)
+	}
+}
+
+func TestFieldForDeviceNewestSeries(t *testing.T) {
+	// Două serii pentru același device/field (înainte și după tag-ul dd_id):
+	// last() dă un rând per serie, primul în CSV fiind cel vechi.
+	c, _ := fakeInflux(t, func(_ string, w http.ResponseWriter) {
+		writeCSV(w, csvHeader+
+			",,0,2026-05-13T09:00:00Z,10,power,dev-1\n"+
+			",,1,2026-05-13T10:00:00Z,42.5,power,dev-1\n")
+	})
+	v, err := c.FieldForDevice(context.Background(), "dev-1", "power", "-5m", 2, "free")
+	if err != nil || v != 42.5 {
+		t.Fatalf("got %v, %v; want the newest value 42.5", v, err)
+	}
+}
+
+func TestFieldForDeviceNoData(t *testing.T) {
+	c, _ := fakeInflux(t, func(string, http.ResponseWriter) {})
+	_, err := c.FieldForDevice(context.Background(), "dev-1", "power", "-5m", 2, "free")
+	if !errors.Is(err, ErrNoData) {
+		t.Fatalf("err = %v, want ErrNoData", err)
+	}
+}
+
+func TestFieldForDeviceUnavailable(t *testing.T) {
+	c, _ := fakeInflux(t, func(bucket string, w http.ResponseWriter) {
+		if bucket == "iot-free" {
+			http.Error(w, `{"code":"internal error","message":"boom"}`, http.StatusInternalServerError)
+			return
+		}
+		writeCSV(w, csvHeader+csvRow("2026-05-13T10:00:00Z", "1", "power", "dev-1"))
+	})
+	// Eroarea pe bucket-ul prioritar nu e mascată de datele din alt bucket.
+	_, err := c.FieldForDevice(context.Background(), "dev-1", "power", "-5m", 2, "free")
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+	if QueryStats()["iot-free"].Errors == 0 {
+		t.Error("error not counted in stats")
+	}
+
+	// 404 care nu e "bucket not found" (org greșit, proxy) nu e tratat ca bucket gol.
+	wrongOrg, _ := fakeInflux(t, func(_ string, w http.ResponseWriter) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"code":"not found","message":"organization name \"org\" not found"}`))
+	})
+	if _, err := wrongOrg.FieldForDevice(context.Background(), "dev-1", "power", "-5m", 2, "free"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("404 org not found: err = %v, want ErrUnavailable", err)
+	}
+
+	down := NewQueryClient("http://127.0.0.1:1", "t", "o", time.Second)
+	defer down.Close()
+	if _, err := down.FieldForDevice(context.Background(), "d", "f", "", 2, "free"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("connection refused: err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	c, _ := fakeInflux(t, func(_ string, w http.ResponseWriter) {
+		time.Sleep(300 * time.Millisecond)
+		writeCSV(w, "")
+	})
+	c.timeout = 50 * time.Millisecond
+	if _, err := c.FieldForDevice(context.Background(), "d", "f", "", 2, "free"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("timeout: err = %v, want ErrUnavailable", err)
+	}
+
+	// Ctx-ul caller-ului anulat (client HTTP plecat) → ctx.Err(), nu 503.
+	c.timeout = time.Second
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.FieldForDevice(ctx, "d", "f", "", 2, "free"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("canceled: err = %v, want context.Canceled", err)
+	}
+}
+
+func TestSeriesMergeAndError(t *testing.T) {
+	start := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+	q := SeriesQuery{
+		TenantID: 2, Plan: "pro", Devices: []string{"dev-1"}, Fields: []string{"power"},
+		Start: start, Stop: start.Add(time.Hour), Window: time.Minute, Fn: "mean",
+	}
+	c, _ := fakeInflux(t, func(bucket string, w http.ResponseWriter) {
+		switch bucket {
+		case "iot-pro":
+			writeCSV(w, csvHeader+csvRow("2026-05-13T10:01:00Z", "2", "power", "dev-1"))
+		case "iot-free":
+			writeCSV(w, csvHeader+
+				csvRow("2026-05-13T10:00:00Z", "1", "power", "dev-1")+
+				csvRow("2026-05-13T10:01:00Z", "99", "power", "dev-1"))
+		}
+	})
+	out, err := c.Series(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out) != 1 || len(out[0].Value) != 2 || out[0].Value[0] != 1 || out[0].Value[1] != 2 {
+		t.Fatalf("series = %+v", out)
+	}
+
+	bad, _ := fakeInflux(t, func(bucket string, w http.ResponseWriter) {
+		if bucket == "iot-enterprise" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeCSV(w, "")
+	})
+	if _, err := bad.Series(context.Background(), q); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	c, _ := fakeInflux(t, func(bucket string, w http.ResponseWriter) {
+		switch bucket {
+		case "iot-pro":
+			writeCSV(w, csvHeader+
+				csvRow("2026-05-13T10:00:00Z", "230.1", "voltage", "dev-1")+
+				csvRow("2026-05-13T10:00:05Z", "5", "power", "dev-2")+
+				csvRow("2026-05-13T10:00:05Z", "7", "voltage", "dev-2")) // necerut
+		case "iot-free":
+			writeCSV(w, csvHeader+
+				csvRow("2026-05-13T09:00:00Z", "229", "voltage", "dev-1")+ // mai vechi
+				csvRow("2026-05-13T10:00:09Z", "42", "power", "dev-1"))
+		}
+	})
+	out, err := c.Latest(context.Background(), LatestQuery{
+		TenantID: 2, Plan: "pro",
+		Fields: map[string][]string{"dev-1": {"power", "voltage"}, "dev-2": {"power", "energy"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := out["dev-1"]["voltage"]; v.Value != 230.1 || v.Time.Minute() != 0 || v.Time.Hour() != 10 {
+		t.Errorf("dev-1 voltage = %+v", v)
+	}
+	if v := out["dev-1"]["power"]; v.Value != 42.0 {
+		t.Errorf("dev-1 power = %+v", v)
+	}
+	if _, ok := out["dev-2"]["voltage"]; ok {
+		t.Error("unrequested pair dev-2/voltage returned")
+	}
+	if _, ok := out["dev-2"]["energy"]; ok {
+		t.Error("dev-2/energy has no data, should be absent")
+	}
+
+	if _, err := c.Latest(context.Background(), LatestQuery{TenantID: 2, Fields: map[string][]string{"d": nil}}); err == nil {
+		t.Error("expected error for empty field list")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	const header = "#datatype,string,long,dateTime:RFC3339,double,string,string,string\n" +
+		"#group,false,false,false,false,true,true,true\n" +
+		"#default,_result,,,,,,\n" +
+		",result,table,_time,_value,_field,device,source\n"
+	c, _ := fakeInflux(t, func(bucket string, w http.ResponseWriter) {
+		switch bucket {
+		case "iot-pro":
+			writeCSV(w, header+
+				",,0,2026-05-13T10:00:00Z,1500,nousat_power,plug,nousat\n"+
+				",,1,2026-05-13T10:00:00Z,230,nousat_voltage,plug,nousat\n")
+		case "iot-free":
+			writeCSV(w, header+
+				",,0,2026-05-13T11:00:00Z,1600,nousat_power,plug,nousat\n"+ // mai nou
+				",,1,2026-05-13T09:00:00Z,229,nousat_voltage,plug,nousat\n")
+		}
+	})
+	recs, err := c.Snapshot(context.Background(), 2, "pro", "plug", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]ExportRecord{}
+	for _, r := range recs {
+		got[r.Field] = r
+	}
+	if len(recs) != 2 || got["nousat_power"].Value != 1600.0 || got["nousat_voltage"].Value != 230.0 {
+		t.Errorf("snapshot = %+v", recs)
+	}
+	if got["nousat_power"].Source != "nousat" || got["nousat_power"].Device != "plug" {
+		t.Errorf("tags = %+v", got["nousat_power"])
+	}
+	if _, err := c.Snapshot(context.Background(), 2, "pro", "", 0); err == nil {
+		t.Error("expected error for empty device")
+	}
+}
@@ -0,0 +1,208 @@
+package influx
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/query"
+)
+
+// maxIdentLen — limită de lungime pentru device / field; escaparea e în flux.go.
+const maxIdentLen = 128
+
+// SeriesLimits — limitele de downsampling per plan: un chart nu poate cere mai
+// mult de MaxPoints puncte per serie și nici o fereastră mai lungă decât
+// retention-ul bucket-ului planului.
+type SeriesLimits struct {
+	MaxRange  time.Duration
+	MaxPoints int
+	MaxSeries int // devices × fields
+	MinWindow time.Duration
+}
+
+// LimitsForPlan — aliniat cu retention-ul din pool.go (7 / 90 / 730 zile).
+func LimitsForPlan(plan string) SeriesLimits {
+	switch plan {
+	case "enterprise":
+		return SeriesLimits{MaxRange: 730 * 24 * time.Hour, MaxPoints: 5000, MaxSeries: 50, MinWindow: 10 * time.Second}
+	case "pro":
+		return SeriesLimits{MaxRange: 90 * 24 * time.Hour, MaxPoints: 2000, MaxSeries: 20, MinWindow: 30 * time.Second}
+	default:
+		return SeriesLimits{MaxRange: 7 * 24 * time.Hour, MaxPoints: 500, MaxSeries: 10, MinWindow: time.Minute}
+	}
+}
+
+// SeriesQuery — un query de chart: N device-uri × M field-uri, agregat pe Window.
+type SeriesQuery struct {
+	TenantID int64
+	Plan     string
+	Devices  []string
+	Fields   []string
+	Start    time.Time
+	Stop     time.Time
+	Window   time.Duration // 0 = ales automat din MaxPoints
+	Fn       string
+}
+
+// Series — o serie în format columnar (time[i] ↔ value[i]), gata de chart.
+type Series struct {
+	Device string      `json:"device"`
+	Field  string      `json:"field"`
+	Time   []time.Time `json:"time"`
+	Value  []float64   `json:"value"`
+}
+
+// Normalize validează query-ul contra limitelor planului și ajustează Window:
+// fereastra cerută e lărgită (downsampling) dacă ar depăși MaxPoints.
+func (q *SeriesQuery) Normalize(lim SeriesLimits) error {
+	if len(q.Devices) == 0 || len(q.Fields) == 0 {
+		return fmt.Errorf("at least one device and one field required")
+	}
+	if n := len(q.Devices) * len(q.Fields); n > lim.MaxSeries {
+		return fmt.Errorf("too many series: %d (max %d for plan)", n, lim.MaxSeries)
+	}
+	for _, s := range append(append([]string{}, q.Devices...), q.Fields...) {
+		if s == "" || len(s) > maxIdentLen {
+			return fmt.Errorf("invalid device/field name %q", s)
+		}
+	}
+	if q.Fn == "" {
+		q.Fn = "mean"
+	}
+	if !AggFunc(q.Fn).valid() {
+		return fmt.Errorf("invalid fn %q (mean, max, min, sum, last)", q.Fn)
+	}
+	if !q.Stop.After(q.Start) {
+		return fmt.Errorf("stop must be after start")
+	}
+	span := q.Stop.Sub(q.Start)
+	if span > lim.MaxRange {
+		return fmt.Errorf("range %s exceeds plan limit %s", span, lim.MaxRange)
+	}
+
+	minWindow := span / time.Duration(lim.MaxPoints)
+	if minWindow < lim.MinWindow {
+		minWindow = lim.MinWindow
+	}
+	if q.Window < minWindow {
+		q.Window = roundWindow(minWindow)
+	}
+	return nil
+}
+
+// roundWindow rotunjește în sus la o durată "frumoasă" (1s, 5s, 1m, 5m, 1h...)
+// ca punctele să cadă pe granițe predictibile în chart.
+func roundWindow(d time.Duration) time.Duration {
+	steps := []time.Duration{
+		time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second,
+		time.Minute, 5 * time.Minute, 10 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+		time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour,
+	}
+	for _, s := range steps {
+		if d <= s {
+			return s
+		}
+	}
+	days := (d + 24*time.Hour - 1) / (24 * time.Hour)
+	return days * 24 * time.Hour
+}
+
+// buildSeriesFlux construiește query-ul pentru un bucket prin builder-ul tipizat
+// (flux.go) — device-urile / field-urile sunt escapate, nu doar validate.
+//
+// group(device, _field) înainte de agregare: același device poate avea mai
+// multe serii în Influx (tag-uri diferite, ex. dd_id scris doar de la un deploy
+// încolo) și fiecare fereastră trebuie agregată peste toate. După group rândurile
+// nu mai sunt ordonate în timp — contează doar pentru fn=last, singurul caz în
+// care plătim sort-ul.
+func buildSeriesFlux(bucket string, q SeriesQuery) (string, error) {
+	fq := DeviceQuery(bucket, q.TenantID, Between(q.Start, q.Stop)).
+		Filter(In("device", q.Devices), In("_field", q.Fields)).
+		Group("device", "_field")
+	if AggFunc(q.Fn) == AggLast {
+		fq = fq.Sort("_time")
+	}
+	return fq.
+		AggregateWindow(q.Window, AggFunc(q.Fn)).
+		Keep("_time", "_value", "device", "_field").
+		Build()
+}
+
+// QuerySeries — QueryClient.Series prin clientul partajat.
+func QuerySeries(ctx context.Context, q SeriesQuery) ([]Series, error) {
+	return DefaultQueryClient().Series(ctx, q)
+}
+
+// Series rulează query-ul pe toate bucket-urile planului (datele pot fi
+// împărțite între bucket-uri după un upgrade/downgrade) și combină rezultatul;
+// la același timestamp câștigă bucket-ul planului curent. Un bucket care nu
+// răspunde oprește query-ul cu ErrUnavailable — un chart parțial ar induce în eroare.
+func (c *QueryClient) Series(ctx context.Context, q SeriesQuery) ([]Series, error) {
+	if q.TenantID <= 0 {
+		return nil, fmt.Errorf("tenant_id required")
+	}
+
+	type key struct{ device, field string }
+	points := map[key]map[time.Time]float64{}
+	for _, bucket := range bucketsToTry(q.Plan) {
+		flux, err := buildSeriesFlux(bucket, q)
+		if err != nil {
+			return nil, err
+		}
+		err = c.run(ctx, bucket, flux, func(rec *query.FluxRecord) {
+			v, ok := toFloat(rec.Value())
+			if !ok {
+				return
+			}
+			device, _ := rec.ValueByKey("device").(string)
+			k := key{device, rec.Field()}
+			if points[k] == nil {
+				points[k] = map[time.Time]float64{}
+			}
+			if _, seen := points[k][rec.Time()]; !seen {
+				points[k][rec.Time()] = v
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]Series, 0, len(points))
+	for _, device := range q.Devices {
+		for _, field := range q.Fields {
+			pts := points[key{device, field}]
+			s := Series{Device: device, Field: field, Time: []time.Time{}, Value: []float64{}}
+			ts := make([]time.Time, 0, len(pts))
+			for t := range pts {
+				ts = append(ts, t)
+			}
+			sort.Slice(ts, func(i, j int) bool { return ts[i].Before(ts[j]) })
+			for _, t := range ts {
+				s.Time = append(s.Time, t)
+				s.Value = append(s.Value, pts[t])
+			}
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case int64:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case bool:
+		if t {
+			return 1, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,93 @@
+package influx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeriesNormalizeDownsamples(t *testing.T) {
+	now := time.Date(2026, 5, 13, 12, 0, 0, 0, time.UTC)
+	q := SeriesQuery{
+		Devices: []string{"dev-1"}, Fields: []string{"power"},
+		Start: now.Add(-7 * 24 * time.Hour), Stop: now,
+		Window: time.Minute,
+	}
+	if err := q.Normalize(LimitsForPlan("free")); err != nil {
+		t.Fatal(err)
+	}
+	// 7 zile / 500 puncte = ~20m10s → rotunjit la 30m
+	if q.Window != 30*time.Minute {
+		t.Errorf("window=%s want 30m", q.Window)
+	}
+	if q.Fn != "mean" {
+		t.Errorf("default fn=%q", q.Fn)
+	}
+
+	// Fereastra cerută mai mare decât minimul rămâne neschimbată.
+	q = SeriesQuery{Devices: []string{"d"}, Fields: []string{"f"}, Start: now.Add(-time.Hour), Stop: now, Window: 5 * time.Minute}
+	if err := q.Normalize(LimitsForPlan("pro")); err != nil || q.Window != 5*time.Minute {
+		t.Errorf("window=%s err=%v", q.Window, err)
+	}
+}
+
+func TestSeriesNormalizeRejects(t *testing.T) {
+	now := time.Now()
+	base := func() SeriesQuery {
+		return SeriesQuery{Devices: []string{"d"}, Fields: []string{"f"}, Start: now.Add(-time.Hour), Stop: now}
+	}
+	cases := map[string]func(q *SeriesQuery){
+		"no devices":      func(q *SeriesQuery) { q.Devices = nil },
+		"range over plan": func(q *SeriesQuery) { q.Start = now.Add(-8 * 24 * time.Hour) },
+		"stop before":     func(q *SeriesQuery) { q.Stop = q.Start },
+		"bad fn":          func(q *SeriesQuery) { q.Fn = "median" },
+		"empty device":    func(q *SeriesQuery) { q.Devices = []string{""} },
+		"too many series": func(q *SeriesQuery) {
+			q.Fields = []string{"a", "b", "c", "d", "e", "f"}
+			q.Devices = []string{"1", "2"}
+		},
+	}
+	for name, mutate := range cases {
+		q := base()
+		mutate(&q)
+		if err := q.Normalize(LimitsForPlan("free")); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestBuildSeriesFlux(t *testing.T) {
+	q := SeriesQuery{
+		TenantID: 2, Devices: []string{"a", "b"}, Fields: []string{"power"},
+		Start: time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC), Stop: time.Date(2026, 5, 14, 0, 0, 0, 0, time.UTC),
+		Window: 5 * time.Minute, Fn: "max",
+	}
+	flux, err := buildSeriesFlux("iot-pro", q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`from(bucket: "iot-pro")`,
+		`range(start: 2026-05-13T00:00:00Z, stop: 2026-05-14T00:00:00Z)`,
+		`r.tenant_id == "2"`,
+		`(r.device == "a" or r.device == "b")`,
+		`group(columns: ["device", "_field"])
+  |> aggregateWindow(every: 300s, fn: max, createEmpty: false)`,
+	} {
+		if !strings.Contains(flux, want) {
+			t.Errorf("flux missing %q:\n%s", want, flux)
+		}
+	}
+	if strings.Contains(flux, "sort(") {
+		t.Errorf("fn=max does not need sort:\n%s", flux)
+	}
+
+	// fn=last: seriile unite de group trebuie re-ordonate în timp.
+	q.Fn = "last"
+	if flux, err = buildSeriesFlux("iot-pro", q); err != nil {
+		t.Fatal(err)
+	}
+	if want := "group(columns: [\"device\", \"_field\"])\n  |> sort(columns: [\"_time\"])\n  |> aggregateWindow"; !strings.Contains(flux, want) {
+		t.Errorf("fn=last flux missing sort after group:\n%s", flux)
+	}
+}
@@ -0,0 +1,77 @@
+package ingest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// MaxBatch — citiri maxime într-un batch.
+const MaxBatch = 1000
+
+// DecodeBatch decodează un batch de citiri bufferate de device:
+//
+//	[
+//	  {"ts": "2026-05-10T12:00:00Z", "payload": {"power": 812}},
+//	  {"ts": 1778414460,             "payload": "power=815,voltage=231"}
+//	]
+//
+// ts: RFC 3339 sau secunde Unix. payload: orice valoare JSON — un string e
+// trimis parser-ului ca atare (raw / keyvalue), restul re-serializat JSON.
+func DecodeBatch(body []byte) ([]Reading, error) {
+	var items []struct {
+		TS      json.RawMessage `json:"ts"`
+		Payload json.RawMessage `json:"payload"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&items); err != nil {
+		return nil, fmt.Errorf("batch must be a JSON array of {ts, payload}: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, errors.New("empty batch")
+	}
+	if len(items) > MaxBatch {
+		return nil, fmt.Errorf("batch has %d readings, max %d", len(items), MaxBatch)
+	}
+
+	out := make([]Reading, len(items))
+	for i, it := range items {
+		if len(it.Payload) == 0 || string(it.Payload) == "null" {
+			return nil, fmt.Errorf("batch[%d]: payload required", i)
+		}
+		t, err := parseTS(it.TS)
+		if err != nil {
+			return nil, fmt.Errorf("batch[%d]: %w", i, err)
+		}
+		payload := []byte(it.Payload)
+		var s string
+		if json.Unmarshal(it.Payload, &s) == nil {
+			payload = []byte(s)
+		}
+		out[i] = Reading{Payload: payload, Time: t}
+	}
+	return out, nil
+}
+
+func parseTS(raw json.RawMessage) (time.Time, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return time.Time{}, errors.New("ts required")
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("ts %q: want RFC 3339", s)
+		}
+		return t, nil
+	}
+	sec, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || sec <= 0 {
+		return time.Time{}, fmt.Errorf("ts %s: want RFC 3339 or Unix seconds", raw)
+	}
+	return time.Unix(sec, 0).UTC(), nil
+}
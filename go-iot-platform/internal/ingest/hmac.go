@@ -0,0 +1,66 @@
+package ingest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Semnătura device-urilor fără cheie API: secretul per device (Django,
+// Device.ingest_secret → Redis "ingestsecret:{serial}") semnează
+//
+//	<timestamp>\n<serial>\n<stream>\n<body>
+//
+// cu HMAC-SHA256, hex. timestamp = secunde Unix, trimis separat (HTTP:
+// X-Timestamp); cererile în afara ferestrei MaxSkew sunt respinse, ca o
+// captură să nu poată fi reluată mai târziu. În fereastră, fiecare semnătură e
+// acceptată o singură dată (api: ClaimSignature pe ReplayWindow) — două citiri
+// identice în aceeași secundă au aceeași semnătură, deci device-ul care le poate
+// trimite pune un nonce / contor în body.
+
+// MaxSkew — diferența maximă acceptată între timestamp-ul semnat și ceasul serverului.
+const MaxSkew = 5 * time.Minute
+
+// ReplayWindow — cât e valabil un timestamp semnat (±MaxSkew) = cât trebuie
+// ținută minte o semnătură acceptată.
+const ReplayWindow = 2 * MaxSkew
+
+// CanonicalSignature — forma sub care se reține o semnătură acceptată: fără
+// prefixul "sha256=", hex lowercase (altfel "AB…" ar ocoli "ab…").
+func CanonicalSignature(signature string) string {
+	return strings.ToLower(strings.TrimPrefix(signature, "sha256="))
+}
+
+// Sign întoarce semnătura hex.
+func Sign(secret []byte, timestamp int64, serial, stream string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d\n%s\n%s\n", timestamp, serial, stream)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify verifică semnătura (hex, opțional cu prefixul "sha256=") și fereastra
+// de timp față de now.
+func Verify(secret []byte, timestamp, signature, serial, stream string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("timestamp must be Unix seconds")
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > MaxSkew || skew < -MaxSkew {
+		return fmt.Errorf("timestamp outside ±%s window", MaxSkew)
+	}
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return errors.New("signature must be hex")
+	}
+	want, _ := hex.DecodeString(Sign(secret, ts, serial, stream, body))
+	if !hmac.Equal(got, want) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
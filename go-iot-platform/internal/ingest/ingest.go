@@ -0,0 +1,63 @@
+// Package ingest — contractul dintre transporturile non-MQTT (HTTP, CoAP) și
+// pipeline-ul de ingest din cmd/main.go: un mesaj e un topic platform-nativ
+// sintetizat (tenants/{tid}/devices/{serial}/up/{stream}) plus una sau mai
+// multe citiri, procesate exact ca un mesaj MQTT pe același topic — lookup
+// tenant, rate limit, matcher, parser, scriere Influx, stream.
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Reading — un payload de device. Time zero = momentul primirii (sau `ts` din
+// payload, pentru parserele care îl citesc); setat pentru citirile din batch-uri
+// trimise de device-uri care au stat offline.
+type Reading struct {
+	Payload []byte
+	Time    time.Time
+}
+
+// Pipeline procesează citirile unui topic. Rate limit-ul se aplică o dată per
+// apel (un batch = un mesaj). Întoarce câte o eroare per citire (nil = scrisă)
+// sau o eroare globală — device necunoscut, tenant greșit, rate limit — caz în
+// care nicio citire nu a fost scrisă.
+type Pipeline func(ctx context.Context, topic string, readings []Reading) ([]error, error)
+
+// Erori globale întoarse de Pipeline; transporturile le mapează pe coduri de
+// răspuns (HTTP 403 / 429, CoAP 4.03 / 4.29).
+var (
+	ErrUnknownDevice  = errors.New("device not registered")
+	ErrTenantMismatch = errors.New("device belongs to another tenant")
+	ErrRateLimited    = errors.New("rate limited")
+)
+
+// streamRe — numele de stream acceptate în URL (același alfabet ca id-urile DD).
+var streamRe = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// Topic construiește topicul platform-nativ pentru serial / stream, după
+// validarea lor (serial fără "/", "+", "#"; stream [a-z][a-z0-9_]*).
+func Topic(tenantID int64, serial, stream string) (string, error) {
+	if tenantID <= 0 {
+		return "", errors.New("tenant_id must be positive")
+	}
+	if serial == "" || len(serial) > 100 || !validSerial(serial) {
+		return "", fmt.Errorf("invalid serial %q", serial)
+	}
+	if !streamRe.MatchString(stream) {
+		return "", fmt.Errorf("invalid stream %q", stream)
+	}
+	return fmt.Sprintf("tenants/%d/devices/%s/up/%s", tenantID, serial, stream), nil
+}
+
+func validSerial(s string) bool {
+	for _, r := range s {
+		if r <= ' ' || r == '/' || r == '+' || r == '#' || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
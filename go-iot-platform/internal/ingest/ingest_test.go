@@ -0,0 +1,154 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTopic(t *testing.T) {
+	if got, err := Topic(3, "SN-01", "meter"); err != nil || got != "tenants/3/devices/SN-01/up/meter" {
+		t.Errorf("Topic = %q, %v", got, err)
+	}
+	for _, tc := range []struct {
+		tid            int64
+		serial, stream string
+	}{
+		{0, "a", "meter"}, {1, "", "meter"}, {1, "a/b", "meter"}, {1, "a+", "meter"}, {1, "a b", "meter"},
+		{1, "a", "Meter"}, {1, "a", "up/x"}, {1, "a", ""}, {1, "a", "#"},
+	} {
+		if _, err := Topic(tc.tid, tc.serial, tc.stream); err == nil {
+			t.Errorf("Topic(%d, %q, %q) accepted", tc.tid, tc.serial, tc.stream)
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	secret := []byte("k3y")
+	now := time.Unix(1778414400, 0)
+	body := []byte("power=812")
+	// python3: hmac.new(b"k3y", b"1778414400\nM1\nmeter\npower=812", "sha256").hexdigest()
+	sig := Sign(secret, now.Unix(), "M1", "meter", body)
+	if sig != "4522a01e0c3d2a707400828f2dd7d81d39a1dd210a29c7cf0e4954c4496fc0df" {
+		t.Fatalf("signature = %q", sig)
+	}
+	if err := Verify(secret, "1778414400", sig, "M1", "meter", body, now.Add(time.Minute)); err != nil {
+		t.Errorf("valid: %v", err)
+	}
+	if err := Verify(secret, "1778414400", "sha256="+sig, "M1", "meter", body, now); err != nil {
+		t.Errorf("sha256= prefix: %v", err)
+	}
+	cases := map[string]error{
+		"body":      Verify(secret, "1778414400", sig, "M1", "meter", []byte("power=813"), now),
+		"serial":    Verify(secret, "1778414400", sig, "M2", "meter", body, now),
+		"stream":    Verify(secret, "1778414400", sig, "M1", "status", body, now),
+		"secret":    Verify([]byte("other"), "1778414400", sig, "M1", "meter", body, now),
+		"old":       Verify(secret, "1778414400", sig, "M1", "meter", body, now.Add(MaxSkew+time.Second)),
+		"future":    Verify(secret, "1778414400", sig, "M1", "meter", body, now.Add(-MaxSkew-time.Second)),
+		"bad ts":    Verify(secret, "yesterday", sig, "M1", "meter", body, now),
+		"bad hex":   Verify(secret, "1778414400", "zz", "M1", "meter", body, now),
+		"truncated": Verify(secret, "1778414400", sig[:10], "M1", "meter", body, now),
+	}
+	for name, err := range cases {
+		if err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	rs, err := DecodeBatch([]byte(`[
+		{"ts": "2026-05-10T12:00:00Z", "payload": {"power": 812}},
+		{"ts": 1778414460, "payload": "power=815,voltage=231"},
+		{"ts": "2026-05-10T12:02:00+02:00", "payload": 21.5}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		payload string
+		ts      time.Time
+	}{
+		{`{"power": 812}`, time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)},
+		{`power=815,voltage=231`, time.Unix(1778414460, 0)},
+		{`21.5`, time.Date(2026, 5, 10, 10, 2, 0, 0, time.UTC)},
+	}
+	for i, w := range want {
+		if string(rs[i].Payload) != w.payload || !rs[i].Time.Equal(w.ts) {
+			t.Errorf("[%d] = %s @ %v, want %s @ %v", i, rs[i].Payload, rs[i].Time, w.payload, w.ts)
+		}
+	}
+
+	for body, want := range map[string]string{
+		`{"ts": 1, "payload": 1}`:               "JSON array",
+		`[]`:                                    "empty batch",
+		`[{"ts": 1778414460}]`:                  "batch[0]: payload required",
+		`[{"payload": 1}]`:                      "batch[0]: ts required",
+		`[{"ts": "yesterday", "payload": 1}]`:   "want RFC 3339",
+		`[{"ts": -5, "payload": 1}]`:            "Unix seconds",
+		`[{"ts": 1, "payload": 1, "extra": 1}]`: "unknown field",
+		"[" + strings.Repeat(`{"ts":1,"payload":1},`, MaxBatch) + `{"ts":1,"payload":1}]`: "max 1000",
+	} {
+		if _, err := DecodeBatch([]byte(body)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%.40s: got %v, want %q", body, err, want)
+		}
+	}
+}
+
+func TestWithRelay(t *testing.T) {
+	type msg struct{ topic, payload string }
+	var sent []msg
+	publish := func(topic string, payload []byte) error {
+		sent = append(sent, msg{topic, string(payload)})
+		return nil
+	}
+	inner := func(_ context.Context, topic string, rs []Reading) ([]error, error) {
+		if strings.Contains(topic, "HOT") {
+			return nil, ErrRateLimited
+		}
+		errs := make([]error, len(rs))
+		for i, r := range rs {
+			if string(r.Payload) == "bad" {
+				errs[i] = errors.New("unparseable")
+			}
+		}
+		return errs, nil
+	}
+	p := WithRelay(inner, publish)
+
+	buffered := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+	readings := []Reading{{Payload: []byte("power=1")}, {Payload: []byte("bad")}, {Payload: []byte("power=2"), Time: buffered}}
+	before := time.Now()
+	errs, err := p(context.Background(), "tenants/3/devices/M1/up/meter", readings)
+	if err != nil || errs[1] == nil {
+		t.Fatalf("errs=%v err=%v", errs, err)
+	}
+	if len(sent) != 2 || sent[0].topic != "relay/tenants/3/devices/M1/up/meter" || sent[1].topic != sent[0].topic {
+		t.Fatalf("relayed = %v", sent)
+	}
+	// Citirea live primește momentul acceptării, cea bufferată își păstrează ts-ul.
+	live, old := DecodeRelay([]byte(sent[0].payload)), DecodeRelay([]byte(sent[1].payload))
+	if string(live.Payload) != "power=1" || live.Time.Before(before.Truncate(time.Second)) {
+		t.Errorf("live relay = %s %q", live.Time, live.Payload)
+	}
+	if string(old.Payload) != "power=2" || !old.Time.Equal(buffered) {
+		t.Errorf("buffered relay = %s %q, want %s", old.Time, old.Payload, buffered)
+	}
+	// Mesaj relay fără plic (ingest vechi): payload-ul ca atare, fără timp.
+	if m := DecodeRelay([]byte(`{"power": 3}`)); string(m.Payload) != `{"power": 3}` || !m.Time.IsZero() {
+		t.Errorf("legacy relay = %+v", m)
+	}
+	if orig, ok := FromRelay(sent[0].topic); !ok || orig != "tenants/3/devices/M1/up/meter" {
+		t.Errorf("FromRelay = %q, %v", orig, ok)
+	}
+	if orig, ok := FromRelay("tenants/3/devices/M1/up/meter"); ok || orig != "tenants/3/devices/M1/up/meter" {
+		t.Errorf("FromRelay(non-relay) = %q, %v", orig, ok)
+	}
+
+	sent = nil
+	if _, err := p(context.Background(), "tenants/3/devices/HOT/up/meter", readings); !errors.Is(err, ErrRateLimited) || len(sent) != 0 {
+		t.Errorf("rejected batch relayed: err=%v sent=%v", err, sent)
+	}
+}
@@ -0,0 +1,80 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go-iot-platform/internal/logging"
+)
+
+// Citirile primite pe HTTP / CoAP nu trec prin broker, deci rule-engine-ul
+// (abonat la tenants/+/devices/+/up/#) nu le-ar vedea: regulile nu s-ar evalua
+// și rule_seen nu s-ar actualiza (alerte absence false). După scriere, WithRelay
+// republică fiecare citire acceptată pe RelayPrefix + topic. Ingest-ul MQTT nu
+// e abonat la relay/ — citirea nu e scrisă de două ori; rule-engine-ul este și
+// scoate prefixul (FromRelay). Device-urile nu pot publica pe relay/: ACL-ul
+// EMQX (Django /api/mqtt/acl/) le permite doar propriile topicuri.
+const RelayPrefix = "relay/"
+
+// RelayTopic — topicul pe care e republicată o citire de pe topic.
+func RelayTopic(topic string) string { return RelayPrefix + topic }
+
+// FromRelay întoarce topicul original al unui mesaj relay; ok=false → topicul
+// nu e relay și e întors neschimbat.
+func FromRelay(topic string) (string, bool) { return strings.CutPrefix(topic, RelayPrefix) }
+
+// RelayMessage — plicul JSON al unei citiri republicate: payload-ul original
+// (base64) și momentul citirii. Fără el, o citire bufferată (?batch=true, ts
+// din batch) ar ajunge la rule-engine ca mesaj live: acțiuni pe date vechi și
+// rule_seen marcat cu ora sosirii.
+type RelayMessage struct {
+	Time    time.Time `json:"time"`
+	Payload []byte    `json:"payload"`
+}
+
+// EncodeRelay — plicul citirii; Time zero (citire live) → momentul acceptării.
+func EncodeRelay(rd Reading) ([]byte, error) {
+	at := rd.Time
+	if at.IsZero() {
+		at = time.Now()
+	}
+	return json.Marshal(RelayMessage{Time: at.UTC(), Payload: rd.Payload})
+}
+
+// DecodeRelay desface plicul. Un mesaj care nu e plic (publicat de un ingest
+// mai vechi, în timpul unui deploy) e întors ca payload, cu Time zero.
+func DecodeRelay(data []byte) RelayMessage {
+	var m RelayMessage
+	if err := json.Unmarshal(data, &m); err != nil || m.Payload == nil || m.Time.IsZero() {
+		return RelayMessage{Payload: data}
+	}
+	return m
+}
+
+// WithRelay învelește pipeline-ul: citirile acceptate (eroare nil) sunt
+// publicate pe RelayTopic după scriere, în plicul RelayMessage. O publicare
+// eșuată e doar log-ată — citirea e deja în Influx, iar device-ul nu trebuie
+// să o retrimită.
+func WithRelay(p Pipeline, publish func(topic string, payload []byte) error) Pipeline {
+	return func(ctx context.Context, topic string, readings []Reading) ([]error, error) {
+		errs, err := p(ctx, topic, readings)
+		if err != nil {
+			return errs, err
+		}
+		for i, rd := range readings {
+			if i < len(errs) && errs[i] != nil {
+				continue
+			}
+			msg, perr := EncodeRelay(rd)
+			if perr == nil {
+				perr = publish(RelayTopic(topic), msg)
+			}
+			if perr != nil {
+				logging.Error("ingest relay publish failed", logging.Fields{"topic": topic, "error": perr.Error()})
+			}
+		}
+		return errs, nil
+	}
+}
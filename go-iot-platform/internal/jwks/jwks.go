@@ -0,0 +1,325 @@
+// Package jwks încarcă chei publice de verificare JWT (RS* / PS* / ES*) dintr-un
+// JSON Web Key Set — fișier local sau URL — și le reîncarcă fără restart.
+//
+// Rotație: emitentul publică cheia nouă în set (kid nou) înainte s-o folosească;
+// un token cu kid necunoscut declanșează un reload imediat (limitat la unul per
+// MinRefreshInterval), iar Run reîncarcă periodic setul.
+package jwks
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MinRefreshInterval — cel mai des reload declanșat de un kid necunoscut.
+const MinRefreshInterval = 30 * time.Second
+
+// ErrKeyNotFound — niciun key din set nu se potrivește cu kid / alg.
+var ErrKeyNotFound = errors.New("jwks: key not found")
+
+// Key — o cheie publică din set.
+type Key struct {
+	ID  string
+	Alg string // opțional în JWK; dacă e setat, token-ul trebuie să aibă același alg
+	Pub crypto.PublicKey
+}
+
+// Set — setul curent de chei, sigur pentru goroutine.
+type Set struct {
+	source string
+	client *http.Client
+
+	mu       sync.RWMutex
+	keys     []Key
+	loadedAt time.Time
+	modTime  time.Time // pentru fișiere: reload doar dacă s-a schimbat
+
+	refreshMu   sync.Mutex
+	lastRefresh time.Time
+}
+
+// Load încarcă setul din source: "http(s)://…" sau cale de fișier.
+func Load(ctx context.Context, source string) (*Set, error) {
+	s := &Set{source: source, client: &http.Client{Timeout: 10 * time.Second}}
+	if err := s.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// FromJSON construiește un set static (fără source, Reload e no-op).
+func FromJSON(data []byte) (*Set, error) {
+	keys, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Set{keys: keys, loadedAt: time.Now()}, nil
+}
+
+func (s *Set) isURL() bool {
+	return strings.HasPrefix(s.source, "https://") || strings.HasPrefix(s.source, "http://")
+}
+
+// Reload recitește source. Un set invalid / gol nu înlocuiește setul curent.
+func (s *Set) Reload(ctx context.Context) error {
+	if s.source == "" {
+		return nil
+	}
+	var (
+		data    []byte
+		modTime time.Time
+		err     error
+	)
+	if s.isURL() {
+		data, err = s.fetch(ctx)
+	} else {
+		var st os.FileInfo
+		if st, err = os.Stat(s.source); err == nil {
+			modTime = st.ModTime()
+			s.mu.RLock()
+			unchanged := !s.modTime.IsZero() && modTime.Equal(s.modTime)
+			s.mu.RUnlock()
+			if unchanged {
+				return nil
+			}
+			data, err = os.ReadFile(s.source)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("jwks: load %s: %w", s.source, err)
+	}
+	keys, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("jwks: %s: %w", s.source, err)
+	}
+	s.mu.Lock()
+	s.keys = keys
+	s.loadedAt = time.Now()
+	s.modTime = modTime
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Set) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// Run reîncarcă setul la fiecare `every` până la anularea ctx.
+func (s *Set) Run(ctx context.Context, every time.Duration) {
+	if s.source == "" || every <= 0 {
+		return
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := s.Reload(ctx); err != nil {
+				log.Printf("⚠️ %v (păstrez setul anterior)", err)
+			}
+		}
+	}
+}
+
+// Keys întoarce cheile compatibile cu kid / alg. kid "" → toate cheile
+// compatibile cu alg (emitenți care nu pun kid în header). Un kid necunoscut
+// declanșează un reload (rate-limited) înainte de ErrKeyNotFound.
+func (s *Set) Keys(ctx context.Context, kid, alg string) ([]Key, error) {
+	if keys := s.match(kid, alg); len(keys) > 0 {
+		return keys, nil
+	}
+	if kid != "" && s.source != "" && s.allowRefresh() {
+		if err := s.Reload(ctx); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+		if keys := s.match(kid, alg); len(keys) > 0 {
+			return keys, nil
+		}
+	}
+	return nil, fmt.Errorf("%w (kid=%q alg=%s)", ErrKeyNotFound, kid, alg)
+}
+
+func (s *Set) allowRefresh() bool {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	if time.Since(s.lastRefresh) < MinRefreshInterval {
+		return false
+	}
+	s.lastRefresh = time.Now()
+	return true
+}
+
+func (s *Set) match(kid, alg string) []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []Key
+	for _, k := range s.keys {
+		if kid != "" && k.ID != kid {
+			continue
+		}
+		if k.Alg != "" && k.Alg != alg {
+			continue
+		}
+		if !compatible(k.Pub, alg) {
+			continue
+		}
+		out = append(out, k)
+	}
+	return out
+}
+
+// compatible — familia algoritmului trebuie să corespundă tipului cheii (nu
+// acceptăm niciodată o cheie publică pentru HS*).
+func compatible(pub crypto.PublicKey, alg string) bool {
+	switch {
+	case strings.HasPrefix(alg, "RS"), strings.HasPrefix(alg, "PS"):
+		_, ok := pub.(*rsa.PublicKey)
+		return ok
+	case strings.HasPrefix(alg, "ES"):
+		_, ok := pub.(*ecdsa.PublicKey)
+		return ok
+	}
+	return false
+}
+
+// Len — numărul de chei din set.
+func (s *Set) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.keys)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// Parse decodează un JWKS ({"keys":[…]}). Cheile de criptare (use=enc) și
+// tipurile nesuportate sunt ignorate; un set fără nicio cheie utilizabilă e eroare.
+func Parse(data []byte) ([]Key, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	var out []Key
+	seen := map[string]bool{}
+	for i, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %d (kid=%q): %w", i, k.Kid, err)
+		}
+		if pub == nil {
+			continue
+		}
+		if k.Kid != "" {
+			if seen[k.Kid] {
+				return nil, fmt.Errorf("duplicate kid %q", k.Kid)
+			}
+			seen[k.Kid] = true
+		}
+		out = append(out, Key{ID: k.Kid, Alg: k.Alg, Pub: pub})
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no usable signing keys")
+	}
+	return out, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := b64int(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("n: %w", err)
+		}
+		e, err := b64int(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("e: %w", err)
+		}
+		if n.BitLen() < 2048 {
+			return nil, fmt.Errorf("RSA key too short (%d bits)", n.BitLen())
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := b64int(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("x: %w", err)
+		}
+		y, err := b64int(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("point not on curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, nil // kty necunoscut (ex: "oct") → ignorat
+}
+
+func b64int(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, errors.New("missing")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
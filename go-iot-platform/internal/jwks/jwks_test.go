@@ -0,0 +1,166 @@
+package jwks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func rsaJWK(t *testing.T, kid string) (map[string]string, *rsa.PrivateKey) {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes()),
+	}, k
+}
+
+func ecJWK(t *testing.T, kid string) (map[string]string, *ecdsa.PrivateKey) {
+	t.Helper()
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": b64(k.X.FillBytes(make([]byte, 32))), "y": b64(k.Y.FillBytes(make([]byte, 32))),
+	}, k
+}
+
+func setJSON(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	b, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestParse(t *testing.T) {
+	r, _ := rsaJWK(t, "r1")
+	e, _ := ecJWK(t, "e1")
+	keys, err := Parse(setJSON(t, r, e,
+		map[string]string{"kty": "oct", "kid": "sym", "k": "c2VjcmV0"},
+		map[string]string{"kty": "RSA", "kid": "enc", "use": "enc", "n": r["n"], "e": r["e"]},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].ID != "r1" || keys[1].ID != "e1" {
+		t.Fatalf("keys = %+v", keys)
+	}
+
+	bad := map[string][]byte{
+		"empty":     []byte(`{"keys":[]}`),
+		"not json":  []byte(`nope`),
+		"off curve": setJSON(t, map[string]string{"kty": "EC", "kid": "x", "crv": "P-256", "x": b64([]byte{1}), "y": b64([]byte{2})}),
+		"short rsa": setJSON(t, map[string]string{"kty": "RSA", "kid": "x", "n": b64([]byte{1, 2, 3}), "e": "AQAB"}),
+		"dup kid":   setJSON(t, r, r),
+	}
+	for name, data := range bad {
+		if _, err := Parse(data); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestKeysSelection(t *testing.T) {
+	r, _ := rsaJWK(t, "r1")
+	e, _ := ecJWK(t, "e1")
+	s, err := FromJSON(setJSON(t, r, e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if k, err := s.Keys(ctx, "r1", "RS256"); err != nil || len(k) != 1 {
+		t.Errorf("r1/RS256: %v %v", k, err)
+	}
+	// alg din JWK e RS256 → RS512 refuzat; cheie EC pentru RS* refuzată
+	for _, c := range [][2]string{{"r1", "RS512"}, {"e1", "RS256"}, {"r1", "HS256"}, {"zz", "RS256"}} {
+		if _, err := s.Keys(ctx, c[0], c[1]); !errors.Is(err, ErrKeyNotFound) {
+			t.Errorf("%v: err = %v", c, err)
+		}
+	}
+	if k, err := s.Keys(ctx, "", "ES256"); err != nil || len(k) != 1 || k[0].ID != "e1" {
+		t.Errorf("no kid ES256: %v %v", k, err)
+	}
+}
+
+func TestFileRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	r1, _ := rsaJWK(t, "k1")
+	r2, _ := rsaJWK(t, "k2")
+	if err := os.WriteFile(path, setJSON(t, r1), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Load(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, setJSON(t, r1, r2), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chtimes(path, time.Now().Add(time.Second), time.Now().Add(time.Second))
+
+	// kid necunoscut → reload imediat
+	if _, err := s.Keys(context.Background(), "k2", "RS256"); err != nil {
+		t.Fatalf("k2 after rotation: %v", err)
+	}
+	if s.Len() != 2 {
+		t.Errorf("len = %d", s.Len())
+	}
+
+	// un fișier stricat nu înlocuiește setul curent
+	if err := os.WriteFile(path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_ = os.Chtimes(path, time.Now().Add(2*time.Second), time.Now().Add(2*time.Second))
+	if err := s.Reload(context.Background()); err == nil {
+		t.Error("expected reload error")
+	}
+	if s.Len() != 2 {
+		t.Errorf("len after bad reload = %d", s.Len())
+	}
+}
+
+func TestURLRefreshRateLimited(t *testing.T) {
+	r1, _ := rsaJWK(t, "k1")
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		_, _ = w.Write(setJSON(t, r1))
+	}))
+	defer srv.Close()
+
+	s, err := Load(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := s.Keys(context.Background(), "missing", "RS256"); err == nil {
+			t.Fatal("expected ErrKeyNotFound")
+		}
+	}
+	// load inițial + un singur refresh în fereastra MinRefreshInterval
+	if n := hits.Load(); n != 2 {
+		t.Errorf("fetches = %d, want 2", n)
+	}
+}
@@ -48,3 +48,7 @@ func Info(msg string, f Fields)  { emit("info", msg, f) }
 func Warn(msg string, f Fields)  { emit("warn", msg, f) }
 func Error(msg string, f Fields) { emit("error", msg, f) }
 func Drop(msg string, f Fields)  { emit("drop", msg, f) }
+
+// Audit — decizii de acces (ex: refuzuri de autorizare în API); level "audit"
+// ca să poată fi filtrate / reținute separat în Loki.
+func Audit(msg string, f Fields) { emit("audit", msg, f) }
@@ -0,0 +1,147 @@
+package matcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"go-iot-platform/internal/parsers"
+)
+
+// Explanation — răspunsul la "ce DD / pattern prinde topicul ăsta și de ce nu
+// celelalte". Folosit de `dd-lint explain`; pe hot path se folosește Match.
+type Explanation struct {
+	Topic      string      `json:"topic"`
+	Payload    bool        `json:"payload_given"`
+	Winner     *Match      `json:"-"`
+	WinnerRank int         `json:"winner_rank"` // -1 = niciun match
+	Candidates []Candidate `json:"candidates"`
+
+	// Doar cu payload și winner: punctul scris de ingest (parsers.Decode) —
+	// tag-urile source / type și field-urile. Builtin: stream-ul are handler
+	// dedicat (parsers.HasBuiltin), deci field-urile NU vin din parser-ul DD-ului.
+	// Normalized: normalized_fields aplicate pe payload (parsers.Normalize),
+	// numele canonice adăugate de rule-engine lângă field-urile scrise.
+	Builtin    bool                   `json:"builtin_handler,omitempty"`
+	Source     string                 `json:"source,omitempty"`
+	Type       string                 `json:"type,omitempty"`
+	Parsed     map[string]interface{} `json:"parsed,omitempty"`
+	Normalized []NormalizedField      `json:"normalized,omitempty"`
+	ParseError string                 `json:"parse_error,omitempty"`
+}
+
+// Candidate — un pattern din registry, în ordinea în care îl încearcă Match.
+type Candidate struct {
+	Rank         int               `json:"rank"`
+	Definition   string            `json:"definition"`
+	Index        int               `json:"index"` // poziția în topic_match[] din DD
+	Pattern      string            `json:"pattern"`
+	Priority     int               `json:"priority,omitempty"`
+	Stream       string            `json:"stream,omitempty"`
+	TopicMatched bool              `json:"topic_matched"`
+	Extracted    map[string]string `json:"extracted,omitempty"`
+	Winner       bool              `json:"winner"`
+	Reason       string            `json:"reason"`
+}
+
+// NormalizedField — un field canonic calculat din payload.
+type NormalizedField struct {
+	Name   string      `json:"name"`
+	Source string      `json:"source"`
+	Value  interface{} `json:"value"`
+	Unit   string      `json:"unit,omitempty"`
+}
+
+// Explain evaluează topicul (și payload-ul, dacă nu e nil) contra tuturor
+// pattern-urilor, fără scurtătura din Match: fiecare candidat primește motivul
+// pentru care a câștigat / a pierdut. Winner e același rezultat ca
+// MatchMessage(topic, payload) — sau Match(topic) pentru payload nil.
+func (m *Matcher) Explain(topic string, payload []byte) *Explanation {
+	ex := &Explanation{Topic: topic, Payload: payload != nil, WinnerRank: -1, Candidates: []Candidate{}}
+
+	var decoded map[string]interface{}
+	decodeErr := ""
+	if payload != nil {
+		if err := json.Unmarshal(payload, &decoded); err != nil || decoded == nil {
+			decodeErr = "payload is not a JSON object"
+		}
+	}
+
+	for rank := range m.patterns {
+		cp := &m.patterns[rank]
+		c := Candidate{
+			Rank: rank, Definition: cp.dd.ID, Index: patternIndex(cp), Pattern: cp.spec.Pattern,
+			Priority: cp.spec.Priority, Stream: cp.spec.Stream,
+		}
+		groups := cp.regex.FindStringSubmatch(topic)
+		c.TopicMatched = groups != nil
+		if groups != nil {
+			c.Extracted = extract(cp.spec.Extract, groups, cp.regex.SubexpNames(), cp.mqttPos)
+		}
+
+		switch {
+		case groups == nil:
+			c.Reason = "topic does not match"
+		case len(cp.payload) > 0 && payload == nil:
+			c.Reason = "payload_match not evaluated: no payload given"
+		case len(cp.payload) > 0 && decodeErr != "":
+			c.Reason = "payload_match failed: " + decodeErr
+		case len(cp.payload) > 0 && payloadFailure(cp.payload, decoded) != "":
+			c.Reason = "payload_match failed: " + payloadFailure(cp.payload, decoded)
+		case ex.Winner != nil:
+			w := ex.Candidates[ex.WinnerRank]
+			c.Reason = fmt.Sprintf("matches, but dd=%s topic_match[%d] is tried first", w.Definition, w.Index)
+		default:
+			c.Winner, c.Reason = true, "winner"
+			ex.WinnerRank = rank
+			ex.Winner = &Match{Definition: cp.dd, Pattern: cp.spec.Pattern, Stream: cp.spec.Stream, Extracted: c.Extracted}
+		}
+		ex.Candidates = append(ex.Candidates, c)
+	}
+
+	if ex.Winner != nil && payload != nil {
+		ex.explainFields(topic, payload)
+	}
+	return ex
+}
+
+// explainFields — ce scrie ingest-ul pentru DD-ul câștigător și normalized_fields.
+func (ex *Explanation) explainFields(topic string, payload []byte) {
+	dd, vars := ex.Winner.Definition, ex.Winner.Extracted
+	ex.Builtin = parsers.HasBuiltin(ex.Winner.Stream)
+	pt, err := parsers.Decode(dd, ex.Winner.Stream, topic, vars, payload)
+	if err != nil {
+		ex.ParseError = err.Error()
+		return
+	}
+	ex.Source, ex.Type, ex.Parsed = pt.Source, pt.Type, pt.Fields
+	raw, err := parsers.Parse(dd, topic, vars, payload)
+	if err != nil {
+		return
+	}
+	for name, v := range parsers.Normalize(dd, raw) {
+		spec := dd.NormalizedFields[name]
+		ex.Normalized = append(ex.Normalized, NormalizedField{Name: name, Source: spec.Source, Value: v, Unit: spec.Unit})
+	}
+	sort.Slice(ex.Normalized, func(i, j int) bool { return ex.Normalized[i].Name < ex.Normalized[j].Name })
+}
+
+// payloadFailure — motivul primei reguli neîndeplinite, "" dacă toate trec.
+func payloadFailure(rules []payloadRule, payload map[string]interface{}) string {
+	for _, r := range rules {
+		if why := ruleFailure(r, payload); why != "" {
+			return why
+		}
+	}
+	return ""
+}
+
+// patternIndex — poziția spec-ului în topic_match[] al DD-ului.
+func patternIndex(cp *compiled) int {
+	for i := range cp.dd.Identification.TopicMatch {
+		if &cp.dd.Identification.TopicMatch[i] == cp.spec {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,89 @@
+package matcher
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-iot-platform/internal/registry"
+)
+
+func productionMatcher(t *testing.T) *Matcher {
+	t.Helper()
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil || len(errs) > 0 {
+		t.Skipf("configs/devices/ not available: %v %v", err, errs)
+	}
+	m, _ := New(reg)
+	return m
+}
+
+func TestExplain(t *testing.T) {
+	m := productionMatcher(t)
+	topic := "zigbee2mqtt/front_door"
+	payload := []byte(`{"contact":false,"battery":91,"voltage":3005,"linkquality":87}`)
+
+	ex := m.Explain(topic, payload)
+	if !reflect.DeepEqual(ex.Winner, m.MatchMessage(topic, payload)) {
+		t.Fatalf("winner %+v differs from MatchMessage", ex.Winner)
+	}
+	if len(ex.Candidates) != m.Count() {
+		t.Errorf("candidates = %d, want all %d patterns", len(ex.Candidates), m.Count())
+	}
+	byDD := map[string]Candidate{}
+	for _, c := range ex.Candidates {
+		if c.TopicMatched {
+			byDD[c.Definition] = c
+		}
+	}
+	if c := byDD["zigbee_contact"]; !c.Winner || c.Extracted["device_id"] != "front_door" || c.Index != 0 {
+		t.Errorf("zigbee_contact: %+v", c)
+	}
+	if c := byDD["zigbee_temperature"]; c.Winner || c.Reason != "matches, but dd=zigbee_contact topic_match[0] is tried first" {
+		t.Errorf("zigbee_temperature: %+v", c)
+	}
+	if len(byDD) != 2 {
+		t.Errorf("topic-matching candidates: %v", byDD)
+	}
+
+	if ex.Parsed["contact"] != false || !ex.Builtin || ex.Source != "zigbee2mqtt" || ex.Type != "sensor" {
+		t.Errorf("parsed = %v builtin=%v source=%s type=%s", ex.Parsed, ex.Builtin, ex.Source, ex.Type)
+	}
+	var names []string
+	for _, f := range ex.Normalized {
+		names = append(names, f.Name)
+	}
+	if strings.Join(names, ",") != "battery_pct,battery_voltage_mv,contact_closed,link_quality" {
+		t.Errorf("normalized = %+v", ex.Normalized)
+	}
+
+	// același topic, fără cheia contact → motivul apare pe candidatul pierdut
+	ex = m.Explain(topic, []byte(`{"temperature":21.5}`))
+	for _, c := range ex.Candidates {
+		if c.Definition == "zigbee_contact" && c.Index == 0 && c.Reason != `payload_match failed: key "contact" missing` {
+			t.Errorf("zigbee_contact reason = %q", c.Reason)
+		}
+	}
+	if ex.Winner == nil || ex.Winner.Definition.ID != "zigbee_temperature" {
+		t.Errorf("winner = %+v", ex.Winner)
+	}
+
+	// Tasmota SENSOR: handler-ul dedicat scrie nousat_*, nu ENERGY din parser-ul DD
+	ex = m.Explain("tele/plug-1/SENSOR", []byte(`{"Time":"2026-05-13T10:00:00","ENERGY":{"Power":7.4,"Total":3.5}}`))
+	if ex.Winner == nil || ex.Winner.Definition.ID != "nous_a1t" || !ex.Builtin || ex.Source != "nousat" {
+		t.Fatalf("sensor: %+v", ex)
+	}
+	if ex.Parsed["nousat_power"] != 7.4 || ex.Parsed["ENERGY"] != nil {
+		t.Errorf("sensor parsed = %v", ex.Parsed)
+	}
+
+	// fără payload: ca Match, fără field-uri
+	ex = m.Explain(topic, nil)
+	if ex.Winner == nil || ex.Winner.Definition.ID != "zigbee_temperature" || ex.Parsed != nil {
+		t.Errorf("no payload: %+v", ex)
+	}
+	if ex = m.Explain("nothing/here", nil); ex.Winner != nil || ex.WinnerRank != -1 {
+		t.Errorf("no match: %+v", ex)
+	}
+}
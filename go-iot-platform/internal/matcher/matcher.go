@@ -7,6 +7,7 @@
 package matcher
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"sort"
@@ -30,7 +31,9 @@ type Match struct {
 
 // Matcher — engine compilat dintr-un Registry; thread-safe pentru read after compile.
 type Matcher struct {
-	patterns []compiled
+	patterns []compiled // în ordinea de prioritate (registry.MatchOrder); rank = index
+	trie     *trieNode  // pattern-urile MQTT, indexate pe segmente
+	regexes  []int      // rank-urile pattern-urilor `~`, crescător — fallback liniar
 }
 
 // compiled — un pattern pre-compilat la New(). Stocăm regex-ul ca să nu re-compilăm
@@ -39,7 +42,8 @@ type compiled struct {
 	dd      *registry.DeviceDefinition
 	spec    *registry.TopicMatchSpec
 	regex   *regexp.Regexp
-	mqttPos []string // dacă pattern-ul e MQTT-stil, nume sintetice "$1","$2"... pentru fiecare `+`
+	mqttPos []string      // dacă pattern-ul e MQTT-stil, nume sintetice "$1","$2"... pentru fiecare `+`
+	payload []payloadRule // payload_match compilat; gol = doar topic
 }
 
 // New construiește un Matcher peste Registry-ul dat. Toate patterns sunt
@@ -57,19 +61,23 @@ func New(reg *registry.Registry) (*Matcher, []error) {
 	m := &Matcher{}
 	var errs []error
 
-	// Sort DD-uri pe ID pentru ordine deterministică (priority by load order).
-	all := reg.All()
-	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
-
-	for _, dd := range all {
-		for i := range dd.Identification.TopicMatch {
-			spec := &dd.Identification.TopicMatch[i]
-			cp, err := compile(dd, spec)
-			if err != nil {
-				errs = append(errs, fmt.Errorf("dd=%s pattern=%q: %w", dd.ID, spec.Pattern, err))
-				continue
-			}
-			m.patterns = append(m.patterns, cp)
+	// Ordine deterministică: priority desc, apoi id DD, apoi ordinea din fișier.
+	for _, ref := range registry.MatchOrder(reg.All()) {
+		dd, spec := ref.DD, ref.Spec()
+		cp, err := compile(dd, spec)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("dd=%s pattern=%q: %w", dd.ID, spec.Pattern, err))
+			continue
+		}
+		m.patterns = append(m.patterns, cp)
+	}
+
+	m.trie = newTrieNode()
+	for rank, cp := range m.patterns {
+		if !strings.HasPrefix(cp.spec.Pattern, "~") {
+			m.trie.insert(cp.spec.Pattern, rank)
+		} else {
+			m.regexes = append(m.regexes, rank)
 		}
 	}
 
@@ -77,13 +85,70 @@ func New(reg *registry.Registry) (*Matcher, []error) {
 }
 
 // Match returnează primul DD al cărui pattern matchează `topic`, sau nil dacă niciunul.
+// Pattern-urile cu payload_match sunt sărite (payload-ul nu e cunoscut) — pentru
+// ingest, unde payload-ul există, folosește MatchMessage.
 //
-// Algoritm: linear scan prin patterns compile-uite (în ordinea dată de New).
-// Pentru < 1000 patterns e sub 50µs pe procesor modern.
+// Algoritm: pattern-urile MQTT sunt indexate într-un trie pe segmente (cost
+// O(adâncime topic)), cele regex (~) rămân o listă de fallback; câștigă primul
+// în ordinea dată de New — vezi match.
 func (m *Matcher) Match(topic string) *Match {
-	for _, cp := range m.patterns {
+	return m.match(topic, nil)
+}
+
+// MatchMessage — ca Match, dar evaluează și payload_match pe payload-ul JSON.
+// Payload-ul e decodat o singură dată, doar dacă un pattern cu payload_match
+// prinde topicul; payload ne-JSON (sau nu obiect) → acele pattern-uri nu prind.
+func (m *Matcher) MatchMessage(topic string, payload []byte) *Match {
+	decoded, done := map[string]interface{}(nil), false
+	return m.match(topic, func() map[string]interface{} {
+		if !done {
+			done = true
+			if err := json.Unmarshal(payload, &decoded); err != nil {
+				decoded = nil
+			}
+		}
+		return decoded
+	})
+}
+
+// MatchPayload — ca MatchMessage, cu payload-ul deja decodat (ex. ultimele
+// valori ale unui device din Influx, în API).
+func (m *Matcher) MatchPayload(topic string, payload map[string]interface{}) *Match {
+	return m.match(topic, func() map[string]interface{} { return payload })
+}
+
+// match — payload nil → pattern-urile cu payload_match sunt sărite.
+//
+// Candidații MQTT vin din trie (toate pattern-urile care prind topicul), cei
+// regex din lista de fallback; sunt încercați în ordinea rank-ului, deci
+// rezultatul e identic cu scanarea liniară a tuturor pattern-urilor. Regex-urile
+// cu rank după primul candidat acceptat nu mai sunt evaluate.
+func (m *Matcher) match(topic string, payload func() map[string]interface{}) *Match {
+	if len(m.patterns) == 0 {
+		return nil
+	}
+	var buf [8]int
+	cands := m.trie.collect(strings.Split(topic, "/"), 0, buf[:0])
+	sort.Ints(cands)
+
+	ci, ri := 0, 0
+	for ci < len(cands) || ri < len(m.regexes) {
+		var rank int
+		fromTrie := ri == len(m.regexes) || ci < len(cands) && cands[ci] < m.regexes[ri]
+		if fromTrie {
+			rank, ci = cands[ci], ci+1
+		} else {
+			rank, ri = m.regexes[ri], ri+1
+		}
+		cp := &m.patterns[rank]
+		if len(cp.payload) > 0 && payload == nil {
+			continue
+		}
 		groups := cp.regex.FindStringSubmatch(topic)
 		if groups == nil {
+			continue // regex care nu prinde (pentru candidații din trie nu se întâmplă)
+		}
+		if len(cp.payload) > 0 && !matchPayload(cp.payload, payload()) {
 			continue
 		}
 		return &Match{
@@ -102,13 +167,17 @@ func (m *Matcher) Count() int { return len(m.patterns) }
 // ── compile + helpers ─────────────────────────────────────────────────────
 
 func compile(dd *registry.DeviceDefinition, spec *registry.TopicMatchSpec) (compiled, error) {
+	rules, err := compilePayload(spec.PayloadMatch)
+	if err != nil {
+		return compiled{}, err
+	}
 	if strings.HasPrefix(spec.Pattern, "~") {
 		raw := strings.TrimPrefix(spec.Pattern, "~")
 		rx, err := regexp.Compile(raw)
 		if err != nil {
 			return compiled{}, fmt.Errorf("regex compile: %w", err)
 		}
-		return compiled{dd: dd, spec: spec, regex: rx}, nil
+		return compiled{dd: dd, spec: spec, regex: rx, payload: rules}, nil
 	}
 
 	// MQTT wildcard pattern → regex
@@ -116,7 +185,7 @@ func compile(dd *registry.DeviceDefinition, spec *registry.TopicMatchSpec) (comp
 	if err != nil {
 		return compiled{}, err
 	}
-	return compiled{dd: dd, spec: spec, regex: rx, mqttPos: mqttPos}, nil
+	return compiled{dd: dd, spec: spec, regex: rx, mqttPos: mqttPos, payload: rules}, nil
 }
 
 // mqttToRegex transformă un MQTT topic filter în regex Go anchorat.
@@ -298,6 +298,34 @@ func TestProductionMatching(t *testing.T) {
 	}
 }
 
+// Payload-based: același topic Z2M, DD ales după cheile din payload.
+func TestProductionPayloadMatching(t *testing.T) {
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil || len(errs) > 0 {
+		t.Skipf("configs/devices/ not available: %v %v", err, errs)
+	}
+	m, _ := New(reg)
+
+	cases := []struct {
+		topic, payload, want string
+	}{
+		{"zigbee2mqtt/front_door", `{"contact":false,"battery":91}`, "zigbee_contact"},
+		{"tenants/2/devices/front_door/up/zigbee", `{"contact":true}`, "zigbee_contact"},
+		{"zigbee2mqtt/livingroom_temp", `{"temperature":21.5,"humidity":56}`, "zigbee_temperature"},
+		{"zigbee2mqtt/livingroom_temp", `not json`, "zigbee_temperature"},
+	}
+	for _, tc := range cases {
+		got := m.MatchMessage(tc.topic, []byte(tc.payload))
+		if got == nil || got.Definition.ID != tc.want {
+			t.Errorf("%s %s → %v, want %s", tc.topic, tc.payload, got, tc.want)
+		}
+	}
+	// fără payload, regulile pe payload nu pot fi evaluate → fallback pe topic
+	if got := m.Match("zigbee2mqtt/front_door"); got == nil || got.Definition.ID != "zigbee_temperature" {
+		t.Errorf("Match without payload → %v", got)
+	}
+}
+
 // ────────────────────────────────────────────────────────────────────────────
 // Benchmark — ținta < 50µs/op pentru ~10 patterns
 // ────────────────────────────────────────────────────────────────────────────
@@ -0,0 +1,112 @@
+package matcher
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go-iot-platform/internal/registry"
+)
+
+// payloadRule — registry.PayloadRule cu regex-ul compilat și path-ul despărțit.
+type payloadRule struct {
+	spec  registry.PayloadRule
+	path  []string
+	regex *regexp.Regexp
+}
+
+func compilePayload(specs []registry.PayloadRule) ([]payloadRule, error) {
+	out := make([]payloadRule, 0, len(specs))
+	for _, spec := range specs {
+		r := payloadRule{spec: spec, path: strings.Split(spec.Key, ".")}
+		if spec.Regex != "" {
+			rx, err := regexp.Compile(spec.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("payload_match key %q: regex compile: %w", spec.Key, err)
+			}
+			r.regex = rx
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// matchPayload — toate regulile trebuie să fie adevărate (AND).
+func matchPayload(rules []payloadRule, payload map[string]interface{}) bool {
+	if payload == nil {
+		return false
+	}
+	for _, r := range rules {
+		if ruleFailure(r, payload) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleFailure — "" dacă regula e îndeplinită, altfel motivul (pentru Explain).
+func ruleFailure(r payloadRule, payload map[string]interface{}) string {
+	v, ok := lookupKey(payload, r.spec.Key, r.path)
+	switch {
+	case r.spec.Absent:
+		if ok {
+			return fmt.Sprintf("key %q present, want absent", r.spec.Key)
+		}
+	case !ok:
+		return fmt.Sprintf("key %q missing", r.spec.Key)
+	case r.spec.Equals != "":
+		if valueString(v) != r.spec.Equals {
+			return fmt.Sprintf("key %q = %q, want %q", r.spec.Key, valueString(v), r.spec.Equals)
+		}
+	case len(r.spec.OneOf) > 0:
+		if !contains(r.spec.OneOf, valueString(v)) {
+			return fmt.Sprintf("key %q = %q, want one of %q", r.spec.Key, valueString(v), r.spec.OneOf)
+		}
+	case r.regex != nil:
+		if !r.regex.MatchString(valueString(v)) {
+			return fmt.Sprintf("key %q = %q, want /%s/", r.spec.Key, valueString(v), r.spec.Regex)
+		}
+	}
+	return ""
+}
+
+// lookupKey — cheia exactă întâi (payload-uri plate cu "." în nume), apoi path
+// prin obiecte imbricate. O valoare JSON null contează ca prezentă.
+func lookupKey(payload map[string]interface{}, key string, path []string) (interface{}, bool) {
+	if v, ok := payload[key]; ok {
+		return v, true
+	}
+	var cur interface{} = payload
+	for _, p := range path {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[p]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// valueString — forma text cu care se compară equals / one_of / regex; JSON
+// numbers (float64) fără zecimale inutile: 1 → "1", 21.5 → "21.5".
+func valueString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
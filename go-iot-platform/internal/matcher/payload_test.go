@@ -0,0 +1,60 @@
+package matcher
+
+import (
+	"testing"
+
+	"go-iot-platform/internal/registry"
+)
+
+func TestPayloadRules(t *testing.T) {
+	z2m := func(priority int, rules ...registry.PayloadRule) registry.TopicMatchSpec {
+		return registry.TopicMatchSpec{Pattern: "zigbee2mqtt/+", Stream: "zigbee", Priority: priority,
+			PayloadMatch: rules, Extract: map[string]string{"device_id": "$1"}}
+	}
+	reg := registry.NewRegistry()
+	for _, dd := range []*registry.DeviceDefinition{
+		// "a_" < "z_": fără priority, fallback-ul ar fi încercat primul
+		ddWith("a_fallback", z2m(0)),
+		ddWith("z_plug", z2m(5, registry.PayloadRule{Key: "model", OneOf: []string{"TS011F", "ZNCZ02LM"}})),
+		ddWith("z_door", z2m(10, registry.PayloadRule{Key: "contact"}, registry.PayloadRule{Key: "tamper", Absent: true})),
+		ddWith("z_meter", z2m(5, registry.PayloadRule{Key: "ENERGY.Power", Regex: `^\d+$`})),
+		ddWith("z_alarm", z2m(5, registry.PayloadRule{Key: "alarm", Equals: "true"})),
+	} {
+		if err := reg.Add(dd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, errs := New(reg)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	cases := map[string]string{
+		`{"contact":true}`:                  "z_door",
+		`{"contact":true,"tamper":false}`:   "a_fallback", // absent încălcat
+		`{"model":"TS011F","contact":null}`: "z_door",     // null = cheie prezentă; priority 10 > 5
+		`{"model":"TS011F"}`:                "z_plug",
+		`{"model":"other"}`:                 "a_fallback",
+		`{"ENERGY":{"Power":42}}`:           "z_meter",
+		`{"ENERGY.Power":"42"}`:             "z_meter", // cheie plată cu punct
+		`{"ENERGY":{"Power":4.2}}`:          "a_fallback",
+		`{"alarm":true}`:                    "z_alarm",
+		`[1,2]`:                             "a_fallback",
+	}
+	for payload, want := range cases {
+		got := m.MatchMessage("zigbee2mqtt/dev1", []byte(payload))
+		if got == nil || got.Definition.ID != want {
+			t.Errorf("%s → %v, want %s", payload, got, want)
+			continue
+		}
+		if got.Extracted["device_id"] != "dev1" {
+			t.Errorf("%s: extracted %v", payload, got.Extracted)
+		}
+	}
+	if got := m.MatchPayload("zigbee2mqtt/dev1", map[string]interface{}{"contact": false}); got == nil || got.Definition.ID != "z_door" {
+		t.Errorf("MatchPayload → %v", got)
+	}
+	if got := m.Match("zigbee2mqtt/dev1"); got == nil || got.Definition.ID != "a_fallback" {
+		t.Errorf("Match → %v", got)
+	}
+}
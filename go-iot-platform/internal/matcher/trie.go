@@ -0,0 +1,68 @@
+package matcher
+
+import "strings"
+
+// trieNode — index pe segmente pentru pattern-urile MQTT (fără `~`).
+//
+// Fiecare pattern e identificat prin rank = poziția în Matcher.patterns (ordinea
+// din registry.MatchOrder); la match se colectează rank-urile tuturor
+// pattern-urilor care prind topicul, iar câștigă cel mai mic — aceeași regulă
+// "primul în ordine" ca scanarea liniară, dar cu cost O(adâncime topic) în loc
+// de O(număr pattern-uri).
+//
+// Semantica e cea din mqttToRegex, segment cu segment:
+//   - literal: egal exact (inclusiv segmentul gol din "/x/…")
+//   - "+": orice segment nevid
+//   - "#" (ultimul): cel puțin încă un segment, posibil gol — "a/#" prinde "a/"
+//     și "a/b/c", dar nu "a"; "#" singur prinde orice
+type trieNode struct {
+	children map[string]*trieNode
+	plus     *trieNode
+	hash     []int // pattern-uri care se termină cu "#" după acest nod
+	end      []int // pattern-uri care se termină exact în acest nod
+}
+
+func newTrieNode() *trieNode {
+	return &trieNode{children: map[string]*trieNode{}}
+}
+
+// insert adaugă pattern-ul (deja validat de mqttToRegex) cu rank-ul dat.
+func (n *trieNode) insert(pattern string, rank int) {
+	for _, seg := range strings.Split(pattern, "/") {
+		switch seg {
+		case "#":
+			n.hash = append(n.hash, rank)
+			return // "#" e mereu ultimul segment
+		case "+":
+			if n.plus == nil {
+				n.plus = newTrieNode()
+			}
+			n = n.plus
+		default:
+			child, ok := n.children[seg]
+			if !ok {
+				child = newTrieNode()
+				n.children[seg] = child
+			}
+			n = child
+		}
+	}
+	n.end = append(n.end, rank)
+}
+
+// collect adaugă la out rank-urile tuturor pattern-urilor care prind segs[i:].
+func (n *trieNode) collect(segs []string, i int, out []int) []int {
+	if len(segs) > i {
+		out = append(out, n.hash...)
+	}
+	if i == len(segs) {
+		return append(out, n.end...)
+	}
+	if child, ok := n.children[segs[i]]; ok {
+		out = child.collect(segs, i+1, out)
+	}
+	if n.plus != nil && segs[i] != "" {
+		out = n.plus.collect(segs, i+1, out)
+	}
+	return out
+}
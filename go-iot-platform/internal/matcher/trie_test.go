@@ -0,0 +1,195 @@
+package matcher
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-iot-platform/internal/registry"
+)
+
+// matchLinear — implementarea de referință (scanare liniară a tuturor regex-urilor,
+// ca înainte de trie); rezultatul trebuie să fie identic cu Match / MatchMessage.
+func matchLinear(m *Matcher, topic string, payload map[string]interface{}) *Match {
+	for _, cp := range m.patterns {
+		if len(cp.payload) > 0 && (payload == nil || !matchPayload(cp.payload, payload)) {
+			continue
+		}
+		if groups := cp.regex.FindStringSubmatch(topic); groups != nil {
+			return &Match{
+				Definition: cp.dd,
+				Pattern:    cp.spec.Pattern,
+				Stream:     cp.spec.Stream,
+				Extracted:  extract(cp.spec.Extract, groups, cp.regex.SubexpNames(), cp.mqttPos),
+			}
+		}
+	}
+	return nil
+}
+
+func TestTrieSemantics(t *testing.T) {
+	cases := []struct {
+		pattern string
+		match   []string
+		noMatch []string
+	}{
+		{"a/#", []string{"a/", "a/b", "a/b/c"}, []string{"a", "b/a"}},
+		{"#", []string{"", "a", "/a/b"}, nil},
+		{"a/+", []string{"a/b"}, []string{"a/", "a", "a/b/c"}},
+		{"+/+", []string{"a/b"}, []string{"/b", "a/", "a"}},
+		{"/+/x", []string{"/1/x"}, []string{"1/x", "//x"}},
+		{"a//b", []string{"a//b"}, []string{"a/b"}},
+		{"+/#", []string{"a/", "a/b/c"}, []string{"a", "/b"}},
+	}
+	for _, tc := range cases {
+		root := newTrieNode()
+		root.insert(tc.pattern, 0)
+		for _, topic := range tc.match {
+			if got := root.collect(strings.Split(topic, "/"), 0, nil); len(got) != 1 {
+				t.Errorf("%q should match %q", tc.pattern, topic)
+			}
+		}
+		for _, topic := range tc.noMatch {
+			if got := root.collect(strings.Split(topic, "/"), 0, nil); len(got) != 0 {
+				t.Errorf("%q should not match %q", tc.pattern, topic)
+			}
+		}
+	}
+}
+
+// TestTrieMatchesLinear — pattern-uri și topicuri aleatoare din același alfabet
+// mic (ca să existe multe suprapuneri); trie + fallback regex == scanare liniară,
+// inclusiv priority și payload_match.
+func TestTrieMatchesLinear(t *testing.T) {
+	rng := rand.New(rand.NewSource(46))
+	segs := []string{"a", "b", "c", "", "1"}
+	randomTopic := func() string {
+		parts := make([]string, 1+rng.Intn(4))
+		for i := range parts {
+			parts[i] = segs[rng.Intn(len(segs))]
+		}
+		return strings.Join(parts, "/")
+	}
+	randomPattern := func() string {
+		if rng.Intn(8) == 0 {
+			return `~^(?P<x>[ab])/(.*)$`
+		}
+		parts := strings.Split(randomTopic(), "/")
+		for i := range parts {
+			switch rng.Intn(4) {
+			case 0:
+				parts[i] = "+"
+			case 1:
+				if i == len(parts)-1 {
+					parts[i] = "#"
+				}
+			}
+		}
+		return strings.Join(parts, "/")
+	}
+
+	reg := registry.NewRegistry()
+	for d := 0; d < 40; d++ {
+		var specs []registry.TopicMatchSpec
+		for p := 0; p < 1+rng.Intn(4); p++ {
+			spec := registry.TopicMatchSpec{Pattern: randomPattern(), Stream: fmt.Sprint("s", p),
+				Priority: rng.Intn(3), Extract: map[string]string{"v": "$1", "x": "x"}}
+			if rng.Intn(5) == 0 {
+				spec.PayloadMatch = []registry.PayloadRule{{Key: "k"}}
+			}
+			specs = append(specs, spec)
+		}
+		if err := reg.Add(ddWith(fmt.Sprintf("dd%02d", d), specs...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, _ := New(reg)
+
+	withKey := map[string]interface{}{"k": 1}
+	for i := 0; i < 5000; i++ {
+		topic := randomTopic()
+		if got, want := m.Match(topic), matchLinear(m, topic, nil); !reflect.DeepEqual(got, want) {
+			t.Fatalf("Match(%q) = %+v, linear = %+v", topic, got, want)
+		}
+		if got, want := m.MatchPayload(topic, withKey), matchLinear(m, topic, withKey); !reflect.DeepEqual(got, want) {
+			t.Fatalf("MatchPayload(%q) = %+v, linear = %+v", topic, got, want)
+		}
+	}
+}
+
+// syntheticMatcher — n pattern-uri în stilul DD-urilor reale: 5 pattern-uri per
+// vendor (topic vendor cu "+", schema platform-nativă, un "#"); un vendor din
+// 10 are și un pattern regex (2% din total).
+func syntheticMatcher(tb testing.TB, n int) (*Matcher, []string) {
+	tb.Helper()
+	reg := registry.NewRegistry()
+	var topics []string
+	for v := 0; v*5 < n; v++ {
+		vendor := fmt.Sprintf("vendor%d", v)
+		specs := []registry.TopicMatchSpec{
+			{Pattern: vendor + "/+/telemetry", Stream: "telemetry", Extract: map[string]string{"device_id": "$1"}},
+			{Pattern: vendor + "/+/state", Stream: "state", Extract: map[string]string{"device_id": "$1"}},
+			{Pattern: "tenants/+/devices/+/up/" + vendor, Stream: "telemetry", Extract: map[string]string{"tenant_id": "$1", "device_id": "$2"}},
+			{Pattern: vendor + "/events/#", Stream: "events"},
+		}
+		if v%10 == 0 {
+			specs = append(specs, registry.TopicMatchSpec{Pattern: fmt.Sprintf(`~^/(?P<device_id>\d+)/%s/[^/]+/telemetry$`, vendor), Stream: "telemetry",
+				Extract: map[string]string{"device_id": "device_id"}})
+		} else {
+			specs = append(specs, registry.TopicMatchSpec{Pattern: vendor + "/+/cmd/+", Stream: "cmd_ack"})
+		}
+		if err := reg.Add(ddWith(fmt.Sprintf("dd_%05d", v), specs...)); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	m, errs := New(reg)
+	if len(errs) > 0 {
+		tb.Fatal(errs)
+	}
+	last := fmt.Sprintf("vendor%d", (n-1)/5)
+	topics = []string{
+		"vendor0/dev1/telemetry",               // primul DD
+		last + "/dev1/state",                   // ultimul DD — worst case pentru scanarea liniară
+		"tenants/2/devices/dev1/up/" + last,    // adâncime 6
+		"/12345/" + last + "/x/telemetry",      // regex fallback
+		"unknown/topic/that/wont/match/at/all", // niciun pattern
+	}
+	return m, topics
+}
+
+func benchmarkMatch(b *testing.B, n int, linear bool) {
+	m, topics := syntheticMatcher(b, n)
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, t := range topics {
+			if linear {
+				_ = matchLinear(m, t, nil)
+			} else {
+				_ = m.Match(t)
+			}
+		}
+	}
+}
+
+func BenchmarkMatch1k(b *testing.B)        { benchmarkMatch(b, 1000, false) }
+func BenchmarkMatch10k(b *testing.B)       { benchmarkMatch(b, 10000, false) }
+func BenchmarkMatchLinear1k(b *testing.B)  { benchmarkMatch(b, 1000, true) }
+func BenchmarkMatchLinear10k(b *testing.B) { benchmarkMatch(b, 10000, true) }
+
+func TestSyntheticMatcher(t *testing.T) {
+	m, topics := syntheticMatcher(t, 1000)
+	if m.Count() != 1000 {
+		t.Fatalf("Count = %d", m.Count())
+	}
+	for _, topic := range topics {
+		if got, want := m.Match(topic), matchLinear(m, topic, nil); !reflect.DeepEqual(got, want) {
+			t.Errorf("Match(%q) = %+v, linear = %+v", topic, got, want)
+		}
+	}
+	if got := m.Match("/12345/vendor190/x/telemetry"); got == nil || got.Definition.ID != "dd_00190" || got.Extracted["device_id"] != "12345" {
+		t.Errorf("regex fallback: %+v", got)
+	}
+}
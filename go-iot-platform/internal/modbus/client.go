@@ -0,0 +1,168 @@
+// Package modbus — Faza 7.5: client Modbus TCP minimal + collector care citește
+// registrele declarate în DD-uri (protocol: modbus_tcp) și le publică pe MQTT
+// ca telemetrie platform-nativă.
+//
+// Clientul acoperă doar ce ne trebuie pentru invertoare / contoare: FC 0x03
+// (read holding registers) și FC 0x04 (read input registers), un request în zbor
+// pe conexiune. Scrierea registrelor (comenzi) nu e implementată.
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Function codes suportate.
+const (
+	FuncReadHolding byte = 0x03
+	FuncReadInput   byte = 0x04
+)
+
+// MaxReadQuantity — limita de registre per request din specificația Modbus.
+const MaxReadQuantity = 125
+
+// DefaultTimeout — termenul per request când contextul nu are deadline.
+const DefaultTimeout = 5 * time.Second
+
+// ExceptionError — răspuns de excepție de la slave (function code | 0x80).
+type ExceptionError struct {
+	Function byte
+	Code     byte
+}
+
+func (e *ExceptionError) Error() string {
+	name := map[byte]string{
+		0x01: "illegal function",
+		0x02: "illegal data address",
+		0x03: "illegal data value",
+		0x04: "server device failure",
+		0x06: "server device busy",
+		0x0B: "gateway target device failed to respond",
+	}[e.Code]
+	if name == "" {
+		name = "unknown exception"
+	}
+	return fmt.Sprintf("modbus: function 0x%02x: exception 0x%02x (%s)", e.Function, e.Code, name)
+}
+
+// Client — o conexiune Modbus TCP. Sigur pentru folosire concurentă (request-urile
+// sunt serializate); după o eroare de I/O conexiunea trebuie închisă și refăcută.
+type Client struct {
+	mu      sync.Mutex
+	conn    net.Conn
+	txID    uint16
+	Timeout time.Duration
+}
+
+// Dial deschide conexiunea TCP către addr (host:port, implicit port 502 e
+// responsabilitatea apelantului).
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("modbus: dial %s: %w", addr, err)
+	}
+	return &Client{conn: conn, Timeout: DefaultTimeout}, nil
+}
+
+// Close închide conexiunea.
+func (c *Client) Close() error { return c.conn.Close() }
+
+// ReadHoldingRegisters — FC 0x03.
+func (c *Client) ReadHoldingRegisters(ctx context.Context, unit byte, addr, qty uint16) ([]uint16, error) {
+	return c.ReadRegisters(ctx, FuncReadHolding, unit, addr, qty)
+}
+
+// ReadInputRegisters — FC 0x04.
+func (c *Client) ReadInputRegisters(ctx context.Context, unit byte, addr, qty uint16) ([]uint16, error) {
+	return c.ReadRegisters(ctx, FuncReadInput, unit, addr, qty)
+}
+
+// ReadRegisters citește qty registre de 16 biți începând cu addr, cu function
+// code-ul dat (FuncReadHolding / FuncReadInput).
+func (c *Client) ReadRegisters(ctx context.Context, fn byte, unit byte, addr, qty uint16) ([]uint16, error) {
+	if fn != FuncReadHolding && fn != FuncReadInput {
+		return nil, fmt.Errorf("modbus: function 0x%02x not supported", fn)
+	}
+	if qty == 0 || qty > MaxReadQuantity {
+		return nil, fmt.Errorf("modbus: quantity %d out of range 1-%d", qty, MaxReadQuantity)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.Timeout)
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	c.txID++
+	req := make([]byte, 12)
+	binary.BigEndian.PutUint16(req[0:], c.txID)
+	binary.BigEndian.PutUint16(req[2:], 0) // protocol id
+	binary.BigEndian.PutUint16(req[4:], 6) // unit + PDU
+	req[6] = unit
+	req[7] = fn
+	binary.BigEndian.PutUint16(req[8:], addr)
+	binary.BigEndian.PutUint16(req[10:], qty)
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, fmt.Errorf("modbus: write: %w", err)
+	}
+
+	pdu, err := c.readResponse(c.txID, unit)
+	if err != nil {
+		return nil, err
+	}
+	if pdu[0] == fn|0x80 {
+		if len(pdu) < 2 {
+			return nil, errors.New("modbus: short exception response")
+		}
+		return nil, &ExceptionError{Function: fn, Code: pdu[1]}
+	}
+	if pdu[0] != fn {
+		return nil, fmt.Errorf("modbus: response function 0x%02x, want 0x%02x", pdu[0], fn)
+	}
+	if len(pdu) < 2 || int(pdu[1]) != 2*int(qty) || len(pdu) != 2+2*int(qty) {
+		return nil, fmt.Errorf("modbus: response carries %d bytes, want %d", len(pdu)-2, 2*qty)
+	}
+	out := make([]uint16, qty)
+	for i := range out {
+		out[i] = binary.BigEndian.Uint16(pdu[2+2*i:])
+	}
+	return out, nil
+}
+
+// readResponse citește un frame MBAP și întoarce PDU-ul (function code + date).
+func (c *Client) readResponse(txID uint16, unit byte) ([]byte, error) {
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, fmt.Errorf("modbus: read header: %w", err)
+	}
+	length := binary.BigEndian.Uint16(header[4:])
+	if length < 2 || length > 254 {
+		return nil, fmt.Errorf("modbus: invalid frame length %d", length)
+	}
+	pdu := make([]byte, length-1)
+	if _, err := io.ReadFull(c.conn, pdu); err != nil {
+		return nil, fmt.Errorf("modbus: read pdu: %w", err)
+	}
+	if got := binary.BigEndian.Uint16(header[0:]); got != txID {
+		return nil, fmt.Errorf("modbus: transaction id %d, want %d", got, txID)
+	}
+	if binary.BigEndian.Uint16(header[2:]) != 0 {
+		return nil, errors.New("modbus: protocol id is not 0")
+	}
+	if header[6] != unit {
+		return nil, fmt.Errorf("modbus: unit id %d, want %d", header[6], unit)
+	}
+	return pdu, nil
+}
@@ -0,0 +1,94 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go-iot-platform/internal/modbus/modbustest"
+)
+
+func newServer(t *testing.T) *modbustest.Server {
+	t.Helper()
+	srv, err := modbustest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClientReadRegisters(t *testing.T) {
+	srv := newServer(t)
+	srv.SetHolding(1, 100, 0x0001, 0x0002, 0xFFFF)
+	srv.SetInput(1, 100, 0x1234)
+	srv.SetHolding(2, 100, 0x0009)
+
+	ctx := context.Background()
+	c, err := Dial(ctx, srv.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if got, err := c.ReadHoldingRegisters(ctx, 1, 100, 3); err != nil || !reflect.DeepEqual(got, []uint16{1, 2, 0xFFFF}) {
+		t.Errorf("holding = %v, %v", got, err)
+	}
+	if got, err := c.ReadInputRegisters(ctx, 1, 100, 1); err != nil || !reflect.DeepEqual(got, []uint16{0x1234}) {
+		t.Errorf("input = %v, %v", got, err)
+	}
+	if got, err := c.ReadHoldingRegisters(ctx, 2, 100, 1); err != nil || got[0] != 9 {
+		t.Errorf("unit 2 = %v, %v", got, err)
+	}
+
+	// adresă nesetată → excepție 0x02; conexiunea rămâne utilizabilă
+	_, err = c.ReadHoldingRegisters(ctx, 1, 101, 5)
+	var exc *ExceptionError
+	if !errors.As(err, &exc) || exc.Code != 0x02 || exc.Function != FuncReadHolding {
+		t.Fatalf("want illegal data address, got %v", err)
+	}
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 100, 1); err != nil {
+		t.Errorf("after exception: %v", err)
+	}
+
+	if _, err := c.ReadRegisters(ctx, 0x06, 1, 100, 1); err == nil {
+		t.Error("write function accepted")
+	}
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 100, MaxReadQuantity+1); err == nil {
+		t.Error("quantity > 125 accepted")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		regs  []uint16
+		typ   string
+		order string
+		want  float64
+	}{
+		{[]uint16{0xFFFE}, "int16", "", -2},
+		{[]uint16{0xFFFE}, "uint16", "", 65534},
+		{[]uint16{0x0201}, "uint16", "BADC", 0x0102},
+		{[]uint16{0x0201}, "uint16", "CDAB", 0x0201},
+		{[]uint16{0x0001, 0x0002}, "uint32", "ABCD", 0x00010002},
+		{[]uint16{0x0002, 0x0001}, "uint32", "CDAB", 0x00010002},
+		{[]uint16{0x0100, 0x0200}, "uint32", "BADC", 0x00010002},
+		{[]uint16{0x0200, 0x0100}, "uint32", "DCBA", 0x00010002},
+		{[]uint16{0xFFFF, 0xDE33}, "int32", "ABCD", -8653},
+		{[]uint16{0x4148, 0x0000}, "float32", "ABCD", 12.5},
+		{[]uint16{0x0000, 0x4148}, "float32", "CDAB", 12.5},
+	}
+	for _, tc := range cases {
+		got, err := Decode(tc.regs, tc.typ, tc.order)
+		if err != nil || got != tc.want {
+			t.Errorf("Decode(%04x, %s, %s) = %v, %v; want %v", tc.regs, tc.typ, tc.order, got, err, tc.want)
+		}
+	}
+	if _, err := Decode([]uint16{1}, "int32", ""); err == nil {
+		t.Error("short register slice accepted")
+	}
+	if _, err := Decode([]uint16{1}, "bool", ""); err == nil {
+		t.Error("unknown type accepted")
+	}
+}
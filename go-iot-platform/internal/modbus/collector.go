@@ -0,0 +1,269 @@
+package modbus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"go-iot-platform/internal/registry"
+)
+
+// DefaultPollInterval — când nici endpoint-ul, nici DD-ul nu setează poll_interval.
+const DefaultPollInterval = 30 * time.Second
+
+// Endpoint — un device Modbus TCP de citit (configs/modbus/endpoints.yaml).
+//
+//	endpoints:
+//	  - serial: "102345678"
+//	    tenant_id: 1
+//	    definition: huawei_sun2000_modbus
+//	    address: 192.168.1.50:502
+//	    unit_id: 1            # opțional, suprascrie modbus.unit_id din DD
+//	    poll_interval: 10s    # opțional, suprascrie modbus.poll_interval din DD
+type Endpoint struct {
+	Serial       string `yaml:"serial"`
+	TenantID     int64  `yaml:"tenant_id"`
+	Definition   string `yaml:"definition"`
+	Address      string `yaml:"address"`
+	UnitID       *int   `yaml:"unit_id,omitempty"`
+	PollInterval string `yaml:"poll_interval,omitempty"`
+}
+
+// LoadEndpoints citește fișierul de endpoint-uri (strict: câmpurile necunoscute
+// sunt erori, ca la DD-uri).
+func LoadEndpoints(path string) ([]Endpoint, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file struct {
+		Endpoints []Endpoint `yaml:"endpoints"`
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil {
+		return nil, fmt.Errorf("%s: yaml decode: %w", path, err)
+	}
+	return file.Endpoints, nil
+}
+
+// Publisher publică un payload pe un topic MQTT (în cmd/modbus-collector: paho,
+// QoS 1).
+type Publisher func(topic string, payload []byte) error
+
+// DialFunc deschide conexiunea către un endpoint; default Dial.
+type DialFunc func(ctx context.Context, addr string) (Conn, error)
+
+// Conn — conexiunea folosită de collector (*Client o implementează).
+type Conn interface {
+	Reader
+	Close() error
+}
+
+// Collector citește periodic endpoint-urile și publică telemetria.
+type Collector struct {
+	publish Publisher
+	dial    DialFunc
+	targets []*target
+	now     func() time.Time
+}
+
+// target — un endpoint cu DD-ul rezolvat și planul de citire.
+type target struct {
+	ep       Endpoint
+	dd       *registry.DeviceDefinition
+	spec     registry.ModbusSpec
+	blocks   []Block
+	interval time.Duration
+	topic    string
+
+	mu   sync.Mutex
+	conn Conn
+}
+
+// New leagă fiecare endpoint de DD-ul lui din registry. Erorile de configurare
+// (DD lipsă / nu e modbus_tcp, câmpuri lipsă) sunt întoarse toate odată; un
+// Collector e construit doar dacă nu există niciuna.
+func New(reg *registry.Registry, endpoints []Endpoint, publish Publisher) (*Collector, error) {
+	c := &Collector{publish: publish, now: time.Now, dial: func(ctx context.Context, addr string) (Conn, error) {
+		return Dial(ctx, addr)
+	}}
+	var errs []error
+	for i, ep := range endpoints {
+		t, err := newTarget(reg, ep)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("endpoints[%d] (%s): %w", i, ep.Serial, err))
+			continue
+		}
+		c.targets = append(c.targets, t)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return c, nil
+}
+
+func newTarget(reg *registry.Registry, ep Endpoint) (*target, error) {
+	switch {
+	case ep.Serial == "":
+		return nil, errors.New("serial required")
+	case ep.TenantID <= 0:
+		return nil, errors.New("tenant_id must be positive")
+	case ep.Address == "":
+		return nil, errors.New("address required")
+	}
+	dd := reg.Get(ep.Definition)
+	if dd == nil {
+		return nil, fmt.Errorf("definition %q not in registry", ep.Definition)
+	}
+	if dd.Protocol != "modbus_tcp" || dd.Modbus == nil {
+		return nil, fmt.Errorf("definition %q is not protocol modbus_tcp", ep.Definition)
+	}
+
+	spec := *dd.Modbus
+	if ep.UnitID != nil {
+		if *ep.UnitID < 0 || *ep.UnitID > 255 {
+			return nil, fmt.Errorf("unit_id %d out of range 0-255", *ep.UnitID)
+		}
+		spec.UnitID = ep.UnitID
+	}
+	interval := DefaultPollInterval
+	for _, s := range []string{spec.PollInterval, ep.PollInterval} {
+		if s == "" {
+			continue
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("poll_interval %q invalid", s)
+		}
+		interval = d
+	}
+	stream := spec.Stream
+	if stream == "" {
+		stream = "telemetry"
+	}
+	return &target{
+		ep: ep, dd: dd, spec: spec, blocks: Plan(&spec), interval: interval,
+		topic: fmt.Sprintf("tenants/%d/devices/%s/up/%s", ep.TenantID, ep.Serial, stream),
+	}, nil
+}
+
+// Run pornește câte o buclă de polling per endpoint și blochează până la
+// anularea contextului. Prima citire e imediată.
+func (c *Collector) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range c.targets {
+		wg.Add(1)
+		go func(t *target) {
+			defer wg.Done()
+			defer t.close()
+			ticker := time.NewTicker(t.interval)
+			defer ticker.Stop()
+			for {
+				if err := c.poll(ctx, t); err != nil && ctx.Err() == nil {
+					log.Printf("modbus: %s (%s): %v", t.ep.Serial, t.ep.Address, err)
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+				}
+			}
+		}(t)
+	}
+	wg.Wait()
+}
+
+// PollOnce citește și publică o dată toate endpoint-urile (secvențial); întoarce
+// erorile per endpoint. Folosit de teste și de `modbus-collector -once`.
+func (c *Collector) PollOnce(ctx context.Context) error {
+	var errs []error
+	for _, t := range c.targets {
+		if err := c.poll(ctx, t); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", t.ep.Serial, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// poll — o citire completă a unui endpoint. Se publică orice s-a citit, chiar
+// dacă unele blocuri au eșuat; o eroare de transport închide conexiunea, care
+// se redeschide la următorul tick.
+func (c *Collector) poll(ctx context.Context, t *target) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.conn == nil {
+		conn, err := c.dial(ctx, t.ep.Address)
+		if err != nil {
+			return err
+		}
+		t.conn = conn
+	}
+
+	values, errs := ReadAll(ctx, t.conn, &t.spec, t.blocks)
+	if errors.Is(errors.Join(errs...), ErrConnBroken) {
+		// timeout / conexiune ruptă: răspunsul poate fi încă în buffer,
+		// deci nu refolosim conexiunea
+		t.conn.Close()
+		t.conn = nil
+	}
+	if len(values) > 0 {
+		payload, err := BuildPayload(t.dd, values, c.now())
+		if err != nil {
+			return err
+		}
+		if err := c.publish(t.topic, payload); err != nil {
+			errs = append(errs, fmt.Errorf("publish %s: %w", t.topic, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *target) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conn != nil {
+		t.conn.Close()
+		t.conn = nil
+	}
+}
+
+// BuildPayload serializează valorile în formatul parser-ului din DD, ca
+// ingest-ul să le decodeze exact ca pe un mesaj trimis de un collector MQTT:
+//
+//	json_with_measurements_array → {"ts": …, "<payload_path>": [{"<key>": name, "<value>": v}, …]}
+//	json                         → {"ts": …, "<name>": v, …}
+func BuildPayload(dd *registry.DeviceDefinition, values []Measurement, ts time.Time) ([]byte, error) {
+	out := map[string]interface{}{"ts": ts.UTC().Format(time.RFC3339)}
+	switch dd.Parser.Type {
+	case "json_with_measurements_array":
+		keyField, valField := dd.Parser.MeasurementKeyField, dd.Parser.MeasurementValueField
+		if keyField == "" {
+			keyField = "key"
+		}
+		if valField == "" {
+			valField = "value"
+		}
+		arr := make([]map[string]interface{}, 0, len(values))
+		for _, m := range values {
+			arr = append(arr, map[string]interface{}{keyField: m.Key, valField: m.Value})
+		}
+		out[dd.Parser.PayloadPath] = arr
+	case "json":
+		for _, m := range values {
+			out[m.Key] = m.Value
+		}
+	default:
+		return nil, fmt.Errorf("parser.type %q not supported for modbus_tcp", dd.Parser.Type)
+	}
+	return json.Marshal(out)
+}
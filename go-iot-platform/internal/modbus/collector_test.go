@@ -0,0 +1,328 @@
+package modbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-iot-platform/internal/parsers"
+	"go-iot-platform/internal/registry"
+)
+
+func TestPlan(t *testing.T) {
+	spec := &registry.ModbusSpec{MaxGap: 2, Registers: []registry.RegisterSpec{
+		{Name: "c", Address: 20, Type: "int16"},
+		{Name: "a", Address: 10, Type: "int32"},
+		{Name: "b", Address: 13, Type: "uint16"}, // gol 1 → același bloc
+		{Name: "i", Address: 10, Type: "uint16", Table: "input"},
+		{Name: "far", Address: 200, Type: "float32"},
+	}}
+	var got []string
+	for _, b := range Plan(spec) {
+		var names []string
+		for _, r := range b.Registers {
+			names = append(names, r.Name)
+		}
+		got = append(got, fmt.Sprintf("%d %d+%d %s", b.Function, b.Start, b.Quantity, strings.Join(names, ",")))
+	}
+	want := []string{"3 10+4 a,b", "3 20+1 c", "3 200+2 far", "4 10+1 i"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Plan = %q, want %q", got, want)
+	}
+
+	// un bloc nu depășește MaxReadQuantity
+	spec = &registry.ModbusSpec{MaxGap: 1000, Registers: []registry.RegisterSpec{
+		{Name: "x", Address: 0, Type: "uint16"}, {Name: "y", Address: 124, Type: "uint32"},
+	}}
+	if blocks := Plan(spec); len(blocks) != 2 {
+		t.Errorf("blocks over 125 registers merged: %+v", blocks)
+	}
+}
+
+type published struct {
+	topic   string
+	payload []byte
+}
+
+func newTestCollector(t *testing.T, reg *registry.Registry, eps []Endpoint) (*Collector, *[]published) {
+	t.Helper()
+	var out []published
+	c, err := New(reg, eps, func(topic string, payload []byte) error {
+		out = append(out, published{topic, payload})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.now = func() time.Time { return time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC) }
+	return c, &out
+}
+
+func productionRegistry(t *testing.T) *registry.Registry {
+	t.Helper()
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil || len(errs) > 0 || reg.Get("huawei_sun2000_modbus") == nil {
+		t.Skipf("configs/devices/ not loadable: %v %v", err, errs)
+	}
+	return reg
+}
+
+// TestCollectorHuawei — DD-ul de producție contra serverului in-process: payload-ul
+// publicat trece prin parser-ul + normalized_fields ale DD-ului ca orice
+// telemetrie MQTT.
+func TestCollectorHuawei(t *testing.T) {
+	reg := productionRegistry(t)
+	srv := newServer(t)
+	srv.SetHolding(1, 32064, 0x0000, 0x21D4) // 8660 W
+	srv.SetHolding(1, 32080, 0xFFFF, 0xFE0C) // -500 W
+	srv.SetHolding(1, 32087, 0x01C5)         // 45.3 °C
+	srv.SetHolding(1, 32114, 0x0000, 0x0C1C) // 31.00 kWh
+	srv.SetHolding(1, 37001, 0xFFFF, 0xFC18) // -1000 W
+	srv.SetHolding(1, 37004, 0x03DE)         // 99.0 %
+	srv.SetHolding(1, 37022, 0x00FA)         // 25.0 °C
+	srv.SetHolding(1, 37113, 0x0000, 0x0190) // 400 W
+
+	c, out := newTestCollector(t, reg, []Endpoint{{
+		Serial: "102345678", TenantID: 7, Definition: "huawei_sun2000_modbus", Address: srv.Addr(),
+	}})
+	if err := c.PollOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(*out) != 1 || (*out)[0].topic != "tenants/7/devices/102345678/up/telemetry" {
+		t.Fatalf("published = %+v", *out)
+	}
+	if srv.Requests() != 8 { // max_gap 0 → un request per registru
+		t.Errorf("requests = %d, want 8", srv.Requests())
+	}
+
+	dd := reg.Get("huawei_sun2000_modbus")
+	fields, err := parsers.Parse(dd, (*out)[0].topic, nil, (*out)[0].payload)
+	if err != nil {
+		t.Fatalf("parse %s: %v", (*out)[0].payload, err)
+	}
+	got := parsers.Normalize(dd, fields)
+	want := map[string]interface{}{
+		"solar_power_kw": 8.66, "active_power_w": -500.0, "inverter_temp_c": 45.3,
+		"daily_yield_kwh": 31.0, "battery_power_kw": -1.0, "battery_soc_pct": 99.0,
+		"battery_temp_c": 25.0, "grid_power_w": 400.0,
+	}
+	for k, v := range want {
+		if g, _ := parsers.ToFloat(got[k]); g != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+	if fields["ts"] != "2026-05-10T12:00:00Z" {
+		t.Errorf("ts = %v", fields["ts"])
+	}
+}
+
+const meterDD = `
+schema_version: "1.1"
+id: meter
+name: "Meter"
+vendor: testco
+protocol: modbus_tcp
+parser:
+  type: json
+capabilities: [power_meter]
+normalized_fields:
+  active_power_w: {source: power, unit: W}
+modbus:
+  unit_id: 3
+  poll_interval: 1m
+  stream: meter
+  byte_order: CDAB
+  max_gap: 10
+  registers:
+    - {name: power, address: 0, type: int32}
+    - {name: voltage, address: 4, type: uint16, scale: 0.1}
+    - {name: missing, address: 50, type: uint16}
+    - {name: freq, address: 0, type: float32, table: input, byte_order: ABCD}
+`
+
+func meterRegistry(t *testing.T) *registry.Registry {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "meter.yaml"), []byte(meterDD), 0644); err != nil {
+		t.Fatal(err)
+	}
+	reg, errs, err := registry.LoadDir(dir)
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("LoadDir: %v %v", err, errs)
+	}
+	return reg
+}
+
+// TestCollectorPartialAndReconnect — un bloc cu excepție nu oprește restul;
+// după o conexiune căzută collector-ul se reconectează la următorul poll.
+func TestCollectorPartialAndReconnect(t *testing.T) {
+	reg := meterRegistry(t)
+	srv := newServer(t)
+	srv.SetHolding(3, 0, 0x86A0, 0x0001, 0, 0, 2301) // 100000 (CDAB), 230.1 V
+	srv.SetInput(3, 0, 0x4248, 0x0000)               // 50.0
+
+	c, out := newTestCollector(t, reg, []Endpoint{{Serial: "m1", TenantID: 2, Definition: "meter", Address: srv.Addr()}})
+	err := c.PollOnce(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "illegal data address") {
+		t.Errorf("want exception for register 50, got %v", err)
+	}
+	if len(*out) != 1 || (*out)[0].topic != "tenants/2/devices/m1/up/meter" {
+		t.Fatalf("published = %+v", *out)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal((*out)[0].payload, &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["power"] != 100000.0 || body["voltage"] != 230.1 || body["freq"] != 50.0 || body["missing"] != nil {
+		t.Errorf("payload = %s", (*out)[0].payload)
+	}
+
+	srv.SetHolding(3, 50, 1)
+	srv.DropConnections()
+	// primul poll după drop vede conexiunea ruptă și o închide; al doilea redial-ează
+	_ = c.PollOnce(context.Background())
+	if err := c.PollOnce(context.Background()); err != nil {
+		t.Fatalf("after reconnect: %v", err)
+	}
+	if last := (*out)[len(*out)-1]; !strings.Contains(string(last.payload), `"missing":1`) {
+		t.Errorf("last payload = %s", last.payload)
+	}
+}
+
+func TestCollectorRun(t *testing.T) {
+	reg := meterRegistry(t)
+	srv := newServer(t)
+	srv.SetHolding(3, 0, 0, 0, 0, 0, 0)
+	srv.SetHolding(3, 50, 0)
+	srv.SetInput(3, 0, 0, 0)
+
+	every := "20ms"
+	pubs := make(chan string, 16)
+	c, err := New(reg, []Endpoint{{Serial: "m1", TenantID: 2, Definition: "meter", Address: srv.Addr(), PollInterval: every}},
+		func(topic string, _ []byte) error { pubs <- topic; return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() { c.Run(ctx); close(done) }()
+	for i := 0; i < 3; i++ {
+		select {
+		case <-pubs:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("poll %d not published", i)
+		}
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not stop on cancel")
+	}
+}
+
+// TestCollectorUnitZero — unit_id: 0 explicit (gateway TCP) se trimite ca
+// atare, nu e confundat cu "nesetat" → 1.
+func TestCollectorUnitZero(t *testing.T) {
+	reg := meterRegistry(t)
+	srv := newServer(t)
+	srv.SetHolding(0, 0, 0x0005, 0, 0, 0, 2301)
+	srv.SetHolding(0, 50, 1)
+	srv.SetInput(0, 0, 0x4248, 0x0000)
+
+	zero := 0
+	c, out := newTestCollector(t, reg, []Endpoint{{Serial: "m1", TenantID: 2, Definition: "meter", Address: srv.Addr(), UnitID: &zero}})
+	if err := c.PollOnce(context.Background()); err != nil {
+		t.Fatalf("unit 0: %v", err)
+	}
+	if len(*out) != 1 || !strings.Contains(string((*out)[0].payload), `"power":5`) {
+		t.Errorf("published = %+v", *out)
+	}
+	if u := (&registry.ModbusSpec{}).Unit(); u != 1 {
+		t.Errorf("unset unit_id → %d, want 1", u)
+	}
+}
+
+// scriptedReader — întoarce erorile date, în ordine, apoi registre zero.
+type scriptedReader struct {
+	errs  []error
+	calls int
+}
+
+func (r *scriptedReader) ReadRegisters(_ context.Context, _ byte, _ byte, _, qty uint16) ([]uint16, error) {
+	r.calls++
+	if r.calls <= len(r.errs) && r.errs[r.calls-1] != nil {
+		return nil, r.errs[r.calls-1]
+	}
+	return make([]uint16, qty), nil
+}
+
+// TestReadAllStopsOnTransportError — huawei (max_gap 0) are 8 blocuri: un
+// SDongle mort nu trebuie să coste 8 × DefaultTimeout per poll.
+func TestReadAllStopsOnTransportError(t *testing.T) {
+	spec := productionRegistry(t).Get("huawei_sun2000_modbus").Modbus
+	blocks := Plan(spec)
+
+	// Excepție Modbus pe primul bloc: restul se citesc.
+	r := &scriptedReader{errs: []error{&ExceptionError{Function: 0x03, Code: 2}}}
+	values, errs := ReadAll(context.Background(), r, spec, blocks)
+	if r.calls != len(blocks) || len(values) != len(blocks)-1 {
+		t.Errorf("exception: calls=%d values=%d, want %d / %d", r.calls, len(values), len(blocks), len(blocks)-1)
+	}
+	if errors.Is(errors.Join(errs...), ErrConnBroken) {
+		t.Errorf("exception reported as broken connection: %v", errs)
+	}
+
+	// Timeout pe al doilea bloc: oprire imediată, conexiunea marcată ruptă.
+	r = &scriptedReader{errs: []error{nil, context.DeadlineExceeded}}
+	values, errs = ReadAll(context.Background(), r, spec, blocks)
+	if r.calls != 2 || len(values) != 1 {
+		t.Errorf("timeout: calls=%d values=%d, want 2 / 1", r.calls, len(values))
+	}
+	if !errors.Is(errors.Join(errs...), ErrConnBroken) {
+		t.Errorf("timeout not reported as broken connection: %v", errs)
+	}
+}
+
+func TestNewRejectsBadEndpoints(t *testing.T) {
+	reg := meterRegistry(t)
+	bad := -1
+	_, err := New(reg, []Endpoint{
+		{Serial: "a", TenantID: 1, Definition: "nope", Address: "x:502"},
+		{Serial: "b", TenantID: 0, Definition: "meter", Address: "x:502"},
+		{Serial: "c", TenantID: 1, Definition: "meter", Address: "x:502", UnitID: &bad},
+		{Serial: "d", TenantID: 1, Definition: "meter", Address: "x:502", PollInterval: "0s"},
+		{TenantID: 1, Definition: "meter", Address: "x:502"},
+	}, nil)
+	for _, want := range []string{
+		`endpoints[0] (a): definition "nope" not in registry`,
+		"endpoints[1] (b): tenant_id must be positive",
+		"endpoints[2] (c): unit_id -1 out of range",
+		`endpoints[3] (d): poll_interval "0s" invalid`,
+		"endpoints[4] (): serial required",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+}
+
+func TestLoadEndpoints(t *testing.T) {
+	eps, err := LoadEndpoints(filepath.Join("..", "..", "..", "configs", "modbus", "endpoints.yaml"))
+	if err != nil || len(eps) == 0 || eps[0].Definition != "huawei_sun2000_modbus" {
+		t.Errorf("example endpoints: %+v %v", eps, err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "e.yaml")
+	os.WriteFile(path, []byte("endpoints:\n  - serial: x\n    adress: 1.2.3.4:502\n"), 0644)
+	if _, err := LoadEndpoints(path); err == nil || !strings.Contains(err.Error(), "adress") {
+		t.Errorf("unknown field accepted: %v", err)
+	}
+}
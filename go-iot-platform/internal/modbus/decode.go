@@ -0,0 +1,64 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// Decode convertește registrele brute (1 sau 2, cum vin pe fir) într-o valoare
+// numerică, după tipul și ordinea octeților din DD.
+//
+// byteOrder descrie poziția pe fir a octeților valorii, A = cel mai semnificativ:
+// ABCD = big-endian (standardul Modbus), CDAB = cuvintele inversate (frecvent la
+// invertoare / contoare), BADC = octeții inversați în fiecare cuvânt, DCBA =
+// little-endian. Pentru tipurile pe 16 biți contează doar inversarea octeților
+// (BADC / DCBA).
+func Decode(regs []uint16, typ, byteOrder string) (float64, error) {
+	if byteOrder == "" {
+		byteOrder = "ABCD"
+	}
+	width := map[string]int{"int16": 1, "uint16": 1, "int32": 2, "uint32": 2, "float32": 2}[typ]
+	if width == 0 {
+		return 0, fmt.Errorf("modbus: type %q unknown", typ)
+	}
+	if len(regs) != width {
+		return 0, fmt.Errorf("modbus: type %s needs %d register(s), got %d", typ, width, len(regs))
+	}
+
+	wire := make([]byte, 2*width)
+	for i, r := range regs {
+		binary.BigEndian.PutUint16(wire[2*i:], r)
+	}
+	value := make([]byte, 2*width)
+	if width == 1 {
+		copy(value, wire)
+		if byteOrder == "BADC" || byteOrder == "DCBA" {
+			value[0], value[1] = wire[1], wire[0]
+		}
+	} else {
+		if len(byteOrder) != 4 {
+			return 0, fmt.Errorf("modbus: byte_order %q unknown", byteOrder)
+		}
+		for i := 0; i < 4; i++ {
+			pos := int(byteOrder[i] - 'A')
+			if pos < 0 || pos > 3 {
+				return 0, fmt.Errorf("modbus: byte_order %q unknown", byteOrder)
+			}
+			value[pos] = wire[i]
+		}
+	}
+
+	switch typ {
+	case "int16":
+		return float64(int16(binary.BigEndian.Uint16(value))), nil
+	case "uint16":
+		return float64(binary.BigEndian.Uint16(value)), nil
+	case "int32":
+		return float64(int32(binary.BigEndian.Uint32(value))), nil
+	case "uint32":
+		return float64(binary.BigEndian.Uint32(value)), nil
+	default: // float32
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(value))), nil
+	}
+}
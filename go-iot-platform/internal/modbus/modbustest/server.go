@@ -0,0 +1,168 @@
+// Package modbustest — server Modbus TCP in-process pentru teste (stand-in
+// pentru un invertor / gateway): registre holding / input setate din test,
+// FC 0x03 / 0x04, excepții pentru adrese nesetate și function codes necunoscute.
+package modbustest
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+// Server ascultă pe 127.0.0.1:0; Addr() dă adresa efectivă.
+type Server struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	holding  map[byte]map[uint16]uint16 // unit → adresă → valoare
+	input    map[byte]map[uint16]uint16
+	requests int
+	conns    map[net.Conn]bool
+	wg       sync.WaitGroup
+}
+
+// NewServer pornește serverul; închiderea e responsabilitatea testului (Close).
+func NewServer() (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:      ln,
+		holding: map[byte]map[uint16]uint16{},
+		input:   map[byte]map[uint16]uint16{},
+		conns:   map[net.Conn]bool{},
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Addr — host:port.
+func (s *Server) Addr() string { return s.ln.Addr().String() }
+
+// SetHolding scrie registre holding consecutive începând cu addr.
+func (s *Server) SetHolding(unit byte, addr uint16, values ...uint16) {
+	s.set(s.holding, unit, addr, values)
+}
+
+// SetInput scrie registre input consecutive începând cu addr.
+func (s *Server) SetInput(unit byte, addr uint16, values ...uint16) {
+	s.set(s.input, unit, addr, values)
+}
+
+// Requests — numărul de request-uri primite (pentru verificarea planului de citire).
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// DropConnections închide conexiunile deschise (simulează restartul device-ului).
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+// Close oprește serverul și așteaptă conexiunile.
+func (s *Server) Close() {
+	s.ln.Close()
+	s.DropConnections()
+	s.wg.Wait()
+}
+
+func (s *Server) set(table map[byte]map[uint16]uint16, unit byte, addr uint16, values []uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if table[unit] == nil {
+		table[unit] = map[uint16]uint16{}
+	}
+	for i, v := range values {
+		table[unit][addr+uint16(i)] = v
+	}
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	header := make([]byte, 7)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		length := binary.BigEndian.Uint16(header[4:])
+		if length < 2 {
+			return
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+		resp := s.handle(header[6], pdu)
+		out := make([]byte, 7, 7+len(resp))
+		copy(out, header[:4])
+		binary.BigEndian.PutUint16(out[4:], uint16(len(resp)+1))
+		out[6] = header[6]
+		if _, err := conn.Write(append(out, resp...)); err != nil {
+			return
+		}
+	}
+}
+
+// handle întoarce PDU-ul de răspuns.
+func (s *Server) handle(unit byte, pdu []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	fn := pdu[0]
+	var table map[uint16]uint16
+	switch fn {
+	case 0x03:
+		table = s.holding[unit]
+	case 0x04:
+		table = s.input[unit]
+	default:
+		return []byte{fn | 0x80, 0x01} // illegal function
+	}
+	if len(pdu) != 5 {
+		return []byte{fn | 0x80, 0x03} // illegal data value
+	}
+	addr, qty := binary.BigEndian.Uint16(pdu[1:]), binary.BigEndian.Uint16(pdu[3:])
+	if qty == 0 || qty > 125 {
+		return []byte{fn | 0x80, 0x03}
+	}
+	resp := []byte{fn, byte(2 * qty)}
+	for i := uint16(0); i < qty; i++ {
+		v, ok := table[addr+i]
+		if !ok {
+			return []byte{fn | 0x80, 0x02} // illegal data address
+		}
+		resp = binary.BigEndian.AppendUint16(resp, v)
+	}
+	return resp
+}
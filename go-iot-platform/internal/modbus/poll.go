@@ -0,0 +1,133 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"go-iot-platform/internal/registry"
+)
+
+// Reader — sursa de registre; *Client o implementează, testele pot folosi un fake.
+type Reader interface {
+	ReadRegisters(ctx context.Context, fn byte, unit byte, addr, qty uint16) ([]uint16, error)
+}
+
+// Block — un request de citire: registre contigue (plus golurile tolerate prin
+// max_gap) din aceeași tabelă, maxim MaxReadQuantity.
+type Block struct {
+	Function  byte
+	Start     uint16
+	Quantity  uint16
+	Registers []registry.RegisterSpec
+}
+
+// Plan grupează registrele din DD în cât mai puține request-uri: sortate după
+// tabelă și adresă, unite cât timp golul dintre ele e <= max_gap și blocul nu
+// depășește MaxReadQuantity.
+func Plan(spec *registry.ModbusSpec) []Block {
+	regs := append([]registry.RegisterSpec{}, spec.Registers...)
+	sort.SliceStable(regs, func(i, j int) bool {
+		fi, fj := function(regs[i].Table), function(regs[j].Table)
+		if fi != fj {
+			return fi < fj
+		}
+		return regs[i].Address < regs[j].Address
+	})
+
+	var blocks []Block
+	for _, r := range regs {
+		fn, width := function(r.Table), registry.ModbusRegisterTypes[r.Type]
+		end := int(r.Address) + width
+		if n := len(blocks); n > 0 {
+			b := &blocks[n-1]
+			bEnd := int(b.Start) + int(b.Quantity)
+			if b.Function == fn && int(r.Address)-bEnd <= spec.MaxGap && end-int(b.Start) <= MaxReadQuantity {
+				if end > bEnd {
+					b.Quantity = uint16(end - int(b.Start))
+				}
+				b.Registers = append(b.Registers, r)
+				continue
+			}
+		}
+		blocks = append(blocks, Block{Function: fn, Start: r.Address, Quantity: uint16(width), Registers: []registry.RegisterSpec{r}})
+	}
+	return blocks
+}
+
+// Measurement — o valoare citită, deja înmulțită cu scale.
+type Measurement struct {
+	Key   string  `json:"key"`
+	Value float64 `json:"value"`
+}
+
+// ErrConnBroken — ReadAll s-a oprit la o eroare de transport (timeout,
+// conexiune ruptă); conexiunea nu mai e refolosibilă.
+var ErrConnBroken = errors.New("modbus: connection broken")
+
+// ReadAll execută planul pe unit-ul dat. O excepție Modbus pe un bloc nu oprește
+// restul: se întorc valorile citite (în ordinea din DD) plus câte o eroare per
+// bloc / registru. O eroare de transport oprește citirea — blocurile rămase ar
+// aștepta fiecare timeout-ul pe aceeași conexiune moartă — și adaugă ErrConnBroken.
+func ReadAll(ctx context.Context, r Reader, spec *registry.ModbusSpec, blocks []Block) ([]Measurement, []error) {
+	unit := spec.Unit()
+	values := map[string]float64{}
+	var errs []error
+	for i, b := range blocks {
+		raw, err := r.ReadRegisters(ctx, b.Function, unit, b.Start, b.Quantity)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("read 0x%02x %d+%d: %w", b.Function, b.Start, b.Quantity, err))
+			var exc *ExceptionError
+			if !errors.As(err, &exc) {
+				errs = append(errs, fmt.Errorf("%w: %d of %d blocks skipped", ErrConnBroken, len(blocks)-i-1, len(blocks)))
+				break
+			}
+			continue
+		}
+		for _, reg := range b.Registers {
+			off := int(reg.Address - b.Start)
+			width := registry.ModbusRegisterTypes[reg.Type]
+			order := reg.ByteOrder
+			if order == "" {
+				order = spec.ByteOrder
+			}
+			v, err := Decode(raw[off:off+width], reg.Type, order)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("register %s: %w", reg.Name, err))
+				continue
+			}
+			if reg.Scale != 0 {
+				v *= reg.Scale
+			}
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				errs = append(errs, fmt.Errorf("register %s: value is not finite", reg.Name))
+				continue
+			}
+			values[reg.Name] = v
+		}
+	}
+
+	var out []Measurement
+	for _, reg := range spec.Registers {
+		if v, ok := values[reg.Name]; ok {
+			out = append(out, Measurement{Key: reg.Name, Value: roundScaled(v)})
+		}
+	}
+	return out, errs
+}
+
+// function — tabela din DD → function code ("" = holding).
+func function(table string) byte {
+	if table == "input" {
+		return FuncReadInput
+	}
+	return FuncReadHolding
+}
+
+// roundScaled taie zgomotul de virgulă mobilă introdus de scale
+// (8660 * 0.001 = 8.660000000000001 → 8.66).
+func roundScaled(v float64) float64 {
+	return math.Round(v*1e9) / 1e9
+}
@@ -0,0 +1,225 @@
+package parsers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+
+	"go-iot-platform/internal/registry"
+)
+
+// Point — ce scrie ingest-ul (cmd/main.go) în measurement-ul "devices" pentru
+// o citire: tag-urile source / type și field-urile. Time zero = momentul
+// primirii (payload-ul nu avea `ts` / `Time`).
+//
+// Decode e singurul loc unde se decid numele field-urilor: ingest-ul le scrie
+// în Influx, rule-engine-ul (rules.DecodePayload) evaluează regulile pe
+// aceleași nume, plus cele canonice din normalized_fields.
+type Point struct {
+	Source string
+	Type   string
+	Fields map[string]interface{}
+	Time   time.Time
+}
+
+var titleCaser = cases.Title(language.Und)
+
+// builtinStreams — stream-urile cu handler dedicat, anterioare registry-ului;
+// field-urile lor nu vin din parser-ul DD-ului (ex. Tasmota SENSOR →
+// nousat_power, nu ENERGY.Power). Schimbarea numelor ar rupe seriile existente.
+var builtinStreams = map[string]func(topic string, payload []byte) (Point, error){
+	"telemetry": decodeSun2000,
+	"emeter":    decodeShellyEmeter,
+	"relay":     decodeShellyRelay,
+	"state":     decodeTasmotaState,
+	"sensor":    decodeTasmotaSensor,
+	"zigbee":    decodeZigbee,
+}
+
+// HasBuiltin — stream-ul are handler dedicat (Decode nu folosește parser-ul DD).
+func HasBuiltin(stream string) bool {
+	_, ok := builtinStreams[stream]
+	return ok
+}
+
+// Decode transformă o citire în punctul scris de ingest:
+//   - stream cu handler dedicat (HasBuiltin) → handler-ul, indiferent de DD;
+//   - DD identificat → parsers.Parse, source = id-ul DD-ului; un `ts` RFC 3339
+//     devine Time, valorile ne-scalare sunt eliminate (Influx acceptă doar scalari);
+//   - altfel → generic / auto_detected (obiect JSON sau o singură valoare "value").
+//
+// dd poate fi nil; stream e cel din matcher, sau din topics.Parse fără match.
+func Decode(dd *registry.DeviceDefinition, stream, topic string, vars map[string]string, payload []byte) (Point, error) {
+	if fn, ok := builtinStreams[stream]; ok {
+		return fn(topic, payload)
+	}
+	if dd != nil {
+		fields, err := Parse(dd, topic, vars, payload)
+		if err != nil {
+			return Point{}, err
+		}
+		pt := Point{Source: dd.ID, Type: stream, Fields: fields}
+		if ts, ok := fields["ts"].(string); ok {
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				pt.Time = t
+				delete(fields, "ts")
+			}
+		}
+		for k, v := range fields {
+			switch v.(type) {
+			case map[string]interface{}, []interface{}, nil:
+				delete(fields, k)
+			}
+		}
+		if len(fields) == 0 {
+			return Point{}, errors.New("payload has no scalar fields")
+		}
+		return pt, nil
+	}
+
+	// Generic / auto_detected fallback — pentru topice care nu match nici un DD.
+	pt := Point{Source: "generic", Type: "auto_detected"}
+	var data map[string]interface{}
+	if err := json.Unmarshal(payload, &data); err == nil {
+		pt.Fields = data
+	} else {
+		valStr := string(payload)
+		var val interface{}
+		if f, err := strconv.ParseFloat(valStr, 64); err == nil {
+			val = f
+		} else {
+			val = valStr
+		}
+		pt.Fields = map[string]interface{}{RawValueField: val}
+	}
+	return pt, nil
+}
+
+// SUN2000 — payload cu array measurements.
+func decodeSun2000(_ string, payload []byte) (Point, error) {
+	var sun struct {
+		Ts           string                   `json:"ts"`
+		Measurements []map[string]interface{} `json:"measurements"`
+		HouseLoad    float64                  `json:"house_load_kw_est"`
+	}
+	if err := json.Unmarshal(payload, &sun); err != nil || len(sun.Measurements) == 0 {
+		return Point{}, errors.New("telemetry: payload has no measurements array")
+	}
+	fields := make(map[string]interface{}, len(sun.Measurements)+1)
+	for _, m := range sun.Measurements {
+		if key, ok := m["key"].(string); ok {
+			if val, ok := m["value"]; ok {
+				fields[key] = val
+			}
+		}
+	}
+	if sun.HouseLoad != 0 {
+		fields["house_load_kw_est"] = sun.HouseLoad
+	}
+	pt := Point{Source: "sun2000", Type: "solar_inverter", Fields: fields}
+	if sun.Ts != "" {
+		if t, err := time.Parse(time.RFC3339, sun.Ts); err == nil {
+			pt.Time = t
+		}
+	}
+	return pt, nil
+}
+
+// Shelly EM — payload e plain string per topic (ex: "1234.56"); field-ul e
+// ultimul segment al topicului, cu majusculă ("power" → "Power").
+func decodeShellyEmeter(topic string, payload []byte) (Point, error) {
+	valStr := string(payload)
+	var value float64
+	if _, err := fmt.Sscanf(valStr, "%f", &value); err != nil {
+		return Point{}, fmt.Errorf("emeter: %q is not a number", valStr)
+	}
+	field := topic[strings.LastIndexByte(topic, '/')+1:]
+	return Point{
+		Source: "shelly", Type: "power_meter",
+		Fields: map[string]interface{}{titleCaser.String(field): value},
+	}, nil
+}
+
+// Shelly relay — payload "on"/"off".
+func decodeShellyRelay(_ string, payload []byte) (Point, error) {
+	state := 0
+	if strings.ToLower(string(payload)) == "on" {
+		state = 1
+	}
+	return Point{Source: "shelly", Type: "relay", Fields: map[string]interface{}{"state": state}}, nil
+}
+
+// Tasmota STATE — Scriem AMBELE:
+//
+//	relay_state — string "ON"/"OFF" (audit, lizibil)
+//	relay_on    — int 1/0 (pentru polling/UI confirmation)
+func decodeTasmotaState(_ string, payload []byte) (Point, error) {
+	var state struct {
+		POWER string `json:"POWER"`
+		RSSI  int    `json:"RSSI"`
+	}
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return Point{}, fmt.Errorf("state: %w", err)
+	}
+	relayOn := 0
+	if strings.EqualFold(state.POWER, "ON") {
+		relayOn = 1
+	}
+	return Point{Source: "nousat", Type: "state", Fields: map[string]interface{}{
+		"relay_state": state.POWER,
+		"relay_on":    relayOn,
+		"rssi":        state.RSSI,
+	}}, nil
+}
+
+// Tasmota SENSOR — payload cu nested ENERGY object. Prefixul `nousat_` pe
+// toate field-urile evită type conflicts pe scopul global al measurement="devices".
+func decodeTasmotaSensor(_ string, payload []byte) (Point, error) {
+	var sensor struct {
+		Time   string `json:"Time"`
+		ENERGY struct {
+			Total         float64 `json:"Total"`
+			Today         float64 `json:"Today"`
+			Yesterday     float64 `json:"Yesterday"`
+			Power         float64 `json:"Power"`
+			ApparentPower float64 `json:"ApparentPower"`
+			ReactivePower float64 `json:"ReactivePower"`
+			Factor        float64 `json:"Factor"`
+			Voltage       float64 `json:"Voltage"`
+			Current       float64 `json:"Current"`
+		} `json:"ENERGY"`
+	}
+	if err := json.Unmarshal(payload, &sensor); err != nil {
+		return Point{}, fmt.Errorf("sensor: %w", err)
+	}
+	pt := Point{Source: "nousat", Type: "energy", Fields: map[string]interface{}{
+		"nousat_power":          sensor.ENERGY.Power,
+		"nousat_apparent_power": sensor.ENERGY.ApparentPower,
+		"nousat_reactive_power": sensor.ENERGY.ReactivePower,
+		"nousat_power_factor":   sensor.ENERGY.Factor,
+		"nousat_voltage":        sensor.ENERGY.Voltage,
+		"nousat_current":        sensor.ENERGY.Current,
+		"nousat_total":          sensor.ENERGY.Total,
+		"nousat_today":          sensor.ENERGY.Today,
+		"nousat_yesterday":      sensor.ENERGY.Yesterday,
+	}}
+	if t, err := time.Parse(time.RFC3339, sensor.Time); err == nil {
+		pt.Time = t
+	}
+	return pt, nil
+}
+
+// Zigbee2MQTT — flat JSON cu chei standard.
+func decodeZigbee(_ string, payload []byte) (Point, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return Point{}, fmt.Errorf("zigbee: %w", err)
+	}
+	return Point{Source: "zigbee2mqtt", Type: "sensor", Fields: data}, nil
+}
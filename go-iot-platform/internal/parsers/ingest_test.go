@@ -0,0 +1,72 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"go-iot-platform/internal/registry"
+)
+
+func TestDecodeBuiltin(t *testing.T) {
+	// DD-ul e ignorat pentru stream-urile cu handler dedicat.
+	dd := &registry.DeviceDefinition{ID: "nous_a1t", Parser: registry.ParserSpec{Type: "json"}}
+	pt, err := Decode(dd, "sensor", "tele/boiler/SENSOR", nil,
+		[]byte(`{"Time":"2026-05-10T12:00:00Z","ENERGY":{"Power":2100}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt.Source != "nousat" || pt.Type != "energy" || pt.Fields["nousat_power"] != 2100.0 {
+		t.Errorf("sensor: %+v", pt)
+	}
+	if !pt.Time.Equal(time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("sensor time: %v", pt.Time)
+	}
+
+	pt, err = Decode(nil, "emeter", "shellies/em-1/emeter/0/power", nil, []byte("1234.56"))
+	if err != nil || pt.Fields["Power"] != 1234.56 || pt.Source != "shelly" {
+		t.Errorf("emeter: %+v %v", pt, err)
+	}
+	if _, err := Decode(nil, "emeter", "shellies/em-1/emeter/0/power", nil, []byte("n/a")); err == nil {
+		t.Error("emeter: expected error for non-numeric payload")
+	}
+
+	pt, _ = Decode(nil, "state", "tele/boiler/STATE", nil, []byte(`{"POWER":"on","RSSI":70}`))
+	if pt.Fields["relay_on"] != 1 || pt.Fields["relay_state"] != "on" || pt.Fields["rssi"] != 70 {
+		t.Errorf("state: %+v", pt)
+	}
+
+	pt, err = Decode(nil, "telemetry", "tenants/2/devices/x/up/telemetry", nil,
+		[]byte(`{"ts":"2026-05-10T12:00:00Z","measurements":[{"key":"battery_soc","value":99}]}`))
+	if err != nil || pt.Fields["battery_soc"] != 99.0 || pt.Time.IsZero() {
+		t.Errorf("telemetry: %+v %v", pt, err)
+	}
+	if _, err := Decode(nil, "telemetry", "t", nil, []byte(`{"measurements":[]}`)); err == nil {
+		t.Error("telemetry: expected error without measurements")
+	}
+}
+
+func TestDecodeDefinitionAndGeneric(t *testing.T) {
+	dd := &registry.DeviceDefinition{ID: "http_power_meter", Parser: registry.ParserSpec{Type: "json"}}
+	pt, err := Decode(dd, "meter", "tenants/2/devices/x/up/meter", nil,
+		[]byte(`{"ts":"2026-05-10T12:00:00Z","power":5,"meta":{"fw":"1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt.Source != "http_power_meter" || pt.Type != "meter" || pt.Fields["power"] != 5.0 || pt.Time.IsZero() {
+		t.Errorf("dd: %+v", pt)
+	}
+	if _, ok := pt.Fields["meta"]; ok {
+		t.Errorf("dd: nested field kept: %v", pt.Fields)
+	}
+	if _, ok := pt.Fields["ts"]; ok {
+		t.Errorf("dd: ts kept as field: %v", pt.Fields)
+	}
+	if _, err := Decode(dd, "meter", "t", nil, []byte(`{"meta":{}}`)); err == nil {
+		t.Error("dd: expected error without scalar fields")
+	}
+
+	pt, _ = Decode(nil, "custom", "tenants/2/devices/x/up/custom", nil, []byte("OK"))
+	if pt.Source != "generic" || pt.Fields[RawValueField] != "OK" {
+		t.Errorf("generic: %+v", pt)
+	}
+}
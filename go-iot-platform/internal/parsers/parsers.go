@@ -0,0 +1,226 @@
+// Package parsers — Faza 4 Parser Engine: transformă payload-ul unui mesaj
+// identificat de matcher într-un map plat de field-uri, conform `parser.type`
+// din Device Definition, și aplică `normalized_fields` peste rezultat.
+//
+// Tipuri suportate (registry.SupportedParserTypes):
+//   - json                          — obiect JSON; field-urile nested rămân nested
+//     (path-uri ca "ENERGY.Power" sunt rezolvate la normalizare)
+//   - json_with_measurements_array  — array [{key, value}] la parser.payload_path,
+//     aplatizat în field-uri; câmpurile scalare top-level sunt păstrate
+//   - raw                           — payload string brut → un singur field
+//   - keyvalue                      — "k1=v1,k2=v2"
+//
+// Vezi: docs/adr/ADR-001-yaml-driven-devices.md
+package parsers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go-iot-platform/internal/registry"
+)
+
+// RawValueField — numele field-ului pentru payload-uri raw când nici topic-ul,
+// nici extract-ul nu dau un nume mai bun.
+const RawValueField = "value"
+
+// Parse decodează payload-ul după parser.type din DD.
+//
+// `vars` sunt variabilele extrase de matcher (Match.Extracted); parser-ul raw
+// folosește vars["field"] ca nume de field, altfel ultimul segment din topic.
+func Parse(dd *registry.DeviceDefinition, topic string, vars map[string]string, payload []byte) (map[string]interface{}, error) {
+	if dd == nil {
+		return nil, fmt.Errorf("parsers: nil device definition")
+	}
+	switch dd.Parser.Type {
+	case "json":
+		return parseJSON(payload)
+	case "json_with_measurements_array":
+		return parseMeasurements(dd.Parser, payload)
+	case "raw":
+		return parseRaw(rawFieldName(topic, vars), payload), nil
+	case "keyvalue":
+		return parseKeyValue(payload)
+	}
+	return nil, fmt.Errorf("parsers: unsupported parser.type %q", dd.Parser.Type)
+}
+
+// Normalize aplică normalized_fields: canonical name ← source path, cu
+// multiplier și rotunjire la decimals. Sursele lipsă sunt ignorate.
+func Normalize(dd *registry.DeviceDefinition, fields map[string]interface{}) map[string]interface{} {
+	if dd == nil || len(dd.NormalizedFields) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(dd.NormalizedFields))
+	for name, spec := range dd.NormalizedFields {
+		v, ok := Lookup(fields, spec.Source)
+		if !ok {
+			continue
+		}
+		out[name] = NormalizeValue(v, spec)
+	}
+	return out
+}
+
+// Lookup rezolvă un path cu puncte ("ENERGY.Power") într-un map nested.
+// Un field top-level care conține literal punctul are prioritate.
+func Lookup(fields map[string]interface{}, path string) (interface{}, bool) {
+	if v, ok := fields[path]; ok {
+		return v, true
+	}
+	var cur interface{} = fields
+	for _, part := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// ToFloat convertește valorile numerice tipice din JSON / parsere la float64.
+func ToFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// ── parsere ───────────────────────────────────────────────────────────────
+
+func parseJSON(payload []byte) (map[string]interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return nil, fmt.Errorf("parsers: json: %w", err)
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		return m, nil
+	}
+	// Scalar / array valid JSON (ex: "21.5") → un singur field.
+	return map[string]interface{}{RawValueField: v}, nil
+}
+
+func parseMeasurements(spec registry.ParserSpec, payload []byte) (map[string]interface{}, error) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(payload, &obj); err != nil {
+		return nil, fmt.Errorf("parsers: json_with_measurements_array: %w", err)
+	}
+	keyField := spec.MeasurementKeyField
+	if keyField == "" {
+		keyField = "key"
+	}
+	valField := spec.MeasurementValueField
+	if valField == "" {
+		valField = "value"
+	}
+
+	raw, _ := Lookup(obj, spec.PayloadPath)
+	arr, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("parsers: %q is not an array", spec.PayloadPath)
+	}
+
+	fields := make(map[string]interface{}, len(arr)+len(obj))
+	for k, v := range obj {
+		if k == spec.PayloadPath {
+			continue
+		}
+		switch v.(type) {
+		case map[string]interface{}, []interface{}:
+			// doar scalarii top-level (ex: house_load_kw_est, ts) sunt copiați
+		default:
+			fields[k] = v
+		}
+	}
+	for _, item := range arr {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		key, ok := m[keyField].(string)
+		if !ok || key == "" {
+			continue
+		}
+		if val, ok := m[valField]; ok {
+			fields[key] = val
+		}
+	}
+	return fields, nil
+}
+
+func parseRaw(field string, payload []byte) map[string]interface{} {
+	return map[string]interface{}{field: scalar(string(payload))}
+}
+
+func parseKeyValue(payload []byte) (map[string]interface{}, error) {
+	fields := map[string]interface{}{}
+	for _, pair := range strings.FieldsFunc(string(payload), func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	}) {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		eq := strings.IndexByte(pair, '=')
+		if eq <= 0 {
+			return nil, fmt.Errorf("parsers: keyvalue: malformed pair %q", pair)
+		}
+		fields[strings.TrimSpace(pair[:eq])] = scalar(pair[eq+1:])
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("parsers: keyvalue: empty payload")
+	}
+	return fields, nil
+}
+
+// rawFieldName — vars["field"] dacă DD-ul îl extrage, altfel ultimul segment al topicului.
+func rawFieldName(topic string, vars map[string]string) string {
+	if f := vars["field"]; f != "" {
+		return f
+	}
+	if i := strings.LastIndexByte(topic, '/'); i >= 0 && i < len(topic)-1 {
+		return topic[i+1:]
+	}
+	return RawValueField
+}
+
+// scalar convertește un string brut la float64 când e numeric, altfel string trimmed.
+func scalar(s string) interface{} {
+	s = strings.TrimSpace(s)
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// NormalizeValue aplică multiplier + decimals unei valori numerice; restul rămân neschimbate.
+func NormalizeValue(v interface{}, spec registry.NormSpec) interface{} {
+	f, ok := ToFloat(v)
+	if !ok {
+		return v
+	}
+	if spec.Multiplier != 0 {
+		f *= spec.Multiplier
+	}
+	if spec.Decimals != nil {
+		p := math.Pow(10, float64(*spec.Decimals))
+		f = math.Round(f*p) / p
+	}
+	return f
+}
@@ -0,0 +1,146 @@
+package parsers
+
+import (
+	"path/filepath"
+	"testing"
+
+	"go-iot-platform/internal/registry"
+)
+
+func intPtr(i int) *int { return &i }
+
+func TestParseJSON(t *testing.T) {
+	dd := &registry.DeviceDefinition{Parser: registry.ParserSpec{Type: "json"}}
+	fields, err := Parse(dd, "tele/x/SENSOR", nil, []byte(`{"ENERGY":{"Power":120},"POWER":"ON"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := Lookup(fields, "ENERGY.Power"); v != float64(120) {
+		t.Errorf("ENERGY.Power=%v", v)
+	}
+	if fields["POWER"] != "ON" {
+		t.Errorf("POWER=%v", fields["POWER"])
+	}
+
+	// Scalar JSON → field "value".
+	fields, err = Parse(dd, "zigbee2mqtt/x", nil, []byte(`21.5`))
+	if err != nil || fields[RawValueField] != 21.5 {
+		t.Errorf("scalar json: %v %v", fields, err)
+	}
+
+	if _, err := Parse(dd, "t", nil, []byte(`not json`)); err == nil {
+		t.Error("expected error for invalid json")
+	}
+}
+
+func TestParseMeasurements(t *testing.T) {
+	dd := &registry.DeviceDefinition{Parser: registry.ParserSpec{
+		Type: "json_with_measurements_array", PayloadPath: "measurements",
+	}}
+	payload := `{"ts":"2026-05-10T12:00:00Z","house_load_kw_est":0.51,
+		"measurements":[{"key":"pv_input_power","value":8.66},{"key":"battery_soc","value":99},{"value":1}],
+		"meta":{"fw":"1.2"}}`
+	fields, err := Parse(dd, "tenants/2/devices/x/up/telemetry", nil, []byte(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["pv_input_power"] != 8.66 || fields["battery_soc"] != float64(99) {
+		t.Errorf("measurements not flattened: %v", fields)
+	}
+	if fields["house_load_kw_est"] != 0.51 || fields["ts"] == nil {
+		t.Errorf("top-level scalars not kept: %v", fields)
+	}
+	if _, ok := fields["meta"]; ok {
+		t.Errorf("nested objects should not be copied: %v", fields)
+	}
+	if _, err := Parse(dd, "t", nil, []byte(`{"measurements":5}`)); err == nil {
+		t.Error("expected error when payload_path is not an array")
+	}
+}
+
+func TestParseRaw(t *testing.T) {
+	dd := &registry.DeviceDefinition{Parser: registry.ParserSpec{Type: "raw"}}
+	fields, _ := Parse(dd, "shellies/em-1/emeter/0/power", nil, []byte("1234.56"))
+	if fields["power"] != 1234.56 {
+		t.Errorf("power=%v", fields)
+	}
+	fields, _ = Parse(dd, "shellies/em-1/relay/0", map[string]string{"field": "relay"}, []byte("on"))
+	if fields["relay"] != "on" {
+		t.Errorf("relay=%v", fields)
+	}
+}
+
+func TestParseKeyValue(t *testing.T) {
+	dd := &registry.DeviceDefinition{Parser: registry.ParserSpec{Type: "keyvalue"}}
+	fields, err := Parse(dd, "t", nil, []byte("temp=21.5, hum=40;state=ok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fields["temp"] != 21.5 || fields["hum"] != float64(40) || fields["state"] != "ok" {
+		t.Errorf("fields=%v", fields)
+	}
+	if _, err := Parse(dd, "t", nil, []byte("garbage")); err == nil {
+		t.Error("expected error for malformed pair")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	dd := &registry.DeviceDefinition{NormalizedFields: map[string]registry.NormSpec{
+		"total_kwh": {Source: "total", Multiplier: 0.001, Decimals: intPtr(3)},
+		"power_w":   {Source: "ENERGY.Power", Decimals: intPtr(0)},
+		"state":     {Source: "POWER"},
+		"missing":   {Source: "nope"},
+	}}
+	out := Normalize(dd, map[string]interface{}{
+		"total":  float64(123456.7),
+		"ENERGY": map[string]interface{}{"Power": 99.6},
+		"POWER":  "ON",
+	})
+	if out["total_kwh"] != 123.457 {
+		t.Errorf("total_kwh=%v", out["total_kwh"])
+	}
+	if out["power_w"] != float64(100) {
+		t.Errorf("power_w=%v", out["power_w"])
+	}
+	if out["state"] != "ON" {
+		t.Errorf("state=%v", out["state"])
+	}
+	if _, ok := out["missing"]; ok {
+		t.Error("missing source should be skipped")
+	}
+}
+
+// TestProductionDefinitions rulează parser-ul fiecărui DD real pe un payload reprezentativ.
+func TestProductionDefinitions(t *testing.T) {
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil {
+		t.Skipf("configs/devices/ not available: %v", err)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("registry errors: %v", errs)
+	}
+	cases := []struct {
+		dd, topic, payload, canonical string
+		want                          float64
+	}{
+		{"huawei_sun2000_3phase", "tenants/2/devices/1/up/telemetry",
+			`{"measurements":[{"key":"pv_input_power","value":8.6612}]}`, "solar_power_kw", 8.661},
+		{"nous_a1t", "tele/boiler/SENSOR", `{"ENERGY":{"Power":1500}}`, "active_power_w", 1500},
+		{"shelly_em", "shellies/em/emeter/0/total", "12345", "total_consumed_kwh", 12.345},
+		{"zigbee_temperature", "zigbee2mqtt/t1", `{"temperature":21.46}`, "temperature_c", 21.5},
+	}
+	for _, tc := range cases {
+		dd := reg.Get(tc.dd)
+		if dd == nil {
+			t.Fatalf("dd %q not loaded", tc.dd)
+		}
+		fields, err := Parse(dd, tc.topic, nil, []byte(tc.payload))
+		if err != nil {
+			t.Errorf("%s: %v", tc.dd, err)
+			continue
+		}
+		if got := Normalize(dd, fields)[tc.canonical]; got != tc.want {
+			t.Errorf("%s: %s=%v want %v", tc.dd, tc.canonical, got, tc.want)
+		}
+	}
+}
@@ -69,6 +69,10 @@ func (l *Limiter) getBucket(m map[string]*bucket, key string, cap float64, now t
 }
 
 func (l *Limiter) refill(b *bucket, rate, cap float64, now time.Time) {
+	b.refill(rate, cap, now)
+}
+
+func (b *bucket) refill(rate, cap float64, now time.Time) {
 	elapsed := now.Sub(b.last).Seconds()
 	if elapsed > 0 {
 		b.tokens += elapsed * rate
@@ -78,3 +82,28 @@ func (l *Limiter) refill(b *bucket, rate, cap float64, now time.Time) {
 		b.last = now
 	}
 }
+
+// Bucket e un singur token bucket, fără chei — ex: limita per conexiune de
+// stream (internal/stream), unde nu există device / tenant de indexat.
+type Bucket struct {
+	mu       sync.Mutex
+	b        bucket
+	rate     float64
+	capacity float64
+}
+
+func NewBucket(rate, capacity float64) *Bucket {
+	return &Bucket{b: bucket{tokens: capacity, last: time.Now()}, rate: rate, capacity: capacity}
+}
+
+// Allow consumă un token dacă e disponibil.
+func (b *Bucket) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.b.refill(b.rate, b.capacity, time.Now())
+	if b.b.tokens < 1 {
+		return false
+	}
+	b.b.tokens -= 1
+	return true
+}
@@ -62,3 +62,19 @@ func TestSeparateTenantsDontInterfere(t *testing.T) {
 		t.Fatalf("tenant-b 1st allow (different tenant)")
 	}
 }
+
+func TestBucket(t *testing.T) {
+	b := NewBucket(10, 2)
+	if !b.Allow() || !b.Allow() {
+		t.Fatalf("expected burst of 2")
+	}
+	if b.Allow() {
+		t.Fatalf("expected deny after burst")
+	}
+	b.mu.Lock()
+	b.b.last = b.b.last.Add(-200 * time.Millisecond)
+	b.mu.Unlock()
+	if !b.Allow() {
+		t.Fatalf("expected allow after refill")
+	}
+}
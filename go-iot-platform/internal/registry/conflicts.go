@@ -0,0 +1,226 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidateAll verifică registry-ul ca întreg — ce Validate (un DD la un moment
+// dat) nu poate vedea:
+//
+//   - pattern-uri topic_match din DD-uri diferite care se suprapun: matcher.Match
+//     ia primul în MatchOrder (priority, apoi id DD), deci celălalt pierde
+//     topicurile comune în tăcere. Un pattern acoperit complet de unul anterior
+//     e raportat "shadowed" (nu mai prinde nimic). Un pattern anterior cu
+//     payload_match nu umbrește nimic — payload-ul discriminează la runtime.
+//   - același nume canonic în normalized_fields cu unit diferit între DD-uri
+//   - stream-uri din topic_match lipsă din telemetry_streams
+//   - placeholder-e {x} în commands[*].topic / payload care nu sunt variabile
+//     extrase de topic_match (plus device_id / tenant_id)
+//
+// Suprapunerea e exactă între pattern-uri MQTT (+ / #). Pentru regex (~) e
+// euristică: testăm regex-ul pe câteva topicuri-martor generate din pattern-ul
+// MQTT opus; două regex-uri între ele nu sunt comparate.
+//
+// Ordinea erorilor e deterministă (DD-uri sortate după id) — output stabil pentru CI.
+func ValidateAll(reg *Registry) []error {
+	if reg == nil {
+		return nil
+	}
+	all := reg.All()
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+
+	var errs []error
+	errs = append(errs, patternConflicts(all)...)
+	errs = append(errs, unitConflicts(all)...)
+	for _, dd := range all {
+		errs = append(errs, streamErrors(dd)...)
+		errs = append(errs, placeholderErrors(dd)...)
+	}
+	return errs
+}
+
+// orderedPattern — un pattern în ordinea în care îl încearcă matcher-ul.
+type orderedPattern struct {
+	dd      *DeviceDefinition
+	index   int
+	raw     string
+	regex   *regexp.Regexp // nil pentru pattern-uri MQTT
+	payload bool           // are payload_match
+}
+
+func (p orderedPattern) String() string {
+	return fmt.Sprintf("dd=%s topic_match[%d] %q", p.dd.ID, p.index, p.raw)
+}
+
+func patternConflicts(all []*DeviceDefinition) []error {
+	var patterns []orderedPattern
+	for _, ref := range MatchOrder(all) {
+		tm := ref.Spec()
+		p := orderedPattern{dd: ref.DD, index: ref.Index, raw: tm.Pattern, payload: len(tm.PayloadMatch) > 0}
+		if strings.HasPrefix(tm.Pattern, "~") {
+			rx, err := regexp.Compile(strings.TrimPrefix(tm.Pattern, "~"))
+			if err != nil {
+				continue // raportat deja de Validate
+			}
+			p.regex = rx
+		}
+		patterns = append(patterns, p)
+	}
+
+	var errs []error
+	for i, first := range patterns {
+		if first.payload {
+			continue
+		}
+		for _, later := range patterns[i+1:] {
+			if first.dd == later.dd {
+				continue
+			}
+			switch {
+			case first.regex == nil && later.regex == nil:
+				a, b := strings.Split(first.raw, "/"), strings.Split(later.raw, "/")
+				if mqttCovers(a, b) {
+					errs = append(errs, fmt.Errorf("%s is shadowed by %s (never matches)", later, first))
+				} else if mqttOverlap(a, b) {
+					errs = append(errs, fmt.Errorf("%s overlaps %s (shared topics go to dd=%s)", later, first, first.dd.ID))
+				}
+			case first.regex != nil && later.regex != nil:
+				// nedecidabil în general; lăsăm pe seama review-ului
+			default:
+				rx, mqtt := first.regex, later.raw
+				if rx == nil {
+					rx, mqtt = later.regex, first.raw
+				}
+				if regexOverlapsMQTT(rx, mqtt) {
+					errs = append(errs, fmt.Errorf("%s overlaps %s (shared topics go to dd=%s)", later, first, first.dd.ID))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// mqttOverlap — există un topic prins de ambele filtre? Semantica e cea a
+// matcher-ului: "+" = un segment nevid, "#" = restul (cel puțin segmentul lui,
+// "a/#" nu prinde "a").
+func mqttOverlap(a, b []string) bool {
+	for i := 0; ; i++ {
+		switch {
+		case i == len(a) && i == len(b):
+			return true
+		case i == len(a) || i == len(b):
+			return false
+		case a[i] == "#" || b[i] == "#":
+			return true
+		case a[i] == "+" && b[i] != "" || b[i] == "+" && a[i] != "":
+			continue
+		case a[i] != b[i]:
+			return false
+		}
+	}
+}
+
+// mqttCovers — orice topic prins de b e prins și de a?
+func mqttCovers(a, b []string) bool {
+	for i := 0; ; i++ {
+		switch {
+		case i == len(a) && i == len(b):
+			return true
+		case i == len(a) || i == len(b):
+			return false
+		case a[i] == "#":
+			return true
+		case b[i] == "#":
+			return false
+		case a[i] == "+" && b[i] != "":
+			continue
+		case a[i] != b[i]:
+			return false
+		}
+	}
+}
+
+// witnessFillers — valori încercate pentru "+" / "#" în topicul-martor
+// (regex-urile vendor cer adesea serial numeric sau hex).
+var witnessFillers = []string{"x", "1", "a1"}
+
+// regexOverlapsMQTT — regex-ul prinde vreun topic-martor al filtrului MQTT?
+func regexOverlapsMQTT(rx *regexp.Regexp, pattern string) bool {
+	for _, filler := range witnessFillers {
+		parts := strings.Split(pattern, "/")
+		for i, p := range parts {
+			if p == "+" || p == "#" {
+				parts[i] = filler
+			}
+		}
+		if rx.MatchString(strings.Join(parts, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+func unitConflicts(all []*DeviceDefinition) []error {
+	type use struct{ dd, unit string }
+	byName := map[string][]use{}
+	for _, dd := range all {
+		for _, name := range sortedKeys(dd.NormalizedFields) {
+			byName[name] = append(byName[name], use{dd.ID, dd.NormalizedFields[name].Unit})
+		}
+	}
+	var errs []error
+	for _, name := range sortedKeys(byName) {
+		uses := byName[name]
+		for _, u := range uses[1:] {
+			if u.unit != uses[0].unit {
+				errs = append(errs, fmt.Errorf("normalized field %s: unit %q in dd=%s vs %q in dd=%s",
+					name, u.unit, u.dd, uses[0].unit, uses[0].dd))
+			}
+		}
+	}
+	return errs
+}
+
+func streamErrors(dd *DeviceDefinition) []error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, tm := range dd.Identification.TopicMatch {
+		if tm.Stream == "" || seen[tm.Stream] {
+			continue
+		}
+		seen[tm.Stream] = true
+		if _, ok := dd.TelemetryStreams[tm.Stream]; !ok {
+			errs = append(errs, fmt.Errorf("dd=%s topic_match[%d]: stream %q missing from telemetry_streams",
+				dd.ID, i, tm.Stream))
+		}
+	}
+	return errs
+}
+
+// placeholderRe — {device_id}; JSON-ul din payload ({"state":"on"}) nu se potrivește.
+var placeholderRe = regexp.MustCompile(`\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func placeholderErrors(dd *DeviceDefinition) []error {
+	known := map[string]bool{"device_id": true, "tenant_id": true}
+	for _, tm := range dd.Identification.TopicMatch {
+		for name := range tm.Extract {
+			known[name] = true
+		}
+	}
+	var errs []error
+	for _, cmdName := range sortedKeys(dd.Commands) {
+		cmd := dd.Commands[cmdName]
+		for _, part := range []struct{ field, tmpl string }{{"topic", cmd.Topic}, {"payload", cmd.Payload}} {
+			for _, m := range placeholderRe.FindAllStringSubmatch(part.tmpl, -1) {
+				if !known[m[1]] {
+					errs = append(errs, fmt.Errorf("dd=%s commands[%s].%s: unknown placeholder {%s}",
+						dd.ID, cmdName, part.field, m[1]))
+				}
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,146 @@
+package registry
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func ddWith(id string, patterns ...string) *DeviceDefinition {
+	dd := &DeviceDefinition{ID: id, TelemetryStreams: map[string]StreamSpec{"up": {}}}
+	for _, p := range patterns {
+		dd.Identification.TopicMatch = append(dd.Identification.TopicMatch, TopicMatchSpec{Pattern: p, Stream: "up"})
+	}
+	return dd
+}
+
+func registryOf(t *testing.T, dds ...*DeviceDefinition) *Registry {
+	t.Helper()
+	reg := NewRegistry()
+	for _, dd := range dds {
+		if err := reg.Add(dd); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return reg
+}
+
+func TestMQTTOverlapAndCover(t *testing.T) {
+	cases := []struct {
+		a, b           string
+		overlap, cover bool
+	}{
+		{"tele/+/SENSOR", "tele/+/STATE", false, false},
+		{"tele/+/SENSOR", "tele/plug/SENSOR", true, true},
+		{"tele/plug/SENSOR", "tele/+/SENSOR", true, false},
+		{"tele/#", "tele/+/STATE", true, true},
+		{"tele/+/STATE", "tele/#", true, false},
+		{"tele/#", "tele", false, false},
+		{"+/a", "b/+", true, false},
+		{"+/x/telemetry", "/x/telemetry", false, false}, // "+" nu prinde segmentul gol
+		{"a/+", "a/+/b", false, false},
+		{"#", "/1/2/telemetry", true, true},
+	}
+	for _, c := range cases {
+		a, b := strings.Split(c.a, "/"), strings.Split(c.b, "/")
+		if got := mqttOverlap(a, b); got != c.overlap {
+			t.Errorf("overlap(%q, %q) = %v", c.a, c.b, got)
+		}
+		if got := mqttOverlap(b, a); got != c.overlap {
+			t.Errorf("overlap(%q, %q) not symmetric", c.b, c.a)
+		}
+		if got := mqttCovers(a, b); got != c.cover {
+			t.Errorf("covers(%q, %q) = %v", c.a, c.b, got)
+		}
+	}
+}
+
+func TestValidateAllPatterns(t *testing.T) {
+	reg := registryOf(t,
+		ddWith("a_generic", "tele/#"),
+		ddWith("b_plug", "tele/+/SENSOR", "zigbee2mqtt/+"),
+		ddWith("c_inverter", "~^/(\\d+)/[^/]+/[^/]+/telemetry$"),
+		ddWith("d_sensor", "zigbee2mqtt/+/availability", "/+/+/+/telemetry"),
+	)
+	errs := fmt.Sprint(ValidateAll(reg))
+	for _, want := range []string{
+		`dd=b_plug topic_match[0] "tele/+/SENSOR" is shadowed by dd=a_generic topic_match[0] "tele/#"`,
+		`dd=d_sensor topic_match[1] "/+/+/+/telemetry" overlaps dd=c_inverter`,
+	} {
+		if !strings.Contains(errs, want) {
+			t.Errorf("missing %q in %s", want, errs)
+		}
+	}
+	if n := len(ValidateAll(reg)); n != 2 {
+		t.Errorf("expected 2 conflicts, got %d: %s", n, errs)
+	}
+}
+
+// payload_match + priority: ordinea e cea din MatchOrder, iar un pattern cu
+// payload_match nu umbrește nimic.
+func TestValidateAllPayloadPriority(t *testing.T) {
+	door := ddWith("door", "zigbee2mqtt/+")
+	door.Identification.TopicMatch[0].Priority = 10
+	door.Identification.TopicMatch[0].PayloadMatch = []PayloadRule{{Key: "contact"}}
+	fallback := ddWith("a_fallback", "zigbee2mqtt/+")
+	if errs := ValidateAll(registryOf(t, door, fallback)); len(errs) != 0 {
+		t.Errorf("payload-discriminated patterns reported: %v", errs)
+	}
+
+	// fallback-ul doar pe topic cu priority mai mare → regula pe payload nu e atinsă
+	fallback.Identification.TopicMatch[0].Priority = 20
+	errs := ValidateAll(registryOf(t, door, fallback))
+	want := `dd=door topic_match[0] "zigbee2mqtt/+" is shadowed by dd=a_fallback topic_match[0]`
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), want) {
+		t.Errorf("errs = %v, want %q", errs, want)
+	}
+
+	order := MatchOrder([]*DeviceDefinition{fallback, door, ddWith("b", "x/+")})
+	var ids []string
+	for _, ref := range order {
+		ids = append(ids, ref.DD.ID)
+	}
+	if strings.Join(ids, ",") != "a_fallback,door,b" {
+		t.Errorf("MatchOrder = %v", ids)
+	}
+}
+
+func TestValidateAllUnitsStreamsPlaceholders(t *testing.T) {
+	a := ddWith("a", "a/+")
+	a.NormalizedFields = map[string]NormSpec{"power_w": {Source: "p", Unit: "W"}, "temp_c": {Source: "t", Unit: "°C"}}
+	b := ddWith("b", "b/+")
+	b.NormalizedFields = map[string]NormSpec{"power_w": {Source: "p", Unit: "kW"}, "temp_c": {Source: "t", Unit: "°C"}}
+	b.Identification.TopicMatch = append(b.Identification.TopicMatch,
+		TopicMatchSpec{Pattern: "b/+/state", Stream: "state", Extract: map[string]string{"serial": "$1"}})
+	b.Commands = map[string]CommandSpec{
+		"on":   {Topic: "cmnd/{serial}/POWER", Payload: `{"state":"on"}`},
+		"set":  {Topic: "cmnd/{device_id}/Set", Payload: "{value}"},
+		"ping": {Topic: "cmnd/{device}/Ping"},
+	}
+	errs := ValidateAll(registryOf(t, a, b))
+	got := fmt.Sprint(errs)
+	for _, want := range []string{
+		`normalized field power_w: unit "kW" in dd=b vs "W" in dd=a`,
+		`dd=b topic_match[1]: stream "state" missing from telemetry_streams`,
+		"dd=b commands[ping].topic: unknown placeholder {device}",
+		"dd=b commands[set].payload: unknown placeholder {value}",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in %s", want, got)
+		}
+	}
+	if len(errs) != 4 {
+		t.Errorf("expected 4 problems, got %d: %s", len(errs), got)
+	}
+}
+
+func TestValidateAllProductionConfigs(t *testing.T) {
+	reg, errs, err := LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("load: %v %v", err, errs)
+	}
+	for _, e := range ValidateAll(reg) {
+		t.Error(e)
+	}
+}
@@ -0,0 +1,252 @@
+package registry
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Moștenire între DD-uri (schema 1.1).
+//
+//	schema_version: "1.1"
+//	id: huawei_sun2000_3phase
+//	extends: base_platform_native      # _base_platform_native.yaml sau id de DD
+//	mixins: [tasmota_commands]          # _tasmota_commands.yaml, …
+//
+// Numele din extends / mixins se caută întâi ca template abstract
+// (_<name>.yaml / _<name>.yml în directorul încărcat — fișierele "_" nu sunt DD-uri
+// de sine stătătoare), apoi ca id al unui DD concret. Template-urile sunt DD-uri
+// parțiale (aceeași schemă strictă, fără câmpuri obligatorii) și pot, la rândul
+// lor, să folosească extends / mixins.
+//
+// Ordinea de aplicare: extends, apoi mixins în ordinea declarată, apoi fișierul
+// însuși — fiecare strat îl suprascrie pe cel dinainte:
+//
+//   - câmpuri scalare (name, vendor, protocol, parser.*, …): valoarea nevidă câștigă
+//   - id, extends, mixins: nu se moștenesc
+//   - normalized_fields / commands / telemetry_streams: merge pe cheie; o cheie
+//     redefinită înlocuiește intrarea moștenită în întregime
+//   - topic_match: intrările stratului nou întâi, apoi cele moștenite cu alt
+//     pattern (matcher-ul încearcă pattern-urile în ordine, deci cele proprii au
+//     prioritate); același pattern → intrarea nouă o înlocuiește
+//   - capabilities: reuniune, cele noi întâi, fără duplicate
+//   - modbus: blocul întreg (nu registru cu registru)
+
+// Resolve întoarce dd cu extends / mixins aplicate. lookup dă DD-ul (deja
+// rezolvat) pentru un nume; dd fără extends / mixins e întors neschimbat.
+// Rezultatul păstrează Extends / Mixins (informativ, pentru GET /go/registry/{id}).
+func Resolve(dd *DeviceDefinition, lookup func(name string) (*DeviceDefinition, error)) (*DeviceDefinition, error) {
+	if dd.Extends == "" && len(dd.Mixins) == 0 {
+		return dd, nil
+	}
+	var layers []*DeviceDefinition
+	if dd.Extends != "" {
+		parent, err := lookup(dd.Extends)
+		if err != nil {
+			return nil, fmt.Errorf("extends %q: %w", dd.Extends, err)
+		}
+		layers = append(layers, parent)
+	}
+	for _, name := range dd.Mixins {
+		mixin, err := lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("mixins %q: %w", name, err)
+		}
+		layers = append(layers, mixin)
+	}
+
+	out := &DeviceDefinition{}
+	for _, l := range layers {
+		out = overlay(out, l)
+	}
+	out = overlay(out, dd)
+	out.ID, out.Extends, out.Mixins = dd.ID, dd.Extends, dd.Mixins
+	out.SourcePath, out.LoadedAt = dd.SourcePath, dd.LoadedAt
+	return out, nil
+}
+
+// overlay — base suprascris de over, după regulile de mai sus. Nu modifică
+// niciunul din argumente.
+func overlay(base, over *DeviceDefinition) *DeviceDefinition {
+	out := *base
+	pick := func(dst *string, v string) {
+		if v != "" {
+			*dst = v
+		}
+	}
+	pick(&out.SchemaVersion, over.SchemaVersion)
+	pick(&out.Name, over.Name)
+	pick(&out.Vendor, over.Vendor)
+	pick(&out.Model, over.Model)
+	pick(&out.Description, over.Description)
+	pick(&out.Protocol, over.Protocol)
+	pick(&out.Parser.Type, over.Parser.Type)
+	pick(&out.Parser.PayloadPath, over.Parser.PayloadPath)
+	pick(&out.Parser.MeasurementKeyField, over.Parser.MeasurementKeyField)
+	pick(&out.Parser.MeasurementValueField, over.Parser.MeasurementValueField)
+
+	out.Identification.TopicMatch = append([]TopicMatchSpec{}, over.Identification.TopicMatch...)
+	own := map[string]bool{}
+	for _, tm := range over.Identification.TopicMatch {
+		own[tm.Pattern] = true
+	}
+	for _, tm := range base.Identification.TopicMatch {
+		if !own[tm.Pattern] {
+			out.Identification.TopicMatch = append(out.Identification.TopicMatch, tm)
+		}
+	}
+
+	out.Capabilities = nil
+	seen := map[string]bool{}
+	for _, c := range append(append([]string{}, over.Capabilities...), base.Capabilities...) {
+		if !seen[c] {
+			seen[c] = true
+			out.Capabilities = append(out.Capabilities, c)
+		}
+	}
+
+	out.NormalizedFields = mergeMap(base.NormalizedFields, over.NormalizedFields)
+	out.Commands = mergeMap(base.Commands, over.Commands)
+	out.TelemetryStreams = mergeMap(base.TelemetryStreams, over.TelemetryStreams)
+	if over.Modbus != nil {
+		out.Modbus = over.Modbus // harta de registre se înlocuiește în întregime
+	}
+	return &out
+}
+
+func mergeMap[V any](base, over map[string]V) map[string]V {
+	if base == nil && over == nil {
+		return nil
+	}
+	out := make(map[string]V, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		out[k] = v
+	}
+	return out
+}
+
+// dirResolver rezolvă extends / mixins pentru LoadDir: template-urile "_" sunt
+// citite leneș (doar cele referite — un _schema.json / _notes.yaml nefolosit nu
+// e parsat), DD-urile concrete vin din fișierele deja decodate.
+type dirResolver struct {
+	templates map[string]string            // name → path (_name.yaml)
+	concrete  map[string]*DeviceDefinition // id → DD decodat, nerezolvat
+	resolved  map[string]*DeviceDefinition // memo, cheie = nume din lookup
+	resolving map[string]bool              // detectare cicluri
+}
+
+func newDirResolver() *dirResolver {
+	return &dirResolver{
+		templates: map[string]string{},
+		concrete:  map[string]*DeviceDefinition{},
+		resolved:  map[string]*DeviceDefinition{},
+		resolving: map[string]bool{},
+	}
+}
+
+// addTemplate înregistrează un fișier "_" ca template; primul găsit câștigă.
+func (r *dirResolver) addTemplate(path string) {
+	name := strings.TrimPrefix(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), "_")
+	if _, ok := r.templates[name]; !ok {
+		r.templates[name] = path
+	}
+}
+
+// addConcrete înregistrează un DD concret decodat; id duplicat → primul câștigă
+// (Registry.Add raportează duplicatul).
+func (r *dirResolver) addConcrete(dd *DeviceDefinition) {
+	if _, ok := r.concrete[dd.ID]; !ok && dd.ID != "" {
+		r.concrete[dd.ID] = dd
+	}
+}
+
+// lookup — template-ul _<name> dacă există, altfel DD-ul concret cu id-ul name.
+func (r *dirResolver) lookup(name string) (*DeviceDefinition, error) {
+	if path, ok := r.templates[name]; ok {
+		return r.memo("_"+name, func() (*DeviceDefinition, error) {
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read template: %w", err)
+			}
+			dd, err := Decode(raw)
+			if err != nil {
+				return nil, fmt.Errorf("template %s: %w", filepath.Base(path), err)
+			}
+			if dd.SchemaVersion != "" && !SupportedSchemaVersions[dd.SchemaVersion] {
+				return nil, fmt.Errorf("template %s: schema_version %q unsupported", filepath.Base(path), dd.SchemaVersion)
+			}
+			dd.SourcePath = path
+			return dd, nil
+		})
+	}
+	if dd, ok := r.concrete[name]; ok {
+		return r.memo(name, func() (*DeviceDefinition, error) { return dd, nil })
+	}
+	return nil, fmt.Errorf("no template _%s.yaml and no definition with id %q", name, name)
+}
+
+// memo rezolvă (o singură dată) DD-ul dat de load; key — "_"+nume pentru
+// template-uri, id pentru DD-uri concrete.
+func (r *dirResolver) memo(key string, load func() (*DeviceDefinition, error)) (*DeviceDefinition, error) {
+	if dd, ok := r.resolved[key]; ok {
+		return dd, nil
+	}
+	if r.resolving[key] {
+		return nil, fmt.Errorf("inheritance cycle through %q", strings.TrimPrefix(key, "_"))
+	}
+	r.resolving[key] = true
+	defer delete(r.resolving, key)
+
+	dd, err := load()
+	if err != nil {
+		return nil, err
+	}
+	out, err := Resolve(dd, r.lookup)
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[key] = out
+	return out, nil
+}
+
+// resolve aplică moștenirea pe un DD concret din director.
+func (r *dirResolver) resolve(dd *DeviceDefinition) (*DeviceDefinition, error) {
+	if r.concrete[dd.ID] == dd {
+		return r.memo(dd.ID, func() (*DeviceDefinition, error) { return dd, nil })
+	}
+	return Resolve(dd, r.lookup) // id duplicat — Registry.Add îl va respinge oricum
+}
+
+// templatesResolved — template-urile care se rezolvă fără erori, pentru
+// Registry.Template. Un fișier "_" care nu e DD (ex. notițe) e ignorat aici;
+// erorile template-urilor referite sunt raportate pe DD-ul care le folosește.
+func (r *dirResolver) templatesResolved() map[string]*DeviceDefinition {
+	out := map[string]*DeviceDefinition{}
+	for name := range r.templates {
+		if dd, err := r.lookup(name); err == nil {
+			out[name] = dd
+		}
+	}
+	return out
+}
+
+// RegistryLookup — lookup pentru Resolve peste DD-urile deja încărcate (fără
+// template-uri: acelea trăiesc doar în directorul de config). Folosit de
+// POST /go/registry/validate.
+func RegistryLookup(reg *Registry) func(name string) (*DeviceDefinition, error) {
+	return func(name string) (*DeviceDefinition, error) {
+		if reg != nil {
+			if t := reg.Template(name); t != nil {
+				return t, nil
+			}
+			if dd := reg.Get(name); dd != nil {
+				return dd, nil
+			}
+		}
+		return nil, fmt.Errorf("no template or definition named %q loaded", name)
+	}
+}
@@ -0,0 +1,197 @@
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const baseTemplate = `
+schema_version: "1.1"
+protocol: mqtt
+identification:
+  topic_match:
+    - pattern: "tenants/+/devices/+/up/shadow"
+      stream: shadow
+      extract: {tenant_id: "$1", device_id: "$2"}
+    - pattern: "tenants/+/devices/+/up/state"
+      stream: state
+      extract: {tenant_id: "$1", device_id: "$2"}
+parser:
+  type: json
+capabilities: [relay]
+normalized_fields:
+  relay_state_str: {source: POWER}
+commands:
+  relay_on: {topic: "base/{device_id}/on", payload: "1"}
+telemetry_streams:
+  shadow: {interval_hint: 60s}
+  state: {interval_hint: 1m}
+`
+
+const tasmotaMixin = `
+commands:
+  relay_on: {topic: "cmnd/{device_id}/POWER", payload: "ON"}
+  relay_off: {topic: "cmnd/{device_id}/POWER", payload: "OFF"}
+`
+
+func TestExtendsAndMixins(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"_base.yaml":    baseTemplate,
+		"_tasmota.yaml": tasmotaMixin,
+		"_notes.yaml":   "not-a-dd", // nereferit → ignorat
+		"plug.yaml": `
+schema_version: "1.1"
+id: plug
+extends: base
+mixins: [tasmota]
+name: "Plug"
+vendor: acme
+identification:
+  topic_match:
+    - pattern: "tele/+/STATE"
+      stream: state
+      extract: {device_id: "$1"}
+    - pattern: "tenants/+/devices/+/up/state"
+      stream: status
+      extract: {tenant_id: "$1", device_id: "$2"}
+capabilities: [power_meter]
+telemetry_streams:
+  state: {interval_hint: 5m}
+  status: {interval_hint: 1m}
+`,
+		"plug_pro.yaml": `
+schema_version: "1.1"
+id: plug_pro
+extends: plug
+name: "Plug Pro"
+commands:
+  relay_off: {topic: "pro/{device_id}/off", payload: "0"}
+`,
+	})
+	reg, errs, err := LoadDir(dir)
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("LoadDir: %v %v", err, errs)
+	}
+	if reg.Count() != 2 || reg.Template("base") == nil || reg.Template("notes") != nil {
+		t.Fatalf("count=%d base=%v notes=%v", reg.Count(), reg.Template("base"), reg.Template("notes"))
+	}
+
+	plug := reg.Get("plug")
+	if plug.Protocol != "mqtt" || plug.Parser.Type != "json" || plug.Extends != "base" {
+		t.Errorf("scalars: %+v", plug)
+	}
+	var patterns []string
+	for _, tm := range plug.Identification.TopicMatch {
+		patterns = append(patterns, tm.Pattern+"="+tm.Stream)
+	}
+	// proprii întâi; pattern-ul redefinit înlocuiește intrarea din template
+	want := []string{"tele/+/STATE=state", "tenants/+/devices/+/up/state=status", "tenants/+/devices/+/up/shadow=shadow"}
+	if !reflect.DeepEqual(patterns, want) {
+		t.Errorf("topic_match = %v, want %v", patterns, want)
+	}
+	if !reflect.DeepEqual(plug.Capabilities, []string{"power_meter", "relay"}) {
+		t.Errorf("capabilities = %v", plug.Capabilities)
+	}
+	// mixin-ul suprascrie template-ul; cheile neatinse rămân
+	if plug.Commands["relay_on"].Topic != "cmnd/{device_id}/POWER" || plug.Commands["relay_off"].Payload != "OFF" {
+		t.Errorf("commands = %+v", plug.Commands)
+	}
+	if plug.TelemetryStreams["state"].IntervalHint != "5m" || plug.TelemetryStreams["shadow"].IntervalHint != "60s" {
+		t.Errorf("telemetry_streams = %+v", plug.TelemetryStreams)
+	}
+	if plug.NormalizedFields["relay_state_str"].Source != "POWER" {
+		t.Errorf("normalized_fields = %+v", plug.NormalizedFields)
+	}
+
+	// DD concret care extinde alt DD concret (deja rezolvat)
+	pro := reg.Get("plug_pro")
+	if pro.Vendor != "acme" || pro.Commands["relay_on"].Payload != "ON" || pro.Commands["relay_off"].Topic != "pro/{device_id}/off" {
+		t.Errorf("plug_pro: %+v", pro)
+	}
+	if len(pro.Identification.TopicMatch) != 3 || !strings.HasSuffix(pro.SourcePath, "plug_pro.yaml") {
+		t.Errorf("plug_pro topic_match / source: %v %s", pro.Identification.TopicMatch, pro.SourcePath)
+	}
+}
+
+func TestInheritanceErrors(t *testing.T) {
+	cases := map[string]struct {
+		files map[string]string
+		want  string
+	}{
+		"missing template": {
+			map[string]string{"a.yaml": strings.Replace(validYAML, `schema_version: "1.0"`, "schema_version: \"1.1\"\nextends: nope", 1)},
+			`extends "nope": no template _nope.yaml`,
+		},
+		"extends needs 1.1": {
+			map[string]string{"_base.yaml": baseTemplate, "a.yaml": validYAML + "extends: base\n"},
+			`extends / mixins require schema_version "1.1"`,
+		},
+		"cycle": {
+			map[string]string{
+				"_a.yaml": "extends: b\n",
+				"_b.yaml": "extends: a\n",
+				"c.yaml":  strings.Replace(validYAML, `schema_version: "1.0"`, "schema_version: \"1.1\"\nextends: a", 1),
+			},
+			"inheritance cycle",
+		},
+		"bad template": {
+			map[string]string{"_base.yaml": "colour: red\n", "a.yaml": strings.Replace(validYAML, `schema_version: "1.0"`, "schema_version: \"1.1\"\nmixins: [base]", 1)},
+			`mixins "base": template _base.yaml: yaml decode`,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reg, errs, err := LoadDir(writeFiles(t, tc.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reg.Count() != 0 || len(errs) != 1 || !strings.Contains(errs[0].Error(), tc.want) {
+				t.Errorf("count=%d errs=%v, want %q", reg.Count(), errs, tc.want)
+			}
+		})
+	}
+}
+
+func TestSchemaVersionsSupported(t *testing.T) {
+	for _, v := range []string{"1.0", "1.1"} {
+		yaml := strings.Replace(validYAML, `schema_version: "1.0"`, `schema_version: "`+v+`"`, 1)
+		if _, errs, _ := LoadDir(writeTempYAML(t, "dev.yaml", yaml)); len(errs) > 0 {
+			t.Errorf("schema_version %s: %v", v, errs)
+		}
+	}
+}
+
+// TestProductionInheritance — huawei extinde _base_platform_native; ordinea
+// pattern-urilor trebuie să rămână cea din DD-ul 1.0 plat.
+func TestProductionInheritance(t *testing.T) {
+	prodDir := filepath.Join("..", "..", "..", "configs", "devices")
+	reg, errs, err := LoadDir(prodDir)
+	if err != nil || len(errs) > 0 {
+		t.Skipf("configs/devices/ not loadable: %v %v", err, errs)
+	}
+	dd := reg.Get("huawei_sun2000_3phase")
+	var streams []string
+	for _, tm := range dd.Identification.TopicMatch {
+		streams = append(streams, tm.Stream)
+	}
+	if want := []string{"telemetry", "telemetry", "shadow", "cmd_ack", "ota"}; !reflect.DeepEqual(streams, want) {
+		t.Errorf("streams = %v, want %v", streams, want)
+	}
+	if dd.Protocol != "mqtt" || dd.TelemetryStreams["shadow"].OfflineAfter != "5m" {
+		t.Errorf("inherited fields missing: %+v", dd)
+	}
+}
@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"bytes"
 	"fmt"
 	"io/fs"
 	"log"
@@ -34,6 +35,16 @@ func LoadDir(dir string) (*Registry, []error, error) {
 	reg := NewRegistry()
 	var errs []error
 
+	// Pasul 1: decodare. Fișierele "_" sunt template-uri (schema 1.1 extends /
+	// mixins), citite doar dacă sunt referite; moștenirea se rezolvă după ce
+	// toate DD-urile concrete sunt decodate (un DD poate extinde alt DD concret).
+	type decoded struct {
+		path string
+		dd   *DeviceDefinition
+	}
+	var files []decoded
+	resolver := newDirResolver()
+
 	walkErr := filepath.WalkDir(dir, func(path string, d fs.DirEntry, walkErr error) error {
 		if walkErr != nil {
 			return walkErr
@@ -41,31 +52,44 @@ func LoadDir(dir string) (*Registry, []error, error) {
 		if d.IsDir() {
 			return nil
 		}
-		// Skip metafiles (ex: _schema.json) și non-YAML
 		base := d.Name()
-		if strings.HasPrefix(base, "_") || strings.HasPrefix(base, ".") {
-			return nil
-		}
 		ext := strings.ToLower(filepath.Ext(base))
-		if ext != ".yaml" && ext != ".yml" {
+		if strings.HasPrefix(base, ".") || ext != ".yaml" && ext != ".yml" {
+			return nil // non-YAML (ex: _schema.json, README.md)
+		}
+		if strings.HasPrefix(base, "_") {
+			resolver.addTemplate(path)
 			return nil
 		}
 
-		dd, err := loadFile(path)
+		dd, err := decodeFile(path)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("%s: %w", path, err))
 			return nil // continuă cu celelalte fișiere
 		}
+		resolver.addConcrete(dd)
+		files = append(files, decoded{path, dd})
+		return nil
+	})
 
-		if existing := reg.Get(dd.ID); existing != nil {
-			errs = append(errs, fmt.Errorf("%s: duplicate id %q (already loaded from %s)",
-				path, dd.ID, existing.SourcePath))
-			return nil
+	// Pasul 2: extends / mixins, validare, înregistrare.
+	for _, f := range files {
+		dd, err := resolver.resolve(f.dd)
+		if err == nil {
+			err = dd.Validate()
+			if err != nil {
+				err = fmt.Errorf("validate: %w", err)
+			}
+		}
+		if err == nil {
+			err = reg.Add(dd)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.path, err))
 		}
+	}
+	reg.templates = resolver.templatesResolved()
 
-		reg.defs[dd.ID] = dd
-		return nil
-	})
 
 	if walkErr != nil {
 		return reg, errs, fmt.Errorf("registry: walk %q: %w", dir, walkErr)
@@ -74,27 +98,33 @@ func LoadDir(dir string) (*Registry, []error, error) {
 	return reg, errs, nil
 }
 
-// loadFile parses a single YAML file into a DeviceDefinition + validates it.
-func loadFile(path string) (*DeviceDefinition, error) {
+// decodeFile parses a single YAML file into a DeviceDefinition (fără validare —
+// un DD 1.1 e complet abia după ce extends / mixins sunt aplicate).
+func decodeFile(path string) (*DeviceDefinition, error) {
 	raw, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read: %w", err)
 	}
 
-	var dd DeviceDefinition
-	dec := yaml.NewDecoder(strings.NewReader(string(raw)))
-	dec.KnownFields(true) // reject unknown fields → catch typos early
-	if err := dec.Decode(&dd); err != nil {
-		return nil, fmt.Errorf("yaml decode: %w", err)
+	dd, err := Decode(raw)
+	if err != nil {
+		return nil, err
 	}
 
 	dd.SourcePath = path
 	dd.LoadedAt = time.Now().UTC()
+	return dd, nil
+}
 
-	if err := dd.Validate(); err != nil {
-		return nil, fmt.Errorf("validate: %w", err)
+// Decode parsează un DD din YAML, strict (câmpurile necunoscute sunt respinse),
+// fără validare — caller-ul alege între Validate (fail-fast) și Violations.
+func Decode(raw []byte) (*DeviceDefinition, error) {
+	var dd DeviceDefinition
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true) // reject unknown fields → catch typos early
+	if err := dec.Decode(&dd); err != nil {
+		return nil, fmt.Errorf("yaml decode: %w", err)
 	}
-
 	return &dd, nil
 }
 
@@ -0,0 +1,30 @@
+package registry
+
+import "sort"
+
+// PatternRef — un topic_match al unui DD, identificat prin poziția în fișier.
+type PatternRef struct {
+	DD    *DeviceDefinition
+	Index int
+}
+
+// Spec întoarce topic_match-ul referit.
+func (p PatternRef) Spec() *TopicMatchSpec {
+	return &p.DD.Identification.TopicMatch[p.Index]
+}
+
+// MatchOrder — ordinea în care matcher-ul încearcă pattern-urile: priority
+// descrescător, apoi id DD, apoi ordinea din fișier. Folosită de matcher.New și
+// de ValidateAll (un pattern e "shadowed" doar de unul încercat înaintea lui).
+func MatchOrder(defs []*DeviceDefinition) []PatternRef {
+	all := append([]*DeviceDefinition{}, defs...)
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+	var out []PatternRef
+	for _, dd := range all {
+		for i := range dd.Identification.TopicMatch {
+			out = append(out, PatternRef{DD: dd, Index: i})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Spec().Priority > out[j].Spec().Priority })
+	return out
+}
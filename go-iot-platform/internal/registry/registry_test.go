@@ -1,6 +1,7 @@
 package registry
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -112,6 +113,142 @@ func TestRejectMissingRequiredFields(t *testing.T) {
 	}
 }
 
+func TestViolationsReportsAll(t *testing.T) {
+	content := strings.Replace(validYAML, `name: "Test Device"`, "", 1)
+	content = strings.Replace(content, "protocol: mqtt", "protocol: carrier_pigeon", 1)
+	content = strings.Replace(content, "type: json", "type: xml", 1)
+	dd, err := Decode([]byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := dd.Violations()
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 violations, got %v", errs)
+	}
+	if dd.Validate().Error() != errs[0].Error() || !strings.Contains(errs[0].Error(), "name required") {
+		t.Errorf("Validate must return the first violation: %v", errs)
+	}
+
+	dd, _ = Decode([]byte(validYAML))
+	if errs := dd.Violations(); len(errs) != 0 {
+		t.Errorf("valid DD: %v", errs)
+	}
+}
+
+func TestPayloadMatchValidation(t *testing.T) {
+	dd, err := Decode([]byte(strings.Replace(validYAML, `        device_id: "$1"`, `        device_id: "$1"
+      priority: 5
+      payload_match:
+        - key: contact
+        - key: ""
+        - key: model
+          equals: A1
+          one_of: [A1, A2]
+        - key: fw
+          regex: "("`, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := fmt.Sprint(dd.Violations())
+	for _, want := range []string{
+		`payload_match / priority require schema_version "1.1"`,
+		"payload_match[1]: key required",
+		`payload_match[2]: key "model": equals / one_of / regex / absent are mutually exclusive`,
+		`payload_match[3]: key "fw": invalid regex`,
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing %q in %s", want, all)
+		}
+	}
+	if strings.Contains(all, "payload_match[0]") {
+		t.Errorf("presence rule rejected: %s", all)
+	}
+}
+
+// modbusYAML — DD modbus_tcp minimal (fără topic_match).
+const modbusYAML = `
+schema_version: "1.1"
+id: meter
+name: "Meter"
+vendor: testco
+protocol: modbus_tcp
+parser:
+  type: json
+capabilities: [power_meter]
+modbus:
+  registers:
+    - {name: power, address: 100, type: int32}
+    - {name: voltage, address: 102, type: uint16, scale: 0.1, table: input}
+`
+
+func TestModbusValidation(t *testing.T) {
+	dd, err := Decode([]byte(modbusYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := dd.Violations(); len(errs) != 0 {
+		t.Errorf("valid modbus DD: %v", errs)
+	}
+
+	bad := strings.Replace(modbusYAML, `    - {name: voltage, address: 102, type: uint16, scale: 0.1, table: input}`, `    - {name: power, address: 101, type: float64, table: coil, byte_order: XYZW}
+    - {name: "", address: 65535, type: int32}
+  unit_id: 300
+  poll_interval: soon
+  byte_order: ABDC`, 1)
+	bad = strings.Replace(bad, "type: json", "type: raw", 1)
+	dd, err = Decode([]byte(bad))
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := fmt.Sprint(dd.Violations())
+	for _, want := range []string{
+		"modbus.unit_id 300 out of range",
+		`modbus.poll_interval "soon" invalid`,
+		`modbus.byte_order "ABDC" unknown`,
+		`modbus.registers[1]: duplicate name "power"`,
+		`modbus.registers[1] (power): type "float64" unknown`,
+		`modbus.registers[1] (power): table "coil" unknown`,
+		`modbus.registers[1] (power): byte_order "XYZW" unknown`,
+		"modbus.registers[2].name required",
+		"address 65535 + 2 register(s) past 65535",
+		`parser.type "raw" not supported for modbus_tcp`,
+	} {
+		if !strings.Contains(all, want) {
+			t.Errorf("missing %q in %s", want, all)
+		}
+	}
+
+	// suprapunere în aceeași tabelă; adresa refolosită în input e OK
+	overlap := strings.Replace(modbusYAML, "address: 102, type: uint16, scale: 0.1, table: input", "address: 101, type: uint16", 1)
+	dd, _ = Decode([]byte(overlap))
+	if all := fmt.Sprint(dd.Violations()); !strings.Contains(all, "holding register 101 already used by power") {
+		t.Errorf("overlap not reported: %s", all)
+	}
+
+	// modbus ↔ protocol
+	dd, _ = Decode([]byte(strings.Replace(modbusYAML, "protocol: modbus_tcp", "protocol: mqtt", 1)))
+	if all := fmt.Sprint(dd.Violations()); !strings.Contains(all, "modbus only allowed with protocol modbus_tcp") || !strings.Contains(all, "topic_match required") {
+		t.Errorf("mqtt + modbus: %s", all)
+	}
+	dd, _ = Decode([]byte(modbusYAML[:strings.Index(modbusYAML, "modbus:\n")]))
+	if all := fmt.Sprint(dd.Violations()); !strings.Contains(all, "modbus required for protocol modbus_tcp") {
+		t.Errorf("missing modbus block: %s", all)
+	}
+}
+
+func TestAddRejectsDuplicate(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Add(&DeviceDefinition{ID: "a", SourcePath: "a.yaml"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := reg.Add(&DeviceDefinition{ID: "a"}); err == nil || !strings.Contains(err.Error(), "a.yaml") {
+		t.Errorf("duplicate: %v", err)
+	}
+	if reg.Count() != 1 || reg.Get("a").SourcePath != "a.yaml" {
+		t.Error("duplicate must not replace the existing definition")
+	}
+}
+
 func TestRejectInvalidID(t *testing.T) {
 	cases := []string{"Test_Device", "test-device", "test device", "TEST", "1test", ""}
 	for _, badID := range cases {
@@ -276,7 +413,7 @@ func TestLoadProductionConfigs(t *testing.T) {
 	}
 
 	// Spot-checks pe ID-urile cunoscute
-	for _, id := range []string{"huawei_sun2000_3phase", "nous_a1t", "shelly_em", "zigbee_temperature"} {
+	for _, id := range []string{"huawei_sun2000_3phase", "huawei_sun2000_modbus", "nous_a1t", "shelly_em", "zigbee_temperature"} {
 		if reg.Get(id) == nil {
 			t.Errorf("expected DD %q to be loaded", id)
 		}
@@ -12,17 +12,32 @@
 // Vezi: docs/adr/ADR-001-yaml-driven-devices.md
 package registry
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
-// CurrentSchemaVersion e versiunea acceptată de loader; orice altă valoare e respinsă.
-const CurrentSchemaVersion = "1.0"
+// CurrentSchemaVersion e versiunea curentă a schemei (DD-urile noi o folosesc).
+const CurrentSchemaVersion = "1.1"
+
+// SupportedSchemaVersions — versiunile acceptate de loader; orice altă valoare e respinsă.
+//
+//   - 1.0 — DD plat, totul într-un fișier
+//   - 1.1 — superset al 1.0: adaugă `extends` și `mixins` (vezi inherit.go),
+//     plus `payload_match` / `priority` în topic_match
+//
+// Migrare 1.0 → 1.1: doar schema_version; un fișier 1.0 e valid ca 1.1 neschimbat.
+var SupportedSchemaVersions = map[string]bool{
+	"1.0": true,
+	"1.1": true,
+}
 
 // SupportedProtocols enumera protocoalele permise în câmpul `protocol`.
 var SupportedProtocols = map[string]bool{
 	"mqtt":       true,
-	"modbus_tcp": false, // implementare planificată în Faza 7.5
-	"http":       false, // planificat
-	"coap":       false, // planificat
+	"modbus_tcp": true, // polling prin cmd/modbus-collector, blocul `modbus:` (Faza 7.5)
+	"http":       true, // POST /go/ingest/{serial}/{stream}, potrivit pe topicul platform-nativ sintetizat
+	"coap":       true, // cmd/main.go cu COAP_ADDR: POST /t/{serial}/{stream}, topic platform-nativ sintetizat
 }
 
 // SupportedParserTypes enumera tipurile de parser permise în câmpul `parser.type`.
@@ -37,7 +52,7 @@ var SupportedParserTypes = map[string]bool{
 // DeviceDefinition reprezintă un fișier YAML din configs/devices/.
 //
 // Tag-urile yaml: sunt necesare pentru gopkg.in/yaml.v3 unmarshal.
-// Tag-urile json: facilitează export prin API (Faza 5 expune /api/v1/registry/).
+// Tag-urile json: facilitează export prin API (GET /go/registry/{id}, internal/api/registry.go).
 type DeviceDefinition struct {
 	SchemaVersion    string                `yaml:"schema_version"     json:"schema_version"`
 	ID               string                `yaml:"id"                 json:"id"`
@@ -46,12 +61,15 @@ type DeviceDefinition struct {
 	Model            string                `yaml:"model,omitempty"    json:"model,omitempty"`
 	Description      string                `yaml:"description,omitempty" json:"description,omitempty"`
 	Protocol         string                `yaml:"protocol"           json:"protocol"`
+	Extends          string                `yaml:"extends,omitempty"  json:"extends,omitempty"` // 1.1: template _<name>.yaml sau id de DD
+	Mixins           []string              `yaml:"mixins,omitempty"   json:"mixins,omitempty"`  // 1.1: aplicate după extends, în ordine
 	Identification   IdentificationSpec    `yaml:"identification"     json:"identification"`
 	Parser           ParserSpec            `yaml:"parser"             json:"parser"`
 	Capabilities     []string              `yaml:"capabilities"       json:"capabilities"`
 	NormalizedFields map[string]NormSpec   `yaml:"normalized_fields,omitempty" json:"normalized_fields,omitempty"`
 	Commands         map[string]CommandSpec `yaml:"commands,omitempty" json:"commands,omitempty"`
 	TelemetryStreams map[string]StreamSpec  `yaml:"telemetry_streams,omitempty" json:"telemetry_streams,omitempty"`
+	Modbus           *ModbusSpec            `yaml:"modbus,omitempty" json:"modbus,omitempty"` // doar protocol: modbus_tcp
 
 	// Internal — populat de loader la încărcare, nu prezent în YAML.
 	SourcePath string    `yaml:"-" json:"source_path,omitempty"`
@@ -80,6 +98,16 @@ type IdentificationSpec struct {
 // în cmd/main.go pentru a decide handler-ul (telemetry / state / sensor /
 // cmd_ack / shadow / ota / emeter / relay / zigbee).
 //
+// PayloadMatch (schema 1.1) — condiții suplimentare pe payload-ul JSON, toate
+// obligatorii (AND). Pentru gateway-uri care publică toate device-urile pe
+// același topic (zigbee2mqtt/+): senzorul de contact se distinge prin cheia
+// `contact`, un model anume prin `model`. Un pattern cu payload_match e sărit de
+// Match(topic) — doar MatchMessage / MatchPayload îl pot evalua.
+//
+// Priority — ordinea de evaluare: priority mai mare întâi (default 0), apoi id
+// DD, apoi ordinea din fișier (vezi MatchOrder). Regulile pe payload au nevoie
+// de priority peste fallback-ul doar pe topic, altfel nu sunt atinse niciodată.
+//
 // Exemplu YAML:
 //
 //	topic_match:
@@ -89,10 +117,31 @@ type IdentificationSpec struct {
 //	  - pattern: "~^/(?P<sn>\\d+)/.*/telemetry$"
 //	    stream: "telemetry"
 //	    extract: { device_id: "sn" }
+//	  - pattern: "zigbee2mqtt/+"
+//	    stream: "zigbee"
+//	    priority: 10
+//	    payload_match:
+//	      - key: contact
+//	      - key: model
+//	        one_of: ["MCCGQ11LM", "SNZB-04"]
 type TopicMatchSpec struct {
-	Pattern string            `yaml:"pattern" json:"pattern"`
-	Stream  string            `yaml:"stream,omitempty" json:"stream,omitempty"`
-	Extract map[string]string `yaml:"extract,omitempty" json:"extract,omitempty"`
+	Pattern      string            `yaml:"pattern" json:"pattern"`
+	Stream       string            `yaml:"stream,omitempty" json:"stream,omitempty"`
+	Extract      map[string]string `yaml:"extract,omitempty" json:"extract,omitempty"`
+	Priority     int               `yaml:"priority,omitempty" json:"priority,omitempty"`
+	PayloadMatch []PayloadRule     `yaml:"payload_match,omitempty" json:"payload_match,omitempty"`
+}
+
+// PayloadRule — o condiție pe o cheie din payload. Key e path cu puncte în
+// obiecte imbricate ("ENERGY.Power"). Fără equals / one_of / regex / absent:
+// cheia trebuie doar să existe. Valorile (inclusiv numere / bool) se compară
+// ca text: `equals: true` prinde `"contact": true`.
+type PayloadRule struct {
+	Key    string   `yaml:"key" json:"key"`
+	Equals string   `yaml:"equals,omitempty" json:"equals,omitempty"`
+	OneOf  []string `yaml:"one_of,omitempty" json:"one_of,omitempty"`
+	Regex  string   `yaml:"regex,omitempty" json:"regex,omitempty"`
+	Absent bool     `yaml:"absent,omitempty" json:"absent,omitempty"` // cheia NU trebuie să existe
 }
 
 // ParserSpec — config pentru parser-ul de payload (Faza 4).
@@ -152,10 +201,75 @@ type StreamSpec struct {
 	OfflineAfter string `yaml:"offline_after,omitempty" json:"offline_after,omitempty"` // "2m" — runtime offline
 }
 
+// ModbusSpec — harta de registre pentru protocol: modbus_tcp (cmd/modbus-collector).
+//
+// Collector-ul citește registrele periodic și publică valorile (după scale)
+// ca payload json_with_measurements_array pe tenants/{tid}/devices/{serial}/up/{stream},
+// cu `name` ca cheie — deci numele trebuie să fie sursele din normalized_fields.
+//
+// Exemplu YAML:
+//
+//	modbus:
+//	  unit_id: 1
+//	  poll_interval: 30s
+//	  byte_order: ABCD          # default; CDAB = word swap, BADC = byte swap, DCBA
+//	  registers:
+//	    - name: pv_input_power
+//	      address: 32064
+//	      type: int32
+//	      scale: 0.001            # W → kW
+//	    - name: battery_soc
+//	      address: 37004
+//	      table: holding          # default; input = FC 0x04
+//	      type: uint16
+//	      scale: 0.1
+type ModbusSpec struct {
+	UnitID       *int           `yaml:"unit_id,omitempty" json:"unit_id,omitempty"`             // nil → 1; 0 e valid (gateway-uri)
+	PollInterval string         `yaml:"poll_interval,omitempty" json:"poll_interval,omitempty"` // default 30s
+	Stream       string         `yaml:"stream,omitempty" json:"stream,omitempty"`               // default telemetry
+	ByteOrder    string         `yaml:"byte_order,omitempty" json:"byte_order,omitempty"`       // default ABCD
+	MaxGap       int            `yaml:"max_gap,omitempty" json:"max_gap,omitempty"`             // registre nefolosite citite ca să unim două blocuri (default 0)
+	Registers    []RegisterSpec `yaml:"registers" json:"registers"`
+}
+
+// Unit — unit id-ul trimis în request-uri: cel configurat (inclusiv 0, pe care
+// unele gateway-uri TCP îl cer), sau 1 când lipsește.
+func (m *ModbusSpec) Unit() byte {
+	if m.UnitID == nil {
+		return 1
+	}
+	return byte(*m.UnitID)
+}
+
+// RegisterSpec — un registru (sau o pereche, pentru tipurile pe 32 biți).
+type RegisterSpec struct {
+	Name      string  `yaml:"name" json:"name"`
+	Address   uint16  `yaml:"address" json:"address"`
+	Table     string  `yaml:"table,omitempty" json:"table,omitempty"` // holding | input
+	Type      string  `yaml:"type" json:"type"`                       // int16 | uint16 | int32 | uint32 | float32
+	Scale     float64 `yaml:"scale,omitempty" json:"scale,omitempty"` // valoare = raw * scale (default 1)
+	ByteOrder string  `yaml:"byte_order,omitempty" json:"byte_order,omitempty"`
+}
+
+// ModbusRegisterTypes — tipurile acceptate în registers[*].type și câte
+// registre de 16 biți ocupă fiecare.
+var ModbusRegisterTypes = map[string]int{
+	"int16":   1,
+	"uint16":  1,
+	"int32":   2,
+	"uint32":  2,
+	"float32": 2,
+}
+
+// ModbusByteOrders — ordinea octeților pe fir, notația uzuală din documentația
+// vendorilor: A = octetul cel mai semnificativ.
+var ModbusByteOrders = map[string]bool{"ABCD": true, "CDAB": true, "BADC": true, "DCBA": true}
+
 // Registry — colecție in-memory de DD-uri cu lookup după ID.
 // Thread-safe pentru read post-load (load se face o singură dată la startup).
 type Registry struct {
-	defs map[string]*DeviceDefinition
+	defs      map[string]*DeviceDefinition
+	templates map[string]*DeviceDefinition // template-uri "_" rezolvate (schema 1.1), după nume
 }
 
 // NewRegistry creates an empty registry.
@@ -163,6 +277,21 @@ func NewRegistry() *Registry {
 	return &Registry{defs: make(map[string]*DeviceDefinition)}
 }
 
+// Add înregistrează un DD deja validat; id duplicat → eroare, registry neschimbat.
+func (r *Registry) Add(dd *DeviceDefinition) error {
+	if existing, ok := r.defs[dd.ID]; ok {
+		return fmt.Errorf("duplicate id %q (already loaded from %s)", dd.ID, existing.SourcePath)
+	}
+	r.defs[dd.ID] = dd
+	return nil
+}
+
+// Template întoarce template-ul abstract _<name>.yaml (rezolvat), sau nil.
+// Template-urile nu apar în Get / All / Count — nu sunt DD-uri de sine stătătoare.
+func (r *Registry) Template(name string) *DeviceDefinition {
+	return r.templates[name]
+}
+
 // Get returns the device definition by ID, or nil if not found.
 func (r *Registry) Get(id string) *DeviceDefinition {
 	return r.defs[id]
@@ -3,90 +3,136 @@ package registry
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 )
 
 // Validate verifies that a single DeviceDefinition is structurally well-formed.
-// Returns the first violation found (fail-fast); aggregated check is in ValidateAll.
+// Returns the first violation found (fail-fast); Violations le întoarce pe toate.
 //
 // Reguli aplicate:
 //
-//   - schema_version == CurrentSchemaVersion
+//   - schema_version in SupportedSchemaVersions; extends / mixins doar de la 1.1
 //   - id non-empty, lowercase, doar [a-z0-9_]
 //   - name non-empty
 //   - protocol in SupportedProtocols (and enabled)
 //   - identification.topic_match non-empty cu min 1 pattern valid
+//   - payload_match: key non-empty, cel mult un operator, regex compilabil
 //   - parser.type in SupportedParserTypes
 //   - capabilities non-empty
 //   - normalized_fields[*].source non-empty când e prezent
 //   - commands[*].topic / payload non-empty
+//   - protocol modbus_tcp: bloc modbus cu registre valide (topic_match opțional)
 func (dd *DeviceDefinition) Validate() error {
-	if dd.SchemaVersion != CurrentSchemaVersion {
-		return fmt.Errorf("schema_version %q unsupported (current: %q)",
-			dd.SchemaVersion, CurrentSchemaVersion)
+	if errs := dd.Violations(); len(errs) > 0 {
+		return errs[0]
 	}
+	return nil
+}
 
-	if dd.ID == "" {
-		return fmt.Errorf("id required")
+// Violations aplică aceleași reguli ca Validate, dar continuă după prima
+// încălcare — pentru tooling de onboarding (POST /go/registry/validate) care
+// vrea toate erorile dintr-o trecere. Ordinea e cea din YAML; map-urile
+// (normalized_fields, commands) sunt parcurse sortat.
+func (dd *DeviceDefinition) Violations() []error {
+	var errs []error
+	add := func(format string, args ...any) { errs = append(errs, fmt.Errorf(format, args...)) }
+
+	if !SupportedSchemaVersions[dd.SchemaVersion] {
+		add("schema_version %q unsupported (supported: %s)", dd.SchemaVersion,
+			strings.Join(sortedKeys(SupportedSchemaVersions), ", "))
+	} else if dd.SchemaVersion == "1.0" && (dd.Extends != "" || len(dd.Mixins) > 0) {
+		add("extends / mixins require schema_version %q", "1.1")
 	}
-	if !idPattern.MatchString(dd.ID) {
-		return fmt.Errorf("id %q invalid (must match %s)", dd.ID, idPattern.String())
+
+	if dd.ID == "" {
+		add("id required")
+	} else if !idPattern.MatchString(dd.ID) {
+		add("id %q invalid (must match %s)", dd.ID, idPattern.String())
 	}
 
 	if dd.Name == "" {
-		return fmt.Errorf("name required")
+		add("name required")
 	}
 
 	if dd.Protocol == "" {
-		return fmt.Errorf("protocol required")
-	}
-	enabled, known := SupportedProtocols[dd.Protocol]
-	if !known {
-		return fmt.Errorf("protocol %q unknown", dd.Protocol)
-	}
-	if !enabled {
-		return fmt.Errorf("protocol %q known but not yet implemented (planned for later phase)", dd.Protocol)
+		add("protocol required")
+	} else if enabled, known := SupportedProtocols[dd.Protocol]; !known {
+		add("protocol %q unknown", dd.Protocol)
+	} else if !enabled {
+		add("protocol %q known but not yet implemented (planned for later phase)", dd.Protocol)
 	}
 
-	if len(dd.Identification.TopicMatch) == 0 {
-		return fmt.Errorf("identification.topic_match required (at least 1 pattern)")
+	// modbus_tcp: device-ul e citit de collector, nu trimite pe MQTT — identificarea
+	// e endpoint-ul configurat, nu un topic
+	if len(dd.Identification.TopicMatch) == 0 && dd.Protocol != "modbus_tcp" {
+		add("identification.topic_match required (at least 1 pattern)")
 	}
 	for i, tm := range dd.Identification.TopicMatch {
 		if err := validateTopicMatch(tm); err != nil {
-			return fmt.Errorf("identification.topic_match[%d]: %w", i, err)
+			errs = append(errs, fmt.Errorf("identification.topic_match[%d]: %w", i, err))
+		}
+		if dd.SchemaVersion == "1.0" && (len(tm.PayloadMatch) > 0 || tm.Priority != 0) {
+			add("identification.topic_match[%d]: payload_match / priority require schema_version %q", i, "1.1")
+		}
+		for j, rule := range tm.PayloadMatch {
+			if err := validatePayloadRule(rule); err != nil {
+				errs = append(errs, fmt.Errorf("identification.topic_match[%d].payload_match[%d]: %w", i, j, err))
+			}
 		}
 	}
 
 	if dd.Parser.Type == "" {
-		return fmt.Errorf("parser.type required")
-	}
-	if !SupportedParserTypes[dd.Parser.Type] {
-		return fmt.Errorf("parser.type %q unknown (valid: %v)",
-			dd.Parser.Type, parserTypesList())
+		add("parser.type required")
+	} else if !SupportedParserTypes[dd.Parser.Type] {
+		add("parser.type %q unknown (valid: %v)", dd.Parser.Type, parserTypesList())
 	}
 	if dd.Parser.Type == "json_with_measurements_array" && dd.Parser.PayloadPath == "" {
-		return fmt.Errorf("parser.payload_path required for json_with_measurements_array")
+		add("parser.payload_path required for json_with_measurements_array")
 	}
 
 	if len(dd.Capabilities) == 0 {
-		return fmt.Errorf("capabilities required (at least 1)")
+		add("capabilities required (at least 1)")
 	}
 
-	for k, nf := range dd.NormalizedFields {
-		if nf.Source == "" {
-			return fmt.Errorf("normalized_fields[%s].source required", k)
+	for _, k := range sortedKeys(dd.NormalizedFields) {
+		if dd.NormalizedFields[k].Source == "" {
+			add("normalized_fields[%s].source required", k)
 		}
 	}
 
-	for cmdName, cmd := range dd.Commands {
-		if cmd.Topic == "" {
-			return fmt.Errorf("commands[%s].topic required", cmdName)
+	switch {
+	case dd.Protocol == "modbus_tcp" && dd.Modbus == nil:
+		add("modbus required for protocol modbus_tcp")
+	case dd.Protocol != "modbus_tcp" && dd.Modbus != nil:
+		add("modbus only allowed with protocol modbus_tcp")
+	case dd.Modbus != nil:
+		errs = append(errs, dd.Modbus.violations()...)
+		// collector-ul produce payload JSON în formatul parser-ului
+		if dd.Parser.Type != "" && dd.Parser.Type != "json" && dd.Parser.Type != "json_with_measurements_array" {
+			add("parser.type %q not supported for modbus_tcp (json | json_with_measurements_array)", dd.Parser.Type)
 		}
+	}
+
+	for _, cmdName := range sortedKeys(dd.Commands) {
 		// Payload poate fi empty string explicit (ex: cmnd/.../State fără payload)
 		// dar topic e mereu obligatoriu.
+		if dd.Commands[cmdName].Topic == "" {
+			add("commands[%s].topic required", cmdName)
+		}
 	}
 
-	return nil
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
 }
 
 // idPattern — id-ul DD-ului trebuie să fie kebab/snake_case strict.
@@ -129,3 +175,92 @@ func parserTypesList() []string {
 	}
 	return out
 }
+
+// validatePayloadRule — key obligatoriu; equals / one_of / regex / absent se
+// exclud reciproc (niciunul = "cheia există").
+func validatePayloadRule(rule PayloadRule) error {
+	if rule.Key == "" {
+		return fmt.Errorf("key required")
+	}
+	ops := 0
+	for _, set := range []bool{rule.Equals != "", len(rule.OneOf) > 0, rule.Regex != "", rule.Absent} {
+		if set {
+			ops++
+		}
+	}
+	if ops > 1 {
+		return fmt.Errorf("key %q: equals / one_of / regex / absent are mutually exclusive", rule.Key)
+	}
+	if rule.Regex != "" {
+		if _, err := regexp.Compile(rule.Regex); err != nil {
+			return fmt.Errorf("key %q: invalid regex %q: %w", rule.Key, rule.Regex, err)
+		}
+	}
+	return nil
+}
+
+// violations — blocul modbus: registre cu nume unic, tip / tabelă / byte order
+// cunoscute, fără suprapuneri în aceeași tabelă.
+func (m *ModbusSpec) violations() []error {
+	var errs []error
+	add := func(format string, args ...any) { errs = append(errs, fmt.Errorf("modbus."+format, args...)) }
+
+	if m.UnitID != nil && (*m.UnitID < 0 || *m.UnitID > 255) {
+		add("unit_id %d out of range 0-255", *m.UnitID)
+	}
+	if m.PollInterval != "" {
+		if d, err := time.ParseDuration(m.PollInterval); err != nil || d <= 0 {
+			add("poll_interval %q invalid", m.PollInterval)
+		}
+	}
+	if m.ByteOrder != "" && !ModbusByteOrders[m.ByteOrder] {
+		add("byte_order %q unknown (valid: ABCD, CDAB, BADC, DCBA)", m.ByteOrder)
+	}
+	if m.MaxGap < 0 {
+		add("max_gap must be >= 0")
+	}
+	if len(m.Registers) == 0 {
+		add("registers required (at least 1)")
+	}
+
+	names := map[string]bool{}
+	used := map[string]map[int]string{} // table → adresă → nume
+	for i, r := range m.Registers {
+		switch {
+		case r.Name == "":
+			add("registers[%d].name required", i)
+		case names[r.Name]:
+			add("registers[%d]: duplicate name %q", i, r.Name)
+		}
+		names[r.Name] = true
+		width, ok := ModbusRegisterTypes[r.Type]
+		if !ok {
+			add("registers[%d] (%s): type %q unknown", i, r.Name, r.Type)
+			width = 1
+		}
+		table := r.Table
+		if table == "" {
+			table = "holding"
+		}
+		if table != "holding" && table != "input" {
+			add("registers[%d] (%s): table %q unknown (holding | input)", i, r.Name, r.Table)
+		}
+		if r.ByteOrder != "" && !ModbusByteOrders[r.ByteOrder] {
+			add("registers[%d] (%s): byte_order %q unknown", i, r.Name, r.ByteOrder)
+		}
+		if int(r.Address)+width > 1<<16 {
+			add("registers[%d] (%s): address %d + %d register(s) past 65535", i, r.Name, r.Address, width)
+		}
+		if used[table] == nil {
+			used[table] = map[int]string{}
+		}
+		for a := int(r.Address); a < int(r.Address)+width; a++ {
+			if other, taken := used[table][a]; taken {
+				add("registers[%d] (%s): %s register %d already used by %s", i, r.Name, table, a, other)
+				break
+			}
+			used[table][a] = r.Name
+		}
+	}
+	return errs
+}
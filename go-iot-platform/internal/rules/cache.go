@@ -9,21 +9,151 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 )
 
-const cacheKeyPrefix = "rules:v1:"
+const (
+	cacheKeyPrefix = "rules:v1:"
 
-// RuleCache fetches and caches rules per tenant in Redis.
-// On cache miss it calls the Django internal API.
+	// invalidateChannel — Django (rules/signals.py) publică {"tenant_id": N} la
+	// save/delete Rule. tenant_id 0 / absent = invalidează tot.
+	invalidateChannel = "rules-cache-invalidate"
+
+	// DefaultResyncInterval — plasa de siguranță: re-fetch complet al tenanților
+	// încărcați, pentru cazul în care un mesaj pub/sub s-a pierdut (reconnect Redis).
+	DefaultResyncInterval = 5 * time.Minute
+
+	// loadTimeout — limita unui load partajat prin singleflight (Redis + Django).
+	loadTimeout = 10 * time.Second
+)
+
+// RuleSet — regulile active ale unui tenant, pre-compilate pentru hot path:
+// doar regulile enabled declanșate de mesaje (schedule / absence sunt rulate
+// de Scheduler), pattern-ul de stream deja split-uit, regex-urile din
+// condiții compile-uite o singură dată.
+//
+// Regulile schedule / absence nu intră în set, dar decid ce salvează
+// RecordState pentru Scheduler: nimic dacă tenantul nu are reguli timed, iar
+// în rule_seen doar stream-urile regulilor absence ("*" pentru absence fără
+// stream și pentru schedule, care iterează SeenDevices).
+type RuleSet struct {
+	TenantID int64
+	LoadedAt time.Time
+	rules    []compiledRule
+	conds    *conditionCache
+	timed    bool
+	seen     map[string]bool
+}
+
+type compiledRule struct {
+	rule    Rule
+	streams []string // nil = orice stream
+}
+
+// NewRuleSet compilează lista de reguli primită de la Django / Redis.
+func NewRuleSet(tenantID int64, rules []Rule) *RuleSet {
+	rs := &RuleSet{TenantID: tenantID, LoadedAt: time.Now(), conds: newConditionCache(nil)}
+	for _, r := range rules {
+		if !r.Enabled {
+			continue
+		}
+		if !r.IsMessageTriggered() {
+			rs.trackTimed(r)
+			continue
+		}
+		cr := compiledRule{rule: r, streams: splitStreams(r.TriggerStreamPattern)}
+		rs.conds.add(r.Conditions)
+		rs.rules = append(rs.rules, cr)
+	}
+	return rs
+}
+
+func (s *RuleSet) trackTimed(r Rule) {
+	s.timed = true
+	if s.seen == nil {
+		s.seen = map[string]bool{}
+	}
+	streams := splitStreams(r.TriggerStreamPattern)
+	if r.TriggerType != TriggerAbsence || len(streams) == 0 {
+		streams = []string{anyStream}
+	}
+	for _, st := range streams {
+		s.seen[st] = true
+	}
+}
+
+// Evaluate evaluează condițiile unei reguli din set, cu regex-urile și
+// expresiile compilate la construirea setului.
+func (s *RuleSet) Evaluate(node ConditionNode, data map[string]interface{}, prevState map[string]interface{}) bool {
+	var cc *conditionCache
+	if s != nil {
+		cc = s.conds
+	}
+	return cc.evaluate(node, data, prevState)
+}
+
+// ForStream returnează regulile enabled care se declanșează pe stream-ul dat.
+func (s *RuleSet) ForStream(stream string) []Rule {
+	if s == nil {
+		return nil
+	}
+	out := make([]Rule, 0, len(s.rules))
+	for _, cr := range s.rules {
+		if cr.streams == nil {
+			out = append(out, cr.rule)
+			continue
+		}
+		for _, p := range cr.streams {
+			if p == stream {
+				out = append(out, cr.rule)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Len — numărul de reguli enabled din set.
+func (s *RuleSet) Len() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.rules)
+}
+
+// RuleCache fetches and caches rules per tenant.
+//
+// Trei nivele:
+//   - local: RuleSet compilat in-process (fără Redis/JSON pe hot path)
+//   - Redis: rules:v1:{tenant} partajat între instanțe
+//   - Django: sursa de adevăr, apelat la miss
+//
+// Invalidarea locală vine pe canalul pub/sub rules-cache-invalidate
+// (vezi SubscribeInvalidations), cu RunResync ca plasă de siguranță.
+//
+// Miss-urile concurente pentru același tenant fac un singur load (singleflight).
+// Un load peste care a venit o invalidare nu e păstrat: generația tenantului
+// (gens, epoch pentru Invalidate(0)) e comparată înainte de scriere.
 type RuleCache struct {
 	rdb        *redis.Client
 	djangoBase string
 	svcUser    string
 	svcPass    string
 	httpClient *http.Client
+	group      singleflight.Group
+
+	mu    sync.RWMutex
+	local map[int64]*RuleSet
+	gens  map[int64]uint64
+	epoch uint64
+
+	statsMu sync.Mutex
+	hits    uint64
+	misses  uint64
 }
 
 func NewRuleCache(rdb *redis.Client, djangoBase, svcUser, svcPass string) *RuleCache {
@@ -33,36 +163,230 @@ func NewRuleCache(rdb *redis.Client, djangoBase, svcUser, svcPass string) *RuleC
 		svcUser:    svcUser,
 		svcPass:    svcPass,
 		httpClient: &http.Client{Timeout: 5 * time.Second},
+		local:      make(map[int64]*RuleSet),
+		gens:       make(map[int64]uint64),
 	}
 }
 
 // GetRules returns enabled rules for a tenant.
-// Tries Redis first; falls back to Django API on miss.
+// Servește din RuleSet-ul local; la miss încarcă din Redis / Django.
 func (c *RuleCache) GetRules(ctx context.Context, tenantID int64) ([]Rule, error) {
+	rs, err := c.GetRuleSet(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Rule, 0, len(rs.rules))
+	for _, cr := range rs.rules {
+		out = append(out, cr.rule)
+	}
+	return out, nil
+}
+
+// GetRuleSet returnează RuleSet-ul compilat al tenantului.
+// Hit local → fără I/O. Miss → Redis, apoi Django; rezultatul rămâne în memorie
+// până la o invalidare pub/sub sau următorul resync.
+func (c *RuleCache) GetRuleSet(ctx context.Context, tenantID int64) (*RuleSet, error) {
+	c.mu.RLock()
+	rs, ok := c.local[tenantID]
+	c.mu.RUnlock()
+	if ok {
+		c.bumpStat(true)
+		return rs, nil
+	}
+	c.bumpStat(false)
+
+	v, err, _ := c.group.Do(strconv.FormatInt(tenantID, 10), func() (interface{}, error) {
+		// Rezultatul servește tuturor celor care așteaptă pe același zbor —
+		// anularea primului apelant nu trebuie să-i pice și pe ceilalți.
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), loadTimeout)
+		defer cancel()
+		gen := c.generation(tenantID)
+		rules, fromDjango, err := c.load(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		rs := NewRuleSet(tenantID, rules)
+		if c.storeLocal(tenantID, rs, gen) && fromDjango {
+			c.storeRedis(ctx, tenantID, rules)
+		}
+		return rs, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*RuleSet), nil
+}
+
+// generation — versiunea curentă a intrării tenantului; se schimbă la orice
+// Invalidate care îl atinge.
+func (c *RuleCache) generation(tenantID int64) [2]uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return [2]uint64{c.epoch, c.gens[tenantID]}
+}
+
+// storeLocal păstrează rs doar dacă nu a venit nicio invalidare de la gen încoace
+// (altfel setul poate fi citit înainte de schimbare; următorul GetRuleSet reîncarcă).
+func (c *RuleCache) storeLocal(tenantID int64, rs *RuleSet, gen [2]uint64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != [2]uint64{c.epoch, c.gens[tenantID]} {
+		return false
+	}
+	c.local[tenantID] = rs
+	return true
+}
+
+// load citește lista de reguli din Redis, la miss din Django (fromDjango = Redis
+// trebuie repopulat de apelant).
+func (c *RuleCache) load(ctx context.Context, tenantID int64) (rules []Rule, fromDjango bool, err error) {
 	key := fmt.Sprintf("%s%d", cacheKeyPrefix, tenantID)
 
 	if c.rdb != nil {
-		data, err := c.rdb.Get(ctx, key).Bytes()
-		if err == nil {
-			var rules []Rule
+		if data, err := c.rdb.Get(ctx, key).Bytes(); err == nil {
 			if json.Unmarshal(data, &rules) == nil {
-				return rules, nil
+				return rules, false, nil
 			}
 		}
 	}
 
-	rules, err := c.fetchFromDjango(ctx, tenantID)
+	rules, err = c.fetchFromDjango(ctx, tenantID)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
+	return rules, true, nil
+}
 
-	// Populate cache with no TTL — invalidated by Django signal on rule change.
-	if c.rdb != nil {
-		if data, err := json.Marshal(rules); err == nil {
-			c.rdb.Set(ctx, key, data, 0)
+// storeRedis populează cache-ul Redis. Django șterge cheia la schimbarea unei
+// reguli; TTL-ul (intervalul de resync) limitează cât supraviețuiește o listă
+// veche scrisă de un fetch care a prins DEL-ul Django în zbor.
+func (c *RuleCache) storeRedis(ctx context.Context, tenantID int64, rules []Rule) {
+	if c.rdb == nil {
+		return
+	}
+	if data, err := json.Marshal(rules); err == nil {
+		c.rdb.Set(ctx, fmt.Sprintf("%s%d", cacheKeyPrefix, tenantID), data, DefaultResyncInterval)
+	}
+}
+
+// Invalidate aruncă RuleSet-ul local al tenantului; tenantID <= 0 golește tot.
+// Cheia Redis e ștearsă de Django înainte de publish, deci următorul GetRuleSet
+// ajunge la Django.
+func (c *RuleCache) Invalidate(tenantID int64) {
+	c.mu.Lock()
+	if tenantID <= 0 {
+		c.local = make(map[int64]*RuleSet)
+		c.gens = make(map[int64]uint64)
+		c.epoch++
+	} else {
+		delete(c.local, tenantID)
+		c.gens[tenantID]++
+	}
+	c.mu.Unlock()
+
+	// Un load pornit înainte de invalidare nu mai e împărțit cu apelanții noi.
+	if tenantID <= 0 {
+		return
+	}
+	c.group.Forget(strconv.FormatInt(tenantID, 10))
+}
+
+// SubscribeInvalidations ascultă canalul Redis pub/sub și invalidează RuleSet-urile locale.
+// Mesajele trimise de Django (rules/signals.py) au format: {"tenant_id": 2}
+func (c *RuleCache) SubscribeInvalidations(ctx context.Context) <-chan error {
+	errCh := make(chan error, 1)
+	if c.rdb == nil {
+		close(errCh)
+		return errCh
+	}
+	go func() {
+		defer close(errCh)
+		sub := c.rdb.Subscribe(ctx, invalidateChannel)
+		defer sub.Close()
+		ch := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-ch:
+				if !ok {
+					return
+				}
+				var payload struct {
+					TenantID int64 `json:"tenant_id"`
+				}
+				if err := json.Unmarshal([]byte(msg.Payload), &payload); err != nil {
+					errCh <- fmt.Errorf("rules invalidation parse: %w", err)
+					continue
+				}
+				c.Invalidate(payload.TenantID)
+			}
+		}
+	}()
+	return errCh
+}
+
+// Resync re-încarcă din Django toți tenanții prezenți în cache-ul local și
+// rescrie Redis. Un tenant care eșuează își păstrează RuleSet-ul vechi.
+func (c *RuleCache) Resync(ctx context.Context) error {
+	c.mu.RLock()
+	tenants := make([]int64, 0, len(c.local))
+	for tid := range c.local {
+		tenants = append(tenants, tid)
+	}
+	c.mu.RUnlock()
+
+	var firstErr error
+	for _, tid := range tenants {
+		gen := c.generation(tid)
+		rules, err := c.fetchFromDjango(ctx, tid)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("resync tenant %d: %w", tid, err)
+			}
+			continue
+		}
+		if c.storeLocal(tid, NewRuleSet(tid, rules), gen) {
+			c.storeRedis(ctx, tid, rules)
+		}
+	}
+	return firstErr
+}
+
+// RunResync rulează Resync la fiecare `every` până la anularea ctx.
+func (c *RuleCache) RunResync(ctx context.Context, every time.Duration) {
+	if every <= 0 {
+		every = DefaultResyncInterval
+	}
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := c.Resync(ctx); err != nil {
+				log.Printf("rules: resync: %v", err)
+			}
 		}
 	}
-	return rules, nil
+}
+
+// Stats returnează (hits, misses) pe cache-ul local, pentru observabilitate.
+func (c *RuleCache) Stats() (uint64, uint64) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	return c.hits, c.misses
+}
+
+func (c *RuleCache) bumpStat(hit bool) {
+	c.statsMu.Lock()
+	defer c.statsMu.Unlock()
+	if hit {
+		c.hits++
+	} else {
+		c.misses++
+	}
 }
 
 func (c *RuleCache) fetchFromDjango(ctx context.Context, tenantID int64) ([]Rule, error) {
@@ -157,3 +481,36 @@ func ParseTopic(topic string) (tenantID int64, serial, stream string, ok bool) {
 	}
 	return tid, parts[3], strings.Join(parts[5:], "/"), true
 }
+
+// FetchTimedRules încarcă regulile enabled schedule + absence din toți tenanții
+// (GET /api/internal/rules/?trigger_type=schedule,absence). Folosit de Scheduler;
+// nu trece prin cache-ul per-tenant — lista e mică și re-citită periodic.
+func (c *RuleCache) FetchTimedRules(ctx context.Context) ([]Rule, error) {
+	url := fmt.Sprintf("%s/api/internal/rules/?trigger_type=%s,%s", c.djangoBase, TriggerSchedule, TriggerAbsence)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.svcUser, c.svcPass)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("rules: django fetch timed: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("rules: django returned %d", resp.StatusCode)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(body, &rules); err != nil {
+		return nil, fmt.Errorf("rules: parse: %w", err)
+	}
+	out := rules[:0]
+	for _, r := range rules {
+		if r.Enabled && !r.IsMessageTriggered() {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,251 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeDjango servește /api/internal/rules/ și numără apelurile.
+func fakeDjango(t *testing.T, rules *[]Rule) (*httptest.Server, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/internal/rules/" {
+			http.NotFound(w, r)
+			return
+		}
+		atomic.AddInt32(&calls, 1)
+		_ = json.NewEncoder(w).Encode(*rules)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRuleCacheLocalHit(t *testing.T) {
+	rules := []Rule{{ID: 1, Name: "r1", Enabled: true, TriggerStreamPattern: "*"}}
+	srv, calls := fakeDjango(t, &rules)
+	c := NewRuleCache(nil, srv.URL, "svc", "pw")
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		rs, err := c.GetRuleSet(ctx, 2)
+		if err != nil {
+			t.Fatalf("GetRuleSet: %v", err)
+		}
+		if rs.Len() != 1 {
+			t.Fatalf("len=%d want 1", rs.Len())
+		}
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("django calls=%d want 1 (local cache should serve repeats)", got)
+	}
+	hits, misses := c.Stats()
+	if hits != 2 || misses != 1 {
+		t.Errorf("stats hits=%d misses=%d want 2/1", hits, misses)
+	}
+}
+
+func TestRuleCacheInvalidate(t *testing.T) {
+	rules := []Rule{{ID: 1, Name: "r1", Enabled: true}}
+	srv, calls := fakeDjango(t, &rules)
+	c := NewRuleCache(nil, srv.URL, "svc", "pw")
+	ctx := context.Background()
+
+	if _, err := c.GetRuleSet(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	rules = append(rules, Rule{ID: 2, Name: "r2", Enabled: true})
+	c.Invalidate(2)
+
+	rs, err := c.GetRuleSet(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rs.Len() != 2 {
+		t.Errorf("after invalidate len=%d want 2", rs.Len())
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("django calls=%d want 2", got)
+	}
+
+	// Invalidate(0) golește toți tenanții.
+	if _, err := c.GetRuleSet(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	c.Invalidate(0)
+	c.mu.RLock()
+	n := len(c.local)
+	c.mu.RUnlock()
+	if n != 0 {
+		t.Errorf("Invalidate(0) left %d tenants", n)
+	}
+}
+
+func TestRuleCacheResync(t *testing.T) {
+	rules := []Rule{{ID: 1, Name: "r1", Enabled: true}}
+	srv, calls := fakeDjango(t, &rules)
+	c := NewRuleCache(nil, srv.URL, "svc", "pw")
+	ctx := context.Background()
+
+	if _, err := c.GetRuleSet(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	rules[0].Enabled = false
+	if err := c.Resync(ctx); err != nil {
+		t.Fatalf("Resync: %v", err)
+	}
+	rs, _ := c.GetRuleSet(ctx, 2)
+	if rs.Len() != 0 {
+		t.Errorf("resync did not pick up disabled rule; len=%d", rs.Len())
+	}
+	if got := atomic.LoadInt32(calls); got != 2 {
+		t.Errorf("django calls=%d want 2", got)
+	}
+}
+
+func TestRuleSetForStream(t *testing.T) {
+	rs := NewRuleSet(2, []Rule{
+		{ID: 1, Enabled: true, TriggerStreamPattern: "*"},
+		{ID: 2, Enabled: true, TriggerStreamPattern: "telemetry, emeter"},
+		{ID: 3, Enabled: true, TriggerStreamPattern: "state"},
+		{ID: 4, Enabled: false, TriggerStreamPattern: "*"},
+	})
+	ids := func(rs []Rule) []int64 {
+		var out []int64
+		for _, r := range rs {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+	cases := map[string][]int64{
+		"telemetry": {1, 2},
+		"emeter":    {1, 2},
+		"state":     {1, 3},
+		"zigbee":    {1},
+	}
+	for stream, want := range cases {
+		got := ids(rs.ForStream(stream))
+		if len(got) != len(want) {
+			t.Errorf("stream %q: got %v want %v", stream, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("stream %q: got %v want %v", stream, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestRuleCacheInvalidateDuringLoad(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release // primul fetch întoarce setul de dinainte de schimbare
+			_ = json.NewEncoder(w).Encode([]Rule{{ID: 1, Enabled: true}})
+			return
+		}
+		_ = json.NewEncoder(w).Encode([]Rule{{ID: 1, Enabled: true}, {ID: 2, Enabled: true}})
+	}))
+	t.Cleanup(srv.Close)
+	c := NewRuleCache(nil, srv.URL, "svc", "pw")
+	ctx := context.Background()
+
+	done := make(chan *RuleSet)
+	go func() {
+		rs, _ := c.GetRuleSet(ctx, 2)
+		done <- rs
+	}()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	c.Invalidate(2)
+	close(release)
+	if rs := <-done; rs.Len() != 1 {
+		t.Fatalf("in-flight load len=%d want 1", rs.Len())
+	}
+
+	// Setul vechi nu a fost păstrat: următorul apel reîncarcă.
+	rs, err := c.GetRuleSet(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(&calls); rs.Len() != 2 || got != 2 {
+		t.Errorf("after invalidate during load: len=%d calls=%d, want 2/2", rs.Len(), got)
+	}
+}
+
+func TestRuleCacheSingleflight(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		_ = json.NewEncoder(w).Encode([]Rule{{ID: 1, Enabled: true}})
+	}))
+	t.Cleanup(srv.Close)
+	c := NewRuleCache(nil, srv.URL, "svc", "pw")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rs, err := c.GetRuleSet(context.Background(), 2); err != nil || rs.Len() != 1 {
+				t.Errorf("GetRuleSet: %v", err)
+			}
+		}()
+	}
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond) // restul miss-urilor ajung în grupul singleflight
+	close(release)
+	wg.Wait()
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("django calls=%d want 1 for concurrent misses", got)
+	}
+}
+
+func TestRuleCacheSingleflightFirstCallerCanceled(t *testing.T) {
+	release := make(chan struct{})
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		_ = json.NewEncoder(w).Encode([]Rule{{ID: 1, Enabled: true}})
+	}))
+	t.Cleanup(srv.Close)
+	c := NewRuleCache(nil, srv.URL, "svc", "pw")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := c.GetRuleSet(ctx, 2)
+		first <- err
+	}()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	second := make(chan error, 1)
+	go func() {
+		_, err := c.GetRuleSet(context.Background(), 2)
+		second <- err
+	}()
+	time.Sleep(20 * time.Millisecond) // al doilea apelant intră pe același zbor
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	<-first
+	if err := <-second; err != nil {
+		t.Fatalf("waiter failed because the first caller canceled: %v", err)
+	}
+}
@@ -8,21 +8,84 @@ import (
 	"strings"
 )
 
+// conditionCache ține regex-urile din condițiile "regex" ale unui set de reguli
+// (pattern → *regexp.Regexp, sau nil pentru pattern invalid) și expresiile din
+// field / value_field (→ *exprNode, nil = path simplu), compile-uite la
+// construirea setului (RuleSet, lista Scheduler-ului). Read-only după build,
+// deci fără lock; trăiește cât setul, deci nu crește odată cu istoria regulilor.
+type conditionCache struct {
+	regexes map[string]*regexp.Regexp
+	exprs   map[string]*exprNode
+}
+
+// newConditionCache compilează condițiile regulilor date.
+func newConditionCache(rules []Rule) *conditionCache {
+	cc := &conditionCache{regexes: map[string]*regexp.Regexp{}, exprs: map[string]*exprNode{}}
+	for _, r := range rules {
+		cc.add(r.Conditions)
+	}
+	return cc
+}
+
+// add parcurge arborele de condiții și compilează ce se poate compila dinainte.
+func (cc *conditionCache) add(node ConditionNode) {
+	if node.Op == "regex" {
+		if pattern, ok := node.Value.(string); ok {
+			if _, seen := cc.regexes[pattern]; !seen {
+				re, _ := regexp.Compile(pattern)
+				cc.regexes[pattern] = re
+			}
+		}
+	}
+	for _, f := range []string{node.Field, node.ValueField} {
+		if _, seen := cc.exprs[f]; f != "" && !seen {
+			cc.exprs[f], _ = parseExpr(f)
+		}
+	}
+	for _, child := range node.Conditions {
+		cc.add(child)
+	}
+	if node.Condition != nil {
+		cc.add(*node.Condition)
+	}
+}
+
+// regex întoarce regex-ul compilat; pattern-urile din afara setului (sau cc nil)
+// sunt compilate ad-hoc, fără să fie reținute.
+func (cc *conditionCache) regex(pattern string) *regexp.Regexp {
+	if cc != nil {
+		if re, ok := cc.regexes[pattern]; ok {
+			return re
+		}
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	return re
+}
+
 // Evaluate recursively evaluates a ConditionNode against data.
 // prevState: map of "field_path" → previous value (for "changed" operator).
+// Compilează regex-urile / expresiile la fiecare apel — pe hot path se folosește
+// RuleSet.Evaluate, cu condițiile compilate o dată per set.
 func Evaluate(node ConditionNode, data map[string]interface{}, prevState map[string]interface{}) bool {
+	return (*conditionCache)(nil).evaluate(node, data, prevState)
+}
+
+func (cc *conditionCache) evaluate(node ConditionNode, data map[string]interface{}, prevState map[string]interface{}) bool {
 	op := strings.ToUpper(node.Operator)
 	switch op {
 	case "AND":
 		for _, child := range node.Conditions {
-			if !Evaluate(child, data, prevState) {
+			if !cc.evaluate(child, data, prevState) {
 				return false
 			}
 		}
 		return len(node.Conditions) > 0
 	case "OR":
 		for _, child := range node.Conditions {
-			if Evaluate(child, data, prevState) {
+			if cc.evaluate(child, data, prevState) {
 				return true
 			}
 		}
@@ -31,19 +94,27 @@ func Evaluate(node ConditionNode, data map[string]interface{}, prevState map[str
 		if node.Condition == nil {
 			return false
 		}
-		return !Evaluate(*node.Condition, data, prevState)
+		return !cc.evaluate(*node.Condition, data, prevState)
 	default:
 		// Leaf condition
 		if node.Field == "" {
 			return false
 		}
-		current := ExtractField(data, node.Field)
-		return compareLeaf(current, node.Op, node.Value, prevState[node.Field])
+		current := cc.field(data, node.Field)
+		value := node.Value
+		if node.ValueField != "" {
+			value = cc.field(data, node.ValueField)
+		}
+		var prev interface{}
+		if node.Op == "changed" {
+			prev = cc.field(prevState, node.Field)
+		}
+		return cc.compareLeaf(current, node.Op, value, prev)
 	}
 }
 
 // compareLeaf evaluates a leaf condition: current <op> value.
-func compareLeaf(current interface{}, op string, value interface{}, prev interface{}) bool {
+func (cc *conditionCache) compareLeaf(current interface{}, op string, value interface{}, prev interface{}) bool {
 	switch op {
 	case "is_null":
 		return current == nil
@@ -72,16 +143,22 @@ func compareLeaf(current interface{}, op string, value interface{}, prev interfa
 	case "not_in":
 		return !inList(current, value)
 	case "contains":
+		if list, ok := current.([]interface{}); ok {
+			return inList(value, list)
+		}
 		return strings.Contains(toString(current), toString(value))
 	case "not_contains":
+		if list, ok := current.([]interface{}); ok {
+			return !inList(value, list)
+		}
 		return !strings.Contains(toString(current), toString(value))
 	case "regex":
 		pattern, ok := value.(string)
 		if !ok {
 			return false
 		}
-		re, err := regexp.Compile(pattern)
-		if err != nil {
+		re := cc.regex(pattern)
+		if re == nil {
 			return false
 		}
 		return re.MatchString(toString(current))
@@ -169,6 +169,20 @@ func TestFieldPath(t *testing.T) {
 		}
 	})
 
+	t.Run("wildcard projection", func(t *testing.T) {
+		v, ok := ExtractField(data, "measurements[*].value").([]interface{})
+		if !ok || len(v) != 2 || v[0] != 5.2 || v[1] != float64(85) {
+			t.Errorf("got %v", v)
+		}
+		keys, _ := ExtractField(data, "measurements.*.key").([]interface{})
+		if len(keys) != 2 || keys[1] != "battery_soc_pct" {
+			t.Errorf("segment wildcard got %v", keys)
+		}
+		if v := ExtractField(data, "measurements[*].missing").([]interface{}); len(v) != 0 {
+			t.Errorf("missing sub-field should project to empty list, got %v", v)
+		}
+	})
+
 	t.Run("missing field returns nil", func(t *testing.T) {
 		v := ExtractField(data, "nonexistent.deep")
 		if v != nil {
@@ -224,3 +238,23 @@ func TestRenderTemplate(t *testing.T) {
 		t.Errorf("got %q want %q", result, want)
 	}
 }
+
+func TestRuleSetCompiledRegex(t *testing.T) {
+	var cond ConditionNode
+	json.Unmarshal([]byte(`{"operator":"OR","conditions":[{"field":"mode","op":"regex","value":"^(auto|eco)$"},{"field":"mode","op":"regex","value":"("}]}`), &cond)
+	rs := NewRuleSet(2, []Rule{{ID: 1, Enabled: true, Conditions: cond}})
+
+	if len(rs.conds.regexes) != 2 || rs.conds.regexes["("] != nil {
+		t.Fatalf("compiled regexes = %v", rs.conds.regexes)
+	}
+	if !rs.Evaluate(cond, map[string]interface{}{"mode": "eco"}, nil) {
+		t.Error("eco should match")
+	}
+	if rs.Evaluate(cond, map[string]interface{}{"mode": "manual"}, nil) {
+		t.Error("manual should not match")
+	}
+	// Un set nou nu moștenește pattern-urile celui vechi.
+	if rs2 := NewRuleSet(2, nil); len(rs2.conds.regexes) != 0 {
+		t.Errorf("fresh set has %d regexes", len(rs2.conds.regexes))
+	}
+}
@@ -0,0 +1,448 @@
+package rules
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExecRecord — o intrare RuleExecution trimisă la Django prin endpoint-ul bulk.
+//
+// Occurrences > 1 apare doar la cooldown_skipped agregat: toate skip-urile
+// unei perechi (regulă, device) dintr-o fereastră de flush devin un singur record.
+type ExecRecord struct {
+	RuleID             int64                    `json:"rule_id"`
+	RuleName           string                   `json:"rule_name"`
+	TenantID           int64                    `json:"tenant_id"`
+	DeviceSerial       string                   `json:"device_serial"`
+	Stream             string                   `json:"stream"`
+	ConditionsSnapshot ConditionNode            `json:"conditions_snapshot"`
+	ActionsTaken       []map[string]interface{} `json:"actions_taken"`
+	Status             ExecStatus               `json:"status"`
+	ErrorMessage       string                   `json:"error_message"`
+	Occurrences        int                      `json:"occurrences"`
+	TriggeredAt        time.Time                `json:"triggered_at"`
+}
+
+// NewExecRecord construiește record-ul pentru o regulă evaluată pe un mesaj.
+func NewExecRecord(rule Rule, msgCtx MessageContext, actions []map[string]interface{}, status ExecStatus, errMsg string) ExecRecord {
+	return ExecRecord{
+		RuleID:             rule.ID,
+		RuleName:           rule.Name,
+		TenantID:           msgCtx.TenantID,
+		DeviceSerial:       msgCtx.Serial,
+		Stream:             msgCtx.Stream,
+		ConditionsSnapshot: rule.Conditions,
+		ActionsTaken:       actions,
+		Status:             status,
+		ErrorMessage:       errMsg,
+		Occurrences:        1,
+		TriggeredAt:        time.Now().UTC(),
+	}
+}
+
+// ExecSink trimite un batch de record-uri la Django (implementat de Executor).
+type ExecSink interface {
+	LogExecutions(ctx context.Context, records []ExecRecord) error
+}
+
+// ExecLogConfig — parametrii ExecLogger; zero values → default-urile de mai jos.
+type ExecLogConfig struct {
+	BatchSize     int           // record-uri per POST bulk; default 200
+	FlushInterval time.Duration // flush periodic; default 2s
+	QueueSize     int           // buffer in-memory; default 10000
+	SpoolPath     string        // fișier JSONL pentru spill când Django pică; "" = fără spill
+	SpoolMaxBytes int64         // plafonul spool-ului; peste el se pierd cele mai vechi record-uri; default 64 MiB
+}
+
+// DefaultSpoolMaxBytes — la ~1 KiB / record, ~65k execuții (ore de outage Django).
+const DefaultSpoolMaxBytes = 64 << 20
+
+type cooldownKey struct {
+	ruleID int64
+	serial string
+}
+
+// ExecLogger decuplează log-ul execuțiilor de handler-ul MQTT.
+//
+// Comportament:
+//   - Log() / CooldownSkipped() nu fac I/O; record-urile intră într-o coadă in-memory
+//   - Run() trimite batch-uri la BatchSize sau la fiecare FlushInterval
+//   - POST eșuat → batch-ul e append-uit în spool (fsync); la următorul flush
+//     reușit spool-ul e re-trimis înainte de record-urile noi
+//   - coadă plină → record-ul e predat goroutine-ului de spill (spillLoop);
+//     dacă și coada lui e plină, record-ul e aruncat și numărat (warning la
+//     următorul flush). Log() nu atinge niciodată discul — nu blocăm evaluarea
+//   - replay-ul redenumește spool-ul (spool.replay) și trimite fără spoolMu
+//     ținut: un Django lent nu ține pe loc spill-urile noi
+//   - spool-ul nu crește peste SpoolMaxBytes: la depășire se renunță la cele
+//     mai vechi record-uri (warning în log), ca un outage lung să nu umple discul
+type ExecLogger struct {
+	sink ExecSink
+	cfg  ExecLogConfig
+	in   chan ExecRecord
+
+	mu        sync.Mutex
+	cooldowns map[cooldownKey]*ExecRecord
+
+	spillQ  chan ExecRecord // overflow-ul cozii, scris pe disc de spillLoop
+	dropped atomic.Int64    // record-uri pierdute de la ultimul flush
+
+	spoolMu sync.Mutex
+}
+
+// NewExecLogger creează logger-ul; Run trebuie pornit separat.
+func NewExecLogger(sink ExecSink, cfg ExecLogConfig) *ExecLogger {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 200
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 2 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.SpoolMaxBytes <= 0 {
+		cfg.SpoolMaxBytes = DefaultSpoolMaxBytes
+	}
+	return &ExecLogger{
+		sink:      sink,
+		cfg:       cfg,
+		in:        make(chan ExecRecord, cfg.QueueSize),
+		spillQ:    make(chan ExecRecord, cfg.QueueSize),
+		cooldowns: make(map[cooldownKey]*ExecRecord),
+	}
+}
+
+// Log pune record-ul în coadă. Nu blochează și nu face I/O.
+func (l *ExecLogger) Log(rec ExecRecord) {
+	select {
+	case l.in <- rec:
+		return
+	default:
+	}
+	if l.cfg.SpoolPath != "" {
+		select {
+		case l.spillQ <- rec:
+			return
+		default:
+		}
+	}
+	l.dropped.Add(1)
+}
+
+// CooldownSkipped agregă skip-urile pe (regulă, device); la flush devin un
+// singur record cooldown_skipped cu Occurrences = numărul de skip-uri.
+func (l *ExecLogger) CooldownSkipped(rule Rule, msgCtx MessageContext) {
+	k := cooldownKey{ruleID: rule.ID, serial: msgCtx.Serial}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if rec, ok := l.cooldowns[k]; ok {
+		rec.Occurrences++
+		return
+	}
+	rec := NewExecRecord(rule, msgCtx, nil, StatusCooldown, "")
+	l.cooldowns[k] = &rec
+}
+
+// Run consumă coada până la anularea ctx, apoi face un ultim flush.
+func (l *ExecLogger) Run(ctx context.Context) {
+	t := time.NewTicker(l.cfg.FlushInterval)
+	defer t.Stop()
+	if l.cfg.SpoolPath != "" {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			l.spillLoop(ctx)
+		}()
+		defer func() { <-done }()
+	}
+	batch := make([]ExecRecord, 0, l.cfg.BatchSize)
+	for {
+		select {
+		case <-ctx.Done():
+			// Drenăm ce a rămas în coadă; Django poate fi deja indisponibil → spool.
+			for {
+				select {
+				case rec := <-l.in:
+					batch = append(batch, rec)
+					continue
+				default:
+				}
+				break
+			}
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			l.flush(shutdownCtx, batch)
+			cancel()
+			return
+		case rec := <-l.in:
+			batch = append(batch, rec)
+			if len(batch) >= l.cfg.BatchSize {
+				l.flush(ctx, batch)
+				batch = batch[:0]
+			}
+		case <-t.C:
+			l.flush(ctx, batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// spillLoop scrie în spool record-urile care nu au încăput în coadă, grupând
+// ce s-a adunat între două scrieri. La anularea ctx drenează restul.
+func (l *ExecLogger) spillLoop(ctx context.Context) {
+	for {
+		var batch []ExecRecord
+		select {
+		case <-ctx.Done():
+			for {
+				select {
+				case rec := <-l.spillQ:
+					batch = append(batch, rec)
+					continue
+				default:
+				}
+				break
+			}
+			l.spillOrDrop(batch)
+			return
+		case rec := <-l.spillQ:
+			batch = append(batch, rec)
+		}
+		for len(batch) < l.cfg.BatchSize {
+			select {
+			case rec := <-l.spillQ:
+				batch = append(batch, rec)
+				continue
+			default:
+			}
+			break
+		}
+		l.spillOrDrop(batch)
+	}
+}
+
+func (l *ExecLogger) spillOrDrop(batch []ExecRecord) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := l.spill(batch); err != nil {
+		log.Printf("rules: exec log overflow spill failed, %d records lost: %v", len(batch), err)
+	}
+}
+
+// flush trimite spool-ul restant, apoi batch-ul curent + cooldown-urile agregate.
+func (l *ExecLogger) flush(ctx context.Context, batch []ExecRecord) {
+	if n := l.dropped.Swap(0); n > 0 {
+		log.Printf("rules: exec log queue full, %d records dropped", n)
+	}
+	l.mu.Lock()
+	for k, rec := range l.cooldowns {
+		batch = append(batch, *rec)
+		delete(l.cooldowns, k)
+	}
+	l.mu.Unlock()
+
+	if err := l.replaySpool(ctx); err != nil {
+		// Django tot indisponibil — nu mai încercăm batch-ul curent, merge direct în spool.
+		if len(batch) > 0 {
+			if serr := l.spill(batch); serr != nil {
+				log.Printf("rules: exec log spill failed, %d records lost: %v", len(batch), serr)
+			}
+		}
+		return
+	}
+	for start := 0; start < len(batch); start += l.cfg.BatchSize {
+		end := start + l.cfg.BatchSize
+		if end > len(batch) {
+			end = len(batch)
+		}
+		if err := l.sink.LogExecutions(ctx, batch[start:end]); err != nil {
+			log.Printf("rules: exec log bulk failed (%v); spilling %d records", err, len(batch)-start)
+			if serr := l.spill(batch[start:]); serr != nil {
+				log.Printf("rules: exec log spill failed, %d records lost: %v", len(batch)-start, serr)
+			}
+			return
+		}
+	}
+}
+
+// spill append-uiește record-urile în spool și face fsync. Dacă spool-ul ar
+// depăși SpoolMaxBytes, e rescris fără cele mai vechi linii.
+func (l *ExecLogger) spill(records []ExecRecord) error {
+	if l.cfg.SpoolPath == "" {
+		return fmt.Errorf("no spool configured")
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("spool encode: %w", err)
+		}
+	}
+
+	l.spoolMu.Lock()
+	defer l.spoolMu.Unlock()
+	if fi, err := os.Stat(l.cfg.SpoolPath); err == nil && fi.Size()+int64(buf.Len()) > l.cfg.SpoolMaxBytes {
+		return l.compactSpool(buf.Bytes())
+	}
+	f, err := os.OpenFile(l.cfg.SpoolPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open spool: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("spool write: %w", err)
+	}
+	return f.Sync()
+}
+
+// compactSpool rescrie spool-ul cu liniile existente + cele noi, renunțând la
+// cele mai vechi până încap în SpoolMaxBytes. Apelat cu spoolMu ținut.
+func (l *ExecLogger) compactSpool(fresh []byte) error {
+	old, err := os.ReadFile(l.cfg.SpoolPath)
+	if err != nil {
+		return fmt.Errorf("read spool: %w", err)
+	}
+	return l.writeCapped(append(old, fresh...))
+}
+
+// writeCapped înlocuiește spool-ul cu data (linii JSONL, cele mai vechi
+// primele), fără liniile vechi care nu încap în SpoolMaxBytes. Apelat cu spoolMu ținut.
+func (l *ExecLogger) writeCapped(data []byte) error {
+	lines := bytes.SplitAfter(data, []byte("\n"))
+	var size int64
+	keep := len(lines)
+	for keep > 0 && size+int64(len(lines[keep-1])) <= l.cfg.SpoolMaxBytes {
+		keep--
+		size += int64(len(lines[keep]))
+	}
+	dropped := 0
+	for _, ln := range lines[:keep] {
+		if len(bytes.TrimSpace(ln)) > 0 {
+			dropped++
+		}
+	}
+	if dropped > 0 {
+		log.Printf("rules: exec spool over %d bytes, dropped %d oldest records", l.cfg.SpoolMaxBytes, dropped)
+	}
+	return writeFileSync(l.cfg.SpoolPath, bytes.Join(lines[keep:], nil))
+}
+
+// replaySpool re-trimite record-urile din spool. Sub spoolMu doar mută
+// spool-ul în spool.replay; trimiterea se face fără lock, ca spill-urile noi
+// să nu aștepte după Django. Un spool.replay rămas de la un crash e trimis
+// întâi (spool-ul curent rămâne pentru flush-ul următor, ordinea se păstrează).
+// La eșec, record-urile netrimise sunt puse înapoi în fața spool-ului.
+func (l *ExecLogger) replaySpool(ctx context.Context) error {
+	if l.cfg.SpoolPath == "" {
+		return nil
+	}
+	taken := l.cfg.SpoolPath + ".replay"
+	l.spoolMu.Lock()
+	if _, err := os.Stat(taken); os.IsNotExist(err) {
+		if err := os.Rename(l.cfg.SpoolPath, taken); err != nil {
+			l.spoolMu.Unlock()
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return fmt.Errorf("take spool: %w", err)
+		}
+	}
+	l.spoolMu.Unlock()
+
+	records, err := readSpool(taken)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(records); start += l.cfg.BatchSize {
+		end := start + l.cfg.BatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		if err := l.sink.LogExecutions(ctx, records[start:end]); err != nil {
+			if werr := l.restoreSpool(taken, records[start:]); werr != nil {
+				log.Printf("rules: spool restore failed: %v", werr)
+			}
+			return err
+		}
+	}
+	if len(records) > 0 {
+		log.Printf("rules: replayed %d spooled exec records", len(records))
+	}
+	return os.Remove(taken)
+}
+
+// restoreSpool pune record-urile netrimise înaintea celor spill-uite între
+// timp, apoi șterge taken. Spool-ul e scris înainte de ștergere: un crash
+// între cele două dublează record-uri, nu le pierde.
+func (l *ExecLogger) restoreSpool(taken string, unsent []ExecRecord) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, rec := range unsent {
+		if err := enc.Encode(rec); err != nil {
+			return fmt.Errorf("spool encode: %w", err)
+		}
+	}
+	l.spoolMu.Lock()
+	defer l.spoolMu.Unlock()
+	cur, err := os.ReadFile(l.cfg.SpoolPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read spool: %w", err)
+	}
+	if err := l.writeCapped(append(buf.Bytes(), cur...)); err != nil {
+		return err
+	}
+	return os.Remove(taken)
+}
+
+func readSpool(path string) ([]ExecRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open spool: %w", err)
+	}
+	defer f.Close()
+	var out []ExecRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for sc.Scan() {
+		var rec ExecRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			log.Printf("rules: skipping corrupt spool line: %v", err)
+			continue
+		}
+		out = append(out, rec)
+	}
+	return out, sc.Err()
+}
+
+// writeFileSync înlocuiește atomic fișierul (tmp + fsync + rename).
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
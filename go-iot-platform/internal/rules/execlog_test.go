@@ -0,0 +1,225 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	mu      sync.Mutex
+	fail    bool
+	batches [][]ExecRecord
+}
+
+func (s *fakeSink) LogExecutions(_ context.Context, records []ExecRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fail {
+		return errors.New("django down")
+	}
+	cp := append([]ExecRecord(nil), records...)
+	s.batches = append(s.batches, cp)
+	return nil
+}
+
+func (s *fakeSink) total() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, b := range s.batches {
+		n += len(b)
+	}
+	return n
+}
+
+func TestExecLoggerBatches(t *testing.T) {
+	sink := &fakeSink{}
+	l := NewExecLogger(sink, ExecLogConfig{BatchSize: 2})
+	msg := MessageContext{TenantID: 2, Serial: "DEV1", Stream: "telemetry"}
+	rule := Rule{ID: 7, Name: "r"}
+
+	var batch []ExecRecord
+	for i := 0; i < 5; i++ {
+		batch = append(batch, NewExecRecord(rule, msg, nil, StatusTriggered, ""))
+	}
+	l.flush(context.Background(), batch)
+
+	if len(sink.batches) != 3 {
+		t.Fatalf("batches=%d want 3 (5 records, batch size 2)", len(sink.batches))
+	}
+	if sink.total() != 5 {
+		t.Errorf("total=%d want 5", sink.total())
+	}
+}
+
+func TestExecLoggerCooldownAggregated(t *testing.T) {
+	sink := &fakeSink{}
+	l := NewExecLogger(sink, ExecLogConfig{})
+	rule := Rule{ID: 7, Name: "r"}
+	for i := 0; i < 10; i++ {
+		l.CooldownSkipped(rule, MessageContext{TenantID: 2, Serial: "DEV1"})
+	}
+	l.CooldownSkipped(rule, MessageContext{TenantID: 2, Serial: "DEV2"})
+	l.flush(context.Background(), nil)
+
+	if sink.total() != 2 {
+		t.Fatalf("records=%d want 2 (one per rule+device)", sink.total())
+	}
+	for _, rec := range sink.batches[0] {
+		want := 1
+		if rec.DeviceSerial == "DEV1" {
+			want = 10
+		}
+		if rec.Occurrences != want || rec.Status != StatusCooldown {
+			t.Errorf("%s: occurrences=%d status=%s, want %d cooldown_skipped",
+				rec.DeviceSerial, rec.Occurrences, rec.Status, want)
+		}
+	}
+
+	// Contoarele se resetează după flush.
+	l.flush(context.Background(), nil)
+	if sink.total() != 2 {
+		t.Errorf("second flush re-sent cooldown records")
+	}
+}
+
+func TestExecLoggerSpillAndReplay(t *testing.T) {
+	sink := &fakeSink{fail: true}
+	spool := filepath.Join(t.TempDir(), "spool.jsonl")
+	l := NewExecLogger(sink, ExecLogConfig{BatchSize: 10, SpoolPath: spool})
+	msg := MessageContext{TenantID: 2, Serial: "DEV1"}
+	rec := NewExecRecord(Rule{ID: 1}, msg, nil, StatusTriggered, "")
+
+	l.flush(context.Background(), []ExecRecord{rec, rec, rec})
+	spooled, err := readSpool(spool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spooled) != 3 {
+		t.Fatalf("spooled=%d want 3", len(spooled))
+	}
+
+	// Django revine: spool-ul e trimis înaintea batch-ului nou, apoi trunchiat.
+	sink.mu.Lock()
+	sink.fail = false
+	sink.mu.Unlock()
+	l.flush(context.Background(), []ExecRecord{rec})
+	if sink.total() != 4 {
+		t.Errorf("delivered=%d want 4", sink.total())
+	}
+	spooled, _ = readSpool(spool)
+	if len(spooled) != 0 {
+		t.Errorf("spool not truncated after replay: %d left", len(spooled))
+	}
+}
+
+func TestExecLoggerSpoolCap(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "spool.jsonl")
+	rec := NewExecRecord(Rule{ID: 1}, MessageContext{TenantID: 2, Serial: "DEV1"}, nil, StatusTriggered, "")
+	line, _ := json.Marshal(rec)
+	perRecord := int64(len(line) + 1)
+
+	l := NewExecLogger(&fakeSink{fail: true}, ExecLogConfig{SpoolPath: spool, SpoolMaxBytes: 5 * perRecord})
+	for i := 0; i < 8; i++ {
+		r := rec
+		r.DeviceSerial = fmt.Sprintf("DEV%d", i) // aceeași lungime pentru toate
+		if err := l.spill([]ExecRecord{r}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fi, err := os.Stat(spool)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() > 5*perRecord {
+		t.Errorf("spool size %d > cap %d", fi.Size(), 5*perRecord)
+	}
+	spooled, _ := readSpool(spool)
+	if len(spooled) != 5 || spooled[0].DeviceSerial != "DEV3" || spooled[4].DeviceSerial != "DEV7" {
+		var got []string
+		for _, r := range spooled {
+			got = append(got, r.DeviceSerial)
+		}
+		t.Errorf("spool kept %v, want the 5 newest (DEV3..DEV7)", got)
+	}
+}
+
+// stallSink blochează LogExecutions până la release, apoi eșuează.
+type stallSink struct {
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *stallSink) LogExecutions(_ context.Context, _ []ExecRecord) error {
+	close(s.entered)
+	<-s.release
+	return errors.New("django timeout")
+}
+
+func TestExecLoggerQueueFullDrops(t *testing.T) {
+	l := NewExecLogger(&fakeSink{}, ExecLogConfig{QueueSize: 1})
+	rec := NewExecRecord(Rule{ID: 1}, MessageContext{TenantID: 2, Serial: "DEV1"}, nil, StatusTriggered, "")
+	for i := 0; i < 3; i++ {
+		l.Log(rec)
+	}
+	if got := l.dropped.Load(); got != 2 {
+		t.Errorf("dropped=%d want 2 (no spool configured)", got)
+	}
+}
+
+func TestExecLoggerReplayDoesNotBlockSpill(t *testing.T) {
+	spool := filepath.Join(t.TempDir(), "spool.jsonl")
+	sink := &stallSink{entered: make(chan struct{}), release: make(chan struct{})}
+	l := NewExecLogger(sink, ExecLogConfig{QueueSize: 1, SpoolPath: spool})
+	mk := func(serial string) ExecRecord {
+		return NewExecRecord(Rule{ID: 1}, MessageContext{TenantID: 2, Serial: serial}, nil, StatusTriggered, "")
+	}
+	if err := l.spill([]ExecRecord{mk("OLD1"), mk("OLD2")}); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := make(chan error, 1)
+	go func() { replayed <- l.replaySpool(context.Background()) }()
+	<-sink.entered
+
+	// Django atârnă: Log și spill-ul de overflow nu așteaptă după replay.
+	start := time.Now()
+	l.Log(mk("Q"))
+	l.Log(mk("NEW"))
+	if d := time.Since(start); d > 100*time.Millisecond {
+		t.Fatalf("Log blocked %v while replay was in flight", d)
+	}
+	spilled := make(chan error, 1)
+	go func() { spilled <- l.spill([]ExecRecord{<-l.spillQ}) }()
+	select {
+	case err := <-spilled:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("spill blocked behind the replay's network call")
+	}
+
+	close(sink.release)
+	if err := <-replayed; err == nil {
+		t.Fatal("replay should report the sink failure")
+	}
+	spooled, _ := readSpool(spool)
+	var got []string
+	for _, r := range spooled {
+		got = append(got, r.DeviceSerial)
+	}
+	if fmt.Sprint(got) != "[OLD1 OLD2 NEW]" {
+		t.Errorf("spool=%v want unsent records before the new spill [OLD1 OLD2 NEW]", got)
+	}
+	if _, err := os.Stat(spool + ".replay"); !os.IsNotExist(err) {
+		t.Errorf("replay file left behind: %v", err)
+	}
+}
@@ -190,6 +190,14 @@ func (e *Executor) LogExecution(ctx context.Context, body map[string]interface{}
 	return e.djangoPost(ctx, "/api/internal/rules/log/", body)
 }
 
+// LogExecutions trimite un batch de execuții prin endpoint-ul bulk (folosit de ExecLogger).
+func (e *Executor) LogExecutions(ctx context.Context, records []ExecRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	return e.djangoPost(ctx, "/api/internal/rules/log/bulk/", map[string]interface{}{"executions": records})
+}
+
 // ── HTTP helpers ──────────────────────────────────────────────────────────────
 
 func (e *Executor) djangoPost(ctx context.Context, path string, body interface{}) error {
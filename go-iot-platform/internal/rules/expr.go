@@ -0,0 +1,366 @@
+package rules
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expresii în câmpul `field` / `value_field` al unei condiții leaf.
+//
+//	pv_input_power - house_load_kw_est          aritmetică: + - * / și paranteze
+//	sum(measurements[*].value)                  agregări pe projecții [*]
+//	max(phases[*].voltage) - min(phases[*].voltage)
+//	len(alarms) * 2
+//
+// Funcții: sum, avg, min, max, len, abs. Un operand ne-numeric (câmp lipsă,
+// string nenumeric) sau împărțirea la zero dau rezultat nil — condiția e falsă
+// (iar is_null adevărat), la fel ca un câmp lipsă.
+//
+// Compatibilitate cu path-urile existente:
+//   - un câmp top-level cu exact numele expresiei are prioritate
+//   - "-" lipit de un identificator face parte din nume ("pv-power");
+//     scăderea cere spațiu înainte: "a - b" / "a -b"
+//   - o expresie care nu se parsează e tratată ca path simplu
+var exprFuncs = map[string]bool{
+	"sum": true, "avg": true, "min": true, "max": true, "len": true, "abs": true,
+}
+
+type exprKind int
+
+const (
+	exprNum exprKind = iota
+	exprPath
+	exprFunc
+	exprBinary
+	exprNeg
+)
+
+type exprNode struct {
+	kind        exprKind
+	num         float64
+	name        string // path sau nume funcție
+	op          byte   // + - * /
+	left, right *exprNode
+}
+
+// evalField întoarce valoarea unui field / expresii pe payload, compilând
+// expresia ad-hoc (teste, Evaluate); evaluarea din RuleSet folosește
+// conditionCache.field.
+func evalField(data map[string]interface{}, field string) interface{} {
+	return (*conditionCache)(nil).field(data, field)
+}
+
+func (cc *conditionCache) field(data map[string]interface{}, field string) interface{} {
+	if v, ok := data[field]; ok {
+		return v
+	}
+	if e := cc.expr(field); e != nil {
+		return e.eval(data)
+	}
+	return ExtractField(data, field)
+}
+
+// expr întoarce expresia compilată (nil = nu e expresie validă → path simplu);
+// cele din afara setului (sau cc nil) sunt compilate fără să fie reținute.
+func (cc *conditionCache) expr(src string) *exprNode {
+	if cc != nil {
+		if e, ok := cc.exprs[src]; ok {
+			return e
+		}
+	}
+	e, err := parseExpr(src)
+	if err != nil {
+		return nil
+	}
+	return e
+}
+
+// parseExpr compilează o expresie de field.
+func parseExpr(src string) (*exprNode, error) {
+	toks, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &exprParser{toks: toks}
+	e, err := p.parseSum()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.toks) {
+		return nil, fmt.Errorf("expr %q: unexpected %q", src, p.toks[p.pos].text)
+	}
+	return e, nil
+}
+
+// ── tokenizer ─────────────────────────────────────────────────────────────
+
+type exprTokKind int
+
+const (
+	tokNum exprTokKind = iota
+	tokIdent
+	tokOp // + - * / ( )
+)
+
+type exprTok struct {
+	kind exprTokKind
+	text string
+	num  float64
+}
+
+func tokenizeExpr(src string) ([]exprTok, error) {
+	var toks []exprTok
+	rs := []rune(src)
+	for i := 0; i < len(rs); {
+		ch := rs[i]
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case strings.ContainsRune("+-*/()", ch):
+			toks = append(toks, exprTok{kind: tokOp, text: string(ch)})
+			i++
+		case unicode.IsDigit(ch) || (ch == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1])):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.') {
+				j++
+			}
+			f, err := strconv.ParseFloat(string(rs[i:j]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("expr: invalid number %q", string(rs[i:j]))
+			}
+			toks = append(toks, exprTok{kind: tokNum, text: string(rs[i:j]), num: f})
+			i = j
+		default:
+			j, depth := i, 0
+		ident:
+			for ; j < len(rs); j++ {
+				c := rs[j]
+				switch {
+				case c == '[':
+					depth++
+				case c == ']':
+					if depth == 0 {
+						return nil, fmt.Errorf("expr: unbalanced ']' in %q", src)
+					}
+					depth--
+				case depth > 0:
+				case unicode.IsSpace(c), strings.ContainsRune("+/()", c):
+					break ident
+				case c == '*' && !(j > i && rs[j-1] == '.'):
+					break ident // "a.*" e segment wildcard, "a*b" e înmulțire
+				}
+			}
+			if depth != 0 {
+				return nil, fmt.Errorf("expr: unbalanced '[' in %q", src)
+			}
+			toks = append(toks, exprTok{kind: tokIdent, text: string(rs[i:j])})
+			i = j
+		}
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("expr: empty")
+	}
+	return toks, nil
+}
+
+// ── parser (recursive descent) ────────────────────────────────────────────
+
+type exprParser struct {
+	toks []exprTok
+	pos  int
+}
+
+func (p *exprParser) peekOp(ops string) (byte, bool) {
+	if p.pos < len(p.toks) && p.toks[p.pos].kind == tokOp && strings.Contains(ops, p.toks[p.pos].text) {
+		return p.toks[p.pos].text[0], true
+	}
+	return 0, false
+}
+
+func (p *exprParser) parseSum() (*exprNode, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOp("+-")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprNode{kind: exprBinary, op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseProduct() (*exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekOp("*/")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &exprNode{kind: exprBinary, op: op, left: left, right: right}
+	}
+}
+
+func (p *exprParser) parseUnary() (*exprNode, error) {
+	if _, ok := p.peekOp("-"); ok {
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &exprNode{kind: exprNeg, left: inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (*exprNode, error) {
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("expr: unexpected end")
+	}
+	tok := p.toks[p.pos]
+	p.pos++
+	switch tok.kind {
+	case tokNum:
+		return &exprNode{kind: exprNum, num: tok.num}, nil
+	case tokIdent:
+		if _, ok := p.peekOp("("); ok {
+			name := strings.ToLower(tok.text)
+			if !exprFuncs[name] {
+				return nil, fmt.Errorf("expr: unknown function %q", tok.text)
+			}
+			p.pos++
+			arg, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.peekOp(")"); !ok {
+				return nil, fmt.Errorf("expr: missing ')' after %s(", name)
+			}
+			p.pos++
+			return &exprNode{kind: exprFunc, name: name, left: arg}, nil
+		}
+		return &exprNode{kind: exprPath, name: tok.text}, nil
+	case tokOp:
+		if tok.text == "(" {
+			inner, err := p.parseSum()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := p.peekOp(")"); !ok {
+				return nil, fmt.Errorf("expr: missing ')'")
+			}
+			p.pos++
+			return inner, nil
+		}
+	}
+	return nil, fmt.Errorf("expr: unexpected %q", tok.text)
+}
+
+// ── evaluare ──────────────────────────────────────────────────────────────
+
+func (e *exprNode) eval(data map[string]interface{}) interface{} {
+	switch e.kind {
+	case exprNum:
+		return e.num
+	case exprPath:
+		return ExtractField(data, e.name)
+	case exprFunc:
+		return applyFunc(e.name, e.left.eval(data))
+	case exprNeg:
+		f, ok := toFloat(e.left.eval(data))
+		if !ok {
+			return nil
+		}
+		return -f
+	case exprBinary:
+		a, b, ok := toFloats(e.left.eval(data), e.right.eval(data))
+		if !ok {
+			return nil
+		}
+		switch e.op {
+		case '+':
+			return a + b
+		case '-':
+			return a - b
+		case '*':
+			return a * b
+		case '/':
+			if b == 0 {
+				return nil
+			}
+			return a / b
+		}
+	}
+	return nil
+}
+
+// applyFunc — agregările ignoră elementele ne-numerice; o listă fără
+// numere dă nil (sum/avg/min/max), nu 0, ca să nu declanșeze "lt" fals.
+func applyFunc(name string, v interface{}) interface{} {
+	if name == "len" {
+		switch t := v.(type) {
+		case []interface{}:
+			return float64(len(t))
+		case map[string]interface{}:
+			return float64(len(t))
+		case string:
+			return float64(len([]rune(t)))
+		case nil:
+			return float64(0)
+		}
+		return float64(1)
+	}
+	if name == "abs" {
+		f, ok := toFloat(v)
+		if !ok {
+			return nil
+		}
+		return math.Abs(f)
+	}
+
+	list, isList := v.([]interface{})
+	if !isList {
+		list = []interface{}{v}
+	}
+	var nums []float64
+	for _, item := range list {
+		if f, ok := toFloat(item); ok {
+			nums = append(nums, f)
+		}
+	}
+	if len(nums) == 0 {
+		return nil
+	}
+	acc := nums[0]
+	for _, f := range nums[1:] {
+		switch name {
+		case "sum", "avg":
+			acc += f
+		case "min":
+			acc = math.Min(acc, f)
+		case "max":
+			acc = math.Max(acc, f)
+		}
+	}
+	if name == "avg" {
+		acc /= float64(len(nums))
+	}
+	return acc
+}
@@ -0,0 +1,118 @@
+package rules
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEvalFieldExpressions(t *testing.T) {
+	data := map[string]interface{}{
+		"pv_input_power":    float64(8.5),
+		"house_load_kw_est": float64(5.25),
+		"pv-power":          float64(3),
+		"status":            "ok",
+		"zero":              float64(0),
+		"measurements": []interface{}{
+			map[string]interface{}{"key": "p1", "value": float64(2)},
+			map[string]interface{}{"key": "p2", "value": float64(7)},
+			map[string]interface{}{"key": "p3", "value": "n/a"},
+		},
+		"phases": map[string]interface{}{
+			"l1": map[string]interface{}{"voltage": float64(229)},
+			"l2": map[string]interface{}{"voltage": float64(235)},
+		},
+	}
+	cases := []struct {
+		expr string
+		want interface{}
+	}{
+		{"pv_input_power - house_load_kw_est", 3.25},
+		{"pv_input_power -house_load_kw_est", 3.25},
+		{"(pv_input_power + 1.5) * 2", float64(20)},
+		{"-pv_input_power / 2", -4.25},
+		{"pv-power", float64(3)}, // cratima lipită = parte din nume
+		{"sum(measurements[*].value)", float64(9)},
+		{"max(measurements[*].value)", float64(7)},
+		{"min(measurements[*].value)", float64(2)},
+		{"avg(measurements[*].value)", 4.5},
+		{"len(measurements)", float64(3)},
+		{"len(measurements[*].value) - 1", float64(2)},
+		{"max(phases.*.voltage) - min(phases.*.voltage)", float64(6)},
+		{"abs(house_load_kw_est - pv_input_power)", 3.25},
+		{"pv_input_power / zero", nil},
+		{"status + 1", nil},
+		{"missing - 1", nil},
+		{"sum(measurements[*].key)", nil},
+	}
+	for _, tc := range cases {
+		if got := evalField(data, tc.expr); got != tc.want {
+			t.Errorf("%q = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseExprErrors(t *testing.T) {
+	for _, src := range []string{"", "a +", "(a - b", "a - b)", "nope(a)", "sum(a", "a[x"} {
+		if _, err := parseExpr(src); err == nil {
+			t.Errorf("%q: expected error", src)
+		}
+	}
+	// Expresiile invalide rămân path-uri simple (compatibilitate).
+	if v := evalField(map[string]interface{}{"a": map[string]interface{}{"b": 1.0}}, "a.b"); v != 1.0 {
+		t.Errorf("plain path got %v", v)
+	}
+}
+
+func TestEvaluateArithmeticAndValueField(t *testing.T) {
+	data := map[string]interface{}{
+		"pv_input_power":    float64(8.5),
+		"house_load_kw_est": float64(5.25),
+		"setpoint":          float64(21),
+		"temperature":       float64(23.5),
+		"alarms":            []interface{}{"overvoltage", "fan"},
+	}
+	tests := []struct {
+		name, condition string
+		want            bool
+	}{
+		{"surplus > 2", `{"field":"pv_input_power - house_load_kw_est","op":"gt","value":2}`, true},
+		{"surplus > 5", `{"field":"pv_input_power - house_load_kw_est","op":"gt","value":5}`, false},
+		{"field vs field", `{"field":"temperature","op":"gt","value_field":"setpoint"}`, true},
+		{"field vs expr", `{"field":"temperature","op":"lt","value_field":"setpoint + 2"}`, false},
+		{"missing value_field", `{"field":"temperature","op":"gt","value_field":"nope"}`, false},
+		{"list contains", `{"field":"alarms","op":"contains","value":"fan"}`, true},
+		{"list not_contains", `{"field":"alarms","op":"not_contains","value":"smoke"}`, true},
+		{"len", `{"field":"len(alarms)","op":"gte","value":2}`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var node ConditionNode
+			if err := json.Unmarshal([]byte(tt.condition), &node); err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if got := Evaluate(node, data, nil); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			// Același rezultat cu expresiile compilate în RuleSet.
+			rs := NewRuleSet(2, []Rule{{ID: 1, Enabled: true, Conditions: node}})
+			if _, ok := rs.conds.exprs[node.Field]; !ok {
+				t.Errorf("field %q not compiled into the rule set", node.Field)
+			}
+			if got := rs.Evaluate(node, data, nil); got != tt.want {
+				t.Errorf("RuleSet.Evaluate got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateChangedNestedPath(t *testing.T) {
+	cond := ConditionNode{Field: "relay.state", Op: "changed"}
+	data := map[string]interface{}{"relay": map[string]interface{}{"state": "off"}}
+	prev := map[string]interface{}{"relay": map[string]interface{}{"state": "on"}}
+	if !Evaluate(cond, data, prev) {
+		t.Error("expected changed=true for nested path")
+	}
+	if Evaluate(cond, data, data) {
+		t.Error("expected changed=false for same nested value")
+	}
+}
@@ -1,6 +1,7 @@
 package rules
 
 import (
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -12,31 +13,79 @@ import (
 //   - "relay.state"                     → nested field
 //   - "measurements.0.value"            → array index
 //   - "measurements[key=active_power_kw].value" → array filter by sub-key
+//   - "measurements[*].value"           → projecție: []interface{} cu value din
+//     fiecare element (elementele fără câmp sunt omise); "*" ca segment face
+//     același lucru pe array-uri sau pe valorile unui obiect
+//
+// Agregările (sum, max, len, ...) și aritmetica sunt în expr.go.
 func ExtractField(data map[string]interface{}, path string) interface{} {
-	parts := splitPath(path)
 	var current interface{} = data
-	for _, part := range parts {
-		if current == nil {
+	projected := false
+	for _, part := range splitPath(path) {
+		if projected {
+			list, _ := current.([]interface{})
+			out := make([]interface{}, 0, len(list))
+			for _, elem := range list {
+				v, proj := stepPath(elem, part)
+				switch {
+				case v == nil:
+				case proj:
+					out = append(out, v.([]interface{})...)
+				default:
+					out = append(out, v)
+				}
+			}
+			current = out
+			continue
+		}
+		v, proj := stepPath(current, part)
+		if v == nil {
 			return nil
 		}
-		switch v := current.(type) {
-		case map[string]interface{}:
-			if strings.Contains(part, "[") {
-				current = extractArrayFilter(v, part)
-			} else {
-				current = v[part]
+		current, projected = v, proj
+	}
+	return current
+}
+
+// stepPath aplică un segment de path; proj=true când rezultatul e o projecție
+// ([*] / *) și segmentele următoare trebuie aplicate pe fiecare element.
+func stepPath(current interface{}, part string) (v interface{}, proj bool) {
+	switch cur := current.(type) {
+	case map[string]interface{}:
+		if part == "*" {
+			keys := make([]string, 0, len(cur))
+			for k := range cur {
+				keys = append(keys, k)
 			}
-		case []interface{}:
-			idx, err := strconv.Atoi(part)
-			if err != nil || idx < 0 || idx >= len(v) {
-				return nil
+			sort.Strings(keys)
+			out := make([]interface{}, 0, len(keys))
+			for _, k := range keys {
+				out = append(out, cur[k])
 			}
-			current = v[idx]
-		default:
-			return nil
+			return out, true
+		}
+		if arrKey, ok := strings.CutSuffix(part, "[*]"); ok {
+			arr, ok := cur[arrKey].([]interface{})
+			if !ok {
+				return nil, false
+			}
+			return arr, true
 		}
+		if strings.Contains(part, "[") {
+			return extractArrayFilter(cur, part), false
+		}
+		return cur[part], false
+	case []interface{}:
+		if part == "*" {
+			return cur, true
+		}
+		idx, err := strconv.Atoi(part)
+		if err != nil || idx < 0 || idx >= len(cur) {
+			return nil, false
+		}
+		return cur[idx], false
 	}
-	return current
+	return nil, false
 }
 
 // splitPath splits "a.b[k=v].c" into ["a", "b[k=v]", "c"].
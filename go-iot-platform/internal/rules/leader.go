@@ -0,0 +1,83 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultLeaderKey — lock-ul Redis ținut de instanța rule-engine care rulează
+// regulile schedule / absence. Regulile message rulează pe toate instanțele
+// ($share), dar cele temporizate trebuie declanșate o singură dată.
+const DefaultLeaderKey = "rule-engine:scheduler-leader"
+
+// renewScript prelungește lock-ul doar dacă e încă al nostru.
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+
+// releaseScript șterge lock-ul doar dacă e încă al nostru.
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// Leader — leader election minimal peste SET NX PX.
+//
+// Deținătorul reînnoiește lock-ul la fiecare Acquire (trebuie apelat mai des
+// decât TTL); dacă instanța moare, lock-ul expiră și altă instanță preia după
+// cel mult TTL.
+type Leader struct {
+	rdb *redis.Client
+	key string
+	id  string
+	ttl time.Duration
+}
+
+// NewLeader creează un candidat cu ID unic (hostname-pid-nanos).
+func NewLeader(rdb *redis.Client, key string, ttl time.Duration) *Leader {
+	host, _ := os.Hostname()
+	return &Leader{
+		rdb: rdb,
+		key: key,
+		id:  fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano()),
+		ttl: ttl,
+	}
+}
+
+// ID — identificatorul acestei instanțe (valoarea lock-ului).
+func (l *Leader) ID() string { return l.id }
+
+// Acquire întoarce true dacă instanța deține lock-ul (reînnoit sau nou obținut).
+// Erorile Redis → false: fără Redis nu putem garanta unicitatea, deci nu rulăm.
+func (l *Leader) Acquire(ctx context.Context) bool {
+	ms := l.ttl.Milliseconds()
+	renewed, err := renewScript.Run(ctx, l.rdb, []string{l.key}, l.id, ms).Int()
+	if err != nil {
+		log.Printf("rules: leader renew: %v", err)
+		return false
+	}
+	if renewed == 1 {
+		return true
+	}
+	ok, err := l.rdb.SetNX(ctx, l.key, l.id, l.ttl).Result()
+	if err != nil {
+		log.Printf("rules: leader acquire: %v", err)
+		return false
+	}
+	return ok
+}
+
+// Release eliberează lock-ul la shutdown, ca altă instanță să preia imediat.
+func (l *Leader) Release(ctx context.Context) {
+	if err := releaseScript.Run(ctx, l.rdb, []string{l.key}, l.id).Err(); err != nil && err != redis.Nil {
+		log.Printf("rules: leader release: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package rules
+
+import (
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/parsers"
+	"go-iot-platform/internal/registry"
+)
+
+// DecodePayload transformă payload-ul unui mesaj în field map-ul pe care se
+// evaluează condițiile.
+//
+// Field-urile sunt exact cele scrise de ingest în Influx (parsers.Decode):
+// handler-ul dedicat al stream-ului (ex. Tasmota SENSOR → nousat_power,
+// Shelly emeter → Power), parser-ul DD-ului sau fallback-ul generic. Cu DD
+// identificat, numele canonice din normalized_fields sunt adăugate lângă
+// ele, fără să le suprascrie — o regulă poate folosi oricare.
+//
+// stream e cel din matcher, sau din topic fără match.
+func DecodePayload(m *matcher.Match, stream, topic string, payload []byte) (map[string]interface{}, error) {
+	var dd *registry.DeviceDefinition
+	var vars map[string]string
+	if m != nil {
+		dd, vars = m.Definition, m.Extracted
+	}
+	pt, err := parsers.Decode(dd, stream, topic, vars, payload)
+	if err != nil {
+		return nil, err
+	}
+	fields := pt.Fields
+	if dd == nil || len(dd.NormalizedFields) == 0 {
+		return fields, nil
+	}
+	// Source-urile din normalized_fields sunt path-uri în payload-ul vendor
+	// (ENERGY.Power), nu în field-urile scrise.
+	raw, err := parsers.Parse(dd, topic, vars, payload)
+	if err != nil {
+		return fields, nil
+	}
+	for name, v := range parsers.Normalize(dd, raw) {
+		if _, exists := fields[name]; !exists {
+			fields[name] = v
+		}
+	}
+	return fields, nil
+}
@@ -0,0 +1,137 @@
+package rules
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-iot-platform/internal/matcher"
+	"go-iot-platform/internal/parsers"
+	"go-iot-platform/internal/registry"
+)
+
+func prodMatcher(t *testing.T) *matcher.Matcher {
+	t.Helper()
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil {
+		t.Skipf("configs/devices/ not available: %v", err)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("registry errors: %v", errs)
+	}
+	m, mErrs := matcher.New(reg)
+	if len(mErrs) > 0 {
+		t.Fatalf("matcher errors: %v", mErrs)
+	}
+	return m
+}
+
+func TestDecodePayloadViaRegistry(t *testing.T) {
+	m := prodMatcher(t)
+
+	cases := []struct {
+		name, topic, payload string
+		cond                 ConditionNode
+	}{
+		{"shelly raw emeter", "shellies/shellyem-ABC/emeter/0/power", "1234.56",
+			ConditionNode{Field: "active_power_w", Op: "gt", Value: 1000.0}},
+		{"shelly raw stored name", "shellies/shellyem-ABC/emeter/0/power", "1234.56",
+			ConditionNode{Field: "Power", Op: "gt", Value: 1000.0}},
+		{"sun2000 measurements", "tenants/2/devices/39371381/up/telemetry",
+			`{"measurements":[{"key":"battery_soc","value":25}]}`,
+			ConditionNode{Field: "battery_soc_pct", Op: "lt", Value: 30.0}},
+		{"tasmota stored name", "tele/boiler/SENSOR", `{"ENERGY":{"Power":2100}}`,
+			ConditionNode{Field: "nousat_power", Op: "gte", Value: 2000.0}},
+		{"tasmota canonical", "tele/boiler/SENSOR", `{"ENERGY":{"Power":2100}}`,
+			ConditionNode{Field: "active_power_w", Op: "gte", Value: 2000.0}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mch := m.Match(tc.topic)
+			fields, err := DecodePayload(mch, mch.Stream, tc.topic, []byte(tc.payload))
+			if err != nil {
+				t.Fatalf("DecodePayload: %v", err)
+			}
+			if !Evaluate(tc.cond, fields, nil) {
+				t.Errorf("condition %+v false on %v", tc.cond, fields)
+			}
+		})
+	}
+}
+
+func TestDecodePayloadWithoutMatch(t *testing.T) {
+	fields, err := DecodePayload(nil, "custom", "tenants/2/devices/x/up/custom", []byte(`{"a":1}`))
+	if err != nil || fields["a"] != float64(1) {
+		t.Errorf("fields=%v err=%v", fields, err)
+	}
+	// La fel ca ingest-ul generic: o valoare simplă devine field-ul "value".
+	fields, err = DecodePayload(nil, "custom", "tenants/2/devices/x/up/custom", []byte("1234.56"))
+	if err != nil || fields["value"] != 1234.56 {
+		t.Errorf("scalar: fields=%v err=%v", fields, err)
+	}
+	// Stream cu handler dedicat și fără DD: tot numele scrise de ingest.
+	fields, err = DecodePayload(nil, "sensor", "tenants/2/devices/x/up/sensor", []byte(`{"ENERGY":{"Power":5}}`))
+	if err != nil || fields["nousat_power"] != float64(5) {
+		t.Errorf("sensor: fields=%v err=%v", fields, err)
+	}
+}
+
+// Pentru fiecare pattern din configs/devices: regulile văd cel puțin field-urile
+// (aceleași nume, aceleași valori) pe care ingest-ul le scrie în Influx.
+func TestDecodePayloadMatchesIngestFields(t *testing.T) {
+	m := prodMatcher(t)
+	reg, _, _ := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+
+	byStream := map[string]string{
+		"telemetry": `{"ts":"2026-05-10T12:00:00Z","house_load_kw_est":0.5,"measurements":[{"key":"battery_soc","value":25}]}`,
+		"emeter":    "1234.56",
+		"relay":     "on",
+		"state":     `{"POWER":"ON","RSSI":70}`,
+		"sensor":    `{"Time":"2026-05-10T12:00:00","ENERGY":{"Power":2100,"Voltage":231}}`,
+		"zigbee":    `{"contact":false,"temperature":21.5,"humidity":48,"battery":91}`,
+	}
+	byParser := map[string]string{
+		"json":     `{"temp":21.4,"rh":48,"bat":87}`,
+		"keyvalue": "power=1200,voltage=230",
+		"raw":      "12.5",
+	}
+	// Stream-uri fără scriere în Influx (ack / OTA / shadow merg în Django).
+	skip := map[string]bool{"cmd_ack": true, "ota": true, "shadow": true}
+
+	for _, dd := range reg.All() {
+		for _, tm := range dd.Identification.TopicMatch {
+			if skip[tm.Stream] || strings.HasPrefix(tm.Pattern, "~") {
+				continue // regex: stream-ul e acoperit și de pattern-ul MQTT al DD-ului
+			}
+			payload, ok := byStream[tm.Stream]
+			if !ok {
+				payload, ok = byParser[dd.Parser.Type]
+			}
+			if !ok {
+				t.Errorf("%s %s: no sample payload for stream %q / parser %q", dd.ID, tm.Pattern, tm.Stream, dd.Parser.Type)
+				continue
+			}
+			topic := strings.NewReplacer("+", "2", "#", "x").Replace(tm.Pattern)
+			mch := m.MatchMessage(topic, []byte(payload))
+			if mch == nil {
+				t.Errorf("%s: %s not matched", dd.ID, topic)
+				continue
+			}
+			pt, err := parsers.Decode(mch.Definition, mch.Stream, topic, mch.Extracted, []byte(payload))
+			if err != nil {
+				t.Errorf("%s %s: ingest decode: %v", dd.ID, topic, err)
+				continue
+			}
+			fields, err := DecodePayload(mch, mch.Stream, topic, []byte(payload))
+			if err != nil {
+				t.Errorf("%s %s: rules decode: %v", dd.ID, topic, err)
+				continue
+			}
+			for name, v := range pt.Fields {
+				if got, ok := fields[name]; !ok || got != v {
+					t.Errorf("%s %s: field %q = %v in Influx, %v (present=%v) for rules", dd.ID, topic, name, v, got, ok)
+				}
+			}
+		}
+	}
+}
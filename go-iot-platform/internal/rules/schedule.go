@@ -0,0 +1,204 @@
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule calculează următoarea rulare a unei reguli trigger_type=schedule.
+type Schedule interface {
+	// Next întoarce primul moment de rulare strict după `after`.
+	Next(after time.Time) time.Time
+}
+
+// MinEveryInterval — limită inferioară pentru "@every"; sub ea Scheduler-ul
+// ar evalua toți device-urile tenantului practic continuu.
+const MinEveryInterval = 10 * time.Second
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseSchedule acceptă:
+//   - cron standard cu 5 câmpuri: "minute hour day-of-month month day-of-week"
+//     (*, liste "1,15", intervale "1-5", pași "*/10" / "8-18/2", nume JAN / MON)
+//   - descriptori: @hourly, @daily, @midnight, @weekly, @monthly, @yearly
+//   - "@every <durată Go>" — ex: "@every 15m", aliniat la multipli de durată (UTC)
+//
+// Cron-ul e evaluat în location-ul primit de Next (Scheduler folosește
+// RULES_SCHEDULE_TZ); "22:00" înseamnă ora locală a instalației, nu UTC.
+func ParseSchedule(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, fmt.Errorf("schedule: empty spec")
+	}
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, fmt.Errorf("schedule: %q: %w", spec, err)
+		}
+		if d < MinEveryInterval {
+			return nil, fmt.Errorf("schedule: %q: interval below %s", spec, MinEveryInterval)
+		}
+		return everySchedule(d), nil
+	}
+	if expanded, ok := cronDescriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	} else if strings.HasPrefix(spec, "@") {
+		return nil, fmt.Errorf("schedule: unknown descriptor %q", spec)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("schedule: %q: expected 5 fields, got %d", spec, len(fields))
+	}
+	var (
+		cs  cronSchedule
+		err error
+	)
+	if cs.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("schedule: minute: %w", err)
+	}
+	if cs.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("schedule: hour: %w", err)
+	}
+	if cs.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("schedule: day-of-month: %w", err)
+	}
+	if cs.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("schedule: month: %w", err)
+	}
+	if cs.dow, err = parseCronField(fields[4], 0, 7, dowNames); err != nil {
+		return nil, fmt.Errorf("schedule: day-of-week: %w", err)
+	}
+	// 7 = duminică, ca 0
+	if cs.dow&(1<<7) != 0 {
+		cs.dow |= 1
+	}
+	cs.domStar = fields[2] == "*" || strings.HasPrefix(fields[2], "*/")
+	cs.dowStar = fields[4] == "*" || strings.HasPrefix(fields[4], "*/")
+	return cs, nil
+}
+
+type everySchedule time.Duration
+
+func (e everySchedule) Next(after time.Time) time.Time {
+	d := time.Duration(e)
+	return after.Truncate(d).Add(d)
+}
+
+// cronSchedule — bitset per câmp (bit i = valoarea i permisă).
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	domStar, dowStar              bool
+}
+
+// maxCronLookahead — un spec valid sintactic dar imposibil ("0 0 31 2 *")
+// nu trebuie să blocheze Next.
+const maxCronLookahead = 5 * 366 * 24 * time.Hour
+
+func (c cronSchedule) Next(after time.Time) time.Time {
+	loc := after.Location()
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronLookahead)
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches — semantica cron clasică: dacă ambele câmpuri de zi sunt
+// restricționate, e suficient să se potrivească unul dintre ele.
+func (c cronSchedule) dayMatches(t time.Time) bool {
+	domOK := c.dom&(1<<uint(t.Day())) != 0
+	dowOK := c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domStar || c.dowStar {
+		return domOK && dowOK
+	}
+	return domOK || dowOK
+}
+
+var (
+	monthNames = map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}
+	dowNames = map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}
+)
+
+func parseCronField(field string, min, max int, names map[string]int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		if part == "" {
+			return 0, fmt.Errorf("empty list element in %q", field)
+		}
+		rangePart, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rangePart, step = part[:i], n
+		}
+		lo, hi := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = cronValue(bounds[0], names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				if hi, err = cronValue(bounds[1], names); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				hi = max // "5/15" = de la 5 până la max, din 15 în 15
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseScheduleNext(t *testing.T) {
+	loc := time.UTC
+	base := time.Date(2026, 5, 13, 21, 30, 0, 0, loc) // miercuri
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{"0 22 * * *", time.Date(2026, 5, 13, 22, 0, 0, 0, loc)},
+		{"*/15 * * * *", time.Date(2026, 5, 13, 21, 45, 0, 0, loc)},
+		{"0 8-18/2 * * *", time.Date(2026, 5, 14, 8, 0, 0, 0, loc)},
+		{"30 6 * * MON-FRI", time.Date(2026, 5, 14, 6, 30, 0, 0, loc)},
+		{"0 0 * * 0", time.Date(2026, 5, 17, 0, 0, 0, 0, loc)},
+		{"0 0 * * 7", time.Date(2026, 5, 17, 0, 0, 0, 0, loc)},
+		{"0 0 1 jan *", time.Date(2027, 1, 1, 0, 0, 0, 0, loc)},
+		{"@daily", time.Date(2026, 5, 14, 0, 0, 0, 0, loc)},
+		{"@hourly", time.Date(2026, 5, 13, 22, 0, 0, 0, loc)},
+		{"@every 15m", time.Date(2026, 5, 13, 21, 45, 0, 0, loc)},
+		// dom și dow restricționate → oricare: 15 mai (vineri) vine înaintea lunii
+		{"0 0 15 * MON", time.Date(2026, 5, 15, 0, 0, 0, 0, loc)},
+	}
+	for _, tc := range cases {
+		s, err := ParseSchedule(tc.spec)
+		if err != nil {
+			t.Errorf("%q: %v", tc.spec, err)
+			continue
+		}
+		if got := s.Next(base); !got.Equal(tc.want) {
+			t.Errorf("%q: next=%s want %s", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestParseScheduleLocation(t *testing.T) {
+	bucharest, err := time.LoadLocation("Europe/Bucharest")
+	if err != nil {
+		t.Skipf("tzdata unavailable: %v", err)
+	}
+	s, _ := ParseSchedule("0 22 * * *")
+	got := s.Next(time.Date(2026, 5, 13, 12, 0, 0, 0, bucharest))
+	if got.Hour() != 22 || got.Location() != bucharest {
+		t.Errorf("next=%s, want 22:00 Europe/Bucharest", got)
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "*/0 * * * *", "5-1 * * * *", "@sometimes", "@every 1s", "@every x",
+	} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("%q: expected error", spec)
+		}
+	}
+}
+
+func TestParseScheduleImpossible(t *testing.T) {
+	s, err := ParseSchedule("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(time.Now()); !got.IsZero() {
+		t.Errorf("31 Feb: next=%s, want zero", got)
+	}
+}
@@ -0,0 +1,294 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SchedulerConfig — parametrii Scheduler; zero values → default-urile de mai jos.
+type SchedulerConfig struct {
+	Tick         time.Duration  // cât de des verificăm schedule-urile / absențele; default 15s
+	RefreshEvery time.Duration  // re-fetch reguli din Django; default 1m
+	LeaderTTL    time.Duration  // TTL lock leader; default 45s (> 2 × Tick)
+	Location     *time.Location // timezone-ul cron-urilor; default time.Local
+}
+
+// Scheduler rulează regulile trigger_type=schedule și absence.
+//
+// Doar leader-ul (lock Redis, vezi Leader) evaluează; celelalte instanțe doar
+// încearcă periodic să preia lock-ul. La preluare, rulările scadente în ultimul
+// LeaderTTL sunt recuperate — marker-ul rule_sched_run:{rule}:{ts} (SET NX)
+// garantează că o rulare nu e declanșată de două ori la failover.
+//
+//   - schedule: la fiecare rulare, evaluează condițiile pe ultima stare a
+//     fiecărui device văzut în tenant (stream-urile din trigger_stream_pattern)
+//   - absence:  device-urile fără mesaj pe stream de AbsenceSeconds declanșează
+//     o dată per perioadă de tăcere (marker rule_absence_fired = last seen,
+//     scris și când condițiile sau cooldown-ul opresc declanșarea)
+type Scheduler struct {
+	rdb     *redis.Client
+	fetch   func(ctx context.Context) ([]Rule, error)
+	exec    *Executor
+	execLog *ExecLogger
+	leader  *Leader
+	cfg     SchedulerConfig
+
+	rules       []timedRule
+	conds       *conditionCache
+	lastRefresh time.Time
+	leading     bool
+}
+
+type timedRule struct {
+	rule     Rule
+	schedule Schedule // nil pentru absence
+	streams  []string // nil = orice stream
+	next     time.Time
+}
+
+// NewScheduler creează scheduler-ul; fetch e de obicei RuleCache.FetchTimedRules.
+func NewScheduler(rdb *redis.Client, fetch func(ctx context.Context) ([]Rule, error), exec *Executor, execLog *ExecLogger, cfg SchedulerConfig) *Scheduler {
+	if cfg.Tick <= 0 {
+		cfg.Tick = 15 * time.Second
+	}
+	if cfg.RefreshEvery <= 0 {
+		cfg.RefreshEvery = time.Minute
+	}
+	if cfg.LeaderTTL <= 0 {
+		cfg.LeaderTTL = 3 * cfg.Tick
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	return &Scheduler{
+		rdb:     rdb,
+		fetch:   fetch,
+		exec:    exec,
+		execLog: execLog,
+		leader:  NewLeader(rdb, DefaultLeaderKey, cfg.LeaderTTL),
+		cfg:     cfg,
+	}
+}
+
+// Run ține bucla scheduler-ului până la anularea ctx; eliberează lock-ul la ieșire.
+func (s *Scheduler) Run(ctx context.Context) {
+	t := time.NewTicker(s.cfg.Tick)
+	defer t.Stop()
+	for {
+		s.step(ctx, time.Now().In(s.cfg.Location))
+		select {
+		case <-ctx.Done():
+			if s.leading {
+				releaseCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+				s.leader.Release(releaseCtx)
+				cancel()
+			}
+			return
+		case <-t.C:
+		}
+	}
+}
+
+func (s *Scheduler) step(ctx context.Context, now time.Time) {
+	if !s.leader.Acquire(ctx) {
+		if s.leading {
+			log.Printf("rule-engine: scheduler leadership lost (%s)", s.leader.ID())
+		}
+		s.leading = false
+		return
+	}
+	if !s.leading {
+		log.Printf("rule-engine: scheduler leader acquired (%s)", s.leader.ID())
+		s.leading = true
+		s.lastRefresh = time.Time{}
+		s.rules = nil
+	}
+	if now.Sub(s.lastRefresh) >= s.cfg.RefreshEvery {
+		rules, err := s.fetch(ctx)
+		if err != nil {
+			log.Printf("rule-engine: scheduler refresh: %v", err)
+		} else {
+			// Primul load după preluare: recuperăm rulările din fereastra de failover.
+			s.setRules(rules, now.Add(-s.cfg.LeaderTTL))
+			s.lastRefresh = now
+		}
+	}
+
+	for _, r := range s.dueSchedules(now) {
+		s.runSchedule(ctx, r, now)
+	}
+	s.runAbsences(ctx, now)
+}
+
+// setRules înlocuiește lista de reguli; păstrează `next` pentru regulile
+// neschimbate, restul pornesc de la `from`.
+func (s *Scheduler) setRules(rules []Rule, from time.Time) {
+	prev := make(map[int64]timedRule, len(s.rules))
+	for _, tr := range s.rules {
+		prev[tr.rule.ID] = tr
+	}
+	out := make([]timedRule, 0, len(rules))
+	for _, r := range rules {
+		tr := timedRule{rule: r, streams: splitStreams(r.TriggerStreamPattern)}
+		switch r.TriggerType {
+		case TriggerSchedule:
+			sched, err := ParseSchedule(r.Schedule)
+			if err != nil {
+				log.Printf("rule-engine: rule %d %q: %v", r.ID, r.Name, err)
+				continue
+			}
+			tr.schedule = sched
+			if old, ok := prev[r.ID]; ok && old.rule.Schedule == r.Schedule && !old.next.IsZero() {
+				tr.next = old.next
+			} else {
+				tr.next = sched.Next(from.In(s.cfg.Location))
+			}
+		case TriggerAbsence:
+			if r.AbsenceSeconds <= 0 {
+				log.Printf("rule-engine: rule %d %q: absence_seconds not set", r.ID, r.Name)
+				continue
+			}
+		default:
+			continue
+		}
+		out = append(out, tr)
+	}
+	s.rules = out
+	s.conds = newConditionCache(rules)
+}
+
+// dueSchedules întoarce regulile schedule scadente și avansează `next`.
+// Rulările multiple ratate (ex: după un failover lung) se comasează într-una.
+func (s *Scheduler) dueSchedules(now time.Time) []timedRule {
+	var due []timedRule
+	for i := range s.rules {
+		tr := &s.rules[i]
+		if tr.schedule == nil || tr.next.IsZero() || tr.next.After(now) {
+			continue
+		}
+		due = append(due, *tr)
+		tr.next = tr.schedule.Next(now)
+	}
+	return due
+}
+
+func (s *Scheduler) runSchedule(ctx context.Context, tr timedRule, now time.Time) {
+	rule := tr.rule
+	// Dedup între instanțe: același slot de rulare nu e declanșat de două ori.
+	runKey := fmt.Sprintf("rule_sched_run:%d:%d", rule.ID, tr.next.Unix())
+	if claimed, err := s.rdb.SetNX(ctx, runKey, s.leader.ID(), 24*time.Hour).Result(); err != nil || !claimed {
+		return
+	}
+	serials, err := SeenDevices(ctx, s.rdb, rule.TenantID)
+	if err != nil {
+		log.Printf("rule-engine: schedule rule %d: seen devices: %v", rule.ID, err)
+		return
+	}
+	for _, serial := range serials {
+		state, err := LatestState(ctx, s.rdb, rule.TenantID, serial, tr.streams)
+		if err != nil || len(state) == 0 {
+			continue
+		}
+		if !conditionsEmpty(rule.Conditions) && !s.conds.evaluate(rule.Conditions, state, nil) {
+			continue
+		}
+		s.fire(ctx, rule, serial, TriggerSchedule, state)
+	}
+	PruneSeen(ctx, s.rdb, rule.TenantID, anyStream, now)
+}
+
+func (s *Scheduler) runAbsences(ctx context.Context, now time.Time) {
+	for _, tr := range s.rules {
+		if tr.rule.TriggerType != TriggerAbsence {
+			continue
+		}
+		streams := tr.streams
+		if len(streams) == 0 {
+			streams = []string{anyStream}
+		}
+		for _, stream := range streams {
+			s.checkAbsence(ctx, tr.rule, stream, now)
+		}
+	}
+}
+
+func (s *Scheduler) checkAbsence(ctx context.Context, rule Rule, stream string, now time.Time) {
+	silence := time.Duration(rule.AbsenceSeconds) * time.Second
+	silent, err := SilentDevices(ctx, s.rdb, rule.TenantID, stream, now.Add(-silence))
+	if err != nil {
+		log.Printf("rule-engine: absence rule %d: %v", rule.ID, err)
+		return
+	}
+	for serial, lastSeen := range silent {
+		if now.Sub(lastSeen) > SeenRetention {
+			continue
+		}
+		// Marker = last seen: aceeași perioadă de tăcere e evaluată o singură dată —
+		// declanșată, oprită de condiții sau de cooldown (altfel cooldown_skipped
+		// s-ar log-a la fiecare tick până expiră cooldown-ul). După un mesaj nou
+		// last seen se schimbă și regula se poate re-declanșa.
+		marker := fmt.Sprintf("rule_absence_fired:%d:%s:%s", rule.ID, serial, stream)
+		seen := fmt.Sprint(lastSeen.Unix())
+		if prev, _ := s.rdb.Get(ctx, marker).Result(); prev == seen {
+			continue
+		}
+		state, err := LatestState(ctx, s.rdb, rule.TenantID, serial, nil)
+		if err != nil {
+			continue // Redis indisponibil — reîncercăm la tick-ul următor
+		}
+		if state == nil {
+			state = map[string]interface{}{}
+		}
+		s.rdb.Set(ctx, marker, seen, SeenRetention) //nolint:errcheck
+		if !conditionsEmpty(rule.Conditions) && !s.conds.evaluate(rule.Conditions, state, nil) {
+			continue
+		}
+		state["last_seen"] = lastSeen.UTC().Format(time.RFC3339)
+		state["silent_seconds"] = int64(now.Sub(lastSeen).Seconds())
+		state["absent_stream"] = stream
+		s.fire(ctx, rule, serial, TriggerAbsence, state)
+	}
+	PruneSeen(ctx, s.rdb, rule.TenantID, stream, now)
+}
+
+// fire aplică cooldown-ul, execută acțiunile și log-ează execuția.
+func (s *Scheduler) fire(ctx context.Context, rule Rule, serial, stream string, state map[string]interface{}) {
+	msgCtx := MessageContext{
+		TenantID: rule.TenantID,
+		Serial:   serial,
+		Stream:   stream,
+		Payload:  state,
+	}
+	if !CheckAndSetCooldown(ctx, s.rdb, rule.ID, serial, rule.CooldownSeconds) {
+		s.execLog.CooldownSkipped(rule, msgCtx)
+		return
+	}
+	results := s.exec.Execute(ctx, rule, msgCtx, 0)
+	s.execLog.Log(NewExecRecord(rule, msgCtx, results, StatusTriggered, ""))
+	log.Printf("rule-engine: %s rule %q fired on %s → %d actions", rule.TriggerType, rule.Name, serial, len(results))
+}
+
+// conditionsEmpty — regulile schedule / absence pot avea condiții goale ({}):
+// "trimite raportul zilnic" / "alertă la orice tăcere".
+func conditionsEmpty(node ConditionNode) bool {
+	return node.Operator == "" && node.Field == ""
+}
+
+func splitStreams(pattern string) []string {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" || pattern == "*" {
+		return nil
+	}
+	var out []string
+	for _, p := range strings.Split(pattern, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,146 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestSchedulerSetRulesAndDue(t *testing.T) {
+	s := NewScheduler(nil, nil, nil, nil, SchedulerConfig{Location: time.UTC})
+	now := time.Date(2026, 5, 13, 21, 59, 30, 0, time.UTC)
+
+	s.setRules([]Rule{
+		{ID: 1, TriggerType: TriggerSchedule, Schedule: "0 22 * * *", Enabled: true},
+		{ID: 2, TriggerType: TriggerSchedule, Schedule: "bogus", Enabled: true},
+		{ID: 3, TriggerType: TriggerAbsence, AbsenceSeconds: 600, TriggerStreamPattern: "telemetry"},
+		{ID: 4, TriggerType: TriggerAbsence}, // fără absence_seconds → ignorată
+		{ID: 5, TriggerType: TriggerMessage},
+	}, now)
+	if len(s.rules) != 2 {
+		t.Fatalf("rules=%d want 2 (invalid schedule / absence / message dropped)", len(s.rules))
+	}
+	if got := s.rules[1].streams; len(got) != 1 || got[0] != "telemetry" {
+		t.Errorf("absence streams=%v", got)
+	}
+
+	if due := s.dueSchedules(now); len(due) != 0 {
+		t.Fatalf("due before 22:00: %d", len(due))
+	}
+	at := now.Add(45 * time.Second)
+	due := s.dueSchedules(at)
+	if len(due) != 1 || due[0].rule.ID != 1 {
+		t.Fatalf("due at 22:00:15 = %v", due)
+	}
+	if !due[0].next.Equal(time.Date(2026, 5, 13, 22, 0, 0, 0, time.UTC)) {
+		t.Errorf("due slot=%s", due[0].next)
+	}
+	if len(s.dueSchedules(at)) != 0 {
+		t.Error("schedule fired twice for the same slot")
+	}
+	if want := time.Date(2026, 5, 14, 22, 0, 0, 0, time.UTC); !s.rules[0].next.Equal(want) {
+		t.Errorf("next=%s want %s", s.rules[0].next, want)
+	}
+
+	// Refresh cu același schedule păstrează next; schedule schimbat îl recalculează.
+	s.setRules([]Rule{{ID: 1, TriggerType: TriggerSchedule, Schedule: "0 22 * * *"}}, at)
+	if want := time.Date(2026, 5, 14, 22, 0, 0, 0, time.UTC); !s.rules[0].next.Equal(want) {
+		t.Errorf("next after refresh=%s want %s", s.rules[0].next, want)
+	}
+	s.setRules([]Rule{{ID: 1, TriggerType: TriggerSchedule, Schedule: "30 22 * * *"}}, at)
+	if want := time.Date(2026, 5, 13, 22, 30, 0, 0, time.UTC); !s.rules[0].next.Equal(want) {
+		t.Errorf("next after schedule change=%s want %s", s.rules[0].next, want)
+	}
+}
+
+func TestRuleSetSkipsTimedRules(t *testing.T) {
+	rs := NewRuleSet(2, []Rule{
+		{ID: 1, Enabled: true},
+		{ID: 2, Enabled: true, TriggerType: TriggerMessage},
+		{ID: 3, Enabled: true, TriggerType: TriggerSchedule, Schedule: "@hourly"},
+		{ID: 4, Enabled: true, TriggerType: TriggerAbsence, AbsenceSeconds: 60},
+	})
+	if rs.Len() != 2 {
+		t.Errorf("len=%d want 2 (schedule/absence rules don't run on messages)", rs.Len())
+	}
+}
+
+// kvHook — Redis in-memory minimal pentru Scheduler: GET/SET [NX], HGETALL,
+// ZRANGEBYSCORE (rule_seen dat de test), ZREMRANGEBYSCORE (ignorat).
+type kvHook struct {
+	kv   map[string]string
+	seen []redis.Z
+}
+
+func (h *kvHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *kvHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error { return nil }
+}
+
+func (h *kvHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		args := cmd.Args()
+		switch c := cmd.(type) {
+		case *redis.StringCmd: // get
+			v, ok := h.kv[args[1].(string)]
+			if !ok {
+				c.SetErr(redis.Nil)
+				return redis.Nil
+			}
+			c.SetVal(v)
+		case *redis.BoolCmd: // set ... nx
+			key := args[1].(string)
+			if _, ok := h.kv[key]; ok {
+				c.SetVal(false)
+				return nil
+			}
+			h.kv[key] = fmt.Sprint(args[2])
+			c.SetVal(true)
+		case *redis.StatusCmd: // set
+			h.kv[args[1].(string)] = fmt.Sprint(args[2])
+			c.SetVal("OK")
+		case *redis.MapStringStringCmd: // hgetall
+			c.SetVal(map[string]string{})
+		case *redis.ZSliceCmd: // zrangebyscore withscores
+			c.SetVal(h.seen)
+		}
+		return nil
+	}
+}
+
+func TestAbsenceCooldownSkippedOncePerSilence(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer rdb.Close()
+	now := time.Date(2026, 5, 13, 22, 0, 0, 0, time.UTC)
+	lastSeen := now.Add(-20 * time.Minute)
+	hook := &kvHook{
+		kv:   map[string]string{"rule_cooldown:9:DEV1": "1"}, // cooldown activ
+		seen: []redis.Z{{Member: "DEV1", Score: float64(lastSeen.Unix())}},
+	}
+	rdb.AddHook(hook)
+
+	execLog := NewExecLogger(&fakeSink{}, ExecLogConfig{})
+	s := NewScheduler(rdb, nil, nil, execLog, SchedulerConfig{Location: time.UTC})
+	rule := Rule{ID: 9, TenantID: 2, TriggerType: TriggerAbsence, AbsenceSeconds: 600, CooldownSeconds: 3600}
+	for i := 0; i < 4; i++ { // tick-uri succesive în aceeași perioadă de tăcere
+		s.checkAbsence(context.Background(), rule, "telemetry", now.Add(time.Duration(i)*15*time.Second))
+	}
+
+	execLog.mu.Lock()
+	var skips []string
+	for k, rec := range execLog.cooldowns {
+		skips = append(skips, fmt.Sprintf("%s=%d", k.serial, rec.Occurrences))
+	}
+	execLog.mu.Unlock()
+	if strings.Join(skips, ",") != "DEV1=1" {
+		t.Errorf("cooldown skips=%v want [DEV1=1] (one per silence period)", skips)
+	}
+	if got := hook.kv["rule_absence_fired:9:DEV1:telemetry"]; got != fmt.Sprint(lastSeen.Unix()) {
+		t.Errorf("absence marker=%q want last seen %d", got, lastSeen.Unix())
+	}
+}
@@ -0,0 +1,178 @@
+package rules
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Ultima stare cunoscută per device — sursa pentru regulile schedule / absence.
+//
+//	rule_state:{tid}:{serial}      HASH stream → payload JSON (TTL StateTTL)
+//	rule_seen:{tid}:{stream}       ZSET serial → unix ts al ultimului mesaj (TTL SeenRetention)
+//	rule_seen:{tid}:*              ZSET serial → unix ts pe orice stream (TTL SeenRetention)
+//
+// Spre deosebire de rule_prev (5 min, pentru op "changed"), starea trăiește
+// zile întregi: o regulă "în fiecare zi la 22:00" trebuie să vadă și un device
+// care a raportat ultima oară la 08:00.
+const (
+	StateTTL = 7 * 24 * time.Hour
+
+	// SeenRetention — device-urile tăcute mai mult de atât sunt scoase din
+	// rule_seen (șterse / decomisionate), altfel absence ar rula pe ele la infinit.
+	SeenRetention = 30 * 24 * time.Hour
+
+	// DefaultMaxReadingAge — o citire mai veche (batch bufferat, trimis după o
+	// pană de rețea) nu mai declanșează acțiuni și nu suprascrie starea; doar
+	// rule_seen o ia în calcul (RecordSeen).
+	DefaultMaxReadingAge = 5 * time.Minute
+
+	anyStream = "*"
+)
+
+func stateKey(tenantID int64, serial string) string {
+	return fmt.Sprintf("rule_state:%d:%s", tenantID, serial)
+}
+
+func seenKey(tenantID int64, stream string) string {
+	return fmt.Sprintf("rule_seen:%d:%s", tenantID, stream)
+}
+
+// RecordState salvează payload-ul decodat ca ultimă stare a device-ului pe stream
+// și marchează momentul mesajului. Apelat de handler-ul MQTT după evaluare.
+//
+// Nu scrie nimic pentru tenanții fără reguli schedule / absence; în rule_seen
+// doar ZSET-urile citite de Scheduler (vezi RuleSet). Fiecare ZSET primește
+// TTL SeenRetention — un tenant / stream rămas fără mesaje dispare singur —
+// iar membrii vechi sunt scoși de PruneSeen la fiecare rulare a regulilor.
+//
+// ts e momentul citirii (plicul relay pentru HTTP / CoAP); rule_seen nu merge
+// niciodată înapoi în timp (ZADD GT), chiar dacă citirile ajung în altă ordine.
+func RecordState(ctx context.Context, rdb *redis.Client, rs *RuleSet, serial, stream string, payload map[string]interface{}, ts time.Time) {
+	if rdb == nil || rs == nil || !rs.timed {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	pipe := rdb.Pipeline()
+	pipe.HSet(ctx, stateKey(rs.TenantID, serial), stream, data)
+	pipe.Expire(ctx, stateKey(rs.TenantID, serial), StateTTL)
+	addSeen(ctx, pipe, rs, serial, stream, ts)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("rules: record state %d/%s: %v", rs.TenantID, serial, err)
+	}
+}
+
+// RecordSeen — doar marcajul rule_seen, pentru citirile vechi (bufferate) care
+// nu trebuie să suprascrie starea curentă a device-ului.
+func RecordSeen(ctx context.Context, rdb *redis.Client, rs *RuleSet, serial, stream string, ts time.Time) {
+	if rdb == nil || rs == nil || !rs.timed {
+		return
+	}
+	pipe := rdb.Pipeline()
+	addSeen(ctx, pipe, rs, serial, stream, ts)
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("rules: record seen %d/%s: %v", rs.TenantID, serial, err)
+	}
+}
+
+func addSeen(ctx context.Context, pipe redis.Pipeliner, rs *RuleSet, serial, stream string, ts time.Time) {
+	score := float64(ts.Unix())
+	for _, st := range [...]string{stream, anyStream} {
+		if !rs.seen[st] {
+			continue
+		}
+		pipe.ZAddGT(ctx, seenKey(rs.TenantID, st), redis.Z{Score: score, Member: serial})
+		pipe.Expire(ctx, seenKey(rs.TenantID, st), SeenRetention)
+	}
+}
+
+// LatestState întoarce ultima stare a device-ului, combinată din stream-urile
+// date (nil = toate). La chei duplicate câștigă stream-ul ultim alfabetic —
+// determinist, chiar dacă arbitrar.
+func LatestState(ctx context.Context, rdb *redis.Client, tenantID int64, serial string, streams []string) (map[string]interface{}, error) {
+	if rdb == nil {
+		return nil, nil
+	}
+	all, err := rdb.HGetAll(ctx, stateKey(tenantID, serial)).Result()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(all))
+	for name := range all {
+		if streamAllowed(streams, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	state := map[string]interface{}{}
+	for _, name := range names {
+		var part map[string]interface{}
+		if err := json.Unmarshal([]byte(all[name]), &part); err != nil {
+			continue
+		}
+		for k, v := range part {
+			state[k] = v
+		}
+	}
+	return state, nil
+}
+
+// SeenDevices — device-urile tenantului care au publicat cel puțin o dată (orice stream).
+func SeenDevices(ctx context.Context, rdb *redis.Client, tenantID int64) ([]string, error) {
+	if rdb == nil {
+		return nil, nil
+	}
+	return rdb.ZRange(ctx, seenKey(tenantID, anyStream), 0, -1).Result()
+}
+
+// SilentDevices — device-urile al căror ultim mesaj pe stream e mai vechi decât
+// `before`, cu momentul ultimului mesaj. stream "*" = orice stream.
+func SilentDevices(ctx context.Context, rdb *redis.Client, tenantID int64, stream string, before time.Time) (map[string]time.Time, error) {
+	if rdb == nil {
+		return nil, nil
+	}
+	zs, err := rdb.ZRangeByScoreWithScores(ctx, seenKey(tenantID, stream), &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(before.Unix(), 10),
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]time.Time, len(zs))
+	for _, z := range zs {
+		if serial, ok := z.Member.(string); ok {
+			out[serial] = time.Unix(int64(z.Score), 0)
+		}
+	}
+	return out, nil
+}
+
+// PruneSeen scoate din rule_seen device-urile tăcute mai mult de SeenRetention.
+func PruneSeen(ctx context.Context, rdb *redis.Client, tenantID int64, stream string, now time.Time) {
+	if rdb == nil {
+		return
+	}
+	max := strconv.FormatInt(now.Add(-SeenRetention).Unix(), 10)
+	rdb.ZRemRangeByScore(ctx, seenKey(tenantID, stream), "-inf", max) //nolint:errcheck
+}
+
+func streamAllowed(streams []string, stream string) bool {
+	if len(streams) == 0 {
+		return true
+	}
+	for _, s := range streams {
+		if s == stream {
+			return true
+		}
+	}
+	return false
+}
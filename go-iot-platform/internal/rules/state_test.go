@@ -0,0 +1,111 @@
+package rules
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// recordHook reține comenzile din pipeline fără să le trimită la Redis.
+type recordHook struct{ cmds []string }
+
+func (h *recordHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h *recordHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error { return nil }
+}
+
+func (h *recordHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, c := range cmds {
+			h.cmds = append(h.cmds, c.Name()+" "+c.Args()[1].(string))
+		}
+		return nil
+	}
+}
+
+func TestRecordStateOnlyForTimedRules(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer rdb.Close()
+	hook := &recordHook{}
+	rdb.AddHook(hook)
+	ctx := context.Background()
+	payload := map[string]interface{}{"soc": 50.0}
+
+	// Doar reguli pe mesaj: nicio scriere.
+	rs := NewRuleSet(2, []Rule{{ID: 1, Enabled: true, TriggerType: TriggerMessage}})
+	RecordState(ctx, rdb, rs, "DEV1", "telemetry", payload, time.Now())
+	if len(hook.cmds) != 0 {
+		t.Fatalf("no timed rules: got %v", hook.cmds)
+	}
+
+	// Absence pe telemetry: starea + doar rule_seen:2:telemetry, cu TTL.
+	rs = NewRuleSet(2, []Rule{
+		{ID: 2, Enabled: true, TriggerType: TriggerAbsence, AbsenceSeconds: 60, TriggerStreamPattern: "telemetry"},
+		{ID: 3, Enabled: false, TriggerType: TriggerSchedule, Schedule: "@hourly"},
+	})
+	RecordState(ctx, rdb, rs, "DEV1", "telemetry", payload, time.Now())
+	want := []string{"hset rule_state:2:DEV1", "expire rule_state:2:DEV1", "zadd rule_seen:2:telemetry", "expire rule_seen:2:telemetry"}
+	if strings.Join(hook.cmds, ",") != strings.Join(want, ",") {
+		t.Errorf("absence on telemetry:\n got %v\nwant %v", hook.cmds, want)
+	}
+
+	// Alt stream: doar starea (schedule-urile dezactivate nu cer "*").
+	hook.cmds = nil
+	RecordState(ctx, rdb, rs, "DEV1", "state", payload, time.Now())
+	if len(hook.cmds) != 2 {
+		t.Errorf("stream without absence rule: got %v", hook.cmds)
+	}
+
+	// Schedule activ → și ZSET-ul "*" (SeenDevices).
+	rs = NewRuleSet(2, []Rule{{ID: 3, Enabled: true, TriggerType: TriggerSchedule, Schedule: "@hourly"}})
+	hook.cmds = nil
+	RecordState(ctx, rdb, rs, "DEV1", "state", payload, time.Now())
+	if got := strings.Join(hook.cmds, ","); !strings.Contains(got, "zadd rule_seen:2:*,expire rule_seen:2:*") || strings.Contains(got, "rule_seen:2:state") {
+		t.Errorf("schedule: got %v", hook.cmds)
+	}
+	// Citire veche (batch bufferat): doar rule_seen, starea curentă rămâne.
+	rs = NewRuleSet(2, []Rule{{ID: 2, Enabled: true, TriggerType: TriggerAbsence, AbsenceSeconds: 60, TriggerStreamPattern: "telemetry"}})
+	hook.cmds = nil
+	RecordSeen(ctx, rdb, rs, "DEV1", "telemetry", time.Now().Add(-time.Hour))
+	if got := strings.Join(hook.cmds, ","); got != "zadd rule_seen:2:telemetry,expire rule_seen:2:telemetry" {
+		t.Errorf("stale reading: got %v", hook.cmds)
+	}
+}
+
+func TestRecordStateSeenNeverMovesBack(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer rdb.Close()
+	var zadd []interface{}
+	rdb.AddHook(pipelineArgsHook(func(args []interface{}) {
+		if args[0] == "zadd" {
+			zadd = args
+		}
+	}))
+	rs := NewRuleSet(2, []Rule{{ID: 2, Enabled: true, TriggerType: TriggerAbsence, AbsenceSeconds: 60}})
+	RecordState(context.Background(), rdb, rs, "DEV1", "telemetry", map[string]interface{}{}, time.Now())
+	if len(zadd) < 3 || zadd[2] != "gt" {
+		t.Errorf("rule_seen zadd = %v, want ZADD key GT …", zadd)
+	}
+}
+
+// pipelineArgsHook — apelează fn cu argumentele fiecărei comenzi din pipeline.
+type pipelineArgsHook func(args []interface{})
+
+func (h pipelineArgsHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h pipelineArgsHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error { return nil }
+}
+
+func (h pipelineArgsHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, c := range cmds {
+			h(c.Args())
+		}
+		return nil
+	}
+}
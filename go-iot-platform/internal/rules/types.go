@@ -2,17 +2,19 @@ package rules
 
 // ConditionNode is a node in the condition DSL tree.
 // Branch: Operator + Conditions (AND/OR) or Operator + Condition (NOT).
-// Leaf:   Field + Op + Value.
+// Leaf:   Field + Op + Value (sau ValueField).
 type ConditionNode struct {
 	// Branch
 	Operator   string          `json:"operator"`
 	Conditions []ConditionNode `json:"conditions"`
 	Condition  *ConditionNode  `json:"condition"` // for NOT
 
-	// Leaf
-	Field string      `json:"field"`
-	Op    string      `json:"op"`
-	Value interface{} `json:"value"`
+	// Leaf — Field / ValueField pot fi path-uri sau expresii (vezi expr.go).
+	// ValueField, când e setat, înlocuiește Value: compară două field-uri.
+	Field      string      `json:"field"`
+	Op         string      `json:"op"`
+	Value      interface{} `json:"value"`
+	ValueField string      `json:"value_field,omitempty"`
 }
 
 // Action is a single action executed when a rule fires.
@@ -39,18 +41,37 @@ type Action struct {
 	Desired map[string]interface{} `json:"desired"`
 }
 
+// Trigger types — mirror Rule.TriggerType in Django.
+//
+//   - message:  evaluat la fiecare mesaj MQTT pe trigger_stream_pattern (default)
+//   - schedule: evaluat pe ultima stare cunoscută a device-urilor, după Schedule
+//   - absence:  declanșat când un device nu a publicat pe stream AbsenceSeconds
+const (
+	TriggerMessage  = "message"
+	TriggerSchedule = "schedule"
+	TriggerAbsence  = "absence"
+)
+
 // Rule mirrors the Django Rule model, cached in Redis.
 type Rule struct {
 	ID                   int64         `json:"id"`
-	TenantID             int64         `json:"tenant"`
+	TenantID             int64         `json:"tenant_id"`
 	Name                 string        `json:"name"`
+	TriggerType          string        `json:"trigger_type"`
 	TriggerStreamPattern string        `json:"trigger_stream_pattern"`
+	Schedule             string        `json:"schedule"`        // cron 5 câmpuri sau "@every 15m"
+	AbsenceSeconds       int           `json:"absence_seconds"` // doar trigger_type=absence
 	Conditions           ConditionNode `json:"conditions"`
 	Actions              []Action      `json:"actions"`
 	CooldownSeconds      int           `json:"cooldown_seconds"`
 	Enabled              bool          `json:"enabled"`
 }
 
+// IsMessageTriggered — regulile fără trigger_type (Django vechi) sunt message.
+func (r Rule) IsMessageTriggered() bool {
+	return r.TriggerType == "" || r.TriggerType == TriggerMessage
+}
+
 // MessageContext carries parsed info about an incoming MQTT message.
 type MessageContext struct {
 	TenantID int64
@@ -0,0 +1,417 @@
+// Package stream — push în timp real al telemetriei către dashboard (/go/stream).
+//
+// Flux:
+//   - ingest (cmd/main.go) publică fiecare punct acceptat, după parsare și
+//     normalizare (FromPoint: normalized_fields ale DD-ului din tag-ul dd_id),
+//     pe canalul Redis "telemetry:{tenant_id}" (Publisher)
+//   - fiecare instanță API ține un Hub: se abonează în Redis doar la tenanții
+//     care au conexiuni deschise local și distribuie evenimentele către
+//     Subscription-urile lor (filtru pe device + rate limit per conexiune)
+//
+// Fără Redis, Publisher livrează direct în Hub-ul local (o singură instanță).
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/influxdata/influxdb-client-go/v2/api/write"
+	"github.com/redis/go-redis/v9"
+
+	"go-iot-platform/internal/export"
+	"go-iot-platform/internal/ratelimit"
+)
+
+const (
+	channelPrefix  = "telemetry:"
+	publishTimeout = time.Second
+	publishQueue   = 4096
+	publishBatch   = 256
+	subBuffer      = 64
+)
+
+// Channel — canalul Redis al unui tenant.
+func Channel(tenantID int64) string {
+	return channelPrefix + strconv.FormatInt(tenantID, 10)
+}
+
+// Event — un punct de telemetrie, așa cum a fost scris în Influx.
+type Event struct {
+	TenantID int64                  `json:"tenant_id"`
+	Device   string                 `json:"device"`
+	Source   string                 `json:"source,omitempty"`
+	Type     string                 `json:"type,omitempty"`
+	Time     time.Time              `json:"time"`
+	Fields   map[string]interface{} `json:"fields"`
+	Units    map[string]string      `json:"units,omitempty"` // doar field-urile normalizate
+}
+
+// FromPoint convertește un punct "devices" într-un Event. Punctele fără
+// tenant (tag "unassigned") nu sunt publicate.
+//
+// Cu n != nil field-urile trec prin normalizarea DD-ului (tag-ul dd_id, altfel
+// tabela globală) — același nume canonic, valoare și unit ca /export?mode=normalized.
+// Field-urile fără nume canonic rămân cu numele brut, ca dashboard-ul să nu
+// piardă date; la coliziune câștigă field-ul normalizat.
+func FromPoint(p *write.Point, n *export.Normalizer) (Event, bool) {
+	if p == nil || p.Name() != "devices" {
+		return Event{}, false
+	}
+	ev := Event{Time: p.Time(), Fields: make(map[string]interface{}, len(p.FieldList()))}
+	var ddID string
+	for _, t := range p.TagList() {
+		switch t.Key {
+		case "tenant_id":
+			ev.TenantID, _ = strconv.ParseInt(t.Value, 10, 64)
+		case "device":
+			ev.Device = t.Value
+		case "source":
+			ev.Source = t.Value
+		case "type":
+			ev.Type = t.Value
+		case "dd_id":
+			ddID = t.Value
+		}
+	}
+	var normalized []export.Row
+	for _, f := range p.FieldList() {
+		if n != nil {
+			if row, ok := n.ApplyDefinition(ddID, ev.Source, export.Row{Field: f.Key, Value: f.Value}); ok {
+				normalized = append(normalized, row)
+				continue
+			}
+		}
+		ev.Fields[f.Key] = f.Value
+	}
+	for _, row := range normalized {
+		ev.Fields[row.Field] = row.Value
+		if row.Unit != "" {
+			if ev.Units == nil {
+				ev.Units = map[string]string{}
+			}
+			ev.Units[row.Field] = row.Unit
+		}
+	}
+	if ev.TenantID <= 0 || ev.Device == "" || len(ev.Fields) == 0 {
+		return Event{}, false
+	}
+	return ev, true
+}
+
+// Publisher publică evenimentele de ingest. Cu Redis, Publish doar pune
+// evenimentul în coadă: Run le trimite în pipeline-uri de până la publishBatch
+// PUBLISH-uri, ca hot path-ul ingest-ului să nu aștepte un round-trip per punct.
+type Publisher struct {
+	rdb   *redis.Client
+	hub   *Hub
+	queue chan Event
+}
+
+// ErrQueueFull — coada de publicare e plină (Redis lent / căzut); evenimentul
+// e pierdut pentru stream, punctul e scris oricum în Influx.
+var ErrQueueFull = errors.New("stream: publish queue full")
+
+// NewPublisher — rdb nil → livrare directă în hub (deployment cu o instanță).
+func NewPublisher(rdb *redis.Client, hub *Hub) *Publisher {
+	p := &Publisher{rdb: rdb, hub: hub}
+	if rdb != nil {
+		p.queue = make(chan Event, publishQueue)
+	}
+	return p
+}
+
+// Publish trimite evenimentul fără să blocheze ingest-ul; cu Redis cere Run.
+func (p *Publisher) Publish(_ context.Context, ev Event) error {
+	if p.rdb == nil {
+		if p.hub != nil {
+			p.hub.Dispatch(ev)
+		}
+		return nil
+	}
+	select {
+	case p.queue <- ev:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Run golește coada în Redis până la anularea ctx. Sub încărcare un batch adună
+// tot ce s-a strâns cât timp pipeline-ul anterior era în zbor; în repaus un
+// eveniment pleacă imediat, singur.
+func (p *Publisher) Run(ctx context.Context) {
+	if p.rdb == nil {
+		return
+	}
+	batch := make([]Event, 0, publishBatch)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-p.queue:
+			batch = append(batch[:0], ev)
+		}
+	fill:
+		for len(batch) < publishBatch {
+			select {
+			case ev := <-p.queue:
+				batch = append(batch, ev)
+			default:
+				break fill
+			}
+		}
+		p.flush(ctx, batch)
+	}
+}
+
+func (p *Publisher) flush(ctx context.Context, batch []Event) {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	pipe := p.rdb.Pipeline()
+	for _, ev := range batch {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			continue
+		}
+		pipe.Publish(ctx, Channel(ev.TenantID), data)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		log.Printf("⚠️ stream: publish %d events: %v", len(batch), err)
+	}
+}
+
+// SubscriptionConfig — limita per conexiune; zero values → 20 ev/s, burst 50.
+type SubscriptionConfig struct {
+	Rate  float64
+	Burst float64
+}
+
+// Subscription — o conexiune de stream: primește doar device-urile ei.
+type Subscription struct {
+	hub      *Hub
+	tenantID int64
+	devices  map[string]bool
+	limiter  *ratelimit.Bucket
+	ch       chan Event
+	dropped  atomic.Uint64
+	once     sync.Once
+}
+
+// C — evenimentele livrate; închis la Close.
+func (s *Subscription) C() <-chan Event { return s.ch }
+
+// TakeDropped întoarce și resetează numărul de evenimente aruncate (rate limit
+// sau client prea lent) de la ultimul apel.
+func (s *Subscription) TakeDropped() uint64 { return s.dropped.Swap(0) }
+
+// Close dezabonează conexiunea din hub.
+func (s *Subscription) Close() {
+	s.once.Do(func() { s.hub.remove(s) })
+}
+
+// Hub distribuie evenimentele către conexiunile locale.
+//
+// mu păzește doar map-ul de subscripții (Dispatch îl ține pe fiecare
+// eveniment); SUBSCRIBE / UNSUBSCRIBE în Redis se fac în afara lui, în
+// syncRedis, serializate de psMu.
+type Hub struct {
+	mu   sync.Mutex
+	subs map[int64]map[*Subscription]struct{}
+
+	psMu      sync.Mutex
+	ps        *redis.PubSub  // nil până la Run / fără Redis
+	redisSubs map[int64]bool // canalele abonate efectiv pe ps
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[int64]map[*Subscription]struct{}{}}
+}
+
+// Subscribe deschide o subscripție pe device-urile date ale unui tenant.
+// Autorizarea device-urilor e responsabilitatea caller-ului.
+func (h *Hub) Subscribe(tenantID int64, devices []string, cfg SubscriptionConfig) *Subscription {
+	if cfg.Rate <= 0 {
+		cfg.Rate = 20
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = 50
+	}
+	s := &Subscription{
+		hub:      h,
+		tenantID: tenantID,
+		devices:  make(map[string]bool, len(devices)),
+		limiter:  ratelimit.NewBucket(cfg.Rate, cfg.Burst),
+		ch:       make(chan Event, subBuffer),
+	}
+	for _, d := range devices {
+		s.devices[d] = true
+	}
+
+	h.mu.Lock()
+	first := h.subs[tenantID] == nil
+	if first {
+		h.subs[tenantID] = map[*Subscription]struct{}{}
+	}
+	h.subs[tenantID][s] = struct{}{}
+	h.mu.Unlock()
+	if first {
+		h.syncRedis()
+	}
+	return s
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	subs := h.subs[s.tenantID]
+	if _, ok := subs[s]; !ok {
+		h.mu.Unlock()
+		return
+	}
+	delete(subs, s)
+	close(s.ch)
+	last := len(subs) == 0
+	if last {
+		delete(h.subs, s.tenantID)
+	}
+	h.mu.Unlock()
+	if last {
+		h.syncRedis()
+	}
+}
+
+// syncRedis aliniază abonamentele Redis la tenanții cu conexiuni locale. Citește
+// starea curentă la fiecare apel, deci ordinea în care rulează apelurile din
+// Subscribe / Close concurente nu contează.
+func (h *Hub) syncRedis() {
+	h.psMu.Lock()
+	defer h.psMu.Unlock()
+	if h.ps == nil {
+		return
+	}
+	var add, drop []int64
+	h.mu.Lock()
+	for tenantID := range h.subs {
+		if !h.redisSubs[tenantID] {
+			add = append(add, tenantID)
+		}
+	}
+	for tenantID := range h.redisSubs {
+		if _, ok := h.subs[tenantID]; !ok {
+			drop = append(drop, tenantID)
+		}
+	}
+	h.mu.Unlock()
+
+	ctx := context.Background()
+	for _, tenantID := range add {
+		if err := h.ps.Subscribe(ctx, Channel(tenantID)); err != nil {
+			log.Printf("⚠️ stream: redis subscribe %s: %v", Channel(tenantID), err)
+			continue
+		}
+		h.redisSubs[tenantID] = true
+	}
+	for _, tenantID := range drop {
+		if err := h.ps.Unsubscribe(ctx, Channel(tenantID)); err != nil {
+			log.Printf("⚠️ stream: redis unsubscribe %s: %v", Channel(tenantID), err)
+			continue
+		}
+		delete(h.redisSubs, tenantID)
+	}
+}
+
+// Close închide toate subscripțiile — handler-ele SSE se termină (la shutdown,
+// http.Server.Shutdown nu anulează request-urile long-lived).
+func (h *Hub) Close() {
+	h.mu.Lock()
+	var all []*Subscription
+	for _, subs := range h.subs {
+		for s := range subs {
+			all = append(all, s)
+		}
+	}
+	h.mu.Unlock()
+	for _, s := range all {
+		s.Close()
+	}
+}
+
+// Dispatch livrează un eveniment conexiunilor locale ale tenantului. Nu
+// blochează: o conexiune peste limită sau cu buffer-ul plin pierde evenimentul.
+func (h *Hub) Dispatch(ev Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs[ev.TenantID] {
+		if !s.devices[ev.Device] {
+			continue
+		}
+		if !s.limiter.Allow() {
+			s.dropped.Add(1)
+			continue
+		}
+		select {
+		case s.ch <- ev:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Run primește evenimentele din Redis până la anularea ctx. Canalele sunt
+// adăugate / scoase dinamic de Subscribe / Close; go-redis se reconectează și
+// re-abonează singur după o cădere a conexiunii.
+func (h *Hub) Run(ctx context.Context, rdb *redis.Client) {
+	// Canalul tenantului 0 (inexistent) ține conexiunea în modul pub/sub și
+	// când nu e nicio conexiune SSE locală.
+	ps := rdb.Subscribe(ctx, Channel(0))
+	defer ps.Close()
+
+	h.psMu.Lock()
+	h.ps, h.redisSubs = ps, map[int64]bool{}
+	h.psMu.Unlock()
+	h.syncRedis()
+	defer func() {
+		h.psMu.Lock()
+		h.ps, h.redisSubs = nil, nil
+		h.psMu.Unlock()
+	}()
+
+	ch := ps.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+			ev, err := decodeEvent(msg.Channel, msg.Payload)
+			if err != nil {
+				log.Printf("⚠️ stream: %v", err)
+				continue
+			}
+			h.Dispatch(ev)
+		}
+	}
+}
+
+// decodeEvent — tenant-ul e luat din numele canalului, nu din payload.
+func decodeEvent(channel, payload string) (Event, error) {
+	tenantID, err := strconv.ParseInt(strings.TrimPrefix(channel, channelPrefix), 10, 64)
+	if err != nil || !strings.HasPrefix(channel, channelPrefix) {
+		return Event{}, fmt.Errorf("unexpected channel %q", channel)
+	}
+	var ev Event
+	if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+		return Event{}, fmt.Errorf("decode %s: %w", channel, err)
+	}
+	ev.TenantID = tenantID
+	return ev, nil
+}
@@ -0,0 +1,216 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	influxdb2 "github.com/influxdata/influxdb-client-go/v2"
+	"github.com/redis/go-redis/v9"
+
+	"go-iot-platform/internal/export"
+	"go-iot-platform/internal/registry"
+)
+
+func TestFromPoint(t *testing.T) {
+	ts := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+	p := influxdb2.NewPoint("devices",
+		map[string]string{"device": "sh-1", "source": "shelly", "type": "em", "tenant_id": "7"},
+		map[string]interface{}{"power": 12.5, "relay": "on"}, ts)
+	ev, ok := FromPoint(p, nil)
+	if !ok {
+		t.Fatal("expected event")
+	}
+	if ev.TenantID != 7 || ev.Device != "sh-1" || ev.Source != "shelly" || !ev.Time.Equal(ts) {
+		t.Errorf("event = %+v", ev)
+	}
+	if ev.Fields["power"] != 12.5 || ev.Fields["relay"] != "on" {
+		t.Errorf("fields = %v", ev.Fields)
+	}
+
+	unassigned := influxdb2.NewPoint("devices",
+		map[string]string{"device": "x", "tenant_id": "unassigned"},
+		map[string]interface{}{"v": 1.0}, ts)
+	if _, ok := FromPoint(unassigned, nil); ok {
+		t.Error("unassigned point must not be published")
+	}
+	if _, ok := FromPoint(influxdb2.NewPoint("other", map[string]string{"device": "x", "tenant_id": "7"},
+		map[string]interface{}{"v": 1.0}, ts), nil); ok {
+		t.Error("non-devices measurement must not be published")
+	}
+}
+
+func TestFromPointNormalized(t *testing.T) {
+	reg, errs, err := registry.LoadDir(filepath.Join("..", "..", "..", "configs", "devices"))
+	if err != nil || len(errs) > 0 {
+		t.Fatalf("load DDs: %v %v", err, errs)
+	}
+	n := export.NewNormalizer(reg, nil)
+	ts := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+
+	ev, ok := FromPoint(influxdb2.NewPoint("devices",
+		map[string]string{"device": "sh-1", "source": "shelly", "tenant_id": "7", "dd_id": "shelly_em"},
+		map[string]interface{}{"Power": 12.34, "relay_on": true}, ts), n)
+	if !ok {
+		t.Fatal("expected event")
+	}
+	if ev.Fields["active_power_w"] != 12.3 || ev.Units["active_power_w"] != "W" {
+		t.Errorf("normalized power: fields=%v units=%v", ev.Fields, ev.Units)
+	}
+	if _, raw := ev.Fields["Power"]; raw {
+		t.Errorf("raw name kept next to the canonical one: %v", ev.Fields)
+	}
+	if ev.Fields["relay_on"] != true {
+		t.Errorf("field without canonical name dropped: %v", ev.Fields)
+	}
+
+	// dd_id decide tabela: contactul Zigbee nu e ghicit din source.
+	ev, _ = FromPoint(influxdb2.NewPoint("devices",
+		map[string]string{"device": "z-1", "source": "zigbee2mqtt", "tenant_id": "7", "dd_id": "zigbee_contact"},
+		map[string]interface{}{"contact": true}, ts), n)
+	if ev.Fields["contact_closed"] != true {
+		t.Errorf("dd_id=zigbee_contact fields=%v", ev.Fields)
+	}
+}
+
+func recv(t *testing.T, s *Subscription) (Event, bool) {
+	t.Helper()
+	select {
+	case ev, ok := <-s.C():
+		return ev, ok
+	case <-time.After(100 * time.Millisecond):
+		return Event{}, false
+	}
+}
+
+func TestHubFilters(t *testing.T) {
+	h := NewHub()
+	pub := NewPublisher(nil, h)
+	a := h.Subscribe(1, []string{"dev-a"}, SubscriptionConfig{})
+	defer a.Close()
+	b := h.Subscribe(2, []string{"dev-a"}, SubscriptionConfig{})
+	defer b.Close()
+
+	_ = pub.Publish(context.Background(), Event{TenantID: 1, Device: "dev-b", Fields: map[string]interface{}{"v": 1}})
+	_ = pub.Publish(context.Background(), Event{TenantID: 1, Device: "dev-a", Fields: map[string]interface{}{"v": 2}})
+
+	ev, ok := recv(t, a)
+	if !ok || ev.Fields["v"] != 2 {
+		t.Fatalf("tenant 1 got %+v ok=%v", ev, ok)
+	}
+	if _, ok := recv(t, b); ok {
+		t.Error("tenant 2 received tenant 1 event")
+	}
+}
+
+// pipelineHook numără PUBLISH-urile din fiecare pipeline, fără Redis real.
+type pipelineHook struct{ batches chan []string }
+
+func (h pipelineHook) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (h pipelineHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(context.Context, redis.Cmder) error { return nil }
+}
+
+func (h pipelineHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(_ context.Context, cmds []redis.Cmder) error {
+		var channels []string
+		for _, c := range cmds {
+			channels = append(channels, c.Name()+" "+c.Args()[1].(string))
+		}
+		h.batches <- channels
+		return nil
+	}
+}
+
+func TestPublisherBatches(t *testing.T) {
+	rdb := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1"})
+	defer rdb.Close()
+	hook := pipelineHook{batches: make(chan []string, 4)}
+	rdb.AddHook(hook)
+
+	pub := NewPublisher(rdb, nil)
+	for i := 0; i < 10; i++ {
+		if err := pub.Publish(context.Background(), Event{TenantID: int64(1 + i%2), Device: "d"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pub.Run(ctx)
+
+	select {
+	case batch := <-hook.batches:
+		if len(batch) != 10 || batch[0] != "publish telemetry:1" || batch[1] != "publish telemetry:2" {
+			t.Errorf("batch = %v, want 10 PUBLISH in one pipeline", batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no pipeline sent")
+	}
+
+	// Coada plină nu blochează ingest-ul.
+	cancel()
+	full := NewPublisher(rdb, nil)
+	var err error
+	for i := 0; i <= publishQueue && err == nil; i++ {
+		err = full.Publish(context.Background(), Event{TenantID: 1})
+	}
+	if !errors.Is(err, ErrQueueFull) {
+		t.Errorf("err = %v, want ErrQueueFull", err)
+	}
+}
+
+func TestHubRateLimit(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(1, []string{"d"}, SubscriptionConfig{Rate: 1, Burst: 3})
+	defer s.Close()
+	for i := 0; i < 10; i++ {
+		h.Dispatch(Event{TenantID: 1, Device: "d"})
+	}
+	if n := len(s.C()); n != 3 {
+		t.Errorf("delivered %d, want 3", n)
+	}
+	if d := s.TakeDropped(); d != 7 {
+		t.Errorf("dropped %d, want 7", d)
+	}
+	if d := s.TakeDropped(); d != 0 {
+		t.Errorf("dropped after take = %d", d)
+	}
+}
+
+func TestSubscriptionClose(t *testing.T) {
+	h := NewHub()
+	s := h.Subscribe(1, []string{"d"}, SubscriptionConfig{})
+	s.Close()
+	s.Close()
+	if _, ok := <-s.C(); ok {
+		t.Error("channel should be closed")
+	}
+	if len(h.subs) != 0 {
+		t.Errorf("tenant entry not removed: %v", h.subs)
+	}
+	h.Dispatch(Event{TenantID: 1, Device: "d"}) // fără panic pe canal închis
+
+	a := h.Subscribe(1, []string{"d"}, SubscriptionConfig{})
+	b := h.Subscribe(2, []string{"d"}, SubscriptionConfig{})
+	h.Close()
+	for _, s := range []*Subscription{a, b} {
+		if _, ok := <-s.C(); ok {
+			t.Error("Close should close every subscription")
+		}
+	}
+}
+
+func TestDecodeEvent(t *testing.T) {
+	ev, err := decodeEvent("telemetry:9", `{"tenant_id":1,"device":"d","fields":{"v":1}}`)
+	if err != nil || ev.TenantID != 9 || ev.Device != "d" {
+		t.Errorf("ev=%+v err=%v", ev, err)
+	}
+	for _, ch := range []string{"telemetry:x", "other:9"} {
+		if _, err := decodeEvent(ch, `{}`); err == nil {
+			t.Errorf("%s: expected error", ch)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package stream
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Ticket-uri pentru EventSource: browserul nu poate seta Authorization pe
+// /go/stream, iar un JWT în query string ajunge în access log-uri (Kong,
+// nginx, proxy). Clientul cere un ticket cu header-ul (POST /go/stream/ticket),
+// apoi deschide /go/stream?ticket=… — ticket-ul e valabil TicketTTL și se
+// consumă la prima folosire, deci nu valorează nimic odată logat.
+//
+//	stream_ticket:{ticket}   STRING contextul de autorizare (TTL TicketTTL)
+const (
+	TicketTTL    = 30 * time.Second
+	ticketPrefix = "stream_ticket:"
+)
+
+// ErrInvalidTicket — ticket necunoscut, expirat sau deja folosit.
+var ErrInvalidTicket = errors.New("invalid or expired stream ticket")
+
+// TicketStore emite și consumă ticket-uri. Cu Redis ticket-ul poate fi folosit
+// pe orice instanță API; fără Redis (o singură instanță) rămâne în memorie.
+type TicketStore struct {
+	rdb *redis.Client
+	now func() time.Time
+
+	mu    sync.Mutex
+	local map[string]localTicket
+}
+
+type localTicket struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewTicketStore — rdb nil → ticket-uri in-process.
+func NewTicketStore(rdb *redis.Client) *TicketStore {
+	return &TicketStore{rdb: rdb, now: time.Now, local: map[string]localTicket{}}
+}
+
+// Issue salvează data (contextul de autorizare serializat) sub un ticket nou.
+func (s *TicketStore) Issue(ctx context.Context, data []byte) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	ticket := base64.RawURLEncoding.EncodeToString(b)
+	if s.rdb != nil {
+		if err := s.rdb.Set(ctx, ticketPrefix+ticket, data, TicketTTL).Err(); err != nil {
+			return "", fmt.Errorf("stream ticket: %w", err)
+		}
+		return ticket, nil
+	}
+	now := s.now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, t := range s.local {
+		if !now.Before(t.expires) {
+			delete(s.local, k)
+		}
+	}
+	s.local[ticket] = localTicket{data: data, expires: now.Add(TicketTTL)}
+	return ticket, nil
+}
+
+// Redeem consumă ticket-ul și întoarce datele salvate la Issue.
+func (s *TicketStore) Redeem(ctx context.Context, ticket string) ([]byte, error) {
+	if ticket == "" {
+		return nil, ErrInvalidTicket
+	}
+	if s.rdb != nil {
+		data, err := s.rdb.GetDel(ctx, ticketPrefix+ticket).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil, ErrInvalidTicket
+		}
+		if err != nil {
+			return nil, fmt.Errorf("stream ticket: %w", err)
+		}
+		return data, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.local[ticket]
+	delete(s.local, ticket)
+	if !ok || !s.now().Before(t.expires) {
+		return nil, ErrInvalidTicket
+	}
+	return t.data, nil
+}
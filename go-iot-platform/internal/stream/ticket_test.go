@@ -0,0 +1,39 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTicketStoreLocal(t *testing.T) {
+	s := NewTicketStore(nil)
+	now := time.Date(2026, 5, 13, 10, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	a, err := s.Issue(ctx, []byte(`{"TenantID":3}`))
+	if err != nil || len(a) < 32 {
+		t.Fatalf("issue: %q %v", a, err)
+	}
+	b, _ := s.Issue(ctx, []byte(`{"TenantID":4}`))
+	if a == b {
+		t.Fatal("tickets must be unique")
+	}
+
+	if data, err := s.Redeem(ctx, a); err != nil || string(data) != `{"TenantID":3}` {
+		t.Errorf("redeem: %s %v", data, err)
+	}
+	if _, err := s.Redeem(ctx, a); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("second redeem: err=%v, want ErrInvalidTicket", err)
+	}
+
+	now = now.Add(TicketTTL)
+	if _, err := s.Redeem(ctx, b); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("expired: err=%v, want ErrInvalidTicket", err)
+	}
+	if _, err := s.Redeem(ctx, ""); !errors.Is(err, ErrInvalidTicket) {
+		t.Errorf("empty: err=%v", err)
+	}
+}